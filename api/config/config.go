@@ -2,9 +2,511 @@ package config
 
 import (
 	"fmt"
+	"havoAPI/internal/version"
+	"math"
+	"net"
 	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"golang.org/x/crypto/bcrypt"
 )
- 
+
+// Config holds every setting the application needs, parsed and validated once at startup.
+// It is populated by Load in main.go and passed to the services, handlers and middlewares
+// through their constructors, so nothing reaches into the environment at request time.
+type Config struct {
+	DBUserName     string // DBUserName is the MySQL user used to connect to the database.
+	DBUserPassword string // DBUserPassword is the password of the MySQL user.
+	DBName         string // DBName is the name of the MySQL database.
+
+	SlowQueryThreshold time.Duration // SlowQueryThreshold is the duration above which a database statement is logged as slow.
+
+	RedisAddr string // RedisAddr is the address (host:port) of the Redis server; optional when CacheEnabled is false.
+	RedisPass string // RedisPass is the password used to authenticate with Redis; optional when CacheEnabled is false.
+
+	RedisDB        int    // RedisDB is the index of the Redis database to use.
+	RedisKeyPrefix string // RedisKeyPrefix is prepended to every Redis key, so environments can share a Redis server.
+
+	PasswordHasher string // PasswordHasher is the algorithm new passwords are hashed with ("bcrypt" or "argon2id").
+	BcryptCost     int    // BcryptCost is the work factor of new bcrypt hashes, within [bcrypt.MinCost, bcrypt.MaxCost].
+
+	JWTSecretKey       string        // JWTSecretKey is the HMAC secret used to sign and verify JWTs.
+	JWTPreviousSecrets []string      // JWTPreviousSecrets are former secrets whose tokens are still accepted during a rotation.
+	JWTTTL             time.Duration // JWTTTL is how long an issued JWT stays valid.
+
+	WeatherAPIKey     string // WeatherAPIKey is the key used to authenticate with WeatherAPI.com.
+	WeatherAPIBaseURL string // WeatherAPIBaseURL is the base URL of the WeatherAPI.com REST API.
+
+	WeatherAPITimeout    time.Duration // WeatherAPITimeout bounds every single attempt of a request to WeatherAPI.
+	WeatherAPIMaxRetries int           // WeatherAPIMaxRetries is how many times a failed WeatherAPI request is retried on transient errors.
+	WeatherAPIUserAgent  string        // WeatherAPIUserAgent is the User-Agent header sent with every request to WeatherAPI.
+
+	WeatherAPIMaxResponseBytes int64 // WeatherAPIMaxResponseBytes caps the size of a WeatherAPI response body read into memory.
+
+	UpstreamBreakerThreshold int           // UpstreamBreakerThreshold is the number of consecutive WeatherAPI failures that opens the circuit; 0 disables it.
+	UpstreamBreakerCooldown  time.Duration // UpstreamBreakerCooldown is how long an open circuit fails fast before a probe request is let through.
+
+	WeatherAPIHistoryDays int // WeatherAPIHistoryDays is how many days back the WeatherAPI plan serves historical weather.
+
+	WeatherAPIBulkEnabled     bool // WeatherAPIBulkEnabled enables the native WeatherAPI bulk endpoint (paid plans only).
+	WeatherAPIBulkBatchSize   int  // WeatherAPIBulkBatchSize is the maximum number of locations sent in a single native bulk call.
+	WeatherAPIBulkConcurrency int  // WeatherAPIBulkConcurrency is the maximum number of native bulk calls in flight for one request.
+
+	Attribution string // Attribution is the data source credit returned in the "source" field of weather responses.
+
+	CacheEnabled     bool          // CacheEnabled caches weather data in Redis; without it every lookup goes to WeatherAPI.
+	CacheTTL         time.Duration // CacheTTL is how long weather data stays in the Redis cache.
+	NegativeCacheTTL time.Duration // NegativeCacheTTL is how long a "location not found" result is remembered.
+	StaleCacheMaxAge time.Duration // StaleCacheMaxAge is how long expired weather data may still be served when WeatherAPI fails; 0 disables it.
+	MinClientMaxAge  time.Duration // MinClientMaxAge is the shortest cache tolerance a client may request with max_age; shorter values are rejected.
+
+	NearestCacheFallback bool    // NearestCacheFallback serves the nearest cached location to coordinate queries when WeatherAPI fails.
+	NearestCacheRadiusKm float64 // NearestCacheRadiusKm is how far away the nearest cached location may be, in kilometers.
+
+	CacheMetricsInterval time.Duration // CacheMetricsInterval is how long a measurement of the weather cache size is reused by the metrics endpoint.
+	CacheRefreshSpec     string        // CacheRefreshSpec is the cron schedule of the periodic cache refresh.
+	WarmCacheOnStart     bool          // WarmCacheOnStart runs the cache refresh once at startup instead of waiting for the first cron tick.
+
+	AppEnv  string // AppEnv names the deployment environment, one of appEnvironments in lower case; it selects the default GinMode.
+	GinMode string // GinMode is the Gin mode ("debug", "release" or "test"); release disables route dumps and debug logs.
+
+	ServerAddr          string        // ServerAddr is the address the HTTP server listens on.
+	ReadHeaderTimeout   time.Duration // ReadHeaderTimeout bounds reading the request headers, cutting off slow-loris clients.
+	ReadTimeout         time.Duration // ReadTimeout bounds reading the whole request, including the body.
+	WriteTimeout        time.Duration // WriteTimeout bounds writing the response; it must leave room for upstream retries.
+	IdleTimeout         time.Duration // IdleTimeout is how long an idle keep-alive connection is kept open.
+	ShutdownDrainPeriod time.Duration // ShutdownDrainPeriod is how long readiness fails before the server stops on shutdown.
+
+	MaxStreamConnections int // MaxStreamConnections caps the concurrent WebSocket and Server-Sent Events connections.
+
+	RedirectTrailingSlash bool // RedirectTrailingSlash redirects a path with an extra or missing trailing slash to the registered route.
+	CaseInsensitiveRoutes bool // CaseInsensitiveRoutes redirects a path differing only in letter case (or uncleaned) to the registered route.
+
+	OTLPEndpoint string // OTLPEndpoint is the OpenTelemetry collector spans are exported to; empty disables the export.
+
+	AdminToken string // AdminToken is the bearer token protecting the admin endpoints; empty disables them.
+
+	MetricsToken string // MetricsToken is the bearer token protecting /metrics (ADMIN_TOKEN by default); empty disables the endpoint.
+
+	TrustedProxies []string // TrustedProxies lists the proxy IPs/CIDRs whose X-Forwarded-For header is trusted.
+
+	AllowedLocations []string // AllowedLocations lists the location names that may be queried; empty with AllowedCountries permits all.
+	AllowedCountries []string // AllowedCountries lists the countries whose locations may be queried.
+
+	RequireHeaderAPIKey bool // RequireHeaderAPIKey rejects API keys passed in the query string, accepting them in headers only.
+
+	AnonymousDailyLimit int            // AnonymousDailyLimit is the number of keyless weather.current requests allowed per client IP and day; 0 requires a key.
+	QuotaResetLocation  *time.Location // QuotaResetLocation is the time zone whose midnight starts a new day for the daily quotas.
+
+	RateLimit       RateLimit            // RateLimit is the service-wide limit of API routes without an override.
+	RouteRateLimits map[string]RateLimit // RouteRateLimits overrides RateLimit for single routes, keyed by route path (e.g. "/api/v1/signup") or method and path (e.g. "POST /api/v1/signup").
+
+	LogRateLimitRejections bool // LogRateLimitRejections logs every request rejected by a rate limiter, for abuse analysis.
+
+	RateLimitPerKey      float64 // RateLimitPerKey is the number of requests per second allowed for a single API key.
+	RateLimitPerKeyBurst int     // RateLimitPerKeyBurst is the maximum burst of requests allowed for a single API key.
+
+	RefreshRateLimitPerUser      float64 // RefreshRateLimitPerUser is the number of forced cache refreshes per second allowed for a single user.
+	RefreshRateLimitPerUserBurst int     // RefreshRateLimitPerUserBurst is the maximum burst of forced cache refreshes allowed for a single user.
+
+	MaxGroupLocations int // MaxGroupLocations is the maximum number of locations in a single saved location group.
+	MaxGroupsPerUser  int // MaxGroupsPerUser is the maximum number of saved location groups a user can own.
+	MaxAPIKeysPerUser int // MaxAPIKeysPerUser is the maximum number of API keys a user can own, including the one created on signup.
+
+	Chaos Chaos // Chaos injects failures into the weather endpoints for client resilience testing; never enabled in production.
+}
+
+// RateLimit is a token bucket setting: Rate requests per second with bursts of up to Burst requests.
+type RateLimit struct {
+	Rate  float64 // Rate is the number of requests per second.
+	Burst int     // Burst is the maximum number of requests allowed at once.
+}
+
+// Chaos configures the fault injection of the weather endpoints, used to test client retries and backoff
+// against a real instance. It is disabled by default and refused when APP_ENV is "production".
+type Chaos struct {
+	Enabled     bool          // Enabled turns the fault injection on (CHAOS_MODE).
+	ErrorRate   float64       // ErrorRate is the fraction of requests answered with a 500 error, from 0 to 1.
+	LatencyRate float64       // LatencyRate is the fraction of requests delayed, from 0 to 1.
+	MaxLatency  time.Duration // MaxLatency is the longest delay added; each delay is picked at random up to it.
+}
+
+// minJWTSecretLength is the minimum length in bytes of JWT_SECRET_KEY, matching the 256-bit output of HS256.
+const minJWTSecretLength = 32
+
+// Load reads all settings from the environment, applies defaults for optional ones
+// and validates them. It returns an error describing the first invalid or missing setting.
+func Load() (*Config, error) {
+	var cfg Config
+	var err error
+
+	// Required settings: the application cannot work without them.
+	required := []struct {
+		key   string
+		value *string
+	}{
+		{"DB_USER_NAME", &cfg.DBUserName},
+		{"DB_USER_PASSWORD", &cfg.DBUserPassword},
+		{"DB_NAME", &cfg.DBName},
+		{"JWT_SECRET_KEY", &cfg.JWTSecretKey},
+		{"API_KEY_FOR_WEATHERAPI", &cfg.WeatherAPIKey},
+	}
+	for _, r := range required {
+		if *r.value, err = LoadEnvironmentVariable(r.key); err != nil {
+			return nil, err
+		}
+	}
+
+	// Reject database settings that can't be expressed in a DSN, instead of failing later with a cryptic connection error.
+	if err := validateDSNComponents(cfg.DBUserName, cfg.DBName); err != nil {
+		return nil, err
+	}
+
+	// Redis is only required while it caches weather data; without the cache, lookups never touch it.
+	if cfg.CacheEnabled, err = loadBoolOrDefault("CACHE_ENABLED", true); err != nil {
+		return nil, err
+	}
+	if cfg.CacheEnabled {
+		if cfg.RedisAddr, err = LoadEnvironmentVariable("REDIS_ADDR"); err != nil {
+			return nil, err
+		}
+		if cfg.RedisPass, err = LoadEnvironmentVariable("REDIS_PASS"); err != nil {
+			return nil, err
+		}
+	} else {
+		cfg.RedisAddr = os.Getenv("REDIS_ADDR")
+		cfg.RedisPass = os.Getenv("REDIS_PASS")
+	}
+
+	// HS256 keys shorter than the hash output make tokens brute-forceable, so refuse to sign with them.
+	if len(cfg.JWTSecretKey) < minJWTSecretLength {
+		return nil, fmt.Errorf("config: JWT_SECRET_KEY must be at least %d bytes long for HS256 (got %d); generate one with `openssl rand -base64 48`", minJWTSecretLength, len(cfg.JWTSecretKey))
+	}
+
+	// Tokens signed with a previous secret stay valid until they expire, so the secret can be rotated without logging everyone out.
+	if cfg.JWTPreviousSecrets, err = loadJWTPreviousSecrets("JWT_PREVIOUS_SECRETS"); err != nil {
+		return nil, err
+	}
+
+	// Optional settings: fall back to defaults matching the previous hardcoded behavior.
+	if cfg.RedisDB, err = loadNonNegativeIntOrDefault("REDIS_DB", 0); err != nil {
+		return nil, err
+	}
+	cfg.RedisKeyPrefix = os.Getenv("REDIS_KEY_PREFIX")
+
+	cfg.WeatherAPIBaseURL = loadEnvironmentVariableOrDefault("WEATHERAPI_BASE_URL", "http://api.weatherapi.com/v1")
+	cfg.WeatherAPIUserAgent = loadEnvironmentVariableOrDefault("WEATHERAPI_USER_AGENT", version.UserAgent())
+
+	if cfg.WeatherAPITimeout, err = loadDurationOrDefault("WEATHERAPI_TIMEOUT", 10*time.Second); err != nil {
+		return nil, err
+	}
+
+	if cfg.WeatherAPIMaxRetries, err = loadNonNegativeIntOrDefault("WEATHERAPI_MAX_RETRIES", 2); err != nil {
+		return nil, err
+	}
+
+	maxResponseBytes, err := loadIntOrDefault("WEATHERAPI_MAX_RESPONSE_BYTES", 4<<20)
+	if err != nil {
+		return nil, err
+	}
+	cfg.WeatherAPIMaxResponseBytes = int64(maxResponseBytes)
+
+	if cfg.UpstreamBreakerThreshold, err = loadNonNegativeIntOrDefault("WEATHERAPI_BREAKER_THRESHOLD", 5); err != nil {
+		return nil, err
+	}
+	if cfg.UpstreamBreakerCooldown, err = loadDurationOrDefault("WEATHERAPI_BREAKER_COOLDOWN", 30*time.Second); err != nil {
+		return nil, err
+	}
+
+	if cfg.WeatherAPIHistoryDays, err = loadIntOrDefault("WEATHERAPI_HISTORY_DAYS", 7); err != nil {
+		return nil, err
+	}
+
+	if cfg.WeatherAPIBulkEnabled, err = loadBoolOrDefault("WEATHERAPI_BULK_ENABLED", false); err != nil {
+		return nil, err
+	}
+
+	if cfg.WeatherAPIBulkBatchSize, err = loadIntOrDefault("WEATHERAPI_BULK_BATCH_SIZE", 50); err != nil {
+		return nil, err
+	}
+
+	if cfg.WeatherAPIBulkConcurrency, err = loadIntOrDefault("WEATHERAPI_BULK_CONCURRENCY", 4); err != nil {
+		return nil, err
+	}
+
+	cfg.Attribution = loadEnvironmentVariableOrDefault("WEATHER_ATTRIBUTION", "Powered by WeatherAPI.com")
+
+	if cfg.SlowQueryThreshold, err = loadDurationOrDefault("SLOW_QUERY_THRESHOLD", 200*time.Millisecond); err != nil {
+		return nil, err
+	}
+
+	if cfg.JWTTTL, err = loadDurationOrDefault("JWT_TTL", 24*time.Hour); err != nil {
+		return nil, err
+	}
+
+	// Hashes of the other algorithm keep verifying, so the hasher can be switched at any time.
+	cfg.PasswordHasher = loadEnvironmentVariableOrDefault("PASSWORD_HASHER", "bcrypt")
+	if cfg.PasswordHasher != "bcrypt" && cfg.PasswordHasher != "argon2id" {
+		return nil, fmt.Errorf("config: environment variable PASSWORD_HASHER must be either bcrypt or argon2id (got %q)", cfg.PasswordHasher)
+	}
+
+	// bcrypt itself rejects costs outside its range, which would only surface on the first signup.
+	if cfg.BcryptCost, err = loadIntOrDefault("BCRYPT_COST", bcrypt.DefaultCost); err != nil {
+		return nil, err
+	}
+	if cfg.BcryptCost < bcrypt.MinCost || cfg.BcryptCost > bcrypt.MaxCost {
+		return nil, fmt.Errorf("config: environment variable BCRYPT_COST must be between %d and %d (got %d)", bcrypt.MinCost, bcrypt.MaxCost, cfg.BcryptCost)
+	}
+
+	if cfg.CacheTTL, err = loadDurationOrDefault("CACHE_TTL", 30*time.Minute); err != nil {
+		return nil, err
+	}
+	// Redis expiries have millisecond precision, and the negative cache needs room below the cache TTL.
+	if cfg.CacheTTL < time.Second {
+		return nil, fmt.Errorf("config: CACHE_TTL (%v) must be at least 1s", cfg.CacheTTL)
+	}
+
+	// The default is shortened for short cache TTLs, so that setting CACHE_TTL alone never fails the check below.
+	if cfg.NegativeCacheTTL, err = loadDurationOrDefault("NEGATIVE_CACHE_TTL", min(2*time.Minute, cfg.CacheTTL/2)); err != nil {
+		return nil, err
+	}
+	if cfg.NegativeCacheTTL >= cfg.CacheTTL {
+		return nil, fmt.Errorf("config: NEGATIVE_CACHE_TTL (%v) must be shorter than CACHE_TTL (%v)", cfg.NegativeCacheTTL, cfg.CacheTTL)
+	}
+
+	if cfg.StaleCacheMaxAge, err = loadNonNegativeDurationOrDefault("STALE_CACHE_MAX_AGE", 0); err != nil {
+		return nil, err
+	}
+
+	if cfg.MinClientMaxAge, err = loadNonNegativeDurationOrDefault("MIN_CLIENT_MAX_AGE", time.Minute); err != nil {
+		return nil, err
+	}
+
+	if cfg.NearestCacheFallback, err = loadBoolOrDefault("NEAREST_CACHE_FALLBACK", false); err != nil {
+		return nil, err
+	}
+	if cfg.NearestCacheRadiusKm, err = loadFloatOrDefault("NEAREST_CACHE_RADIUS_KM", 10); err != nil {
+		return nil, err
+	}
+
+	if cfg.CacheMetricsInterval, err = loadNonNegativeDurationOrDefault("CACHE_METRICS_INTERVAL", time.Minute); err != nil {
+		return nil, err
+	}
+
+	cfg.CacheRefreshSpec = loadEnvironmentVariableOrDefault("CACHE_REFRESH_SCHEDULE", "@every 30m")
+
+	if cfg.WarmCacheOnStart, err = loadBoolOrDefault("WARM_CACHE_ON_STARTUP", false); err != nil {
+		return nil, err
+	}
+
+	if cfg.AppEnv, err = loadAppEnv("APP_ENV"); err != nil {
+		return nil, err
+	}
+	if cfg.GinMode, err = loadGinMode("GIN_MODE", cfg.AppEnv); err != nil {
+		return nil, err
+	}
+
+	// Keep honoring PORT, which gin's router.Run used before the explicit http.Server.
+	cfg.ServerAddr = loadEnvironmentVariableOrDefault("SERVER_ADDR", ":"+loadEnvironmentVariableOrDefault("PORT", "8080"))
+
+	if cfg.ShutdownDrainPeriod, err = loadDurationOrDefault("SHUTDOWN_DRAIN_PERIOD", 5*time.Second); err != nil {
+		return nil, err
+	}
+
+	// Server timeouts: without them a client can hold a connection open indefinitely.
+	if cfg.ReadHeaderTimeout, err = loadDurationOrDefault("SERVER_READ_HEADER_TIMEOUT", 5*time.Second); err != nil {
+		return nil, err
+	}
+
+	if cfg.ReadTimeout, err = loadDurationOrDefault("SERVER_READ_TIMEOUT", 15*time.Second); err != nil {
+		return nil, err
+	}
+
+	if cfg.WriteTimeout, err = loadDurationOrDefault("SERVER_WRITE_TIMEOUT", 60*time.Second); err != nil {
+		return nil, err
+	}
+
+	if cfg.IdleTimeout, err = loadDurationOrDefault("SERVER_IDLE_TIMEOUT", 120*time.Second); err != nil {
+		return nil, err
+	}
+
+	if cfg.MaxStreamConnections, err = loadIntOrDefault("STREAM_MAX_CONNECTIONS", 1000); err != nil {
+		return nil, err
+	}
+
+	if cfg.RedirectTrailingSlash, err = loadBoolOrDefault("ROUTE_REDIRECT_TRAILING_SLASH", true); err != nil {
+		return nil, err
+	}
+	if cfg.CaseInsensitiveRoutes, err = loadBoolOrDefault("ROUTE_CASE_INSENSITIVE", false); err != nil {
+		return nil, err
+	}
+
+	// The standard OpenTelemetry variable, also read by the exporter itself along with the other OTEL_EXPORTER_OTLP_* settings
+	cfg.OTLPEndpoint = os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT")
+
+	cfg.AdminToken = os.Getenv("ADMIN_TOKEN")
+
+	// Scrapers get a token of their own, so the admin token needn't be handed to the monitoring system
+	if cfg.MetricsToken = os.Getenv("METRICS_TOKEN"); cfg.MetricsToken == "" {
+		cfg.MetricsToken = cfg.AdminToken
+	}
+
+	if cfg.TrustedProxies, err = loadTrustedProxies("TRUSTED_PROXIES"); err != nil {
+		return nil, err
+	}
+
+	cfg.AllowedLocations, cfg.AllowedCountries = loadLocationAllowlist("LOCATION_ALLOWLIST")
+
+	if cfg.RequireHeaderAPIKey, err = loadBoolOrDefault("REQUIRE_HEADER_API_KEY", false); err != nil {
+		return nil, err
+	}
+
+	if cfg.AnonymousDailyLimit, err = loadNonNegativeIntOrDefault("ANONYMOUS_DAILY_LIMIT", 0); err != nil {
+		return nil, err
+	}
+	if cfg.QuotaResetLocation, err = loadLocationOrDefault("QUOTA_RESET_TZ", "UTC"); err != nil {
+		return nil, err
+	}
+
+	if cfg.RateLimit.Rate, err = loadFloatOrDefault("RATE_LIMIT", 10); err != nil {
+		return nil, err
+	}
+
+	if cfg.RateLimit.Burst, err = loadIntOrDefault("RATE_LIMIT_BURST", 30); err != nil {
+		return nil, err
+	}
+
+	if cfg.RouteRateLimits, err = loadRouteRateLimits("RATE_LIMIT_ROUTES"); err != nil {
+		return nil, err
+	}
+
+	if cfg.LogRateLimitRejections, err = loadBoolOrDefault("LOG_RATE_LIMIT_REJECTIONS", false); err != nil {
+		return nil, err
+	}
+
+	if cfg.RateLimitPerKey, err = loadFloatOrDefault("RATE_LIMIT_PER_KEY", 1); err != nil {
+		return nil, err
+	}
+
+	if cfg.RateLimitPerKeyBurst, err = loadIntOrDefault("RATE_LIMIT_PER_KEY_BURST", 10); err != nil {
+		return nil, err
+	}
+
+	if cfg.RefreshRateLimitPerUser, err = loadFloatOrDefault("REFRESH_RATE_LIMIT_PER_USER", 0.1); err != nil {
+		return nil, err
+	}
+
+	if cfg.RefreshRateLimitPerUserBurst, err = loadIntOrDefault("REFRESH_RATE_LIMIT_PER_USER_BURST", 3); err != nil {
+		return nil, err
+	}
+
+	// Every location of a group costs upstream quota whenever the group is fetched.
+	if cfg.MaxGroupLocations, err = loadIntOrDefault("MAX_GROUP_LOCATIONS", 50); err != nil {
+		return nil, err
+	}
+	if cfg.MaxGroupsPerUser, err = loadIntOrDefault("MAX_GROUPS_PER_USER", 20); err != nil {
+		return nil, err
+	}
+
+	// Every key is looked up and cached on its own, so users can't mint them without bound.
+	if cfg.MaxAPIKeysPerUser, err = loadIntOrDefault("MAX_API_KEYS_PER_USER", 10); err != nil {
+		return nil, err
+	}
+
+	// Fault injection for resilience testing; it must never reach production.
+	if cfg.Chaos.Enabled, err = loadBoolOrDefault("CHAOS_MODE", false); err != nil {
+		return nil, err
+	}
+	if cfg.Chaos.Enabled && cfg.AppEnv == "production" {
+		return nil, fmt.Errorf("config: CHAOS_MODE can't be enabled when APP_ENV is production")
+	}
+
+	if cfg.Chaos.ErrorRate, err = loadFractionOrDefault("CHAOS_ERROR_RATE", 0.05); err != nil {
+		return nil, err
+	}
+
+	if cfg.Chaos.LatencyRate, err = loadFractionOrDefault("CHAOS_LATENCY_RATE", 0.1); err != nil {
+		return nil, err
+	}
+
+	if cfg.Chaos.MaxLatency, err = loadNonNegativeDurationOrDefault("CHAOS_MAX_LATENCY", 2*time.Second); err != nil {
+		return nil, err
+	}
+
+	return &cfg, nil
+}
+
+// appEnvironments lists the accepted values of APP_ENV. Unknown names (e.g. "prod") are refused rather than
+// treated as non-production, since production-only safeguards such as the CHAOS_MODE refusal depend on the name.
+var appEnvironments = []string{"development", "test", "staging", "production"}
+
+// loadAppEnv reads the deployment environment from the environment variable, defaulting to "development".
+// The name is matched case-insensitively and returned in lower case, so "Production" is production too.
+func loadAppEnv(key string) (string, error) {
+	appEnv := strings.ToLower(strings.TrimSpace(loadEnvironmentVariableOrDefault(key, "development")))
+	if !slices.Contains(appEnvironments, appEnv) {
+		return "", fmt.Errorf("config: environment variable %s must be one of %s (got %q)", key, strings.Join(appEnvironments, ", "), os.Getenv(key))
+	}
+	return appEnv, nil
+}
+
+// loadGinMode reads the Gin mode from the environment variable. When it is not set, production
+// environments run in release mode and all others in debug mode, like Gin's own default.
+func loadGinMode(key, appEnv string) (string, error) {
+	mode := os.Getenv(key)
+	if mode == "" {
+		if appEnv == "production" {
+			return "release", nil
+		}
+		return "debug", nil
+	}
+
+	switch mode {
+	case "debug", "release", "test":
+		return mode, nil
+	}
+	return "", fmt.Errorf("config: environment variable %s must be one of debug, release or test (got %q)", key, mode)
+}
+
+// JWTVerificationKeys returns the secrets JWTs are verified with: the current secret first, then the previous ones.
+func (cfg *Config) JWTVerificationKeys() []string {
+	return append([]string{cfg.JWTSecretKey}, cfg.JWTPreviousSecrets...)
+}
+
+// DSN builds the Data Source Name used to connect to the MySQL database.
+// It is formatted by the MySQL driver itself, so that it always parses back into the same settings:
+// the password may contain any character (including '@', '/', ':' and '?'), while the user and database
+// names are checked by validateDSNComponents when the config is loaded.
+func (cfg *Config) DSN() string {
+	dsn := mysql.NewConfig()
+	dsn.User = cfg.DBUserName
+	dsn.Passwd = cfg.DBUserPassword
+	dsn.DBName = cfg.DBName
+	dsn.ParseTime = true
+	return dsn.FormatDSN()
+}
+
+// validateDSNComponents checks the parts of the DSN that the MySQL driver can't escape.
+// The user name ends at its first ':' and the database name at its first '?', so those characters
+// would silently be read as the password and the parameters. Database names follow MySQL's own rules:
+// at most 64 characters, and no '/', '\' or '.'.
+func validateDSNComponents(user, dbName string) error {
+	if strings.Contains(user, ":") {
+		return fmt.Errorf("config: DB_USER_NAME must not contain ':', which separates the user from the password in the DSN")
+	}
+	if i := strings.IndexAny(dbName, "/\\.?"); i >= 0 {
+		return fmt.Errorf("config: DB_NAME must not contain %q, which is not allowed in a MySQL database name", dbName[i])
+	}
+	if len(dbName) > 64 {
+		return fmt.Errorf("config: DB_NAME must be at most 64 characters long, got %d", len(dbName))
+	}
+	return nil
+}
+
 // LoadEnvironmentVariable retrieves the value of an environment variable by its key.
 // It returns the value of the environment variable as a string if it exists,
 // or an error if the variable is not set or is empty.
@@ -20,3 +522,274 @@ func LoadEnvironmentVariable(key string) (string, error) {
 	// Return the environment variable value if found.
 	return value, nil
 }
+
+// loadEnvironmentVariableOrDefault retrieves the value of an environment variable by its key,
+// returning the provided default value if the variable is not set or is empty.
+func loadEnvironmentVariableOrDefault(key, defaultValue string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return defaultValue
+}
+
+// loadDurationOrDefault parses an environment variable as a time.Duration (e.g. "30m", "24h"),
+// returning the default value if the variable is not set and an error if it is malformed or not positive.
+func loadDurationOrDefault(key string, defaultValue time.Duration) (time.Duration, error) {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue, nil
+	}
+
+	duration, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("config: invalid duration in environment variable %s: %v", key, err)
+	}
+	if duration <= 0 {
+		return 0, fmt.Errorf("config: environment variable %s must be a positive duration", key)
+	}
+
+	return duration, nil
+}
+
+// loadNonNegativeDurationOrDefault parses an environment variable as a time.Duration that may be zero,
+// for settings where zero disables a feature. It returns the default value if the variable is not set
+// and an error if it is malformed or negative.
+func loadNonNegativeDurationOrDefault(key string, defaultValue time.Duration) (time.Duration, error) {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue, nil
+	}
+
+	duration, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("config: invalid duration in environment variable %s: %v", key, err)
+	}
+	if duration < 0 {
+		return 0, fmt.Errorf("config: environment variable %s must not be negative", key)
+	}
+
+	return duration, nil
+}
+
+// loadIntOrDefault parses an environment variable as a positive integer,
+// returning the default value if the variable is not set and an error if it is malformed or not positive.
+func loadIntOrDefault(key string, defaultValue int) (int, error) {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue, nil
+	}
+
+	number, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("config: invalid integer in environment variable %s: %v", key, err)
+	}
+	if number <= 0 {
+		return 0, fmt.Errorf("config: environment variable %s must be a positive integer", key)
+	}
+
+	return number, nil
+}
+
+// loadNonNegativeIntOrDefault parses an environment variable as a non-negative integer (e.g. an index),
+// returning the default value if the variable is not set and an error if it is malformed or negative.
+func loadNonNegativeIntOrDefault(key string, defaultValue int) (int, error) {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue, nil
+	}
+
+	number, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("config: invalid integer in environment variable %s: %v", key, err)
+	}
+	if number < 0 {
+		return 0, fmt.Errorf("config: environment variable %s must not be negative", key)
+	}
+
+	return number, nil
+}
+
+// loadFloatOrDefault parses an environment variable as a positive floating-point number,
+// returning the default value if the variable is not set and an error if it is malformed or not positive.
+func loadFloatOrDefault(key string, defaultValue float64) (float64, error) {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue, nil
+	}
+
+	number, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0, fmt.Errorf("config: invalid number in environment variable %s: %v", key, err)
+	}
+	// ParseFloat accepts "NaN" and "Inf", which no setting can use
+	if math.IsNaN(number) || math.IsInf(number, 0) || number <= 0 {
+		return 0, fmt.Errorf("config: environment variable %s must be a positive number", key)
+	}
+
+	return number, nil
+}
+
+// loadFractionOrDefault parses an environment variable as a fraction between 0 and 1 (e.g. "0.05" for 5%),
+// returning the default value if the variable is not set and an error if it is malformed or out of range.
+func loadFractionOrDefault(key string, defaultValue float64) (float64, error) {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue, nil
+	}
+
+	fraction, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0, fmt.Errorf("config: invalid number in environment variable %s: %v", key, err)
+	}
+	if fraction < 0 || fraction > 1 {
+		return 0, fmt.Errorf("config: environment variable %s must be between 0 and 1", key)
+	}
+
+	return fraction, nil
+}
+
+// loadTrustedProxies parses a comma-separated list of proxy IP addresses or CIDR ranges.
+// An unset variable yields an empty list, meaning no proxy is trusted and X-Forwarded-For is ignored.
+func loadTrustedProxies(key string) ([]string, error) {
+	var proxies []string
+
+	for _, proxy := range strings.Split(os.Getenv(key), ",") {
+		proxy = strings.TrimSpace(proxy)
+		if proxy == "" {
+			continue
+		}
+
+		// Each entry must be either a plain IP address or a CIDR range
+		if net.ParseIP(proxy) == nil {
+			if _, _, err := net.ParseCIDR(proxy); err != nil {
+				return nil, fmt.Errorf("config: invalid proxy %q in environment variable %s", proxy, key)
+			}
+		}
+		proxies = append(proxies, proxy)
+	}
+
+	return proxies, nil
+}
+
+// loadJWTPreviousSecrets parses a comma-separated list of former JWT secrets, each held to the same minimum length
+// as JWT_SECRET_KEY. An unset variable yields an empty list, accepting tokens signed with the current secret only.
+func loadJWTPreviousSecrets(key string) ([]string, error) {
+	var secrets []string
+
+	for i, secret := range strings.Split(os.Getenv(key), ",") {
+		secret = strings.TrimSpace(secret)
+		if secret == "" {
+			continue
+		}
+		if len(secret) < minJWTSecretLength {
+			return nil, fmt.Errorf("config: secret #%d in environment variable %s must be at least %d bytes long for HS256 (got %d)", i+1, key, minJWTSecretLength, len(secret))
+		}
+		secrets = append(secrets, secret)
+	}
+
+	return secrets, nil
+}
+
+// rateLimitMethods lists the HTTP methods a per-route rate limit may be restricted to.
+var rateLimitMethods = []string{"GET", "HEAD", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"}
+
+// loadRouteRateLimits parses a comma-separated list of per-route rate limits in the form "[<method> ]<path>=<rate>:<burst>",
+// e.g. "POST /api/v1/signup=0.1:3,/api/v1/weather.current=20:40". Paths are the registered route patterns,
+// including parameters such as ":name". An entry without a method applies to every method of the path.
+// The limits are keyed by "<METHOD> <path>" or "<path>". An unset variable yields no overrides.
+func loadRouteRateLimits(key string) (map[string]RateLimit, error) {
+	limits := make(map[string]RateLimit)
+
+	for _, entry := range strings.Split(os.Getenv(key), ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		route, setting, ok := strings.Cut(entry, "=")
+		rateValue, burstValue, hasBurst := strings.Cut(setting, ":")
+		method, path, hasMethod := strings.Cut(strings.TrimSpace(route), " ")
+		if !hasMethod {
+			method, path = "", method
+		}
+		path = strings.TrimSpace(path)
+		if !ok || !strings.HasPrefix(path, "/") || !hasBurst {
+			return nil, fmt.Errorf("config: invalid entry %q in environment variable %s: want [<method> ]<path>=<rate>:<burst>", entry, key)
+		}
+		if hasMethod {
+			method = strings.ToUpper(method)
+			if !slices.Contains(rateLimitMethods, method) {
+				return nil, fmt.Errorf("config: invalid method %q in entry %q of environment variable %s: must be one of %s", method, entry, key, strings.Join(rateLimitMethods, ", "))
+			}
+			route = method + " " + path
+		} else {
+			route = path
+		}
+
+		rate, err := strconv.ParseFloat(rateValue, 64)
+		if err != nil || math.IsNaN(rate) || math.IsInf(rate, 0) || rate <= 0 {
+			return nil, fmt.Errorf("config: invalid rate %q for %s in environment variable %s: must be a positive number", rateValue, route, key)
+		}
+		burst, err := strconv.Atoi(burstValue)
+		if err != nil || burst <= 0 {
+			return nil, fmt.Errorf("config: invalid burst %q for %s in environment variable %s: must be a positive integer", burstValue, route, key)
+		}
+
+		limits[route] = RateLimit{Rate: rate, Burst: burst}
+	}
+
+	return limits, nil
+}
+
+// loadLocationAllowlist parses a comma-separated list of allowed locations.
+// Entries prefixed with "country:" (e.g. "country:Uzbekistan") allow every location of that country;
+// other entries are exact location names. An unset variable yields empty lists, permitting every location.
+func loadLocationAllowlist(key string) ([]string, []string) {
+	var locations, countries []string
+
+	for _, entry := range strings.Split(os.Getenv(key), ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		if country, ok := strings.CutPrefix(entry, "country:"); ok {
+			if country = strings.TrimSpace(country); country != "" {
+				countries = append(countries, country)
+			}
+			continue
+		}
+		locations = append(locations, entry)
+	}
+
+	return locations, countries
+}
+
+// loadBoolOrDefault parses an environment variable as a boolean ("true", "false", "1", "0", ...),
+// returning the default value if the variable is not set and an error if it is malformed.
+func loadBoolOrDefault(key string, defaultValue bool) (bool, error) {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue, nil
+	}
+
+	flag, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, fmt.Errorf("config: invalid boolean in environment variable %s: %v", key, err)
+	}
+
+	return flag, nil
+}
+
+// loadLocationOrDefault parses an environment variable as an IANA time zone name (e.g. "Asia/Tashkent"),
+// returning the default zone if the variable is not set and an error if the zone is unknown.
+func loadLocationOrDefault(key string, defaultValue string) (*time.Location, error) {
+	value := loadEnvironmentVariableOrDefault(key, defaultValue)
+
+	location, err := time.LoadLocation(value)
+	if err != nil {
+		return nil, fmt.Errorf("config: invalid time zone in environment variable %s: %v", key, err)
+	}
+
+	return location, nil
+}
@@ -0,0 +1,392 @@
+package config
+
+import (
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// setRequiredEnv sets every required environment variable to a valid value for the duration of the test.
+func setRequiredEnv(t *testing.T) {
+	t.Helper()
+	t.Setenv("DB_USER_NAME", "havo")
+	t.Setenv("DB_USER_PASSWORD", "secret")
+	t.Setenv("DB_NAME", "havo")
+	t.Setenv("JWT_SECRET_KEY", strings.Repeat("k", minJWTSecretLength))
+	t.Setenv("API_KEY_FOR_WEATHERAPI", "upstream-secret")
+	t.Setenv("REDIS_ADDR", "localhost:6379")
+	t.Setenv("REDIS_PASS", "secret")
+}
+
+func TestLoadNegativeCacheTTL(t *testing.T) {
+	tests := []struct {
+		name        string
+		cacheTTL    string
+		negativeTTL string
+		want        time.Duration
+		wantErr     bool
+	}{
+		{name: "defaults", want: 2 * time.Minute},
+		{name: "long cache ttl keeps the default", cacheTTL: "1h", want: 2 * time.Minute},
+		{name: "short cache ttl shortens the default", cacheTTL: "2m", want: time.Minute},
+		{name: "very short cache ttl", cacheTTL: "90s", want: 45 * time.Second},
+		{name: "explicit value", cacheTTL: "10m", negativeTTL: "30s", want: 30 * time.Second},
+		{name: "explicit value equal to the cache ttl", cacheTTL: "2m", negativeTTL: "2m", wantErr: true},
+		{name: "explicit value longer than the cache ttl", cacheTTL: "1m", negativeTTL: "2m", wantErr: true},
+		{name: "cache ttl below a second", cacheTTL: "500ms", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setRequiredEnv(t)
+			t.Setenv("CACHE_TTL", tt.cacheTTL)
+			t.Setenv("NEGATIVE_CACHE_TTL", tt.negativeTTL)
+
+			cfg, err := Load()
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("Load() succeeded with NEGATIVE_CACHE_TTL %v", cfg.NegativeCacheTTL)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Load() failed: %v", err)
+			}
+			if cfg.NegativeCacheTTL != tt.want {
+				t.Errorf("NegativeCacheTTL = %v, want %v", cfg.NegativeCacheTTL, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadPositiveIntSettings(t *testing.T) {
+	tests := []struct {
+		env          string
+		field        func(cfg *Config) int
+		defaultValue int
+	}{
+		{env: "MAX_API_KEYS_PER_USER", field: func(cfg *Config) int { return cfg.MaxAPIKeysPerUser }, defaultValue: 10},
+		{env: "STREAM_MAX_CONNECTIONS", field: func(cfg *Config) int { return cfg.MaxStreamConnections }, defaultValue: 1000},
+		{env: "MAX_GROUP_LOCATIONS", field: func(cfg *Config) int { return cfg.MaxGroupLocations }, defaultValue: 50},
+		{env: "MAX_GROUPS_PER_USER", field: func(cfg *Config) int { return cfg.MaxGroupsPerUser }, defaultValue: 20},
+		{env: "WEATHERAPI_MAX_RESPONSE_BYTES", field: func(cfg *Config) int { return int(cfg.WeatherAPIMaxResponseBytes) }, defaultValue: 4 << 20},
+	}
+	for _, tt := range tests {
+		t.Run(tt.env, func(t *testing.T) {
+			for value, want := range map[string]int{"": tt.defaultValue, "1": 1, "25": 25} {
+				setRequiredEnv(t)
+				t.Setenv(tt.env, value)
+				cfg, err := Load()
+				if err != nil {
+					t.Fatalf("Load() with %s=%q failed: %v", tt.env, value, err)
+				}
+				if got := tt.field(cfg); got != want {
+					t.Errorf("%s=%q loaded as %d, want %d", tt.env, value, got, want)
+				}
+			}
+			for _, value := range []string{"0", "-1", "ten", "1.5"} {
+				setRequiredEnv(t)
+				t.Setenv(tt.env, value)
+				if _, err := Load(); err == nil || !strings.Contains(err.Error(), tt.env) {
+					t.Errorf("Load() with %s=%q returned %v, want an error naming the setting", tt.env, value, err)
+				}
+			}
+		})
+	}
+}
+
+func TestLoadDSNComponents(t *testing.T) {
+	tests := []struct {
+		name     string
+		user     string
+		password string
+		dbName   string
+		wantErr  string // wantErr is the setting named by the error, if loading fails.
+	}{
+		{name: "plain", user: "havo", password: "secret", dbName: "havo"},
+		{name: "password with separators", user: "havo", password: "p@ss/w:rd?x=1", dbName: "havo"},
+		{name: "user with '@' and '/'", user: "ha@v/o", password: "secret", dbName: "havo_db-1"},
+		{name: "longest database name", user: "havo", password: "secret", dbName: strings.Repeat("d", 64)},
+		{name: "user with ':'", user: "ha:vo", password: "secret", dbName: "havo", wantErr: "DB_USER_NAME"},
+		{name: "database name with '?'", user: "havo", password: "secret", dbName: "havo?tls=false", wantErr: "DB_NAME"},
+		{name: "database name with '/'", user: "havo", password: "secret", dbName: "ha/vo", wantErr: "DB_NAME"},
+		{name: "database name with '\\'", user: "havo", password: "secret", dbName: "ha\\vo", wantErr: "DB_NAME"},
+		{name: "database name with '.'", user: "havo", password: "secret", dbName: "ha.vo", wantErr: "DB_NAME"},
+		{name: "database name too long", user: "havo", password: "secret", dbName: strings.Repeat("d", 65), wantErr: "DB_NAME"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setRequiredEnv(t)
+			t.Setenv("DB_USER_NAME", tt.user)
+			t.Setenv("DB_USER_PASSWORD", tt.password)
+			t.Setenv("DB_NAME", tt.dbName)
+
+			cfg, err := Load()
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("Load() returned %v, want an error naming %s", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Load() failed: %v", err)
+			}
+
+			// The DSN parses back into the very same settings
+			dsn, err := mysql.ParseDSN(cfg.DSN())
+			if err != nil {
+				t.Fatalf("DSN %q doesn't parse: %v", cfg.DSN(), err)
+			}
+			if dsn.User != tt.user || dsn.Passwd != tt.password || dsn.DBName != tt.dbName || !dsn.ParseTime {
+				t.Errorf("DSN parses as user %q, password %q and database %q, want %q, %q and %q", dsn.User, dsn.Passwd, dsn.DBName, tt.user, tt.password, tt.dbName)
+			}
+		})
+	}
+}
+
+func TestLoadNearestCacheRadius(t *testing.T) {
+	for value, want := range map[string]float64{"": 10, "2.5": 2.5, "50": 50} {
+		setRequiredEnv(t)
+		t.Setenv("NEAREST_CACHE_RADIUS_KM", value)
+		cfg, err := Load()
+		if err != nil {
+			t.Fatalf("Load() with NEAREST_CACHE_RADIUS_KM=%q failed: %v", value, err)
+		}
+		if cfg.NearestCacheRadiusKm != want {
+			t.Errorf("NEAREST_CACHE_RADIUS_KM=%q loaded as %v, want %v", value, cfg.NearestCacheRadiusKm, want)
+		}
+	}
+	// A negative or NaN radius would match no entry at all, silently disabling the fallback
+	for _, value := range []string{"0", "-5", "NaN", "Inf", "-Inf", "ten"} {
+		setRequiredEnv(t)
+		t.Setenv("NEAREST_CACHE_RADIUS_KM", value)
+		if _, err := Load(); err == nil || !strings.Contains(err.Error(), "NEAREST_CACHE_RADIUS_KM") {
+			t.Errorf("Load() with NEAREST_CACHE_RADIUS_KM=%q returned %v, want an error naming the setting", value, err)
+		}
+	}
+}
+
+func TestLoadBcryptCost(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    int
+		wantErr bool
+	}{
+		{value: "", want: bcrypt.DefaultCost},
+		{value: strconv.Itoa(bcrypt.MinCost), want: bcrypt.MinCost},
+		{value: strconv.Itoa(bcrypt.MaxCost), want: bcrypt.MaxCost},
+		{value: strconv.Itoa(bcrypt.MinCost - 1), wantErr: true},
+		{value: strconv.Itoa(bcrypt.MaxCost + 1), wantErr: true},
+		{value: "0", wantErr: true},
+		{value: "high", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			setRequiredEnv(t)
+			t.Setenv("BCRYPT_COST", tt.value)
+
+			cfg, err := Load()
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("Load() succeeded with BCRYPT_COST %d", cfg.BcryptCost)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Load() failed: %v", err)
+			}
+			if cfg.BcryptCost != tt.want {
+				t.Errorf("BcryptCost = %d, want %d", cfg.BcryptCost, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadGlobalRateLimit(t *testing.T) {
+	for _, env := range []string{"RATE_LIMIT", "RATE_LIMIT_BURST"} {
+		for _, value := range []string{"0", "-1", "NaN", "Inf", "fast"} {
+			t.Run(env+"="+value, func(t *testing.T) {
+				setRequiredEnv(t)
+				t.Setenv(env, value)
+				if _, err := Load(); err == nil || !strings.Contains(err.Error(), env) {
+					t.Errorf("Load() returned %v, want an error naming %s", err, env)
+				}
+			})
+		}
+	}
+
+	setRequiredEnv(t)
+	t.Setenv("RATE_LIMIT", "2.5")
+	t.Setenv("RATE_LIMIT_BURST", "5")
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	if want := (RateLimit{Rate: 2.5, Burst: 5}); cfg.RateLimit != want {
+		t.Errorf("RateLimit = %+v, want %+v", cfg.RateLimit, want)
+	}
+}
+
+func TestLoadRouteRateLimits(t *testing.T) {
+	t.Setenv("RATE_LIMIT_ROUTES", " POST /api/v1/signup=0.1:3, get /api/v1/weather=5:10,/api/v1/login=0.5:5")
+	limits, err := loadRouteRateLimits("RATE_LIMIT_ROUTES")
+	if err != nil {
+		t.Fatalf("loadRouteRateLimits() failed: %v", err)
+	}
+	want := map[string]RateLimit{
+		"POST /api/v1/signup": {Rate: 0.1, Burst: 3},
+		"GET /api/v1/weather": {Rate: 5, Burst: 10},
+		"/api/v1/login":       {Rate: 0.5, Burst: 5},
+	}
+	if len(limits) != len(want) {
+		t.Fatalf("limits = %v, want %v", limits, want)
+	}
+	for route, limit := range want {
+		if limits[route] != limit {
+			t.Errorf("limits[%q] = %+v, want %+v", route, limits[route], limit)
+		}
+	}
+
+	for _, value := range []string{
+		"FETCH /api/v1/signup=1:1",
+		"POST api/v1/signup=1:1",
+		"/api/v1/signup=1",
+		"/api/v1/signup=NaN:1",
+		"/api/v1/signup=0:1",
+		"/api/v1/signup=1:0",
+	} {
+		t.Setenv("RATE_LIMIT_ROUTES", value)
+		if _, err := loadRouteRateLimits("RATE_LIMIT_ROUTES"); err == nil {
+			t.Errorf("loadRouteRateLimits() accepted %q", value)
+		}
+	}
+}
+
+func TestJWTVerificationKeys(t *testing.T) {
+	current := strings.Repeat("k", minJWTSecretLength)
+	previous := strings.Repeat("p", minJWTSecretLength)
+	older := strings.Repeat("o", minJWTSecretLength)
+
+	setRequiredEnv(t)
+	t.Setenv("JWT_PREVIOUS_SECRETS", previous+", ,"+older)
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	want := []string{current, previous, older}
+	if got := cfg.JWTVerificationKeys(); !slices.Equal(got, want) {
+		t.Errorf("JWTVerificationKeys() = %q, want %q", got, want)
+	}
+
+	// A previous secret is held to the same minimum length as the current one
+	t.Setenv("JWT_PREVIOUS_SECRETS", "short")
+	if _, err := Load(); err == nil {
+		t.Error("Load() accepted a previous secret below the minimum length")
+	}
+}
+
+func TestLoadMetricsToken(t *testing.T) {
+	tests := []struct {
+		name         string
+		adminToken   string
+		metricsToken string
+		want         string
+	}{
+		{name: "own token", adminToken: "admin-token", metricsToken: "metrics-token", want: "metrics-token"},
+		{name: "defaults to the admin token", adminToken: "admin-token", want: "admin-token"},
+		{name: "disabled without either", want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setRequiredEnv(t)
+			t.Setenv("ADMIN_TOKEN", tt.adminToken)
+			t.Setenv("METRICS_TOKEN", tt.metricsToken)
+			cfg, err := Load()
+			if err != nil {
+				t.Fatalf("Load() failed: %v", err)
+			}
+			if cfg.MetricsToken != tt.want {
+				t.Errorf("MetricsToken = %q, want %q", cfg.MetricsToken, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadRouteRedirects(t *testing.T) {
+	setRequiredEnv(t)
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	// Gin's trailing slash redirect stays on, and letter case matters unless asked otherwise
+	if !cfg.RedirectTrailingSlash || cfg.CaseInsensitiveRoutes {
+		t.Errorf("trailing slash redirect %v and case-insensitive routes %v by default, want true and false", cfg.RedirectTrailingSlash, cfg.CaseInsensitiveRoutes)
+	}
+
+	t.Setenv("ROUTE_REDIRECT_TRAILING_SLASH", "false")
+	t.Setenv("ROUTE_CASE_INSENSITIVE", "true")
+	if cfg, err = Load(); err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	if cfg.RedirectTrailingSlash || !cfg.CaseInsensitiveRoutes {
+		t.Errorf("trailing slash redirect %v and case-insensitive routes %v, want false and true", cfg.RedirectTrailingSlash, cfg.CaseInsensitiveRoutes)
+	}
+}
+
+func TestLoadAppEnv(t *testing.T) {
+	tests := []struct {
+		value       string
+		want        string
+		wantGinMode string
+		wantErr     bool
+	}{
+		{value: "", want: "development", wantGinMode: "debug"},
+		{value: "staging", want: "staging", wantGinMode: "debug"},
+		{value: "production", want: "production", wantGinMode: "release"},
+		{value: "Production", want: "production", wantGinMode: "release"},
+		{value: " PRODUCTION ", want: "production", wantGinMode: "release"},
+		{value: "prod", wantErr: true},
+		{value: "live", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			setRequiredEnv(t)
+			t.Setenv("APP_ENV", tt.value)
+			cfg, err := Load()
+			if tt.wantErr {
+				if err == nil || !strings.Contains(err.Error(), "APP_ENV") {
+					t.Fatalf("Load() returned %v, want an error naming APP_ENV", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Load() failed: %v", err)
+			}
+			if cfg.AppEnv != tt.want || cfg.GinMode != tt.wantGinMode {
+				t.Errorf("AppEnv, GinMode = %q, %q, want %q, %q", cfg.AppEnv, cfg.GinMode, tt.want, tt.wantGinMode)
+			}
+		})
+	}
+}
+
+func TestChaosModeIsRefusedInProduction(t *testing.T) {
+	for _, appEnv := range []string{"production", "Production", "PRODUCTION"} {
+		setRequiredEnv(t)
+		t.Setenv("APP_ENV", appEnv)
+		t.Setenv("CHAOS_MODE", "true")
+		if _, err := Load(); err == nil || !strings.Contains(err.Error(), "CHAOS_MODE") {
+			t.Errorf("Load() with APP_ENV=%q returned %v, want CHAOS_MODE refused", appEnv, err)
+		}
+	}
+
+	setRequiredEnv(t)
+	t.Setenv("APP_ENV", "staging")
+	t.Setenv("CHAOS_MODE", "true")
+	if _, err := Load(); err != nil {
+		t.Errorf("Load() with CHAOS_MODE in staging failed: %v", err)
+	}
+}
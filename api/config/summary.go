@@ -0,0 +1,130 @@
+package config
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// redacted replaces a secret value in the config summary. Unset secrets are reported as such,
+// so the summary still tells whether a secret was provided without revealing it.
+func redacted(secret string) string {
+	if secret == "" {
+		return "(not set)"
+	}
+	return "[REDACTED]"
+}
+
+// enabled renders a feature flag in the config summary.
+func enabled(on bool) string {
+	if on {
+		return "enabled"
+	}
+	return "disabled"
+}
+
+// routeRateLimits renders the per-route rate limit overrides in the config summary, sorted by route.
+func routeRateLimits(limits map[string]RateLimit) string {
+	if len(limits) == 0 {
+		return "none"
+	}
+
+	routes := make([]string, 0, len(limits))
+	for route, limit := range limits {
+		routes = append(routes, fmt.Sprintf("%s %v req/s burst %d", route, limit.Rate, limit.Burst))
+	}
+	sort.Strings(routes)
+	return strings.Join(routes, ", ")
+}
+
+// chaos renders the fault injection settings in the config summary; the rates only matter when it is enabled.
+func chaos(c Chaos) string {
+	if !c.Enabled {
+		return enabled(false)
+	}
+	return fmt.Sprintf("ENABLED (%v%% errors, %v%% delayed up to %v)", c.ErrorRate*100, c.LatencyRate*100, c.MaxLatency)
+}
+
+// anonymousLimit renders the keyless request allowance in the config summary.
+func anonymousLimit(limit int) string {
+	if limit == 0 {
+		return "disabled (API key required)"
+	}
+	return fmt.Sprintf("%d requests per IP and day", limit)
+}
+
+// circuitBreaker renders the WeatherAPI circuit breaker settings in the config summary; a threshold of 0 disables it.
+func circuitBreaker(threshold int, cooldown time.Duration) string {
+	if threshold == 0 {
+		return "disabled"
+	}
+	return fmt.Sprintf("opens after %d consecutive failures for %v", threshold, cooldown)
+}
+
+// tracing describes where OpenTelemetry spans are exported to.
+func tracing(endpoint string) string {
+	if endpoint == "" {
+		return "disabled"
+	}
+	return "OTLP to " + endpoint
+}
+
+// Summary renders the effective config as human-readable lines for the startup log.
+// Every secret (DB password, Redis password, JWT secret, WeatherAPI key, admin token) is redacted.
+func (cfg *Config) Summary() string {
+	lines := []struct {
+		name  string
+		value any
+	}{
+		{"environment", fmt.Sprintf("%s (gin mode %s)", cfg.AppEnv, cfg.GinMode)},
+		{"bind address", cfg.ServerAddr},
+		{"server timeouts", fmt.Sprintf("read header %v, read %v, write %v, idle %v", cfg.ReadHeaderTimeout, cfg.ReadTimeout, cfg.WriteTimeout, cfg.IdleTimeout)},
+		{"shutdown drain period", cfg.ShutdownDrainPeriod},
+		{"stream connections", fmt.Sprintf("at most %d", cfg.MaxStreamConnections)},
+		{"tracing", tracing(cfg.OTLPEndpoint)},
+		{"route redirects", fmt.Sprintf("trailing slash %s, case-insensitive %s", enabled(cfg.RedirectTrailingSlash), enabled(cfg.CaseInsensitiveRoutes))},
+		{"database", fmt.Sprintf("%s@/%s (password %s)", cfg.DBUserName, cfg.DBName, redacted(cfg.DBUserPassword))},
+		{"slow query threshold", cfg.SlowQueryThreshold},
+		{"redis address", fmt.Sprintf("%s (password %s)", cfg.RedisAddr, redacted(cfg.RedisPass))},
+		{"redis database", fmt.Sprintf("%d (key prefix %q)", cfg.RedisDB, cfg.RedisKeyPrefix)},
+		{"weatherapi", fmt.Sprintf("%s (key %s)", cfg.WeatherAPIBaseURL, redacted(cfg.WeatherAPIKey))},
+		{"weatherapi requests", fmt.Sprintf("timeout %v, %d retries, user agent %q, responses up to %d bytes", cfg.WeatherAPITimeout, cfg.WeatherAPIMaxRetries, cfg.WeatherAPIUserAgent, cfg.WeatherAPIMaxResponseBytes)},
+		{"weatherapi circuit breaker", circuitBreaker(cfg.UpstreamBreakerThreshold, cfg.UpstreamBreakerCooldown)},
+		{"weatherapi history", fmt.Sprintf("last %d days", cfg.WeatherAPIHistoryDays)},
+		{"weatherapi bulk endpoint", fmt.Sprintf("%s (batches of %d, %d concurrent)", enabled(cfg.WeatherAPIBulkEnabled), cfg.WeatherAPIBulkBatchSize, cfg.WeatherAPIBulkConcurrency)},
+		{"attribution", fmt.Sprintf("%q", cfg.Attribution)},
+		{"password hasher", cfg.PasswordHasher},
+		{"bcrypt cost", cfg.BcryptCost},
+		{"jwt", fmt.Sprintf("ttl %v (secret %s, %d previous secrets accepted)", cfg.JWTTTL, redacted(cfg.JWTSecretKey), len(cfg.JWTPreviousSecrets))},
+		{"weather cache", enabled(cfg.CacheEnabled)},
+		{"cache ttl", cfg.CacheTTL},
+		{"negative cache ttl", cfg.NegativeCacheTTL},
+		{"stale cache max age", cfg.StaleCacheMaxAge},
+		{"min client max age", cfg.MinClientMaxAge},
+		{"nearest cache fallback", fmt.Sprintf("%s (within %v km)", enabled(cfg.NearestCacheFallback), cfg.NearestCacheRadiusKm)},
+		{"cache metrics interval", cfg.CacheMetricsInterval},
+		{"cache refresh schedule", fmt.Sprintf("%s (warm on startup %s)", cfg.CacheRefreshSpec, enabled(cfg.WarmCacheOnStart))},
+		{"header-only api keys", enabled(cfg.RequireHeaderAPIKey)},
+		{"anonymous daily limit", anonymousLimit(cfg.AnonymousDailyLimit)},
+		{"quota reset time zone", cfg.QuotaResetLocation},
+		{"rate limit", fmt.Sprintf("%v req/s, burst %d (route overrides %s)", cfg.RateLimit.Rate, cfg.RateLimit.Burst, routeRateLimits(cfg.RouteRateLimits))},
+		{"per-key rate limit", fmt.Sprintf("%v req/s, burst %d", cfg.RateLimitPerKey, cfg.RateLimitPerKeyBurst)},
+		{"forced refresh limit", fmt.Sprintf("%v req/s per user, burst %d", cfg.RefreshRateLimitPerUser, cfg.RefreshRateLimitPerUserBurst)},
+		{"max locations per group", cfg.MaxGroupLocations},
+		{"max groups per user", cfg.MaxGroupsPerUser},
+		{"max api keys per user", cfg.MaxAPIKeysPerUser},
+		{"rate limit rejection log", enabled(cfg.LogRateLimitRejections)},
+		{"chaos mode", chaos(cfg.Chaos)},
+		{"trusted proxies", fmt.Sprintf("%v", cfg.TrustedProxies)},
+		{"location allowlist", fmt.Sprintf("locations %v, countries %v", cfg.AllowedLocations, cfg.AllowedCountries)},
+		{"admin endpoints", fmt.Sprintf("%s (token %s)", enabled(cfg.AdminToken != ""), redacted(cfg.AdminToken))},
+		{"metrics endpoint", fmt.Sprintf("%s (token %s)", enabled(cfg.MetricsToken != ""), redacted(cfg.MetricsToken))},
+	}
+
+	var b strings.Builder
+	for _, line := range lines {
+		fmt.Fprintf(&b, "  %-24s %v\n", line.name+":", line.value)
+	}
+	return b.String()
+}
@@ -0,0 +1,185 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"havoAPI/api/helpers"
+	"havoAPI/internal/services"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Pagination bounds for the user's alerts list.
+const (
+	defaultAlertsPageLimit = 20  // Number of alerts returned when no limit is given
+	maxAlertsPageLimit     = 100 // Upper bound on the limit of a single page
+)
+
+// AlertsHandler is a struct that holds the service for threshold and alert operations.
+type AlertsHandler struct {
+	alerts services.AlertsServiceInterface // Interface to interact with the alerts service layer
+}
+
+// NewAlertsHandler creates a new instance of AlertsHandler with the provided alerts service.
+func NewAlertsHandler(alerts services.AlertsServiceInterface) *AlertsHandler {
+	return &AlertsHandler{alerts: alerts}
+}
+
+// CreateThreshold registers a new threshold for the logged-in user.
+// It expects a JSON body with the location, metric, operator and value of the threshold.
+func (service *AlertsHandler) CreateThreshold(c *gin.Context) {
+	var form thresholdForm
+
+	// Bind incoming JSON data to the threshold form
+	if err := c.ShouldBindJSON(&form); err != nil {
+		helpers.RespondWithValidationErrors(c, err, form)
+		return
+	}
+
+	// Get the userID from the context (which should have been set during authentication)
+	userID, _ := c.Get("userID")
+	user_id := int(userID.(float64))
+
+	// Register the threshold
+	threshold, err := service.alerts.CreateThreshold(user_id, form.Location, form.Metric, form.Operator, *form.Value)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidThreshold) {
+			helpers.ClientError(c, http.StatusBadRequest, fmt.Sprintf("%v", err))
+			return
+		}
+		helpers.ServerError(c, err)
+		return
+	}
+
+	// Return the created threshold
+	c.JSON(http.StatusCreated, thresholdResponse(threshold))
+}
+
+// UpdateThreshold replaces one of the logged-in user's thresholds.
+// It expects the same JSON body as CreateThreshold; the threshold starts over as not crossed.
+func (service *AlertsHandler) UpdateThreshold(c *gin.Context) {
+	// Parse the threshold ID from the URL path
+	thresholdID, err := strconv.Atoi(c.Param("id"))
+	if err != nil || thresholdID <= 0 {
+		helpers.ClientError(c, http.StatusBadRequest, "'id' must be a positive integer")
+		return
+	}
+
+	var form thresholdForm
+
+	// Bind incoming JSON data to the threshold form
+	if err := c.ShouldBindJSON(&form); err != nil {
+		helpers.RespondWithValidationErrors(c, err, form)
+		return
+	}
+
+	// Get the userID from the context (which should have been set during authentication)
+	userID, _ := c.Get("userID")
+	user_id := int(userID.(float64))
+
+	// Replace the threshold, which must belong to the user
+	threshold, err := service.alerts.UpdateThreshold(user_id, thresholdID, form.Location, form.Metric, form.Operator, *form.Value)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidThreshold) {
+			helpers.ClientError(c, http.StatusBadRequest, fmt.Sprintf("%v", err))
+			return
+		}
+		if errors.Is(err, services.ErrThresholdNotFound) {
+			helpers.ClientError(c, http.StatusNotFound, "Threshold not found")
+			return
+		}
+		helpers.ServerError(c, err)
+		return
+	}
+
+	// Return the updated threshold
+	c.JSON(http.StatusOK, thresholdResponse(threshold))
+}
+
+// thresholdResponse builds the body returned for a created or updated threshold.
+// Thresholds of locations outside the periodic cache refresh carry a warning, since they are only
+// evaluated while a client happens to keep the location cached.
+func thresholdResponse(threshold services.Threshold) gin.H {
+	response := gin.H{
+		"threshold": threshold,
+	}
+	if !threshold.Evaluated {
+		response["warning"] = fmt.Sprintf("'%s' is not refreshed periodically, so this threshold is only checked while its weather data happens to be cached and may never trigger.", threshold.Location)
+	}
+	return response
+}
+
+// ListThresholds returns all thresholds registered by the logged-in user.
+func (service *AlertsHandler) ListThresholds(c *gin.Context) {
+	// Get the userID from the context (which should have been set during authentication)
+	userID, _ := c.Get("userID")
+	user_id := int(userID.(float64))
+
+	// Fetch the user's thresholds
+	thresholds, err := service.alerts.ListThresholds(user_id)
+	if err != nil {
+		helpers.ServerError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"thresholds": thresholds,
+	})
+}
+
+// DeleteThreshold deletes one of the logged-in user's thresholds.
+func (service *AlertsHandler) DeleteThreshold(c *gin.Context) {
+	// Parse the threshold ID from the URL path
+	thresholdID, err := strconv.Atoi(c.Param("id"))
+	if err != nil || thresholdID <= 0 {
+		helpers.ClientError(c, http.StatusBadRequest, "'id' must be a positive integer")
+		return
+	}
+
+	// Get the userID from the context (which should have been set during authentication)
+	userID, _ := c.Get("userID")
+	user_id := int(userID.(float64))
+
+	// Delete the threshold, which must belong to the user
+	if err := service.alerts.DeleteThreshold(user_id, thresholdID); err != nil {
+		if errors.Is(err, services.ErrThresholdNotFound) {
+			helpers.ClientError(c, http.StatusNotFound, "Threshold not found")
+			return
+		}
+		helpers.ServerError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Threshold deleted",
+	})
+}
+
+// ListAlerts returns a page of the alerts triggered by the logged-in user's thresholds, newest first.
+func (service *AlertsHandler) ListAlerts(c *gin.Context) {
+	// Extract and validate the pagination parameters from the URL
+	limit, offset, err := helpers.GetPaginationFromUrl(c, defaultAlertsPageLimit, maxAlertsPageLimit)
+	if err != nil {
+		helpers.ClientError(c, http.StatusBadRequest, fmt.Sprintf("%v", err))
+		return
+	}
+
+	// Get the userID from the context (which should have been set during authentication)
+	userID, _ := c.Get("userID")
+	user_id := int(userID.(float64))
+
+	// Fetch the requested page of alerts
+	alerts, err := service.alerts.ListAlerts(user_id, limit, offset)
+	if err != nil {
+		helpers.ServerError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"alerts": alerts,
+		"limit":  limit,
+		"offset": offset,
+	})
+}
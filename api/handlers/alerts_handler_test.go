@@ -0,0 +1,64 @@
+package handlers
+
+import (
+	"havoAPI/internal/services"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// fakeAlertsService is an AlertsServiceInterface whose behavior is set per test.
+type fakeAlertsService struct {
+	services.AlertsServiceInterface
+
+	updateThreshold func(userID, thresholdID int, location, metric, operator string, value float64) (services.Threshold, error)
+}
+
+func (s *fakeAlertsService) UpdateThreshold(userID, thresholdID int, location, metric, operator string, value float64) (services.Threshold, error) {
+	return s.updateThreshold(userID, thresholdID, location, metric, operator, value)
+}
+
+func TestUpdateThreshold(t *testing.T) {
+	alerts := &fakeAlertsService{updateThreshold: func(userID, thresholdID int, location, metric, operator string, value float64) (services.Threshold, error) {
+		if userID != 7 || thresholdID != 3 {
+			return services.Threshold{}, services.ErrThresholdNotFound
+		}
+		return services.Threshold{ID: thresholdID, Location: location, Metric: metric, Operator: operator, Value: value, Evaluated: location == "Spain"}, nil
+	}}
+	handler := asUser(7, NewAlertsHandler(alerts).UpdateThreshold)
+
+	tests := []struct {
+		name        string
+		target      string
+		location    string
+		wantStatus  int
+		wantWarning bool
+	}{
+		{name: "refreshed location", target: "/user/thresholds/3", location: "Spain", wantStatus: http.StatusOK},
+		{name: "location outside the refresh", target: "/user/thresholds/3", location: "Tashkent", wantStatus: http.StatusOK, wantWarning: true},
+		{name: "threshold of another user", target: "/user/thresholds/4", location: "Spain", wantStatus: http.StatusNotFound},
+		{name: "invalid id", target: "/user/thresholds/x", location: "Spain", wantStatus: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body := strings.NewReader(`{"location": "` + tt.location + `", "metric": "temp_c", "operator": ">", "value": 0}`)
+			w := serve(t, http.MethodPut, "/user/thresholds/:id", handler, tt.target, body)
+			assertStatus(t, w, tt.wantStatus)
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+
+			var response struct {
+				Threshold services.Threshold `json:"threshold"`
+				Warning   string             `json:"warning"`
+			}
+			decodeBody(t, w, &response)
+			if response.Threshold.Value != 0 || response.Threshold.Location != tt.location {
+				t.Errorf("threshold = %+v, want the updated values", response.Threshold)
+			}
+			if (response.Warning != "") != tt.wantWarning {
+				t.Errorf("warning = %q, want a warning: %v", response.Warning, tt.wantWarning)
+			}
+		})
+	}
+}
@@ -0,0 +1,116 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"havoAPI/internal/services"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Supported values of the bulk 'format' request option.
+const (
+	bulkFormatJSON = "json" // bulkFormatJSON returns the bulk response as JSON (the default).
+	bulkFormatCSV  = "csv"  // bulkFormatCSV returns the found locations as CSV rows, for spreadsheets.
+)
+
+// bulkCSVFilename is the file name suggested to clients downloading a CSV bulk response.
+const bulkCSVFilename = "weather.csv"
+
+// Markers of the trailing comment records of a CSV bulk response, in their first field.
+const (
+	bulkCSVNotFound    = "# not found"    // bulkCSVNotFound marks a location that was not found.
+	bulkCSVNotModified = "# not modified" // bulkCSVNotModified marks a location that was not modified since the given time.
+)
+
+// bulkCSVHeader lists the columns of a CSV bulk response, in order.
+var bulkCSVHeader = []string{
+	"name", "region", "country", "lat", "lon", "tz_id", "localtime", "last_updated",
+	"temp_c", "temp_f", "temp_k", "temp_trend", "wind_kph", "wind_mph", "cloud", "vis_km", "pressure_mb", "condition", "source",
+}
+
+// writeBulkCSV streams the bulk weather data as CSV with a header row and one row per found location.
+// Locations that were not found or not modified are listed in trailing comment records, so that no requested
+// location is silently dropped. A comment record holds a marker field starting with '#' and the location in
+// a field of its own, which encoding/csv quotes as needed: the line always starts with '#' whatever the
+// location contains, and readers skipping comments (csv.Reader.Comment) never see it as data.
+func writeBulkCSV(c *gin.Context, bulkWeatherData []services.FormattedWeatherData, notFoundList, notModifiedList []string) {
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+	c.Header("Content-Disposition", `attachment; filename="`+bulkCSVFilename+`"`)
+	c.Status(http.StatusOK)
+
+	w := csv.NewWriter(c.Writer)
+	rows := [][]string{bulkCSVHeader}
+	for _, data := range bulkWeatherData {
+		rows = append(rows, bulkCSVRow(data))
+	}
+	for _, notFound := range notFoundList {
+		rows = append(rows, []string{bulkCSVNotFound, notFound})
+	}
+	for _, notModified := range notModifiedList {
+		rows = append(rows, []string{bulkCSVNotModified, notModified})
+	}
+
+	// The status is already sent, so a write error can only be logged
+	if err := w.WriteAll(rows); err != nil {
+		log.Printf("failed to write CSV bulk response: %v", err)
+	}
+}
+
+// bulkCSVRow renders the weather data of a location as a CSV row matching bulkCSVHeader.
+// Imperial columns are left empty unless they were requested with units=both, and the Kelvin column unless units=kelvin.
+func bulkCSVRow(data services.FormattedWeatherData) []string {
+	return []string{
+		data.Name,
+		data.Region,
+		data.Country,
+		formatCSVFloat(data.Lat),
+		formatCSVFloat(data.Lon),
+		data.TzID,
+		formatCSVTimePointer(data.LocalTime),
+		formatCSVTime(data.LastUpdated),
+		formatCSVFloat(data.TempC),
+		formatCSVOptionalFloat(data.TempF),
+		formatCSVOptionalFloat(data.TempK),
+		data.TempTrend,
+		formatCSVFloat(data.WindKph),
+		formatCSVOptionalFloat(data.WindMph),
+		strconv.Itoa(data.Cloud),
+		formatCSVFloat(data.VisibilityKm),
+		formatCSVFloat(data.PressureMb),
+		data.Condition,
+		data.Source,
+	}
+}
+
+// formatCSVFloat formats a number with the shortest representation that round-trips.
+func formatCSVFloat(value float64) string {
+	return strconv.FormatFloat(value, 'f', -1, 64)
+}
+
+// formatCSVOptionalFloat formats an optional number, or returns an empty cell if it is not set.
+func formatCSVOptionalFloat(value *float64) string {
+	if value == nil {
+		return ""
+	}
+	return formatCSVFloat(*value)
+}
+
+// formatCSVTimePointer formats an optional time of a CSV row, leaving the cell empty if it is not set.
+func formatCSVTimePointer(value *time.Time) string {
+	if value == nil {
+		return ""
+	}
+	return formatCSVTime(*value)
+}
+
+// formatCSVTime formats a time as RFC 3339, or returns an empty cell if it is not set.
+func formatCSVTime(value time.Time) string {
+	if value.IsZero() {
+		return ""
+	}
+	return value.Format(time.RFC3339)
+}
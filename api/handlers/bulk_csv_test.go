@@ -0,0 +1,61 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"havoAPI/internal/services"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestWriteBulkCSV(t *testing.T) {
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	data := []services.FormattedWeatherData{weatherAt("London")}
+	writeBulkCSV(c, data, []string{`Paris, "TX"`}, []string{"Oslo"})
+
+	assertStatus(t, w, http.StatusOK)
+	if got := w.Header().Get("Content-Type"); !strings.HasPrefix(got, "text/csv") {
+		t.Errorf("Content-Type = %q, want text/csv", got)
+	}
+
+	// Every trailing line is a comment, even when the location contains CSV delimiters or quotes
+	lines := strings.Split(strings.TrimSuffix(w.Body.String(), "\n"), "\n")
+	if len(lines) != 4 {
+		t.Fatalf("got %d lines, want a header, a row and two comments:\n%s", len(lines), w.Body.String())
+	}
+	for _, line := range lines[2:] {
+		if !strings.HasPrefix(line, "#") {
+			t.Errorf("comment line %q doesn't start with '#'", line)
+		}
+	}
+
+	// A reader skipping comments sees the header and the data rows only
+	reader := csv.NewReader(strings.NewReader(w.Body.String()))
+	reader.Comment = '#'
+	rows, err := reader.ReadAll()
+	if err != nil {
+		t.Fatalf("response is not valid CSV: %v", err)
+	}
+	if len(rows) != 2 || !slices.Equal(rows[0], bulkCSVHeader) || rows[1][0] != "London" {
+		t.Errorf("rows = %q, want the header and the London row", rows)
+	}
+
+	// A reader keeping them gets the marker and the location back unchanged
+	reader = csv.NewReader(strings.NewReader(w.Body.String()))
+	reader.FieldsPerRecord = -1
+	records, err := reader.ReadAll()
+	if err != nil {
+		t.Fatalf("response is not valid CSV: %v", err)
+	}
+	want := [][]string{{bulkCSVNotFound, `Paris, "TX"`}, {bulkCSVNotModified, "Oslo"}}
+	for i, record := range records[2:] {
+		if !slices.Equal(record, want[i]) {
+			t.Errorf("comment record %d = %q, want %q", i, record, want[i])
+		}
+	}
+}
@@ -0,0 +1,50 @@
+package handlers
+
+import (
+	"havoAPI/internal/services"
+	"net/http"
+)
+
+// bulkOutcome is the result of a single location of a bulk request, listed in the "results" of a
+// multi-status response. Status uses the code a single lookup of the location would have returned.
+type bulkOutcome struct {
+	Q      string `json:"q"`               // Q is the location query as sent.
+	Status int    `json:"status"`          // Status is 200 for a found, 304 for a not modified and 404 for a missing location.
+	Error  string `json:"error,omitempty"` // Error describes why the location is missing.
+}
+
+// bulkOutcomes lists the outcome of every query of a bulk request, in request order.
+func bulkOutcomes(results []services.BulkResult) []bulkOutcome {
+	outcomes := make([]bulkOutcome, 0, len(results))
+	for _, result := range results {
+		switch {
+		case result.Data != nil:
+			outcomes = append(outcomes, bulkOutcome{Q: result.Query, Status: http.StatusOK})
+		case result.NotModified:
+			outcomes = append(outcomes, bulkOutcome{Q: result.Query, Status: http.StatusNotModified})
+		default:
+			outcomes = append(outcomes, bulkOutcome{Q: result.Query, Status: http.StatusNotFound, Error: result.Error})
+		}
+	}
+	return outcomes
+}
+
+// bulkStatus selects the status of a multi-status bulk response: 200 when every location succeeded
+// (found or not modified), 404 when none did, and 207 Multi-Status for a mix of both.
+func bulkStatus(outcomes []bulkOutcome) int {
+	failed := 0
+	for _, outcome := range outcomes {
+		if outcome.Status == http.StatusNotFound {
+			failed++
+		}
+	}
+
+	switch {
+	case failed == 0:
+		return http.StatusOK
+	case failed == len(outcomes):
+		return http.StatusNotFound
+	default:
+		return http.StatusMultiStatus
+	}
+}
@@ -0,0 +1,148 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"havoAPI/api/helpers"
+	"havoAPI/internal/services"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CacheRefresher runs a full refresh of the weather cache, calling progress (if not nil) after every location.
+// It returns services.ErrCacheRefreshInProgress if another refresh is already running.
+type CacheRefresher func(progress func(services.RefreshProgress)) error
+
+// CacheRefreshWatcher follows the cache refresh in progress (see services.WeatherAPIService.WatchCacheRefresh).
+// It returns services.ErrNoCacheRefreshInProgress if no refresh is running.
+type CacheRefreshWatcher func() (<-chan services.RefreshEvent, func(), error)
+
+// CacheStatsSource reports the entries of the weather cache and its hit and miss counts (see services.WeatherAPIService.CacheStats).
+type CacheStatsSource func(ctx context.Context) (services.CacheStats, error)
+
+// CacheHandler is a struct that lets admins trigger a cache refresh, follow its progress and inspect the cache.
+type CacheHandler struct {
+	refresh CacheRefresher      // Runs the refresh, including the work that follows it (e.g. checking thresholds)
+	watch   CacheRefreshWatcher // Follows the refresh in progress
+	stats   CacheStatsSource    // Lists the cached locations with their hit and miss counts
+}
+
+// NewCacheHandler creates a new instance of CacheHandler with the provided refresh function, refresh watcher and stats source.
+func NewCacheHandler(refresh CacheRefresher, watch CacheRefreshWatcher, stats CacheStatsSource) *CacheHandler {
+	return &CacheHandler{refresh: refresh, watch: watch, stats: stats}
+}
+
+// CacheStats lists every cached location with its remaining TTL, last refresh time and hit and miss counts,
+// along with the totals since startup, so operators can spot hot locations and tune the warm list.
+// If the cache is disabled, it responds with 409 Conflict.
+func (handler *CacheHandler) CacheStats(c *gin.Context) {
+	stats, err := handler.stats(c.Request.Context())
+	if err != nil {
+		if errors.Is(err, services.ErrCacheDisabled) {
+			helpers.ClientError(c, http.StatusConflict, "The weather cache is disabled (CACHE_ENABLED=false), so there are no stats to report.")
+			return
+		}
+		helpers.ServerError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, stats)
+}
+
+// CacheRefresh starts a cache refresh and responds with 202 Accepted once the first location is done,
+// without waiting for the whole refresh. Its progress can be followed with CacheRefreshStream.
+// The refresh keeps running if the client disconnects. If a refresh is already running, it responds with 409 Conflict.
+func (handler *CacheHandler) CacheRefresh(c *gin.Context) {
+	// The refresh outlives the request, so its result channel must never block once nobody waits for it
+	started := make(chan struct{})
+	result := make(chan error, 1)
+	var once sync.Once
+	go func() {
+		result <- handler.refresh(func(services.RefreshProgress) {
+			once.Do(func() { close(started) })
+		})
+	}()
+
+	// Wait for the refresh to start, so that a refresh that can't start is reported with a regular status code
+	select {
+	case <-started:
+	case err := <-result:
+		if errors.Is(err, services.ErrCacheRefreshInProgress) {
+			helpers.ClientError(c, http.StatusConflict, "A cache refresh is already in progress. Follow it at GET /api/v1/admin/cache/refresh/stream.")
+			return
+		}
+		if errors.Is(err, services.ErrCacheDisabled) {
+			helpers.ClientError(c, http.StatusConflict, "The weather cache is disabled (CACHE_ENABLED=false), so there is nothing to refresh.")
+			return
+		}
+		if err != nil {
+			helpers.ServerError(c, err)
+			return
+		}
+		// A refresh without any location finishes at once
+	case <-c.Request.Context().Done():
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"message": "The cache refresh has started. Follow its progress at GET /api/v1/admin/cache/refresh/stream.",
+	})
+}
+
+// CacheRefreshStream follows the cache refresh in progress, whoever started it, and streams its progress
+// as Server-Sent Events: a "progress" event with the latest counts whenever a location is done, then a single
+// "done" event with the final counts or an "error" event if the refresh failed. It never starts a refresh itself,
+// so reconnecting clients can't trigger one; if no refresh is running, it responds with 409 Conflict without streaming.
+// A client that falls behind skips intermediate progress events, and disconnecting leaves the refresh running.
+func (handler *CacheHandler) CacheRefreshStream(c *gin.Context) {
+	events, stop, err := handler.watch()
+	if errors.Is(err, services.ErrNoCacheRefreshInProgress) {
+		helpers.ClientError(c, http.StatusConflict, "No cache refresh is in progress. Start one with POST /api/v1/admin/cache/refresh.")
+		return
+	}
+	if err != nil {
+		helpers.ServerError(c, err)
+		return
+	}
+	defer stop()
+
+	// A full refresh takes minutes, longer than the server's write timeout
+	if err := http.NewResponseController(c.Writer).SetWriteDeadline(time.Time{}); err != nil {
+		log.Printf("failed to clear refresh stream write deadline: %v", err)
+	}
+
+	c.Header("Cache-Control", "no-cache")
+	c.Header("X-Accel-Buffering", "no") // Keep reverse proxies from buffering the events
+	c.Status(http.StatusOK)
+	c.Writer.Flush()
+
+	// Forward every event until the refresh ends or the client disconnects
+	clientGone := c.Request.Context().Done()
+	for {
+		select {
+		case event, ok := <-events:
+			if !ok {
+				return
+			}
+			switch {
+			case !event.Done:
+				c.SSEvent("progress", event.Progress)
+			case event.Err != nil:
+				// Internal error details are only logged (by the refresh), never sent to the client
+				c.SSEvent("error", gin.H{"error": "The cache refresh failed. Check the server logs for details."})
+			default:
+				c.SSEvent("done", event.Progress)
+			}
+			c.Writer.Flush()
+			if event.Done {
+				return
+			}
+		case <-clientGone:
+			return
+		}
+	}
+}
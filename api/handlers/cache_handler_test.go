@@ -0,0 +1,119 @@
+package handlers
+
+import (
+	"context"
+	"havoAPI/internal/services"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// refreshWatch is a CacheRefreshWatcher over a channel fed by the test, recording when the subscription ends.
+type refreshWatch struct {
+	events  chan services.RefreshEvent
+	stopped chan struct{}
+}
+
+func newRefreshWatch() *refreshWatch {
+	return &refreshWatch{events: make(chan services.RefreshEvent), stopped: make(chan struct{})}
+}
+
+func (w *refreshWatch) watch() (<-chan services.RefreshEvent, func(), error) {
+	return w.events, func() { close(w.stopped) }, nil
+}
+
+// noRefresh is a CacheRefreshWatcher for a service without a refresh in progress.
+func noRefresh() (<-chan services.RefreshEvent, func(), error) {
+	return nil, nil, services.ErrNoCacheRefreshInProgress
+}
+
+// countingRefresher returns a CacheRefresher that counts its calls and reports a single location.
+func countingRefresher(calls *atomic.Int32) CacheRefresher {
+	return func(progress func(services.RefreshProgress)) error {
+		calls.Add(1)
+		progress(services.RefreshProgress{Location: "London", Done: 1, Total: 1, Succeeded: 1})
+		return nil
+	}
+}
+
+func TestCacheRefreshStreamNeverStartsARefresh(t *testing.T) {
+	var calls atomic.Int32
+	handler := NewCacheHandler(countingRefresher(&calls), noRefresh, nil)
+
+	w := serve(t, http.MethodGet, "/refresh/stream", handler.CacheRefreshStream, "/refresh/stream", nil)
+	assertStatus(t, w, http.StatusConflict)
+	if got := calls.Load(); got != 0 {
+		t.Errorf("GET started %d refreshes, want none", got)
+	}
+
+	w = serve(t, http.MethodPost, "/refresh", handler.CacheRefresh, "/refresh", nil)
+	assertStatus(t, w, http.StatusAccepted)
+	if got := calls.Load(); got != 1 {
+		t.Errorf("POST started %d refreshes, want 1", got)
+	}
+}
+
+func TestCacheRefreshReportsARefreshInProgress(t *testing.T) {
+	handler := NewCacheHandler(func(func(services.RefreshProgress)) error {
+		return services.ErrCacheRefreshInProgress
+	}, noRefresh, nil)
+
+	w := serve(t, http.MethodPost, "/refresh", handler.CacheRefresh, "/refresh", nil)
+	assertStatus(t, w, http.StatusConflict)
+}
+
+func TestCacheRefreshStreamFollowsTheRefreshInProgress(t *testing.T) {
+	watch := newRefreshWatch()
+	handler := NewCacheHandler(nil, watch.watch, nil)
+	go func() {
+		watch.events <- services.RefreshEvent{Progress: services.RefreshProgress{Location: "London", Done: 1, Total: 2, Succeeded: 1}}
+		watch.events <- services.RefreshEvent{Progress: services.RefreshProgress{Location: "Paris", Done: 2, Total: 2, Succeeded: 1, Failed: 1}, Done: true}
+	}()
+
+	w := serve(t, http.MethodGet, "/refresh/stream", handler.CacheRefreshStream, "/refresh/stream", nil)
+	assertStatus(t, w, http.StatusOK)
+	body := w.Body.String()
+	for _, want := range []string{"event:progress", `"location":"London"`, "event:done", `"failed":1`} {
+		if !strings.Contains(body, want) {
+			t.Errorf("stream is missing %s:\n%s", want, body)
+		}
+	}
+	if strings.Contains(body, "event:error") {
+		t.Errorf("stream of a successful refresh has an error event:\n%s", body)
+	}
+}
+
+func TestCacheRefreshStreamEndsWhenTheClientDisconnects(t *testing.T) {
+	watch := newRefreshWatch()
+	handler := NewCacheHandler(nil, watch.watch, nil)
+	router := gin.New()
+	router.GET("/refresh/stream", handler.CacheRefreshStream)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	served := make(chan struct{})
+	go func() {
+		defer close(served)
+		r := httptest.NewRequest(http.MethodGet, "/refresh/stream", nil).WithContext(ctx)
+		router.ServeHTTP(httptest.NewRecorder(), r)
+	}()
+
+	// The stream is following the refresh once it accepts an event
+	watch.events <- services.RefreshEvent{Progress: services.RefreshProgress{Location: "London", Done: 1, Total: 2}}
+	cancel()
+
+	select {
+	case <-served:
+	case <-time.After(5 * time.Second):
+		t.Fatal("stream kept running after the client disconnected")
+	}
+	select {
+	case <-watch.stopped:
+	default:
+		t.Error("stream did not end its subscription after the client disconnected")
+	}
+}
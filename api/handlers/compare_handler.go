@@ -0,0 +1,91 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"havoAPI/api/helpers"
+	"havoAPI/internal/services"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxCompareLocations is the maximum number of locations a single comparison may list.
+const maxCompareLocations = 20
+
+// CompareWeatherData handles the comparison of the current weather of several locations.
+// It expects an API key and a comma-separated list of locations in 'q' (e.g. q=London,Tokyo,NYC),
+// fetches them like a bulk request and returns them sorted by the 'sort' metric, with aggregates.
+func (service *WeatherHandler) CompareWeatherData(c *gin.Context) {
+	// Extract the API key and the list of locations from the URL
+	apiKey, query, err := helpers.GetParametersFromUrl(c)
+	if err != nil {
+		helpers.ClientError(c, http.StatusBadRequest, fmt.Sprintf("%v", err))
+		return
+	}
+
+	// Split the list of locations, dropping blank entries
+	queries := helpers.SplitLocationList(query)
+	if len(queries) < 2 {
+		helpers.ClientError(c, http.StatusBadRequest, "parameter q must list at least two comma-separated locations")
+		return
+	}
+	if len(queries) > maxCompareLocations {
+		helpers.ClientError(c, http.StatusBadRequest, fmt.Sprintf("parameter q may list at most %d locations", maxCompareLocations))
+		return
+	}
+
+	// Extract the metric to sort by
+	metric := c.DefaultQuery("sort", services.CompareByTemp)
+	if !services.ValidCompareMetric(metric) {
+		helpers.ClientError(c, http.StatusBadRequest, fmt.Sprintf("parameter sort must be either '%s' or '%s'", services.CompareByTemp, services.CompareByWind))
+		return
+	}
+
+	// Extract the requested unit system
+	units, err := helpers.GetUnitsFromUrl(c)
+	if err != nil {
+		helpers.ClientError(c, http.StatusBadRequest, fmt.Sprintf("%v", err))
+		return
+	}
+
+	// Authorize the API key
+	scopes, err := service.weather.APIKeyAuthorization(c.Request.Context(), apiKey)
+	if err != nil {
+		// Handle case where the API key is invalid or disabled
+		if errors.Is(err, services.ErrAPIKeyNotFound) {
+			helpers.ClientError(c, http.StatusUnauthorized, "API key has been disabled.")
+			return
+		}
+		// For other errors, respond with a server error
+		helpers.ServerError(c, err)
+		return
+	}
+
+	// A comparison fetches several locations at once, like a bulk request
+	if scopeDenied(c, scopes, services.ScopeBulk) {
+		return
+	}
+
+	// Fetch the weather data of every location
+	results, err := service.weather.FetchBulkWeatherData(queries, nil)
+	if err != nil {
+		// Handle errors reported by WeatherAPI about the service itself
+		if upstreamErrorResponse(c, err) {
+			return
+		}
+		helpers.ServerError(c, err)
+		return
+	}
+	weatherData, notFoundList, _ := services.SplitBulkResults(results)
+
+	// Add the fields required by the requested unit system
+	for i := range weatherData {
+		weatherData[i] = services.ApplyUnits(weatherData[i], units)
+	}
+
+	// Send the sorted locations and their aggregates, along with the locations that were not found
+	comparison := services.CompareWeatherData(weatherData, metric)
+	comparison.NotFound = notFoundList
+	c.JSON(http.StatusOK, comparison)
+}
@@ -0,0 +1,71 @@
+package handlers
+
+import (
+	"havoAPI/internal/services"
+	"net/http"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestCompareWeatherData(t *testing.T) {
+	temps := map[string]float64{"London": 12, "Tokyo": 24, "Oslo": -3}
+	weather := &fakeWeatherService{
+		fetchBulkWeatherData: func(queries []string, modifiedSince map[string]time.Time) ([]services.BulkResult, error) {
+			var results []services.BulkResult
+			for _, q := range queries {
+				tempC, ok := temps[q]
+				if !ok {
+					results = append(results, services.BulkResult{Query: q, Error: q})
+					continue
+				}
+				data := weatherAt(q)
+				data.TempC = tempC
+				data.WindKph = 40 - tempC
+				results = append(results, services.BulkResult{Query: q, Data: &data})
+			}
+			return results, nil
+		},
+	}
+	handler := NewWeatherHandler(weather).CompareWeatherData
+
+	tests := []struct {
+		name       string
+		target     string
+		wantStatus int
+		wantOrder  []string
+		notFound   []string
+	}{
+		{name: "by temperature", target: "/compare?key=k&q=London,Tokyo,Oslo", wantStatus: http.StatusOK, wantOrder: []string{"Tokyo", "London", "Oslo"}},
+		{name: "by wind", target: "/compare?key=k&q=London,Tokyo,Oslo&sort=wind_kph", wantStatus: http.StatusOK, wantOrder: []string{"Oslo", "London", "Tokyo"}},
+		{name: "with an unknown location", target: "/compare?key=k&q=London,Atlantis", wantStatus: http.StatusOK, wantOrder: []string{"London"}, notFound: []string{"Atlantis"}},
+		{name: "single location", target: "/compare?key=k&q=London", wantStatus: http.StatusBadRequest},
+		{name: "unknown metric", target: "/compare?key=k&q=London,Tokyo&sort=humidity", wantStatus: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := serve(t, http.MethodGet, "/compare", handler, tt.target, nil)
+			assertStatus(t, w, tt.wantStatus)
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+
+			var comparison services.WeatherComparison
+			decodeBody(t, w, &comparison)
+			if len(comparison.Locations) != len(tt.wantOrder) {
+				t.Fatalf("got %d locations, want %v", len(comparison.Locations), tt.wantOrder)
+			}
+			for i, name := range tt.wantOrder {
+				if comparison.Locations[i].Name != name {
+					t.Errorf("location %d = %q, want %q", i, comparison.Locations[i].Name, name)
+				}
+			}
+			if !slices.Equal(comparison.NotFound, tt.notFound) {
+				t.Errorf("not_found = %v, want %v", comparison.NotFound, tt.notFound)
+			}
+			if comparison.TempC == nil || comparison.WindKph == nil {
+				t.Errorf("summaries = %+v, %+v, want both", comparison.TempC, comparison.WindKph)
+			}
+		})
+	}
+}
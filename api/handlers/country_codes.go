@@ -0,0 +1,119 @@
+package handlers
+
+import (
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/language"
+	"golang.org/x/text/language/display"
+	"golang.org/x/text/runes"
+	"golang.org/x/text/transform"
+	"golang.org/x/text/unicode/norm"
+)
+
+// countryNameAliases maps country names that WeatherAPI reports differently from the English CLDR names
+// to their ISO 3166-1 alpha-2 codes. Names are matched after normalizeCountryName, so case, accents,
+// "&" versus "and" and "St." versus "Saint" don't need entries of their own.
+var countryNameAliases = map[string]string{
+	"united states of america":         "US",
+	"usa":                              "US",
+	"usa united states of america":     "US",
+	"uk":                               "GB",
+	"great britain":                    "GB",
+	"czech republic":                   "CZ",
+	"cote d ivoire":                    "CI",
+	"ivory coast":                      "CI",
+	"myanmar":                          "MM",
+	"burma":                            "MM",
+	"congo":                            "CG",
+	"republic of the congo":            "CG",
+	"democratic republic of the congo": "CD",
+	"democratic republic of congo":     "CD",
+	"hong kong":                        "HK",
+	"macau":                            "MO",
+	"macao":                            "MO",
+	"palestine":                        "PS",
+	"turkiye":                          "TR",
+	"north macedonia":                  "MK",
+	"eswatini":                         "SZ",
+	"east timor":                       "TL",
+	"timor l este":                     "TL",
+	"kyrgyz republic":                  "KG",
+	"cabo verde":                       "CV",
+	"korea south":                      "KR",
+	"korea north":                      "KP",
+	"russian federation":               "RU",
+	"vatican":                          "VA",
+	"holy see":                         "VA",
+	"st vincent":                       "VC",
+	"st vincent and the grenadines":    "VC",
+	"turks and caicos":                 "TC",
+	"virgin islands us":                "VI",
+	"us virgin islands":                "VI",
+}
+
+// countryCodes maps normalized English country names to their ISO 3166-1 alpha-2 codes.
+// It holds the CLDR display name of every country, plus countryNameAliases.
+var countryCodes = buildCountryCodes()
+
+// buildCountryCodes reverses the English display names of every ISO 3166-1 country.
+func buildCountryCodes() map[string]string {
+	codes := make(map[string]string)
+	namer := display.English.Regions()
+	for first := 'A'; first <= 'Z'; first++ {
+		for second := 'A'; second <= 'Z'; second++ {
+			region, err := language.ParseRegion(string([]rune{first, second}))
+			if err != nil || !region.IsCountry() {
+				continue
+			}
+
+			// Withdrawn codes (e.g. "BU" for Burma) resolve to their successor
+			region = region.Canonicalize()
+			name := namer.Name(region)
+			if name == "" {
+				continue
+			}
+			codes[normalizeCountryName(name)] = region.String()
+
+			// Also match names without a parenthesized CLDR qualifier, e.g. "Myanmar (Burma)";
+			// other qualified names (e.g. "Hong Kong SAR China") are covered by countryNameAliases
+			if short, _, found := strings.Cut(name, " ("); found {
+				codes[normalizeCountryName(short)] = region.String()
+			}
+		}
+	}
+	for name, code := range countryNameAliases {
+		codes[name] = code
+	}
+	return codes
+}
+
+// normalizeCountryName folds the spelling differences of country names: letter case, accents, HTML-escaped
+// or spelled-out ampersands, punctuation and the "Saint" prefix (e.g. "São Tomé &amp; Príncipe" and
+// "Sao Tome and Principe" both become "sao tome and principe", "Saint Lucia" and "St. Lucia" become "st lucia").
+func normalizeCountryName(name string) string {
+	// Remove accents by decomposing the letters and dropping the combining marks
+	folded, _, err := transform.String(transform.Chain(norm.NFD, runes.Remove(runes.In(unicode.Mn)), norm.NFC), name)
+	if err != nil {
+		folded = name
+	}
+	folded = strings.ToLower(folded)
+	folded = strings.NewReplacer("&amp;", " and ", "&", " and ").Replace(folded)
+
+	// Punctuation separates words like spaces do, e.g. "Guinea-Bissau" and "Timor L'Este"
+	words := strings.FieldsFunc(folded, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	for i, word := range words {
+		if word == "saint" {
+			words[i] = "st"
+		}
+	}
+	return strings.Join(words, " ")
+}
+
+// countryCode returns the ISO 3166-1 alpha-2 code of a country named as WeatherAPI names it,
+// or an empty string if the name matches no known country.
+func countryCode(country string) string {
+	return countryCodes[normalizeCountryName(country)]
+}
@@ -0,0 +1,84 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"havoAPI/api/config"
+	"havoAPI/internal/clock"
+	"havoAPI/internal/models"
+	"havoAPI/internal/services"
+	"net/http"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// notFoundUsersDB is a users table in which every lookup fails with a wrapped model error.
+type notFoundUsersDB struct {
+	models.DBContractUsers
+}
+
+func (db *notFoundUsersDB) RetrieveUserCredentials(username string) (int, string, error) {
+	return 0, "", fmt.Errorf("retrieving %q: %w", username, models.ErrUserNotFound)
+}
+
+// notFoundThresholdsDB is a thresholds table in which every update fails with a wrapped model error.
+type notFoundThresholdsDB struct {
+	models.DBContractThresholds
+}
+
+func (db *notFoundThresholdsDB) UpdateThreshold(userID, thresholdID int, location, metric, operator string, value float64) (models.Threshold, error) {
+	return models.Threshold{}, fmt.Errorf("updating threshold %d: %w", thresholdID, models.ErrThresholdNotFound)
+}
+
+// TestModelErrorsReachHandlers runs real services between the handlers and failing model stand-ins,
+// checking that the sentinel errors raised by the models are still recognized by the handlers.
+func TestModelErrorsReachHandlers(t *testing.T) {
+	t.Run("user not found", func(t *testing.T) {
+		users := services.NewUsersService(&notFoundUsersDB{}, nil, services.BcryptHasher{Cost: bcrypt.MinCost}, nil, clock.Real{}, 10)
+		handler := NewUsersHandler(users, &config.Config{}, clock.Real{}).Login
+
+		body := strings.NewReader(`{"username": "nobody", "password": "Secret-password-1"}`)
+		w := serve(t, http.MethodPost, "/login", handler, "/login", body)
+		assertStatus(t, w, http.StatusNotFound)
+	})
+
+	t.Run("threshold not found", func(t *testing.T) {
+		alerts := services.NewAlertsService(&notFoundThresholdsDB{}, nil)
+		handler := asUser(7, NewAlertsHandler(alerts).UpdateThreshold)
+
+		body := strings.NewReader(`{"location": "Spain", "metric": "temp_c", "operator": ">", "value": 0}`)
+		w := serve(t, http.MethodPut, "/user/thresholds/:id", handler, "/user/thresholds/3", body)
+		assertStatus(t, w, http.StatusNotFound)
+	})
+}
+
+func TestServiceErrorsMatchModelErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		service error
+		model   error
+	}{
+		{"user not found", services.ErrUserNotFound, models.ErrUserNotFound},
+		{"username exists", services.ErrUsernameExists, models.ErrDuplicatedUsername},
+		{"email exists", services.ErrEmailExists, models.ErrDuplicatedEmail},
+		{"schema missing", services.ErrSchemaMissing, models.ErrSchemaMissing},
+		{"API key not found", services.ErrAPIKeyNotFound, models.ErrAPIKeyNotFound},
+		{"API key exists", services.ErrAPIKeyAlreadyExists, models.ErrUserAPIKeyExists},
+		{"ambiguous prefix", services.ErrAmbiguousAPIKeyPrefix, models.ErrAmbiguousAPIKeyPrefix},
+		{"threshold not found", services.ErrThresholdNotFound, models.ErrThresholdNotFound},
+		{"group not found", services.ErrGroupNotFound, models.ErrGroupNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			wrapped := fmt.Errorf("query failed: %w", tt.model)
+			if !errors.Is(wrapped, tt.service) {
+				t.Errorf("errors.Is(%v, %v) = false, want the model error to match the service error", wrapped, tt.service)
+			}
+			if strings.HasPrefix(tt.service.Error(), "models:") {
+				t.Errorf("service error %q carries the models prefix", tt.service)
+			}
+		})
+	}
+}
@@ -0,0 +1,21 @@
+package handlers
+
+import (
+	"fmt"
+	"havoAPI/api/helpers"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// NotFound answers requests to unknown paths with the JSON error format used by every other route,
+// instead of Gin's default plaintext 404.
+func NotFound(c *gin.Context) {
+	helpers.ClientErrorWithCode(c, http.StatusNotFound, "NOT_FOUND", fmt.Sprintf("no route for %s", c.Request.URL.Path))
+}
+
+// MethodNotAllowed answers requests to a known path with an unsupported method (e.g. PUT /api/v1/weather.current)
+// with the JSON error format used by every other route.
+func MethodNotAllowed(c *gin.Context) {
+	helpers.ClientErrorWithCode(c, http.StatusMethodNotAllowed, "METHOD_NOT_ALLOWED", fmt.Sprintf("method %s is not allowed for %s", c.Request.Method, c.Request.URL.Path))
+}
@@ -0,0 +1,92 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"havoAPI/internal/services"
+	"io"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func init() {
+	gin.SetMode(gin.TestMode)
+}
+
+// fakeWeatherService is a WeatherAPIServiceInterface whose behavior is set per test.
+// Methods without a function set panic through the nil embedded interface, so a test
+// fails loudly if it reaches a call it didn't expect.
+type fakeWeatherService struct {
+	services.WeatherAPIServiceInterface
+
+	fetchWeatherData     func(ctx context.Context, query string, opts services.WeatherOptions) (services.FormattedWeatherData, error)
+	fetchBulkWeatherData func(queries []string, modifiedSince map[string]time.Time) ([]services.BulkResult, error)
+
+	authorizedKeys []string // authorizedKeys records the API keys passed to APIKeyAuthorization.
+}
+
+// APIKeyAuthorization accepts every key without restricting its scopes.
+func (s *fakeWeatherService) APIKeyAuthorization(ctx context.Context, apiKey string) (services.APIKeyScopes, error) {
+	s.authorizedKeys = append(s.authorizedKeys, apiKey)
+	return nil, nil
+}
+
+// SubscribeWeatherUpdates subscribes to a channel that never delivers an update.
+func (s *fakeWeatherService) SubscribeWeatherUpdates(queries []string) (<-chan services.FormattedWeatherData, func(), error) {
+	return make(chan services.FormattedWeatherData), func() {}, nil
+}
+
+// CachedWeatherDataHash reports that nothing is cached, so conditional requests fall through to a fetch.
+func (s *fakeWeatherService) CachedWeatherDataHash(query string, opts services.WeatherOptions) (string, error) {
+	return "", services.ErrNoDataCache
+}
+
+func (s *fakeWeatherService) FetchWeatherData(ctx context.Context, query string, opts services.WeatherOptions) (services.FormattedWeatherData, error) {
+	return s.fetchWeatherData(ctx, query, opts)
+}
+
+func (s *fakeWeatherService) FetchBulkWeatherData(queries []string, modifiedSince map[string]time.Time) ([]services.BulkResult, error) {
+	return s.fetchBulkWeatherData(queries, modifiedSince)
+}
+
+// serve routes a single request through a handler registered on the given path and returns the recorded response.
+func serve(t *testing.T, method, path string, handler gin.HandlerFunc, target string, body io.Reader) *httptest.ResponseRecorder {
+	t.Helper()
+	router := gin.New()
+	router.Handle(method, path, handler)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(method, target, body))
+	return w
+}
+
+// decodeBody decodes a JSON response body into v, failing the test if it isn't valid JSON.
+func decodeBody(t *testing.T, w *httptest.ResponseRecorder, v any) {
+	t.Helper()
+	if err := json.Unmarshal(w.Body.Bytes(), v); err != nil {
+		t.Fatalf("response is not valid JSON: %v\n%s", err, w.Body.String())
+	}
+}
+
+// assertStatus fails the test if the response doesn't have the wanted status.
+func assertStatus(t *testing.T, w *httptest.ResponseRecorder, want int) {
+	t.Helper()
+	if w.Code != want {
+		t.Fatalf("status = %d, want %d; body: %s", w.Code, want, w.Body.String())
+	}
+}
+
+// weatherAt returns minimal weather data for a named location.
+func weatherAt(name string) services.FormattedWeatherData {
+	return services.FormattedWeatherData{Name: name, Country: "Testland"}
+}
+
+// asUser wraps a handler behind a stand-in for the JWT middleware, which sets the ID of the logged-in user.
+func asUser(userID int, handler gin.HandlerFunc) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set("userID", float64(userID))
+		handler(c)
+	}
+}
@@ -1,5 +1,7 @@
 package handlers
 
+import "time"
+
 // newUserForm represents the structure of the data required to create a new user during signup.
 // It includes the user's name, surname, username, and password. All fields are required during validation.
 type newUserForm struct {
@@ -9,6 +11,16 @@ type newUserForm struct {
 	Password string `json:"password" binding:"required"` // The password for the user; must be provided in the request body
 }
 
+// importUserForm represents a single user of an admin import.
+// Its fields are validated per row by the handler rather than with binding tags,
+// so that one incomplete row does not reject the whole import.
+type importUserForm struct {
+	Name     string `json:"name"`     // The user's first name
+	Surname  string `json:"surname"`  // The user's last name
+	Username string `json:"username"` // The desired username
+	Password string `json:"password"` // The initial password; must pass the same rules as on signup
+}
+
 // userLoginForm represents the structure of the data required for user login.
 // It includes the user's username and password for authentication. Both fields are required during validation.
 type userLoginForm struct {
@@ -16,6 +28,46 @@ type userLoginForm struct {
 	Password string `json:"password" binding:"required"` // The user's password for login; must be provided in the request body
 }
 
+// changePasswordForm represents the structure of the data required to change the logged-in user's password.
+// Both the current and the new password are required; the new one must pass the same rules as on signup.
+type changePasswordForm struct {
+	CurrentPassword string `json:"current_password" binding:"required"` // The user's current password; must be provided in the request body
+	NewPassword     string `json:"new_password" binding:"required"`     // The new password for the user; must be provided in the request body
+}
+
+// apiKeyForm represents the structure of the data required to create an additional API key.
+// An empty or missing list of scopes creates an unrestricted key.
+type apiKeyForm struct {
+	Scopes []string `json:"scopes"` // The scopes the key is limited to (current, bulk, stream, astronomy)
+}
+
+// apiKeysDeleteForm represents the structure of the data required to delete several of the user's API keys at once.
+type apiKeysDeleteForm struct {
+	Keys []string `json:"keys" binding:"required"` // The IDs or prefixes (at least 8 characters) of the keys to delete
+}
+
+// preferencesForm represents the structure of the data required to store the user's preferences.
+// Both fields are optional; an empty or missing value clears the preference.
+type preferencesForm struct {
+	Units string `json:"units"` // The preferred unit system (metric or both)
+	Lang  string `json:"lang"`  // The preferred WeatherAPI language code (e.g. fr)
+}
+
+// thresholdForm represents the structure of the data required to register a weather threshold.
+// Value is a pointer so that a threshold of 0 (e.g. "temp_c < 0") passes the required check.
+type thresholdForm struct {
+	Location string   `json:"location" binding:"required"` // The location query to watch; must be provided in the request body
+	Metric   string   `json:"metric" binding:"required"`   // The metric to compare (temp_c, wind_kph or cloud); must be provided in the request body
+	Operator string   `json:"operator" binding:"required"` // The comparison (">" or "<"); must be provided in the request body
+	Value    *float64 `json:"value" binding:"required"`    // The threshold value; must be provided in the request body
+}
+
+// groupForm represents the structure of the data required to save a named location group.
+// The group name is taken from the URL path.
+type groupForm struct {
+	Locations []string `json:"locations" binding:"required"` // The location queries of the group; must be provided in the request body
+}
+
 // LocationsForm represents the structure of the form for submitting location data.
 // The Locations field is a slice of Location objects and is required for form submission.
 type LocationsForm struct {
@@ -24,6 +76,10 @@ type LocationsForm struct {
 
 // Location represents a single location query.
 // The Q field stores the query string and is required for a valid Location.
+// It takes anything a single lookup accepts: a name, a postal code, an "iata:" airport or a "lat,lon" pair.
+// LastUpdated optionally holds the last observation time the client saw for the location,
+// in which case the location is only returned if newer data is available.
 type Location struct {
-	Q string `json:"q" binding:"required"` // The location query string, must not be empty.
+	Q           string     `json:"q" binding:"required"` // The location query string, must not be empty.
+	LastUpdated *time.Time `json:"last_updated"`         // The last "last_updated" value the client received for this location (optional).
 }
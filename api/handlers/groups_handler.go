@@ -0,0 +1,183 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"havoAPI/api/helpers"
+	"havoAPI/internal/services"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GroupsHandler is a struct that holds the services for managing location groups and fetching their weather.
+type GroupsHandler struct {
+	groups  services.GroupsServiceInterface     // Interface to interact with the groups service layer
+	weather services.WeatherAPIServiceInterface // Interface to authorize API keys and fetch the weather of a group
+}
+
+// NewGroupsHandler creates a new instance of GroupsHandler with the provided groups and weather services.
+func NewGroupsHandler(groups services.GroupsServiceInterface, weather services.WeatherAPIServiceInterface) *GroupsHandler {
+	return &GroupsHandler{groups: groups, weather: weather}
+}
+
+// SaveGroup creates or replaces one of the logged-in user's location groups.
+// It expects the group name in the URL path and a JSON body with the locations of the group.
+func (service *GroupsHandler) SaveGroup(c *gin.Context) {
+	var form groupForm
+
+	// Bind incoming JSON data to the group form
+	if err := c.ShouldBindJSON(&form); err != nil {
+		helpers.RespondWithValidationErrors(c, err, form)
+		return
+	}
+
+	// Get the userID from the context (which should have been set during authentication)
+	userID, _ := c.Get("userID")
+	user_id := int(userID.(float64))
+
+	// Save the group under the name from the URL path
+	group, err := service.groups.SaveGroup(user_id, c.Param("name"), form.Locations)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidGroup) {
+			helpers.ClientError(c, http.StatusBadRequest, fmt.Sprintf("%v", err))
+			return
+		}
+		if errors.Is(err, services.ErrGroupLimitReached) {
+			helpers.ClientError(c, http.StatusConflict, fmt.Sprintf("%v", err))
+			return
+		}
+		helpers.ServerError(c, err)
+		return
+	}
+
+	// Return the saved group
+	c.JSON(http.StatusOK, gin.H{
+		"group": group,
+	})
+}
+
+// ListGroups returns all location groups of the logged-in user.
+func (service *GroupsHandler) ListGroups(c *gin.Context) {
+	// Get the userID from the context (which should have been set during authentication)
+	userID, _ := c.Get("userID")
+	user_id := int(userID.(float64))
+
+	// Fetch the user's groups
+	groups, err := service.groups.ListGroups(user_id)
+	if err != nil {
+		helpers.ServerError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"groups": groups,
+	})
+}
+
+// DeleteGroup deletes one of the logged-in user's location groups.
+func (service *GroupsHandler) DeleteGroup(c *gin.Context) {
+	// Get the userID from the context (which should have been set during authentication)
+	userID, _ := c.Get("userID")
+	user_id := int(userID.(float64))
+
+	// Delete the group, which must belong to the user
+	if err := service.groups.DeleteGroup(user_id, c.Param("name")); err != nil {
+		if errors.Is(err, services.ErrGroupNotFound) {
+			helpers.ClientError(c, http.StatusNotFound, "Group not found")
+			return
+		}
+		helpers.ServerError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Group deleted",
+	})
+}
+
+// GroupWeatherData handles the retrieval of weather data for every location of a named group.
+// It expects an API key and the group name ('name') from the URL; the group must belong to the key's user.
+func (service *GroupsHandler) GroupWeatherData(c *gin.Context) {
+	// Extract the API key from the headers or the URL
+	apiKey := helpers.APIKeyFromRequest(c)
+	if len(strings.TrimSpace(apiKey)) == 0 {
+		helpers.ClientError(c, http.StatusBadRequest, "api key is missing or invalid. Please include a valid API key in your request")
+		return
+	}
+
+	// Extract the group name from the URL
+	name := c.Query("name")
+	if name == "" {
+		helpers.ClientError(c, http.StatusBadRequest, "parameter name is missing")
+		return
+	}
+
+	// Extract the requested unit system
+	units, err := helpers.GetUnitsFromUrl(c)
+	if err != nil {
+		helpers.ClientError(c, http.StatusBadRequest, fmt.Sprintf("%v", err))
+		return
+	}
+
+	// Extract the requested shape of the items
+	shape, err := helpers.GetShapeFromUrl(c)
+	if err != nil {
+		helpers.ClientError(c, http.StatusBadRequest, fmt.Sprintf("%v", err))
+		return
+	}
+
+	// Authorize the API key
+	scopes, err := service.weather.APIKeyAuthorization(c.Request.Context(), apiKey)
+	if err != nil {
+		if errors.Is(err, services.ErrAPIKeyNotFound) {
+			helpers.ClientError(c, http.StatusUnauthorized, "API key has been disabled.")
+			return
+		}
+		helpers.ServerError(c, err)
+		return
+	}
+
+	// Reject keys whose scopes don't include this endpoint
+	if scopeDenied(c, scopes, services.ScopeBulk) {
+		return
+	}
+
+	// Resolve the group to its locations
+	locations, err := service.groups.GroupLocations(apiKey, name)
+	if err != nil {
+		if errors.Is(err, services.ErrGroupNotFound) {
+			helpers.ClientError(c, http.StatusNotFound, "Group not found")
+			return
+		}
+		helpers.ServerError(c, err)
+		return
+	}
+
+	// Fetch the weather data of the group's locations like a bulk request
+	results, err := service.weather.FetchBulkWeatherData(locations, nil)
+	if err != nil {
+		if upstreamErrorResponse(c, err) {
+			return
+		}
+		helpers.ServerError(c, err)
+		return
+	}
+	bulkWeatherData, notFoundList, _ := services.SplitBulkResults(results)
+
+	// Add the fields required by the requested unit system
+	for i := range bulkWeatherData {
+		bulkWeatherData[i] = services.ApplyUnits(bulkWeatherData[i], units)
+	}
+
+	// Send the group's weather data, along with the locations that were not found
+	response := gin.H{
+		"group": name,                                     // The name of the requested group
+		"bulk":  bulkWeatherItems(bulkWeatherData, shape), // Weather data for found locations
+	}
+	if len(notFoundList) > 0 {
+		response["not_found"] = notFoundList // Locations that were not found
+	}
+	c.JSON(http.StatusOK, response)
+}
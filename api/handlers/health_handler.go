@@ -0,0 +1,83 @@
+package handlers
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// HealthCheck verifies that a single dependency (e.g. MySQL or Redis) is reachable.
+type HealthCheck func(ctx context.Context) error
+
+// healthCheckTimeout bounds how long the readiness probe waits for all dependency checks.
+const healthCheckTimeout = 2 * time.Second
+
+// HealthHandler is a struct that serves the liveness and readiness probes.
+// Liveness only tells whether the process responds, while readiness also checks dependencies
+// and can be switched off during graceful shutdown so traffic drains before the server stops.
+type HealthHandler struct {
+	checks map[string]HealthCheck // Named dependency checks run by the readiness probe
+	ready  atomic.Bool            // Whether the service is willing to receive traffic
+}
+
+// NewHealthHandler creates a new instance of HealthHandler with the provided dependency checks.
+// The handler starts in the not-ready state; call SetReady once startup is complete.
+func NewHealthHandler(checks map[string]HealthCheck) *HealthHandler {
+	return &HealthHandler{checks: checks}
+}
+
+// SetReady marks the service as ready or not ready to receive traffic.
+// It is set to false at the start of graceful shutdown so load balancers stop routing new requests.
+func (service *HealthHandler) SetReady(ready bool) {
+	service.ready.Store(ready)
+}
+
+// Liveness responds with 200 as long as the process is able to serve requests.
+// It performs no dependency checks, so a slow database never causes the process to be restarted.
+func (service *HealthHandler) Liveness(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"status": "alive",
+	})
+}
+
+// Readiness responds with 200 only if the service is not shutting down and all dependencies are reachable.
+// Otherwise it responds with 503 and reports which dependency failed.
+func (service *HealthHandler) Readiness(c *gin.Context) {
+	// Fail fast while starting up or draining connections during shutdown
+	if !service.ready.Load() {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"status": "not ready",
+		})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), healthCheckTimeout)
+	defer cancel()
+
+	// Run every dependency check and collect the results
+	status := http.StatusOK
+	results := make(map[string]string, len(service.checks))
+	for name, check := range service.checks {
+		if err := check(ctx); err != nil {
+			log.Printf("readiness check %s failed: %v", name, err)
+			results[name] = "unavailable"
+			status = http.StatusServiceUnavailable
+			continue
+		}
+		results[name] = "ok"
+	}
+
+	readiness := "ready"
+	if status != http.StatusOK {
+		readiness = "not ready"
+	}
+
+	c.JSON(status, gin.H{
+		"status": readiness,
+		"checks": results,
+	})
+}
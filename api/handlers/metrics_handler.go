@@ -0,0 +1,67 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"havoAPI/internal/services"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CacheFootprintSource measures the size of the weather cache (see services.WeatherAPIService.CacheFootprint).
+type CacheFootprintSource func(ctx context.Context) (services.CacheFootprint, error)
+
+// StreamCounter reports the number of streaming connections currently served (see middlewares.StreamLimiter.Active).
+type StreamCounter func() int64
+
+// UpstreamCircuitSource reports the state of the WeatherAPI circuit breaker (see services.WeatherAPIService.UpstreamCircuit).
+type UpstreamCircuitSource func() (services.CircuitState, int)
+
+// MetricsHandler is a struct that exposes operational gauges in the Prometheus text format.
+type MetricsHandler struct {
+	footprint CacheFootprintSource  // Measures the number of weather keys and their memory usage
+	streams   StreamCounter         // Counts the open WebSocket and Server-Sent Events connections
+	circuit   UpstreamCircuitSource // Reports whether requests to WeatherAPI fail fast
+}
+
+// NewMetricsHandler creates a new instance of MetricsHandler with the provided cache footprint source,
+// stream counter and circuit breaker state.
+func NewMetricsHandler(footprint CacheFootprintSource, streams StreamCounter, circuit UpstreamCircuitSource) *MetricsHandler {
+	return &MetricsHandler{footprint: footprint, streams: streams, circuit: circuit}
+}
+
+// Metrics reports the number of weather cache keys and their estimated memory usage,
+// the number of open streaming connections and the state of the WeatherAPI circuit breaker as Prometheus gauges.
+// The memory gauge is left out when Redis can't report memory usage. If the last measurement of the cache
+// failed (e.g. Redis is unreachable), the cache gauges are left out rather than reported as an empty cache,
+// and havoapi_weather_cache_measurement_success tells so; the other gauges don't depend on Redis and are always reported.
+func (service *MetricsHandler) Metrics(c *gin.Context) {
+	var b strings.Builder
+	footprint, err := service.footprint(c.Request.Context())
+	if err != nil {
+		log.Printf("failed to measure the weather cache: %v", err)
+		writeGauge(&b, "havoapi_weather_cache_measurement_success", "Whether the last measurement of the weather cache succeeded.", 0)
+	} else {
+		writeGauge(&b, "havoapi_weather_cache_measurement_success", "Whether the last measurement of the weather cache succeeded.", 1)
+		writeGauge(&b, "havoapi_weather_cache_keys", "Number of weather keys in the Redis cache.", footprint.Keys)
+		if footprint.EstimatedBytes >= 0 {
+			writeGauge(&b, "havoapi_weather_cache_memory_bytes", "Estimated memory usage of the weather keys, extrapolated from a sample.", footprint.EstimatedBytes)
+			writeGauge(&b, "havoapi_weather_cache_memory_sampled_keys", "Number of weather keys whose memory usage was measured.", int64(footprint.SampledKeys))
+		}
+		writeGauge(&b, "havoapi_weather_cache_collected_timestamp_seconds", "Unix time at which the weather cache was last measured.", footprint.CollectedAt.Unix())
+	}
+	writeGauge(&b, "havoapi_stream_connections", "Number of open WebSocket and Server-Sent Events connections.", service.streams())
+	state, failures := service.circuit()
+	writeGauge(&b, "havoapi_upstream_circuit_state", "State of the WeatherAPI circuit breaker: 0 closed, 1 half-open, 2 open.", int64(state))
+	writeGauge(&b, "havoapi_upstream_consecutive_failures", "Number of consecutive transient WeatherAPI failures.", int64(failures))
+
+	c.Data(http.StatusOK, "text/plain; version=0.0.4; charset=utf-8", []byte(b.String()))
+}
+
+// writeGauge appends a gauge with its HELP and TYPE lines in the Prometheus text format.
+func writeGauge(b *strings.Builder, name, help string, value int64) {
+	fmt.Fprintf(b, "# HELP %s %s\n# TYPE %s gauge\n%s %d\n", name, help, name, name, value)
+}
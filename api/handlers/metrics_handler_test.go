@@ -0,0 +1,56 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"havoAPI/internal/services"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMetricsReportStreamsWhateverTheCacheMeasurement(t *testing.T) {
+	tests := []struct {
+		name        string
+		footprint   CacheFootprintSource
+		wantCache   bool
+		wantSuccess string
+	}{
+		{
+			name: "measured",
+			footprint: func(context.Context) (services.CacheFootprint, error) {
+				return services.CacheFootprint{Keys: 12, EstimatedBytes: -1, CollectedAt: time.Now()}, nil
+			},
+			wantCache:   true,
+			wantSuccess: "havoapi_weather_cache_measurement_success 1\n",
+		},
+		{
+			name: "measurement failed",
+			footprint: func(context.Context) (services.CacheFootprint, error) {
+				return services.CacheFootprint{}, errors.New("redis: connection refused")
+			},
+			wantCache:   false,
+			wantSuccess: "havoapi_weather_cache_measurement_success 0\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := NewMetricsHandler(tt.footprint, func() int64 { return 3 }, func() (services.CircuitState, int) {
+				return services.CircuitClosed, 0
+			})
+
+			w := serve(t, http.MethodGet, "/metrics", handler.Metrics, "/metrics", nil)
+			assertStatus(t, w, http.StatusOK)
+			body := w.Body.String()
+			for _, want := range []string{tt.wantSuccess, "havoapi_stream_connections 3\n", "havoapi_upstream_circuit_state 0\n"} {
+				if !strings.Contains(body, want) {
+					t.Errorf("metrics are missing %q:\n%s", want, body)
+				}
+			}
+			if got := strings.Contains(body, "havoapi_weather_cache_keys "); got != tt.wantCache {
+				t.Errorf("metrics report the cache keys = %v, want %v:\n%s", got, tt.wantCache, body)
+			}
+		})
+	}
+}
@@ -0,0 +1,51 @@
+package handlers
+
+import (
+	"errors"
+	"havoAPI/api/helpers"
+	"havoAPI/api/middlewares"
+	"havoAPI/internal/services"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RateLimitHandler is a struct that reports the rate limiter state of API keys.
+// It needs the weather service to authorize keys and the limiter registry to read their buckets.
+type RateLimitHandler struct {
+	weather  services.WeatherAPIServiceInterface // Interface used to authorize the API key
+	limiters *middlewares.RateLimiterRegistry    // Registry holding the per-key token buckets
+}
+
+// NewRateLimitHandler creates a new instance of RateLimitHandler with the provided weather service and limiter registry.
+func NewRateLimitHandler(weather services.WeatherAPIServiceInterface, limiters *middlewares.RateLimiterRegistry) *RateLimitHandler {
+	return &RateLimitHandler{weather: weather, limiters: limiters}
+}
+
+// RateLimitStatus returns the caller's remaining allowance, the limit and the seconds until the bucket is full again.
+// It expects the API key in a header or the 'key' query parameter and does not consume a token itself.
+func (service *RateLimitHandler) RateLimitStatus(c *gin.Context) {
+	// Extract the API key from the headers or the URL
+	apiKey := helpers.APIKeyFromRequest(c)
+	if len(strings.TrimSpace(apiKey)) == 0 {
+		helpers.ClientError(c, http.StatusBadRequest, "api key is missing or invalid. Please include a valid API key in your request")
+		return
+	}
+
+	// Authorize the API key so that limiter state is only reported to real key holders
+	_, err := service.weather.APIKeyAuthorization(c.Request.Context(), apiKey)
+	if err != nil {
+		if errors.Is(err, services.ErrAPIKeyNotFound) {
+			helpers.ClientError(c, http.StatusUnauthorized, "API key has been disabled.")
+			return
+		}
+		helpers.ServerError(c, err)
+		return
+	}
+
+	// Return the current state of the key's token bucket
+	c.JSON(http.StatusOK, gin.H{
+		"rate_limit": service.limiters.Status(apiKey),
+	})
+}
@@ -0,0 +1,88 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"havoAPI/api/helpers"
+	"havoAPI/internal/services"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// singleLocationParameters are the weather.current parameters that only apply to a single location.
+// The bulk lookup behind repeated 'q' parameters can't honor them, so they are rejected rather than ignored.
+var singleLocationParameters = []string{"airport", "lang", "aqi", "include", "ambiguous", "refresh", "max_age"}
+
+// weatherDataForQueries handles GET weather.current with repeated 'q' parameters (e.g. q=London&q=Paris).
+// The locations are fetched like a bulk request and answered in the same format, so location names
+// containing commas (e.g. "Paris, France") and coordinate pairs need no escaping or joining.
+// It takes the bulk parameters (units, format, shape, compact, multi_status) and requires
+// an API key with the bulk scope; the anonymous allowance only covers single locations.
+func (service *WeatherHandler) weatherDataForQueries(c *gin.Context, queries []string) {
+	// Extract the API key from the headers or the URL query string
+	apiKey := helpers.APIKeyFromRequest(c)
+	if strings.TrimSpace(apiKey) == "" {
+		helpers.ClientError(c, http.StatusBadRequest, fmt.Sprintf("%v", helpers.ErrMissingAPIKey))
+		return
+	}
+
+	// Reject the parameters a bulk lookup would silently ignore
+	for _, param := range singleLocationParameters {
+		if _, ok := c.GetQuery(param); ok {
+			helpers.ClientError(c, http.StatusBadRequest, fmt.Sprintf("parameter %s can't be combined with repeated q parameters", param))
+			return
+		}
+	}
+
+	// Extract the requested units, format, shape and status behavior
+	opts, err := bulkOptionsFromUrl(c)
+	if err != nil {
+		helpers.ClientError(c, http.StatusBadRequest, fmt.Sprintf("%v", err))
+		return
+	}
+
+	// Trim the locations and drop blank ones, as for the body of a bulk request
+	var qValues []string
+	for _, q := range queries {
+		if q = strings.TrimSpace(q); q != "" {
+			qValues = append(qValues, q)
+		}
+	}
+	if len(qValues) == 0 {
+		helpers.ClientError(c, http.StatusBadRequest, "parameter q is missing")
+		return
+	}
+
+	// Authorize the API key
+	scopes, err := service.weather.APIKeyAuthorization(c.Request.Context(), apiKey)
+	if err != nil {
+		// Handle case where the API key is invalid or disabled
+		if errors.Is(err, services.ErrAPIKeyNotFound) {
+			helpers.ClientError(c, http.StatusUnauthorized, "API key has been disabled.")
+			return
+		}
+		// For other errors, respond with a server error
+		helpers.ServerError(c, err)
+		return
+	}
+
+	// A list of locations is a multi-location lookup, whichever form it is sent in
+	if scopeDenied(c, scopes, services.ScopeBulk) {
+		return
+	}
+
+	// Fetch the locations like a bulk request without conditional locations
+	results, err := service.weather.FetchBulkWeatherData(qValues, nil)
+	if err != nil {
+		// Handle errors reported by WeatherAPI about the service itself
+		if upstreamErrorResponse(c, err) {
+			return
+		}
+		helpers.ServerError(c, err)
+		return
+	}
+
+	writeBulkWeatherData(c, results, opts)
+}
@@ -0,0 +1,85 @@
+package handlers
+
+import (
+	"context"
+	"havoAPI/internal/services"
+	"net/http"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestWeatherDataQueryForms(t *testing.T) {
+	tests := []struct {
+		name   string
+		target string
+		single []string // single lists the queries of single-location lookups.
+		bulk   []string // bulk lists the queries of the bulk lookup.
+	}{
+		{"single", "/weather?key=k&q=London", []string{"London"}, nil},
+		// A comma is part of the location (coordinates, "Paris, France"), so the list isn't split
+		{"comma-separated", "/weather?key=k&q=Paris,%20France", []string{"Paris, France"}, nil},
+		{"coordinates", "/weather?key=k&q=48.85,2.35", []string{"48.85,2.35"}, nil},
+		{"repeated", "/weather?key=k&q=London&q=Paris,%20France&q=%20&q=48.85,2.35", nil, []string{"London", "Paris, France", "48.85,2.35"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var single, bulk []string
+			weather := &fakeWeatherService{
+				fetchWeatherData: func(ctx context.Context, query string, opts services.WeatherOptions) (services.FormattedWeatherData, error) {
+					single = append(single, query)
+					return weatherAt(query), nil
+				},
+				fetchBulkWeatherData: func(queries []string, modifiedSince map[string]time.Time) ([]services.BulkResult, error) {
+					bulk = append(bulk, queries...)
+					var results []services.BulkResult
+					for _, q := range queries {
+						data := weatherAt(q)
+						results = append(results, services.BulkResult{Query: q, Data: &data})
+					}
+					return results, nil
+				},
+			}
+			w := serve(t, http.MethodGet, "/weather", NewWeatherHandler(weather).WeatherData, tt.target, nil)
+			assertStatus(t, w, http.StatusOK)
+
+			if !slices.Equal(single, tt.single) || !slices.Equal(bulk, tt.bulk) {
+				t.Errorf("single lookups %q and bulk lookup %q, want %q and %q", single, bulk, tt.single, tt.bulk)
+			}
+			if tt.bulk == nil {
+				return
+			}
+			var response struct {
+				Bulk []services.FormattedWeatherData `json:"bulk"`
+			}
+			decodeBody(t, w, &response)
+			var names []string
+			for _, data := range response.Bulk {
+				names = append(names, data.Name)
+			}
+			if !slices.Equal(names, tt.bulk) {
+				t.Errorf("bulk = %q, want %q", names, tt.bulk)
+			}
+		})
+	}
+}
+
+func TestRepeatedQueriesRejectSingleLocationParameters(t *testing.T) {
+	for _, param := range singleLocationParameters {
+		t.Run(param, func(t *testing.T) {
+			// The fake has no lookups set, so reaching one fails the test
+			weather := &fakeWeatherService{}
+			w := serve(t, http.MethodGet, "/weather", NewWeatherHandler(weather).WeatherData, "/weather?key=k&q=London&q=Paris&"+param+"=1", nil)
+			assertStatus(t, w, http.StatusBadRequest)
+		})
+	}
+}
+
+func TestRepeatedQueriesRequireAnAPIKey(t *testing.T) {
+	weather := &fakeWeatherService{}
+	w := serve(t, http.MethodGet, "/weather", NewWeatherHandler(weather).WeatherData, "/weather?q=London&q=Paris", nil)
+	assertStatus(t, w, http.StatusBadRequest)
+	if len(weather.authorizedKeys) != 0 {
+		t.Errorf("authorized keys %q without an API key", weather.authorizedKeys)
+	}
+}
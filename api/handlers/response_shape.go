@@ -0,0 +1,152 @@
+package handlers
+
+import (
+	"havoAPI/api/helpers"
+	"havoAPI/internal/services"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// nestedWeatherData is the nested shape of the weather data of a location (shape=nested).
+// It separates the location from the current weather, matching the structure of WeatherAPI's own responses.
+type nestedWeatherData struct {
+	Location           nestedLocation        `json:"location"`             // Location holds where the data applies.
+	Current            nestedCurrent         `json:"current"`              // Current holds the current weather at the location.
+	Source             string                `json:"source"`               // Source attributes the data to the provider that served it.
+	Query              string                `json:"query,omitempty"`      // Query is the location query as sent by the client.
+	MatchedNameDiffers bool                  `json:"matched_name_differs"` // MatchedNameDiffers is set when the query was resolved to a location with another name.
+	Meta               *services.WeatherMeta `json:"meta,omitempty"`       // Meta tells how fresh the data is; it is only set when requested.
+}
+
+// nestedLocation holds the location fields of the nested shape.
+type nestedLocation struct {
+	Name      string     `json:"name"`                // Name represents the name of the location.
+	Region    string     `json:"region"`              // Region represents the state or province of the location.
+	Country   string     `json:"country"`             // Country represents the country of the location.
+	Lat       float64    `json:"lat"`                 // Lat is the latitude of the location.
+	Lon       float64    `json:"lon"`                 // Lon is the longitude of the location.
+	TzID      string     `json:"tz_id"`               // TzID is the IANA time zone of the location.
+	LocalTime *time.Time `json:"localtime,omitempty"` // LocalTime is the local time at the location when the data was fetched, if known.
+}
+
+// nestedCurrent holds the current weather fields of the nested shape, including their color codes.
+type nestedCurrent struct {
+	LastUpdated       time.Time            `json:"last_updated"`                 // LastUpdated is the local time of the upstream observation.
+	TempC             float64              `json:"temp_c"`                       // Temperature in Celsius.
+	TempF             *float64             `json:"temp_f,omitempty"`             // Temperature in Fahrenheit, when imperial units are requested.
+	TempK             *float64             `json:"temp_k,omitempty"`             // Temperature in Kelvin, when Kelvin units are requested.
+	TempColor         string               `json:"temp_color"`                   // TempColor is the color code of the temperature.
+	TempTrend         string               `json:"temp_trend"`                   // TempTrend compares the temperature to the previous snapshot.
+	WindKph           float64              `json:"wind_kph"`                     // Wind speed in kilometers per hour.
+	WindMph           *float64             `json:"wind_mph,omitempty"`           // Wind speed in miles per hour, when imperial units are requested.
+	WindColor         string               `json:"wind_color"`                   // WindColor is the color code of the wind speed.
+	Cloud             int                  `json:"cloud"`                        // Cloud cover percentage.
+	CloudColor        string               `json:"cloud_color"`                  // CloudColor is the color code of the cloud cover.
+	VisibilityKm      float64              `json:"vis_km"`                       // Visibility in kilometers.
+	PressureMb        float64              `json:"pressure_mb"`                  // Atmospheric pressure in millibars.
+	Humidity          int                  `json:"humidity"`                     // Relative humidity in percent.
+	Comfort           string               `json:"comfort"`                      // Comfort summarizes temperature, humidity and wind in a category.
+	Condition         string               `json:"condition,omitempty"`          // Condition describes the weather condition.
+	ConditionCategory string               `json:"condition_category,omitempty"` // ConditionCategory classifies the condition (clear, cloudy, fog, rain, snow or storm).
+	AirQuality        *services.AirQuality `json:"air_quality,omitempty"`        // AirQuality is only set when air quality data was requested.
+}
+
+// compactWeatherData is the minimal shape of the weather data of a location (compact=true), for bandwidth-constrained
+// clients such as IoT devices. Keys are one or two letters long and no color codes are included.
+type compactWeatherData struct {
+	N  string  `json:"n"`           // N is the name of the location.
+	R  string  `json:"r,omitempty"` // R is the state or province of the location, telling apart places of the same name.
+	C  string  `json:"c,omitempty"` // C is the ISO 3166-1 alpha-2 code of the country, left out if the country is unknown.
+	T  float64 `json:"t"`           // T is the temperature in Celsius.
+	W  float64 `json:"w"`           // W is the wind speed in kilometers per hour.
+	Cl int     `json:"cl"`          // Cl is the cloud cover percentage.
+}
+
+// toCompactWeatherData converts the flat weather data of a location to the compact shape.
+func toCompactWeatherData(data services.FormattedWeatherData) compactWeatherData {
+	return compactWeatherData{
+		N:  data.Name,
+		R:  data.Region,
+		C:  countryCode(data.Country),
+		T:  data.TempC,
+		W:  data.WindKph,
+		Cl: data.Cloud,
+	}
+}
+
+// toNestedWeatherData converts the flat weather data of a location to the nested shape.
+func toNestedWeatherData(data services.FormattedWeatherData) nestedWeatherData {
+	return nestedWeatherData{
+		Location: nestedLocation{
+			Name:      data.Name,
+			Region:    data.Region,
+			Country:   data.Country,
+			Lat:       data.Lat,
+			Lon:       data.Lon,
+			TzID:      data.TzID,
+			LocalTime: data.LocalTime,
+		},
+		Current: nestedCurrent{
+			LastUpdated:       data.LastUpdated,
+			TempC:             data.TempC,
+			TempF:             data.TempF,
+			TempK:             data.TempK,
+			TempColor:         data.TempColor,
+			TempTrend:         data.TempTrend,
+			WindKph:           data.WindKph,
+			WindMph:           data.WindMph,
+			WindColor:         data.WindColor,
+			Cloud:             data.Cloud,
+			CloudColor:        data.CloudColor,
+			VisibilityKm:      data.VisibilityKm,
+			PressureMb:        data.PressureMb,
+			Humidity:          data.Humidity,
+			Comfort:           data.Comfort,
+			Condition:         data.Condition,
+			ConditionCategory: data.ConditionCategory,
+			AirQuality:        data.AirQuality,
+		},
+		Source:             data.Source,
+		Query:              data.Query,
+		MatchedNameDiffers: data.MatchedNameDiffers,
+		Meta:               data.Meta,
+	}
+}
+
+// singleWeatherResponse builds the response body of a single location in the requested shape.
+// The flat shape keeps the historical {"location": {...}} envelope, while the nested shape
+// is returned as is, with its "location" and "current" objects at the top level like WeatherAPI.
+// The compact shape is returned as is as well, saving the bytes of the envelope.
+func singleWeatherResponse(data services.FormattedWeatherData, shape string) any {
+	switch shape {
+	case helpers.ShapeNested:
+		return toNestedWeatherData(data)
+	case helpers.ShapeCompact:
+		return toCompactWeatherData(data)
+	}
+	return gin.H{"location": data}
+}
+
+// bulkWeatherItems returns the weather data of several locations in the requested shape.
+// The result always encodes as a JSON array, empty rather than null when no location was found.
+func bulkWeatherItems(bulkWeatherData []services.FormattedWeatherData, shape string) any {
+	switch shape {
+	case helpers.ShapeNested:
+		nested := make([]nestedWeatherData, 0, len(bulkWeatherData))
+		for _, data := range bulkWeatherData {
+			nested = append(nested, toNestedWeatherData(data))
+		}
+		return nested
+	case helpers.ShapeCompact:
+		compact := make([]compactWeatherData, 0, len(bulkWeatherData))
+		for _, data := range bulkWeatherData {
+			compact = append(compact, toCompactWeatherData(data))
+		}
+		return compact
+	}
+	if bulkWeatherData == nil {
+		return []services.FormattedWeatherData{}
+	}
+	return bulkWeatherData
+}
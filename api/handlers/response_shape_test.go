@@ -0,0 +1,91 @@
+package handlers
+
+import (
+	"encoding/json"
+	"havoAPI/api/helpers"
+	"havoAPI/internal/services"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestEveryShapeCarriesTheRegion(t *testing.T) {
+	data := services.FormattedWeatherData{Name: "Portland", Region: "Maine", Country: "United States of America"}
+
+	for _, shape := range []string{helpers.ShapeFlat, helpers.ShapeNested, helpers.ShapeCompact} {
+		body, err := json.Marshal(singleWeatherResponse(data, shape))
+		if err != nil {
+			t.Fatalf("%s: marshal failed: %v", shape, err)
+		}
+		if !strings.Contains(string(body), `"Maine"`) {
+			t.Errorf("%s shape has no region: %s", shape, body)
+		}
+	}
+
+	if row := bulkCSVRow(data); !slices.Contains(row, "Maine") {
+		t.Errorf("CSV row has no region: %v", row)
+	}
+}
+
+func TestCompactShapeLeavesOutAnEmptyRegion(t *testing.T) {
+	body, err := json.Marshal(toCompactWeatherData(services.FormattedWeatherData{Name: "Tashkent"}))
+	if err != nil {
+		t.Fatalf("marshal failed: %v", err)
+	}
+	if strings.Contains(string(body), `"r"`) {
+		t.Errorf("compact data has an empty region: %s", body)
+	}
+}
+
+func TestCountryCode(t *testing.T) {
+	tests := []struct {
+		country string
+		want    string
+	}{
+		{country: "United Kingdom", want: "GB"},
+		{country: "United States of America", want: "US"},
+		{country: "Uzbekistan", want: "UZ"},
+		{country: "uzbekistan", want: "UZ"},
+		{country: "Russia", want: "RU"},
+		{country: "South Korea", want: "KR"},
+		{country: "Czech Republic", want: "CZ"},
+		{country: "Bosnia &amp; Herzegovina", want: "BA"},
+		{country: "Trinidad and Tobago", want: "TT"},
+		{country: "Cote D Ivoire", want: "CI"},
+		{country: "Côte d’Ivoire", want: "CI"},
+		{country: "Reunion", want: "RE"},
+		{country: "St. Lucia", want: "LC"},
+		{country: "Saint Lucia", want: "LC"},
+		{country: "Myanmar", want: "MM"},
+		{country: "Hong Kong", want: "HK"},
+		{country: "Guinea Bissau", want: "GW"},
+		{country: "Timor L'Este", want: "TL"},
+		{country: "Virgin Islands (US)", want: "VI"},
+		{country: "Kyrgyz Republic", want: "KG"},
+		{country: "Cruise Ship", want: ""},
+		{country: "", want: ""},
+	}
+	for _, tt := range tests {
+		if got := countryCode(tt.country); got != tt.want {
+			t.Errorf("countryCode(%q) = %q, want %q", tt.country, got, tt.want)
+		}
+	}
+}
+
+func TestCompactShapeCarriesTheCountryCode(t *testing.T) {
+	body, err := json.Marshal(toCompactWeatherData(services.FormattedWeatherData{Name: "London", Country: "United Kingdom"}))
+	if err != nil {
+		t.Fatalf("marshal failed: %v", err)
+	}
+	if !strings.Contains(string(body), `"c":"GB"`) {
+		t.Errorf("compact data has no country code: %s", body)
+	}
+
+	body, err = json.Marshal(toCompactWeatherData(services.FormattedWeatherData{Name: "Atlantis", Country: "Testland"}))
+	if err != nil {
+		t.Fatalf("marshal failed: %v", err)
+	}
+	if strings.Contains(string(body), `"c"`) {
+		t.Errorf("compact data of an unknown country has a country code: %s", body)
+	}
+}
@@ -0,0 +1,161 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"havoAPI/api/helpers"
+	"havoAPI/internal/services"
+	"log"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/net/websocket"
+)
+
+// maxStreamLocations is the maximum number of locations a single stream connection may subscribe to.
+const maxStreamLocations = 10
+
+// streamProtocol is the WebSocket subprotocol of the weather stream. Browsers sending their API key as an
+// "apikey.<key>" subprotocol must offer it too, since they drop connections that select none of their offers,
+// and selecting the key would echo it back.
+const streamProtocol = "havo.stream"
+
+// streamMessage is a single message pushed to a weather stream client.
+// Exactly one of Location and Error is set.
+type streamMessage struct {
+	Location *services.FormattedWeatherData `json:"location,omitempty"` // Location is the latest weather data of a subscribed location.
+	Q        string                         `json:"q,omitempty"`        // Q is the query of the location an error refers to.
+	Error    string                         `json:"error,omitempty"`    // Error describes why a subscribed location can't be served.
+}
+
+// WeatherStream upgrades the request to a WebSocket and pushes the weather data of the requested locations
+// every time it is refreshed in the cache. It expects an API key and a comma-separated list of locations
+// ('q', e.g. "London,Paris") from the URL. Browsers, which can't set headers on WebSockets, may offer the key
+// as an "apikey.<key>" subprotocol along with the havo.stream subprotocol instead. The current data of every location is sent right after connecting.
+func (service *WeatherHandler) WeatherStream(c *gin.Context) {
+	// Extract API key and the list of locations from the request URL
+	apiKey, query, err := helpers.GetParametersFromUrl(c)
+	if err != nil {
+		helpers.ClientError(c, http.StatusBadRequest, fmt.Sprintf("%v", err))
+		return
+	}
+
+	// Split the list of locations, dropping blank entries
+	queries := helpers.SplitLocationList(query)
+	if len(queries) == 0 {
+		helpers.ClientError(c, http.StatusBadRequest, "parameter q is missing")
+		return
+	}
+	if len(queries) > maxStreamLocations {
+		helpers.ClientError(c, http.StatusBadRequest, fmt.Sprintf("parameter q may list at most %d locations", maxStreamLocations))
+		return
+	}
+
+	// Authorize the API key before upgrading the connection
+	scopes, err := service.weather.APIKeyAuthorization(c.Request.Context(), apiKey)
+	if err != nil {
+		if errors.Is(err, services.ErrAPIKeyNotFound) {
+			helpers.ClientError(c, http.StatusUnauthorized, "API key has been disabled.")
+			return
+		}
+		helpers.ServerError(c, err)
+		return
+	}
+
+	// Reject keys whose scopes don't include this endpoint
+	if scopeDenied(c, scopes, services.ScopeStream) {
+		return
+	}
+
+	// Subscribe before fetching the current data, so no refresh in between is missed
+	updates, unsubscribe, err := service.weather.SubscribeWeatherUpdates(queries)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidCoordinates) || errors.Is(err, services.ErrInvalidIATACode) {
+			helpers.ClientError(c, http.StatusBadRequest, fmt.Sprintf("%v", err))
+			return
+		}
+		if errors.Is(err, services.ErrLocationNotAllowed) {
+			helpers.ClientError(c, http.StatusForbidden, fmt.Sprintf("%v", err))
+			return
+		}
+		helpers.ServerError(c, err)
+		return
+	}
+	defer unsubscribe()
+
+	// Upgrade the connection; the Origin header is not checked since access is granted by the API key
+	server := websocket.Server{
+		Handshake: selectStreamProtocol,
+		Handler: func(ws *websocket.Conn) {
+			service.streamWeather(c.Request.Context(), ws, queries, updates)
+		},
+	}
+	server.ServeHTTP(c.Writer, c.Request)
+}
+
+// selectStreamProtocol accepts the connection with the havo.stream subprotocol if the client offered it,
+// and without a subprotocol otherwise. The other offers (e.g. the API key) are never selected.
+func selectStreamProtocol(config *websocket.Config, r *http.Request) error {
+	offered := config.Protocol
+	config.Protocol = nil
+	if slices.Contains(offered, streamProtocol) {
+		config.Protocol = []string{streamProtocol}
+	}
+	return nil
+}
+
+// streamWeather sends the current weather data of every location, then pushes every update
+// until the client disconnects, the request context is canceled or a message can't be delivered.
+func (service *WeatherHandler) streamWeather(ctx context.Context, ws *websocket.Conn, queries []string, updates <-chan services.FormattedWeatherData) {
+	defer ws.Close()
+
+	// The stream outlives the server's read and write timeouts, which would otherwise cut it off
+	if err := ws.SetDeadline(time.Time{}); err != nil {
+		log.Printf("failed to clear stream deadlines: %v", err)
+		return
+	}
+
+	// Stop streaming when the client closes the connection; clients are not expected to send anything
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go func() {
+		var discard string
+		for websocket.Message.Receive(ws, &discard) == nil {
+		}
+		cancel()
+	}()
+
+	// Send the current data of every location first
+	for _, q := range queries {
+		message := streamMessage{}
+		weatherData, err := service.weather.FetchWeatherData(ctx, q, services.WeatherOptions{})
+		switch {
+		case err == nil:
+			message.Location = &weatherData
+		case errors.Is(err, services.ErrNoLocationFound), errors.Is(err, services.ErrLocationNotAllowed):
+			message.Q, message.Error = q, fmt.Sprintf("%v", err)
+		default:
+			// The location is still subscribed and will be pushed with the next refresh
+			log.Printf("failed to fetch initial stream data for %s: %v", q, err)
+			continue
+		}
+		if err := websocket.JSON.Send(ws, message); err != nil {
+			return
+		}
+	}
+
+	// Push every refresh of the subscribed locations
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case weatherData := <-updates:
+			if err := websocket.JSON.Send(ws, streamMessage{Location: &weatherData}); err != nil {
+				return
+			}
+		}
+	}
+}
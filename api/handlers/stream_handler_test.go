@@ -0,0 +1,61 @@
+package handlers
+
+import (
+	"context"
+	"havoAPI/api/middlewares"
+	"havoAPI/internal/services"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/net/websocket"
+)
+
+// dialStream connects to the weather stream of a server that refuses API keys in the query string,
+// offering the given subprotocols, and returns the connection with the first message received.
+func dialStream(t *testing.T, weather *fakeWeatherService, protocols []string) (*websocket.Conn, streamMessage) {
+	t.Helper()
+	router := gin.New()
+	router.GET("/weather.stream", middlewares.RequireHeaderAPIKey(), NewWeatherHandler(weather).WeatherStream)
+	server := httptest.NewServer(router)
+	t.Cleanup(server.Close)
+
+	config, err := websocket.NewConfig("ws"+strings.TrimPrefix(server.URL, "http")+"/weather.stream?q=London", "http://localhost/")
+	if err != nil {
+		t.Fatalf("NewConfig failed: %v", err)
+	}
+	config.Protocol = protocols
+	ws, err := websocket.DialConfig(config)
+	if err != nil {
+		t.Fatalf("dialing the stream failed: %v", err)
+	}
+	t.Cleanup(func() { ws.Close() })
+
+	var message streamMessage
+	if err := websocket.JSON.Receive(ws, &message); err != nil {
+		t.Fatalf("receiving the first message failed: %v", err)
+	}
+	return ws, message
+}
+
+func TestWeatherStreamAcceptsTheAPIKeyAsASubprotocol(t *testing.T) {
+	weather := &fakeWeatherService{
+		fetchWeatherData: func(ctx context.Context, query string, opts services.WeatherOptions) (services.FormattedWeatherData, error) {
+			return weatherAt(query), nil
+		},
+	}
+
+	ws, message := dialStream(t, weather, []string{streamProtocol, "apikey.browser-key"})
+	if message.Location == nil || message.Location.Name != "London" {
+		t.Errorf("first message = %+v, want the weather data of London", message)
+	}
+	if len(weather.authorizedKeys) != 1 || weather.authorizedKeys[0] != "browser-key" {
+		t.Errorf("authorized keys = %q, want the key of the subprotocol", weather.authorizedKeys)
+	}
+
+	// The stream protocol is selected, so the key is never echoed back
+	if got := ws.Config().Protocol; len(got) != 1 || got[0] != streamProtocol {
+		t.Errorf("selected protocols = %q, want [%s]", got, streamProtocol)
+	}
+}
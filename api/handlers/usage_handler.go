@@ -0,0 +1,55 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"havoAPI/api/helpers"
+	"havoAPI/internal/services"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// UsageHandler is a struct that holds the service reporting the daily usage of API keys.
+type UsageHandler struct {
+	usage services.UsageServiceInterface // Interface to interact with the usage service layer
+}
+
+// NewUsageHandler creates a new instance of UsageHandler with the provided usage service.
+func NewUsageHandler(usage services.UsageServiceInterface) *UsageHandler {
+	return &UsageHandler{usage: usage}
+}
+
+// APIKeyUsage returns the daily request totals of one of the logged-in user's API keys.
+// It expects the API key in the URL path and an optional date range ('from' and 'to', YYYY-MM-DD) in the query.
+func (service *UsageHandler) APIKeyUsage(c *gin.Context) {
+	// Get the userID from the context (which should have been set during authentication)
+	userID, _ := c.Get("userID")
+	user_id := int(userID.(float64))
+
+	// Fetch the usage series of the key within the requested range
+	usage, err := service.usage.DailyUsage(user_id, c.Param("key"), c.Query("from"), c.Query("to"))
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidUsageRange) {
+			helpers.ClientError(c, http.StatusBadRequest, fmt.Sprintf("%v", err))
+			return
+		}
+		if errors.Is(err, services.ErrAPIKeyNotFound) {
+			helpers.ClientError(c, http.StatusNotFound, fmt.Sprintf("%v", err))
+			return
+		}
+		helpers.ServerError(c, err)
+		return
+	}
+
+	// Sum the daily totals for convenience
+	total := 0
+	for _, day := range usage {
+		total += day.Requests
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"usage": usage,
+		"total": total,
+	})
+}
@@ -3,9 +3,14 @@ package handlers
 import (
 	"errors"
 	"fmt"
+	"havoAPI/api/config"
 	"havoAPI/api/helpers"
+	"havoAPI/internal/clock"
 	"havoAPI/internal/services"
+	"log"
 	"net/http"
+	"strconv"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 )
@@ -13,12 +18,14 @@ import (
 // UserHandler is a struct that holds the service for user-related operations.
 type UserHandler struct {
 	user services.UsersServiceInterface // Interface to interact with the user service layer
+	cfg  *config.Config                 // Application config (JWT secret and TTL)
+	clk  clock.Clock                    // Time source of the issued JWTs
 }
 
-// NewUsersHandler creates a new instance of UserHandler with the provided user service.
+// NewUsersHandler creates a new instance of UserHandler with the provided user service, config and clock.
 // This is typically called when setting up the handler for routing.
-func NewUsersHandler(user services.UsersServiceInterface) *UserHandler {
-	return &UserHandler{user: user}
+func NewUsersHandler(user services.UsersServiceInterface, cfg *config.Config, clk clock.Clock) *UserHandler {
+	return &UserHandler{user: user, cfg: cfg, clk: clk}
 }
 
 // Signup handles the user signup process.
@@ -49,6 +56,11 @@ func (service *UserHandler) Signup(c *gin.Context) {
 			helpers.ClientError(c, http.StatusConflict, "Username already exists. Consider using a different one or check if you already have an account.")
 			return
 		}
+		// Handle case when the email already belongs to another account
+		if errors.Is(err, services.ErrEmailExists) {
+			helpers.ClientError(c, http.StatusConflict, "Email already exists. Check if you already have an account.")
+			return
+		}
 		// If another error occurs, respond with a server error
 		helpers.ServerError(c, err)
 		return
@@ -92,7 +104,7 @@ func (service *UserHandler) Login(c *gin.Context) {
 	}
 
 	// Create and sign a JWT token for the authenticated user
-	tokenString, err := helpers.CreateAndSignJWT(userID)
+	tokenString, err := helpers.CreateAndSignJWT(service.clk, userID, service.cfg.JWTSecretKey, service.cfg.JWTTTL)
 	if err != nil {
 		// Respond with a server error if JWT creation fails
 		helpers.ServerError(c, err)
@@ -108,9 +120,17 @@ func (service *UserHandler) Login(c *gin.Context) {
 	})
 }
 
-// Logout handles user logout by clearing the JWT token from the client's cookies.
-// It sends a success message once the token is removed.
+// Logout handles user logout by revoking the JWT token server-side and clearing it from the client's cookies.
+// It sends a success message once the token is removed, so a stolen copy of the token stops working too.
 func (service *UserHandler) Logout(c *gin.Context) {
+	// Revoke the token until its expiry (the ID and expiry were set by the authorization middleware)
+	jti := c.GetString("jti")
+	expiresAt := c.GetTime("tokenExpiresAt")
+	if err := service.user.RevokeToken(jti, expiresAt); err != nil {
+		helpers.ServerError(c, err)
+		return
+	}
+
 	// Clear the JWT token stored in the "u_auth" cookie
 	c.SetCookie("u_auth", "", -1, "", "", false, true)
 
@@ -139,3 +159,300 @@ func (service *UserHandler) UserDashboard(c *gin.Context) {
 		"Your API key": apiKey,
 	})
 }
+
+// CreateAPIKey creates an additional API key for the logged-in user, optionally limited to a set of scopes.
+// It expects a JSON body with the requested scopes; weather endpoints outside them answer the key with 403 Forbidden.
+func (service *UserHandler) CreateAPIKey(c *gin.Context) {
+	var form apiKeyForm
+
+	// Bind incoming JSON data to the API key form
+	if err := c.ShouldBindJSON(&form); err != nil {
+		helpers.RespondWithValidationErrors(c, err, form)
+		return
+	}
+
+	// Get the userID from the context (which should have been set during authentication)
+	userID, _ := c.Get("userID")
+	user_id := int(userID.(float64))
+
+	// Generate the key with the requested scopes
+	apiKey, err := service.user.CreateAPIKey(user_id, form.Scopes)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidScope) {
+			helpers.ClientError(c, http.StatusBadRequest, fmt.Sprintf("%v", err))
+			return
+		}
+		if errors.Is(err, services.ErrAPIKeyLimitReached) || errors.Is(err, services.ErrAPIKeyAlreadyExists) {
+			helpers.ClientError(c, http.StatusConflict, fmt.Sprintf("%v", err))
+			return
+		}
+		helpers.ServerError(c, err)
+		return
+	}
+
+	// Return the new key and its scopes
+	c.JSON(http.StatusCreated, apiKey)
+}
+
+// DeleteAPIKeys deletes several of the logged-in user's API keys in one transaction.
+// It expects a JSON body listing key IDs or key prefixes and reports the outcome of each one;
+// keys of other users are reported as not found.
+func (service *UserHandler) DeleteAPIKeys(c *gin.Context) {
+	var form apiKeysDeleteForm
+
+	// Bind incoming JSON data to the deletion form
+	if err := c.ShouldBindJSON(&form); err != nil {
+		helpers.RespondWithValidationErrors(c, err, form)
+		return
+	}
+
+	// Get the userID from the context (which should have been set during authentication)
+	userID, _ := c.Get("userID")
+	user_id := int(userID.(float64))
+
+	// Delete the keys owned by the user
+	results, err := service.user.DeleteAPIKeys(user_id, form.Keys)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidAPIKeyDeletion) {
+			helpers.ClientError(c, http.StatusBadRequest, fmt.Sprintf("%v", err))
+			return
+		}
+		helpers.ServerError(c, err)
+		return
+	}
+
+	// Count the deleted keys for the summary
+	deleted := 0
+	for _, result := range results {
+		if result.Deleted {
+			deleted++
+		}
+	}
+
+	// Return the outcome of every requested key
+	c.JSON(http.StatusOK, gin.H{
+		"deleted": deleted,
+		"results": results,
+	})
+}
+
+// UpdatePreferences stores the logged-in user's preferred units and language.
+// Weather requests made with the user's JWT cookie use them whenever the units or lang parameter is left out.
+func (service *UserHandler) UpdatePreferences(c *gin.Context) {
+	var form preferencesForm
+
+	// Bind incoming JSON data to the preferences form
+	if err := c.ShouldBindJSON(&form); err != nil {
+		helpers.RespondWithValidationErrors(c, err, form)
+		return
+	}
+
+	// Get the userID from the context (which should have been set during authentication)
+	userID, _ := c.Get("userID")
+	user_id := int(userID.(float64))
+
+	// Validate and store the preferences
+	prefs := services.Preferences{Units: form.Units, Lang: form.Lang}
+	if err := service.user.SetPreferences(user_id, prefs); err != nil {
+		if errors.Is(err, services.ErrInvalidPreferences) {
+			helpers.ClientError(c, http.StatusBadRequest, fmt.Sprintf("%v", err))
+			return
+		}
+		if errors.Is(err, services.ErrUserNotFound) {
+			helpers.ClientError(c, http.StatusNotFound, "User not found")
+			return
+		}
+		helpers.ServerError(c, err)
+		return
+	}
+
+	// Return the stored preferences
+	c.JSON(http.StatusOK, gin.H{
+		"preferences": prefs,
+	})
+}
+
+// ChangePassword changes the logged-in user's password.
+// It expects a JSON body with the current and the new password. On success every existing session of the user
+// is invalidated, and a fresh JWT is issued so the current client stays logged in.
+func (service *UserHandler) ChangePassword(c *gin.Context) {
+	var form changePasswordForm
+
+	// Bind incoming JSON data to the changePassword form
+	if err := c.ShouldBindJSON(&form); err != nil {
+		helpers.RespondWithValidationErrors(c, err, form)
+		return
+	}
+
+	// Validate the new password with the same rules as on signup
+	if err := helpers.ValidatePassword(form.NewPassword); err != nil {
+		helpers.ClientError(c, http.StatusBadRequest, fmt.Sprintf("%v", err))
+		return
+	}
+
+	// Get the userID from the context (which should have been set during authentication)
+	userID, _ := c.Get("userID")
+	user_id := int(userID.(float64))
+
+	// Change the password and invalidate all sessions issued before now
+	if err := service.user.ChangePassword(user_id, form.CurrentPassword, form.NewPassword); err != nil {
+		if errors.Is(err, services.ErrInvalidUserCredentials) {
+			helpers.ClientError(c, http.StatusUnauthorized, "Current password is incorrect")
+			return
+		}
+		if errors.Is(err, services.ErrUserNotFound) {
+			helpers.ClientError(c, http.StatusNotFound, "User not found")
+			return
+		}
+		helpers.ServerError(c, err)
+		return
+	}
+
+	// Issue a fresh token, since the one used for this request is no longer valid
+	tokenString, err := helpers.CreateAndSignJWT(service.clk, user_id, service.cfg.JWTSecretKey, service.cfg.JWTTTL)
+	if err != nil {
+		helpers.ServerError(c, err)
+		return
+	}
+	helpers.SetCookie(c, tokenString)
+
+	// Return a success response after the password change
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Password changed. All other sessions have been logged out.",
+	})
+}
+
+// RevokeUserSessions invalidates every JWT issued to the given user so far, logging them out everywhere.
+// It is intended for internal operations (e.g. a compromised account) and must be protected by the admin authorization middleware.
+func (service *UserHandler) RevokeUserSessions(c *gin.Context) {
+	// Parse the user ID from the URL path
+	userID, err := strconv.Atoi(c.Param("id"))
+	if err != nil || userID <= 0 {
+		helpers.ClientError(c, http.StatusBadRequest, "'id' must be a positive integer")
+		return
+	}
+
+	// Invalidate all sessions issued before now
+	if err := service.user.InvalidateSessions(userID); err != nil {
+		if errors.Is(err, services.ErrUserNotFound) {
+			helpers.ClientError(c, http.StatusNotFound, "User not found")
+			return
+		}
+		helpers.ServerError(c, err)
+		return
+	}
+
+	// Return a success response after the sessions are revoked
+	c.JSON(http.StatusOK, gin.H{
+		"message": fmt.Sprintf("All sessions of user %d have been revoked", userID),
+	})
+}
+
+// Pagination bounds for the admin users list.
+const (
+	defaultUsersPageLimit = 20  // Number of users returned when no limit is given
+	maxUsersPageLimit     = 100 // Upper bound on the limit to prevent expensive full-table scans
+)
+
+// ListUsers returns a paginated list of users with their non-sensitive fields and the total user count.
+// It is intended for internal operations and must be protected by the admin authorization middleware.
+func (service *UserHandler) ListUsers(c *gin.Context) {
+	// Extract and validate the pagination parameters from the URL
+	limit, offset, err := helpers.GetPaginationFromUrl(c, defaultUsersPageLimit, maxUsersPageLimit)
+	if err != nil {
+		helpers.ClientError(c, http.StatusBadRequest, fmt.Sprintf("%v", err))
+		return
+	}
+
+	// Fetch the requested page of users and the total count
+	users, total, err := service.user.ListUsers(limit, offset)
+	if err != nil {
+		helpers.ServerError(c, err)
+		return
+	}
+
+	// Return the page of users along with the pagination details
+	c.JSON(http.StatusOK, gin.H{
+		"users":  users,
+		"total":  total,
+		"limit":  limit,
+		"offset": offset,
+	})
+}
+
+// maxImportUsers bounds the number of users of a single admin import, since every row hashes a password.
+const maxImportUsers = 100
+
+// importUserResult reports the outcome of a single row of an admin import.
+type importUserResult struct {
+	Index    int    `json:"index"`             // Index is the position of the row in the request.
+	Username string `json:"username"`          // Username is the username of the row.
+	Created  bool   `json:"created"`           // Created tells whether the user was created.
+	APIKey   string `json:"api_key,omitempty"` // APIKey is the API key generated for a created user.
+	Error    string `json:"error,omitempty"`   // Error describes why the row was rejected.
+}
+
+// ImportUsers creates users in bulk from a JSON array of users, each with a generated API key.
+// Every row is validated and created on its own, so a bad row (e.g. a duplicate username or a weak password)
+// is reported in the results without aborting the rest of the import.
+// It is intended for internal operations and must be protected by the admin authorization middleware.
+func (service *UserHandler) ImportUsers(c *gin.Context) {
+	var rows []importUserForm
+
+	// Bind incoming JSON data to the list of users
+	if err := c.ShouldBindJSON(&rows); err != nil {
+		helpers.ClientError(c, http.StatusBadRequest, "request body must be a JSON array of users")
+		return
+	}
+	if len(rows) == 0 {
+		helpers.ClientError(c, http.StatusBadRequest, "at least one user is required")
+		return
+	}
+	if len(rows) > maxImportUsers {
+		helpers.ClientError(c, http.StatusBadRequest, fmt.Sprintf("at most %d users can be imported at once", maxImportUsers))
+		return
+	}
+
+	// Create every row separately, collecting the outcome of each
+	results := make([]importUserResult, 0, len(rows))
+	created := 0
+	for i, row := range rows {
+		result := importUserResult{Index: i, Username: row.Username}
+
+		// Validate the row like a signup would
+		if strings.TrimSpace(row.Name) == "" || strings.TrimSpace(row.Surname) == "" || strings.TrimSpace(row.Username) == "" {
+			result.Error = "name, surname and username are required"
+			results = append(results, result)
+			continue
+		}
+		if err := helpers.ValidatePassword(row.Password); err != nil {
+			result.Error = fmt.Sprintf("%v", err)
+			results = append(results, result)
+			continue
+		}
+
+		// Create the user and their API key
+		apiKey, err := service.user.ImportUser(row.Name, row.Surname, row.Username, row.Password)
+		switch {
+		case err == nil:
+			result.Created, result.APIKey = true, apiKey
+			created++
+		case errors.Is(err, services.ErrUsernameExists):
+			result.Error = "username already exists"
+		case errors.Is(err, services.ErrEmailExists):
+			result.Error = "email already exists"
+		default:
+			// Keep the internals out of the response, like ServerError does
+			log.Printf("failed to import user %q: %v", row.Username, err)
+			result.Error = "an unexpected server error occurred"
+		}
+		results = append(results, result)
+	}
+
+	// Return the outcome of every row along with the totals
+	c.JSON(http.StatusOK, gin.H{
+		"results": results,
+		"created": created,
+		"failed":  len(rows) - created,
+	})
+}
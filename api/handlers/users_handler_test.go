@@ -0,0 +1,56 @@
+package handlers
+
+import (
+	"fmt"
+	"havoAPI/api/config"
+	"havoAPI/internal/clock"
+	"havoAPI/internal/services"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// fakeUsersService is a UsersServiceInterface whose behavior is set per test.
+type fakeUsersService struct {
+	services.UsersServiceInterface
+
+	insertNewUser func(name, surname, username, password string) error
+}
+
+func (s *fakeUsersService) InsertNewUser(name, surname, username, password string) error {
+	return s.insertNewUser(name, surname, username, password)
+}
+
+func TestSignupReportsDuplicates(t *testing.T) {
+	tests := []struct {
+		name        string
+		err         error
+		wantStatus  int
+		wantMessage string
+	}{
+		{name: "created", wantStatus: http.StatusCreated},
+		{name: "username taken", err: services.ErrUsernameExists, wantStatus: http.StatusConflict, wantMessage: "Username already exists"},
+		{name: "email taken", err: services.ErrEmailExists, wantStatus: http.StatusConflict, wantMessage: "Email already exists"},
+		{name: "other failure", err: fmt.Errorf("insert failed"), wantStatus: http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			users := &fakeUsersService{insertNewUser: func(name, surname, username, password string) error {
+				return tt.err
+			}}
+			handler := NewUsersHandler(users, &config.Config{}, clock.Real{}).Signup
+
+			body := strings.NewReader(`{"name": "Ada", "surname": "Lovelace", "username": "ada", "password": "Secret-password-1!"}`)
+			w := serve(t, http.MethodPost, "/signup", handler, "/signup", body)
+			assertStatus(t, w, tt.wantStatus)
+
+			var response struct {
+				Error string `json:"error"`
+			}
+			decodeBody(t, w, &response)
+			if !strings.HasPrefix(response.Error, tt.wantMessage) {
+				t.Errorf("error = %q, want it to start with %q", response.Error, tt.wantMessage)
+			}
+		})
+	}
+}
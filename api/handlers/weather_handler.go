@@ -5,7 +5,13 @@ import (
 	"fmt"
 	"havoAPI/api/helpers"
 	"havoAPI/internal/services"
+	"havoAPI/internal/units"
+	"log"
+	"net"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
@@ -25,47 +31,443 @@ func NewWeatherHandler(weather services.WeatherAPIServiceInterface) *WeatherHand
 // WeatherData handles the retrieval of weather data for a specific location.
 // It expects an API key and a query parameter (location) from the URL,
 // performs authorization and fetches the weather data for the location.
+// Requests without a key are served from the anonymous allowance of the client IP, if one is configured.
+// Repeated 'q' parameters switch to the bulk behavior (see weatherDataForQueries).
 func (service *WeatherHandler) WeatherData(c *gin.Context) {
+	// Several 'q' parameters (e.g. q=London&q=Paris) ask for a list of locations, served like a bulk request
+	if queries := c.QueryArray("q"); len(queries) > 1 {
+		service.weatherDataForQueries(c, queries)
+		return
+	}
+
 	// Extract API key and query (location) from the request URL
 	apiKey, query, err := helpers.GetParametersFromUrl(c)
+	anonymous := errors.Is(err, helpers.ErrMissingAPIKey)
+	if anonymous {
+		// Without a key, the location query still has to be valid
+		query, err = helpers.GetQueryFromUrl(c)
+	}
 	if err != nil {
 		// If there is an issue with the parameters, respond with an error
 		helpers.ClientError(c, http.StatusBadRequest, fmt.Sprintf("%v", err))
 		return
 	}
 
+	// Extract the requested units, language and extra data
+	opts, err := weatherOptionsFromUrl(c)
+	if err != nil {
+		helpers.ClientError(c, http.StatusBadRequest, fmt.Sprintf("%v", err))
+		return
+	}
+
+	// Extract the requested response shape
+	shape, err := helpers.GetShapeFromUrl(c)
+	if err != nil {
+		helpers.ClientError(c, http.StatusBadRequest, fmt.Sprintf("%v", err))
+		return
+	}
+	// The compact shape has no room for the metadata block
+	if opts.Meta && shape == helpers.ShapeCompact {
+		helpers.ClientError(c, http.StatusBadRequest, "parameter include=meta can't be combined with compact=true")
+		return
+	}
+
+	if anonymous {
+		// Count keyless requests against the daily allowance of the client IP
+		if !service.allowAnonymous(c) {
+			return
+		}
+	} else {
+		// Authorize the API key
+		scopes, err := service.weather.APIKeyAuthorization(c.Request.Context(), apiKey)
+		if err != nil {
+			// Handle case where the API key is invalid or disabled
+			if errors.Is(err, services.ErrAPIKeyNotFound) {
+				helpers.ClientError(c, http.StatusUnauthorized, "API key has been disabled.")
+				return
+			}
+			// For other errors, respond with a server error
+			helpers.ServerError(c, err)
+			return
+		}
+
+		// Reject keys whose scopes don't include this endpoint
+		if scopeDenied(c, scopes, services.ScopeCurrent) {
+			return
+		}
+	}
+
+	// Geolocate the caller by their IP address instead of a named location
+	if query == autoIPQuery {
+		service.weatherDataByIP(c, opts, shape)
+		return
+	}
+
+	// Optionally let the client disambiguate queries that match several locations
+	switch c.Query("ambiguous") {
+	case "", "first":
+		// Default behavior: use whatever location WeatherAPI picks first
+	case "list":
+		candidates, err := service.weather.SearchLocations(c.Request.Context(), query)
+		if err != nil {
+			if errors.Is(err, services.ErrNoLocationFound) {
+				helpers.ClientError(c, http.StatusNotFound, fmt.Sprintf("%v", err))
+				return
+			}
+			if errors.Is(err, services.ErrInvalidCoordinates) || errors.Is(err, services.ErrInvalidIATACode) {
+				helpers.ClientError(c, http.StatusBadRequest, fmt.Sprintf("%v", err))
+				return
+			}
+			// The candidates are subject to the location allowlist, like the weather itself
+			if errors.Is(err, services.ErrLocationNotAllowed) {
+				helpers.ClientError(c, http.StatusForbidden, fmt.Sprintf("%v", err))
+				return
+			}
+			if upstreamErrorResponse(c, err) {
+				return
+			}
+			helpers.ServerError(c, err)
+			return
+		}
+
+		// More than one match: return the candidates instead of arbitrary weather
+		if len(candidates) > 1 {
+			c.JSON(http.StatusMultipleChoices, gin.H{
+				"message":    "The query matches multiple locations. Please refine it using one of the candidates.",
+				"candidates": candidates,
+			})
+			return
+		}
+	default:
+		helpers.ClientError(c, http.StatusBadRequest, "parameter ambiguous must be either 'first' or 'list'")
+		return
+	}
+
+	// Answer conditional requests from the cached content hash alone, skipping the fetch and decoding of the data.
+	// Any error (e.g. nothing cached) falls through to a regular lookup, which reports it if it persists.
+	// Responses with metadata change every second (the remaining TTL), so they are never revalidated,
+	// and a forced refresh or a max age must be checked against the entry itself whatever the client has.
+	if c.GetHeader("If-None-Match") != "" && !opts.Meta && !opts.ForceRefresh && opts.MaxAge == 0 {
+		hash, err := service.weather.CachedWeatherDataHash(query, opts)
+		if etag := weatherETag(hash, opts.Units, shape); err == nil && helpers.IfNoneMatch(c, etag) {
+			c.Header("ETag", etag)
+			c.Status(http.StatusNotModified)
+			return
+		}
+	}
+
+	// Fetch weather data based on the query (location)
+	weatherData, err := service.weather.FetchWeatherData(c.Request.Context(), query, opts)
+	if err != nil {
+		// Handle case where no location is found
+		if errors.Is(err, services.ErrNoLocationFound) {
+			helpers.ClientError(c, http.StatusNotFound, fmt.Sprintf("%v", err))
+			return
+		}
+		// Handle case where the coordinates are out of range, the airport code is malformed
+		// or the cache tolerance is below the configured minimum
+		if errors.Is(err, services.ErrInvalidCoordinates) || errors.Is(err, services.ErrInvalidIATACode) || errors.Is(err, services.ErrMaxAgeTooShort) {
+			helpers.ClientError(c, http.StatusBadRequest, fmt.Sprintf("%v", err))
+			return
+		}
+		// Handle case where the location is outside the configured allowlist
+		if errors.Is(err, services.ErrLocationNotAllowed) {
+			helpers.ClientError(c, http.StatusForbidden, fmt.Sprintf("%v", err))
+			return
+		}
+		// Handle errors reported by WeatherAPI about the service itself
+		if upstreamErrorResponse(c, err) {
+			return
+		}
+		// Respond with a server error if another issue occurs
+		helpers.ServerError(c, err)
+		return
+	}
+
+	// Tell the client when the data is an expired copy served because the weather provider failed
+	if weatherData.Stale {
+		c.Header("X-Cache", "STALE")
+	} else if weatherData.NearestMatch {
+		// The data describes a nearby location, so it can't be revalidated for the queried coordinates
+		c.Header("X-Cache", "NEAREST")
+	} else if weatherData.ContentHash != "" && !opts.Meta {
+		// Let the client revalidate the cached data with If-None-Match
+		c.Header("ETag", weatherETag(weatherData.ContentHash, opts.Units, shape))
+	}
+
+	// Return the fetched weather data in the response, in the requested shape
+	c.JSON(http.StatusOK, singleWeatherResponse(weatherData, shape))
+}
+
+// allowAnonymous counts a request without an API key against the daily anonymous allowance of the client IP,
+// reporting the requests left in the X-Anonymous-Remaining header. If anonymous access is disabled,
+// it responds like any request missing its key; once the allowance is used up, with 401 Unauthorized.
+// It returns false if it responded, leaving the response to the caller otherwise.
+func (service *WeatherHandler) allowAnonymous(c *gin.Context) bool {
+	remaining, err := service.weather.AllowAnonymousRequest(c.ClientIP())
+	switch {
+	case errors.Is(err, services.ErrAnonymousAccessDisabled):
+		helpers.ClientError(c, http.StatusBadRequest, fmt.Sprintf("%v", helpers.ErrMissingAPIKey))
+		return false
+	case errors.Is(err, services.ErrAnonymousLimitExceeded):
+		helpers.ClientError(c, http.StatusUnauthorized, "The daily limit of requests without an API key is used up. Sign up at /api/v1/signup for a free API key to continue.")
+		return false
+	case err != nil:
+		helpers.ServerError(c, err)
+		return false
+	}
+
+	c.Header("X-Anonymous-Remaining", strconv.Itoa(remaining))
+	return true
+}
+
+// scopeDenied responds with 403 Forbidden when the API key's scopes don't include the scope of the endpoint.
+// It returns false if the key may call the endpoint, leaving the response to the caller.
+func scopeDenied(c *gin.Context, scopes services.APIKeyScopes, scope string) bool {
+	if scopes.Allows(scope) {
+		return false
+	}
+	helpers.ClientError(c, http.StatusForbidden, fmt.Sprintf("This API key is not allowed to call this endpoint (scope '%s' required).", scope))
+	return true
+}
+
+// upstreamErrorResponse responds with an accurate status when WeatherAPI rejected a request because of
+// the service's own key, quota or request, rather than the requested location.
+// It returns false if err is not such an error, leaving the response to the caller.
+func upstreamErrorResponse(c *gin.Context, err error) bool {
+	switch {
+	case errors.Is(err, services.ErrUpstreamUnauthorized), errors.Is(err, services.ErrUpstreamKeyDisabled):
+		// The upstream credentials are misconfigured; log the cause for the operators
+		log.Println(err)
+		helpers.ClientError(c, http.StatusUnauthorized, "The weather provider rejected this service's credentials. Please contact the administrator.")
+	case errors.Is(err, services.ErrUpstreamQuotaExceeded):
+		log.Println(err)
+		helpers.ClientError(c, http.StatusServiceUnavailable, "The weather provider quota has been exhausted. Please try again later.")
+	case errors.Is(err, services.ErrUpstreamUnavailable):
+		log.Println(err)
+		helpers.ClientError(c, http.StatusServiceUnavailable, "The weather provider is temporarily unavailable. Please try again later.")
+	case errors.Is(err, services.ErrUpstreamMissingQuery):
+		helpers.ClientError(c, http.StatusBadRequest, "parameter q is missing or invalid. Please include a valid location in your request")
+	case errors.Is(err, services.ErrUpstreamInvalidRequest), errors.Is(err, services.ErrUpstreamInternal):
+		log.Println(err)
+		helpers.ClientError(c, http.StatusBadGateway, "The weather provider could not process the request. Please try again later.")
+	default:
+		return false
+	}
+	return true
+}
+
+// AstronomyData handles the retrieval of sunrise, sunset, moonrise, moonset and moon phase for a location.
+// It expects an API key, a query parameter (location) and an optional date (YYYY-MM-DD, defaults to today at the location) from the URL.
+func (service *WeatherHandler) AstronomyData(c *gin.Context) {
+	// Extract API key and query (location) from the request URL
+	apiKey, query, err := helpers.GetParametersFromUrl(c)
+	if err != nil {
+		helpers.ClientError(c, http.StatusBadRequest, fmt.Sprintf("%v", err))
+		return
+	}
+
+	// Validate the optional date
+	date := c.Query("date")
+	if date != "" {
+		if _, err := time.Parse(services.AstronomyDateLayout, date); err != nil {
+			helpers.ClientError(c, http.StatusBadRequest, "parameter date must be a valid date in the format YYYY-MM-DD")
+			return
+		}
+	}
+
 	// Authorize the API key
-	_, err = service.weather.APIKeyAuthorization(apiKey)
+	scopes, err := service.weather.APIKeyAuthorization(c.Request.Context(), apiKey)
 	if err != nil {
-		// Handle case where the API key is invalid or disabled
 		if errors.Is(err, services.ErrAPIKeyNotFound) {
 			helpers.ClientError(c, http.StatusUnauthorized, "API key has been disabled.")
 			return
 		}
-		// For other errors, respond with a server error
 		helpers.ServerError(c, err)
 		return
 	}
 
-	// Fetch weather data based on the query (location)
-	weatherData, err := service.weather.FetchWeatherData(query)
+	// Reject keys whose scopes don't include this endpoint
+	if scopeDenied(c, scopes, services.ScopeAstronomy) {
+		return
+	}
+
+	// Fetch the astronomy data for the location and date
+	astronomy, err := service.weather.FetchAstronomyData(query, date)
 	if err != nil {
-		// Handle case where no location is found
 		if errors.Is(err, services.ErrNoLocationFound) {
 			helpers.ClientError(c, http.StatusNotFound, fmt.Sprintf("%v", err))
 			return
 		}
-		// Respond with a server error if another issue occurs
+		if errors.Is(err, services.ErrInvalidCoordinates) || errors.Is(err, services.ErrInvalidIATACode) {
+			helpers.ClientError(c, http.StatusBadRequest, fmt.Sprintf("%v", err))
+			return
+		}
+		if errors.Is(err, services.ErrLocationNotAllowed) {
+			helpers.ClientError(c, http.StatusForbidden, fmt.Sprintf("%v", err))
+			return
+		}
+		if upstreamErrorResponse(c, err) {
+			return
+		}
+		helpers.ServerError(c, err)
+		return
+	}
+
+	// Return the astronomy data in the response
+	c.JSON(http.StatusOK, gin.H{
+		"astronomy": astronomy,
+	})
+}
+
+// HistoryData handles the retrieval of the recorded daily weather of a location over past days.
+// It expects an API key, a query parameter (location), a date and an optional end_date (YYYY-MM-DD) from the URL.
+func (service *WeatherHandler) HistoryData(c *gin.Context) {
+	// Extract API key and query (location) from the request URL
+	apiKey, query, err := helpers.GetParametersFromUrl(c)
+	if err != nil {
+		helpers.ClientError(c, http.StatusBadRequest, fmt.Sprintf("%v", err))
+		return
+	}
+
+	// The date is required; the range itself is validated by the service against the lookback window
+	date := c.Query("date")
+	if date == "" {
+		helpers.ClientError(c, http.StatusBadRequest, "parameter date is missing")
+		return
+	}
+
+	// Authorize the API key
+	scopes, err := service.weather.APIKeyAuthorization(c.Request.Context(), apiKey)
+	if err != nil {
+		if errors.Is(err, services.ErrAPIKeyNotFound) {
+			helpers.ClientError(c, http.StatusUnauthorized, "API key has been disabled.")
+			return
+		}
+		helpers.ServerError(c, err)
+		return
+	}
+
+	// Reject keys whose scopes don't include this endpoint
+	if scopeDenied(c, scopes, services.ScopeHistory) {
+		return
+	}
+
+	// Fetch the history of the location over the requested days
+	history, err := service.weather.FetchHistoryData(query, date, c.Query("end_date"))
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidHistoryRange) || errors.Is(err, services.ErrInvalidCoordinates) || errors.Is(err, services.ErrInvalidIATACode) {
+			helpers.ClientError(c, http.StatusBadRequest, fmt.Sprintf("%v", err))
+			return
+		}
+		if errors.Is(err, services.ErrNoLocationFound) {
+			helpers.ClientError(c, http.StatusNotFound, fmt.Sprintf("%v", err))
+			return
+		}
+		if errors.Is(err, services.ErrLocationNotAllowed) {
+			helpers.ClientError(c, http.StatusForbidden, fmt.Sprintf("%v", err))
+			return
+		}
+		if upstreamErrorResponse(c, err) {
+			return
+		}
 		helpers.ServerError(c, err)
 		return
 	}
 
-	// Return the fetched weather data in the response
+	// Return the history in the response
 	c.JSON(http.StatusOK, gin.H{
-		"location": weatherData, // Send the weather data for the location
+		"history": history,
 	})
 }
 
+// autoIPQuery is the special value of the 'q' parameter asking to geolocate the caller by IP address.
+const autoIPQuery = "auto:ip"
+
+// weatherDataByIP responds with weather data for the caller's location, determined from their IP address.
+// The IP is resolved by Gin from X-Forwarded-For only when the request comes through a trusted proxy.
+func (service *WeatherHandler) weatherDataByIP(c *gin.Context, opts services.WeatherOptions, shape string) {
+	// Determine the caller's real IP address
+	ip := net.ParseIP(c.ClientIP())
+	if ip == nil {
+		helpers.ClientError(c, http.StatusBadRequest, "Could not determine your IP address. Please provide a location in parameter q instead.")
+		return
+	}
+
+	// Fetch weather data for the IP address (never cached)
+	weatherData, err := service.weather.FetchWeatherDataByIP(c.Request.Context(), ip.String(), opts)
+	if err != nil {
+		if errors.Is(err, services.ErrNoLocationFound) {
+			helpers.ClientError(c, http.StatusNotFound, "Could not determine a location from your IP address. Please provide a location in parameter q instead.")
+			return
+		}
+		if errors.Is(err, services.ErrLocationNotAllowed) {
+			helpers.ClientError(c, http.StatusForbidden, fmt.Sprintf("%v", err))
+			return
+		}
+		if upstreamErrorResponse(c, err) {
+			return
+		}
+		helpers.ServerError(c, err)
+		return
+	}
+
+	// Return the fetched weather data in the response, in the requested shape
+	c.JSON(http.StatusOK, singleWeatherResponse(weatherData, shape))
+}
+
+// WeatherDataHead answers HEAD requests for weather.current.
+// It performs the same API key authorization as WeatherData and checks whether the location is cached,
+// but returns only the status code and the X-Cache/Cache-Control headers without a response body.
+func (service *WeatherHandler) WeatherDataHead(c *gin.Context) {
+	// Extract API key and query (location) from the request URL
+	apiKey, query, err := helpers.GetParametersFromUrl(c)
+	if err != nil {
+		c.Status(http.StatusBadRequest)
+		return
+	}
+
+	// Authorize the API key
+	scopes, err := service.weather.APIKeyAuthorization(c.Request.Context(), apiKey)
+	if err != nil {
+		if errors.Is(err, services.ErrAPIKeyNotFound) {
+			c.Status(http.StatusUnauthorized)
+			return
+		}
+		helpers.ServerError(c, err)
+		return
+	}
+
+	// Reject keys whose scopes don't include this endpoint
+	if !scopes.Allows(services.ScopeCurrent) {
+		c.Status(http.StatusForbidden)
+		return
+	}
+
+	// Check whether the location is currently cached and for how long
+	ttl, err := service.weather.CachedWeatherDataTTL(query)
+	if err != nil {
+		// A cache miss is not an error: the next GET will fetch fresh data from the upstream
+		if errors.Is(err, services.ErrNoDataCache) {
+			c.Header("X-Cache", "MISS")
+			c.Header("Cache-Control", "no-cache")
+			c.Status(http.StatusOK)
+			return
+		}
+		if errors.Is(err, services.ErrInvalidCoordinates) || errors.Is(err, services.ErrInvalidIATACode) {
+			c.Status(http.StatusBadRequest)
+			return
+		}
+		helpers.ServerError(c, err)
+		return
+	}
+
+	// Report a cache hit together with the remaining freshness lifetime
+	c.Header("X-Cache", "HIT")
+	c.Header("Cache-Control", fmt.Sprintf("public, max-age=%d", int(ttl.Seconds())))
+	c.Status(http.StatusOK)
+}
+
 // BulkWeatherData handles the retrieval of weather data for multiple locations at once.
 // It expects an API key and a list of locations from the request body.
 func (service *WeatherHandler) BulkWeatherData(c *gin.Context) {
@@ -77,8 +479,15 @@ func (service *WeatherHandler) BulkWeatherData(c *gin.Context) {
 		return
 	}
 
+	// Extract the requested units, format, shape and status behavior
+	opts, err := bulkOptionsFromUrl(c)
+	if err != nil {
+		helpers.ClientError(c, http.StatusBadRequest, fmt.Sprintf("%v", err))
+		return
+	}
+
 	// Authorize the API key
-	_, err = service.weather.APIKeyAuthorization(apiKey)
+	scopes, err := service.weather.APIKeyAuthorization(c.Request.Context(), apiKey)
 	if err != nil {
 		// Handle case where the API key is invalid or disabled
 		if errors.Is(err, services.ErrAPIKeyNotFound) {
@@ -90,6 +499,11 @@ func (service *WeatherHandler) BulkWeatherData(c *gin.Context) {
 		return
 	}
 
+	// Reject keys whose scopes don't include this endpoint
+	if scopeDenied(c, scopes, services.ScopeBulk) {
+		return
+	}
+
 	// Parse the request body to extract the list of locations
 	var locations LocationsForm
 	if err := c.ShouldBindJSON(&locations); err != nil {
@@ -101,25 +515,181 @@ func (service *WeatherHandler) BulkWeatherData(c *gin.Context) {
 	// Filter valid location queries to avoid unnecessary API calls
 	qValues := helpers.FilterValidQValues(locations)
 
+	// Collect the observation times the client already has, to skip locations without newer data
+	modifiedSince := make(map[string]time.Time)
+	for _, location := range locations.Locations {
+		if location.LastUpdated != nil {
+			// Key by the trimmed query, which is how FilterValidQValues passes it on.
+			modifiedSince[strings.TrimSpace(location.Q)] = *location.LastUpdated
+		}
+	}
+
 	// Fetch bulk weather data for the valid locations
-	bulkWeatherData, notFoundList, err := service.weather.FetchBulkWeatherData(qValues)
+	results, err := service.weather.FetchBulkWeatherData(qValues, modifiedSince)
 	if err != nil {
+		// Handle errors reported by WeatherAPI about the service itself
+		if upstreamErrorResponse(c, err) {
+			return
+		}
 		// If there is an error fetching the weather data, respond with a server error
 		helpers.ServerError(c, err)
 		return
 	}
 
-	// If there are locations not found, include them in the response
+	writeBulkWeatherData(c, results, opts)
+}
+
+// weatherOptionsFromUrl extracts the optional 'units', 'lang', 'aqi', 'include', 'refresh' and 'max_age' query parameters from the URL.
+// 'include' is a comma-separated list of optional response blocks; only 'meta' is supported.
+// 'max_age' is the cache tolerance of the client in seconds.
+// Missing units and lang fall back to the logged-in user's preferences; other missing parameters
+// keep the defaults of services.WeatherOptions.
+// It returns an error if any parameter has an unsupported value.
+func weatherOptionsFromUrl(c *gin.Context) (services.WeatherOptions, error) {
+	units, err := helpers.GetUnitsFromUrl(c)
+	if err != nil {
+		return services.WeatherOptions{}, err
+	}
+
+	lang := helpers.QueryOrPreference(c, "lang", "preferredLang", "")
+	if lang != "" && !services.ValidLang(lang) {
+		return services.WeatherOptions{}, fmt.Errorf("parameter lang must be a WeatherAPI language code (e.g. 'fr' or 'zh_tw')")
+	}
+
+	var aqi bool
+	switch c.DefaultQuery("aqi", "no") {
+	case "no":
+	case "yes":
+		aqi = true
+	default:
+		return services.WeatherOptions{}, fmt.Errorf("parameter aqi must be either 'yes' or 'no'")
+	}
+
+	var meta bool
+	for _, block := range strings.Split(c.Query("include"), ",") {
+		switch strings.TrimSpace(block) {
+		case "":
+		case "meta":
+			meta = true
+		default:
+			return services.WeatherOptions{}, fmt.Errorf("parameter include only supports 'meta'")
+		}
+	}
+
+	// The refresh itself is guarded (login and rate limit) by middlewares.ForceRefreshLimiter
+	var forceRefresh bool
+	switch c.DefaultQuery("refresh", "false") {
+	case "false":
+	case "true":
+		forceRefresh = true
+	default:
+		return services.WeatherOptions{}, fmt.Errorf("parameter refresh must be either 'true' or 'false'")
+	}
+
+	// The service rejects tolerances shorter than its configured minimum
+	var maxAge time.Duration
+	if value, ok := c.GetQuery("max_age"); ok {
+		seconds, err := strconv.Atoi(value)
+		if err != nil || seconds <= 0 {
+			return services.WeatherOptions{}, fmt.Errorf("parameter max_age must be a positive number of seconds")
+		}
+		maxAge = time.Duration(seconds) * time.Second
+	}
+
+	// Return the validated options
+	return services.WeatherOptions{Units: units, Lang: lang, AQI: aqi, Meta: meta, ForceRefresh: forceRefresh, MaxAge: maxAge}, nil
+}
+
+// bulkOptions holds the parameters shaping a bulk response.
+type bulkOptions struct {
+	units       string // units is the requested unit system
+	format      string // format is either bulkFormatJSON or bulkFormatCSV
+	shape       string // shape is the shape of the JSON items
+	multiStatus bool   // multiStatus lets the status reflect partial success
+}
+
+// bulkOptionsFromUrl extracts the 'units', 'format', 'shape', 'compact' and 'multi_status' parameters of a bulk request.
+// It returns an error if any of them is invalid, or if multi_status is combined with format=csv.
+func bulkOptionsFromUrl(c *gin.Context) (bulkOptions, error) {
+	// Extract the requested unit system
+	units, err := helpers.GetUnitsFromUrl(c)
+	if err != nil {
+		return bulkOptions{}, err
+	}
+
+	// Extract the requested response format
+	format := c.DefaultQuery("format", bulkFormatJSON)
+	if format != bulkFormatJSON && format != bulkFormatCSV {
+		return bulkOptions{}, fmt.Errorf("parameter format must be either '%s' or '%s'", bulkFormatJSON, bulkFormatCSV)
+	}
+
+	// Extract the requested shape of the JSON items
+	shape, err := helpers.GetShapeFromUrl(c)
+	if err != nil {
+		return bulkOptions{}, err
+	}
+
+	// Extract whether the status should reflect partial success; CSV exports have no room for per-location results
+	multiStatus, err := helpers.GetMultiStatusFromUrl(c)
+	if err != nil {
+		return bulkOptions{}, err
+	}
+	if multiStatus && format == bulkFormatCSV {
+		return bulkOptions{}, fmt.Errorf("parameter multi_status=true can't be combined with format=csv")
+	}
+
+	return bulkOptions{units: units, format: format, shape: shape, multiStatus: multiStatus}, nil
+}
+
+// writeBulkWeatherData sends the results of a bulk lookup with the requested options.
+// It is shared by the POST bulk endpoint and GET weather.current with repeated 'q' parameters.
+func writeBulkWeatherData(c *gin.Context, results []services.BulkResult, opts bulkOptions) {
+	bulkWeatherData, notFoundList, notModifiedList := services.SplitBulkResults(results)
+
+	// Add the fields required by the requested unit system
+	for i := range bulkWeatherData {
+		bulkWeatherData[i] = services.ApplyUnits(bulkWeatherData[i], opts.units)
+	}
+
+	// Stream the found locations as CSV rows when requested
+	if opts.format == bulkFormatCSV {
+		writeBulkCSV(c, bulkWeatherData, notFoundList, notModifiedList)
+		return
+	}
+
+	// Send the bulk weather data, along with the locations that were not found or have no newer data
+	response := gin.H{
+		"bulk": bulkWeatherItems(bulkWeatherData, opts.shape), // Weather data for found locations
+	}
 	if len(notFoundList) > 0 {
-		c.JSON(http.StatusOK, gin.H{
-			"bulk":      bulkWeatherData, // Weather data for found locations
-			"not_found": notFoundList,    // Locations that were not found
-		})
+		response["not_found"] = notFoundList // Locations that were not found
+	}
+	if len(notModifiedList) > 0 {
+		response["not_modified"] = notModifiedList // Locations whose data is not newer than the client's copy
+	}
+
+	// On request, enumerate the outcome of every location and let the status tell full, partial and no success apart
+	if opts.multiStatus {
+		outcomes := bulkOutcomes(results)
+		response["results"] = outcomes // Outcome of every location, in request order
+		c.JSON(bulkStatus(outcomes), response)
 		return
 	}
+	c.JSON(http.StatusOK, response)
+}
 
-	// If all locations were found, return just the weather data
-	c.JSON(http.StatusOK, gin.H{
-		"bulk": bulkWeatherData, // Send the bulk weather data
-	})
+// weatherETag derives the entity tag of a weather response from the content hash of the cached data.
+// The hash covers the data as serialized from the fixed-field FormattedWeatherData struct, never from a map,
+// so identical data always yields the same tag. Options that change the body without changing the cached data
+// (units and shape) are appended in a fixed order, so that a client whose units change on the same URL
+// (e.g. through stored preferences) never gets 304 for a body it doesn't have. The defaults append nothing.
+func weatherETag(hash, system, shape string) string {
+	tag := hash
+	if system != units.Metric {
+		tag += "-" + system
+	}
+	if shape != helpers.ShapeFlat {
+		tag += "-" + shape
+	}
+	return helpers.FormatETag(tag)
 }
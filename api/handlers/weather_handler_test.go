@@ -0,0 +1,235 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"havoAPI/api/config"
+	"havoAPI/internal/clock"
+	"havoAPI/internal/services"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestWeatherDataRejectsOutOfRangeCoordinates(t *testing.T) {
+	weather := &fakeWeatherService{
+		fetchWeatherData: func(ctx context.Context, query string, opts services.WeatherOptions) (services.FormattedWeatherData, error) {
+			return services.FormattedWeatherData{}, fmt.Errorf("lookup %q: %w", query, services.ErrInvalidCoordinates)
+		},
+	}
+	handler := NewWeatherHandler(weather)
+
+	w := serve(t, http.MethodGet, "/weather", handler.WeatherData, "/weather?key=k&q=999,999", nil)
+	assertStatus(t, w, http.StatusBadRequest)
+	var body struct {
+		Error string `json:"error"`
+	}
+	decodeBody(t, w, &body)
+	if body.Error == "" {
+		t.Error("response has no error message")
+	}
+}
+
+func TestWeatherDataStatusForUpstreamErrors(t *testing.T) {
+	tests := []struct {
+		err  error
+		want int
+	}{
+		{services.ErrNoLocationFound, http.StatusNotFound},
+		{services.ErrUpstreamUnauthorized, http.StatusUnauthorized},
+		{services.ErrUpstreamKeyDisabled, http.StatusUnauthorized},
+		{services.ErrUpstreamQuotaExceeded, http.StatusServiceUnavailable},
+		{services.ErrUpstreamMissingQuery, http.StatusBadRequest},
+		{services.ErrUpstreamInvalidRequest, http.StatusBadGateway},
+		{services.ErrUpstreamInternal, http.StatusBadGateway},
+	}
+	for _, tt := range tests {
+		t.Run(tt.err.Error(), func(t *testing.T) {
+			weather := &fakeWeatherService{
+				fetchWeatherData: func(ctx context.Context, query string, opts services.WeatherOptions) (services.FormattedWeatherData, error) {
+					return services.FormattedWeatherData{}, fmt.Errorf("lookup %q: %w", query, tt.err)
+				},
+			}
+			w := serve(t, http.MethodGet, "/weather", NewWeatherHandler(weather).WeatherData, "/weather?key=k&q=London", nil)
+			assertStatus(t, w, tt.want)
+		})
+	}
+}
+
+func TestWeatherDataRejectsShortMaxAges(t *testing.T) {
+	weather := &fakeWeatherService{
+		fetchWeatherData: func(ctx context.Context, query string, opts services.WeatherOptions) (services.FormattedWeatherData, error) {
+			if opts.MaxAge != 30*time.Second {
+				t.Errorf("max age = %v, want 30s", opts.MaxAge)
+			}
+			return services.FormattedWeatherData{}, fmt.Errorf("%w of 60 seconds", services.ErrMaxAgeTooShort)
+		},
+	}
+	w := serve(t, http.MethodGet, "/weather", NewWeatherHandler(weather).WeatherData, "/weather?key=k&q=London&max_age=30", nil)
+	assertStatus(t, w, http.StatusBadRequest)
+	var body struct {
+		Error string `json:"error"`
+	}
+	decodeBody(t, w, &body)
+	if !strings.Contains(body.Error, "60 seconds") {
+		t.Errorf("error = %q, want it to name the minimum", body.Error)
+	}
+}
+
+func TestWeatherDataMarksStaleData(t *testing.T) {
+	weather := &fakeWeatherService{
+		fetchWeatherData: func(ctx context.Context, query string, opts services.WeatherOptions) (services.FormattedWeatherData, error) {
+			data := weatherAt("London")
+			data.Stale = true
+			data.ContentHash = "abc"
+			return data, nil
+		},
+	}
+	w := serve(t, http.MethodGet, "/weather", NewWeatherHandler(weather).WeatherData, "/weather?key=k&q=London", nil)
+	assertStatus(t, w, http.StatusOK)
+	if got := w.Header().Get("X-Cache"); got != "STALE" {
+		t.Errorf("X-Cache = %q, want STALE", got)
+	}
+	if etag := w.Header().Get("ETag"); etag != "" {
+		t.Errorf("stale data was served with ETag %s", etag)
+	}
+}
+
+func TestBulkWeatherDataAlwaysReturnsAnArray(t *testing.T) {
+	weather := &fakeWeatherService{
+		fetchBulkWeatherData: func(queries []string, modifiedSince map[string]time.Time) ([]services.BulkResult, error) {
+			// None of the locations is found, so there is no data to list
+			var results []services.BulkResult
+			for _, q := range queries {
+				results = append(results, services.BulkResult{Query: q, Error: q})
+			}
+			return results, nil
+		},
+	}
+	handler := NewWeatherHandler(weather).BulkWeatherData
+
+	for _, query := range []string{"", "&shape=nested", "&compact=true"} {
+		t.Run("shape"+query, func(t *testing.T) {
+			body := strings.NewReader(`{"locations": [{"q": "Atlantis"}, {"q": "El Dorado"}]}`)
+			w := serve(t, http.MethodPost, "/bulk", handler, "/bulk?key=k&q=bulk"+query, body)
+			assertStatus(t, w, http.StatusOK)
+
+			var response map[string]json.RawMessage
+			decodeBody(t, w, &response)
+			if got := string(response["bulk"]); got != "[]" {
+				t.Errorf("bulk = %s, want []", got)
+			}
+			var notFound []string
+			if err := json.Unmarshal(response["not_found"], &notFound); err != nil || len(notFound) != 2 {
+				t.Errorf("not_found = %s, want both locations", response["not_found"])
+			}
+		})
+	}
+}
+
+func TestBulkWeatherDataMultiStatus(t *testing.T) {
+	found := func(q string) services.BulkResult {
+		data := weatherAt(q)
+		return services.BulkResult{Query: q, Data: &data}
+	}
+	notFound := func(q string) services.BulkResult {
+		return services.BulkResult{Query: q, Error: q + " not found"}
+	}
+	notModified := func(q string) services.BulkResult {
+		return services.BulkResult{Query: q, NotModified: true}
+	}
+
+	tests := []struct {
+		name     string
+		results  []services.BulkResult
+		status   int
+		statuses []int
+	}{
+		{"all found", []services.BulkResult{found("London"), notModified("Paris"), found("London")}, http.StatusOK, []int{200, 304, 200}},
+		{"mixed", []services.BulkResult{found("London"), notFound("Atlantis"), notModified("Paris"), notFound("Atlantis")}, http.StatusMultiStatus, []int{200, 404, 304, 404}},
+		{"none found", []services.BulkResult{notFound("Atlantis"), notFound("El Dorado")}, http.StatusNotFound, []int{404, 404}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			weather := &fakeWeatherService{
+				fetchBulkWeatherData: func(queries []string, modifiedSince map[string]time.Time) ([]services.BulkResult, error) {
+					return tt.results, nil
+				},
+			}
+			body := strings.NewReader(`{"locations": [{"q": "London"}]}`)
+			w := serve(t, http.MethodPost, "/bulk", NewWeatherHandler(weather).BulkWeatherData, "/bulk?key=k&q=bulk&multi_status=true", body)
+			assertStatus(t, w, tt.status)
+
+			var response struct {
+				Results []bulkOutcome `json:"results"`
+			}
+			decodeBody(t, w, &response)
+			if len(response.Results) != len(tt.results) {
+				t.Fatalf("results = %+v, want one per query", response.Results)
+			}
+			for i, outcome := range response.Results {
+				result := tt.results[i]
+				if outcome.Q != result.Query || outcome.Status != tt.statuses[i] || outcome.Error != result.Error {
+					t.Errorf("results[%d] = %+v, want %s with status %d and error %q", i, outcome, result.Query, tt.statuses[i], result.Error)
+				}
+			}
+		})
+	}
+}
+
+// keyedWeatherService is a real weather service behind a stand-in for the API key check,
+// for tests that need the service's own rules rather than a fake's.
+type keyedWeatherService struct {
+	*services.WeatherAPIService
+}
+
+// APIKeyAuthorization accepts every key without restricting its scopes.
+func (s keyedWeatherService) APIKeyAuthorization(ctx context.Context, apiKey string) (services.APIKeyScopes, error) {
+	return nil, nil
+}
+
+func TestAmbiguousListEnforcesTheAllowlist(t *testing.T) {
+	var requests atomic.Int32
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.Write([]byte(`[
+			{"name": "Springfield", "region": "Illinois", "country": "United States of America"},
+			{"name": "Springfield", "region": "Saint Andrew", "country": "Jamaica"}
+		]`))
+	}))
+	defer upstream.Close()
+
+	tests := []struct {
+		name      string
+		locations []string
+		countries []string
+		requests  int32
+	}{
+		// An unlisted name can never pass, so the search isn't even sent
+		{name: "names only", locations: []string{"London"}, requests: 0},
+		{name: "countries", countries: []string{"France"}, requests: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			requests.Store(0)
+			cfg := &config.Config{
+				WeatherAPIBaseURL:          upstream.URL,
+				WeatherAPITimeout:          5 * time.Second,
+				WeatherAPIMaxResponseBytes: 4 << 20,
+				AllowedLocations:           tt.locations,
+				AllowedCountries:           tt.countries,
+			}
+			weather := keyedWeatherService{services.NewWeatherAPIService(nil, services.NewRedisClient(cfg), cfg, clock.Real{})}
+
+			w := serve(t, http.MethodGet, "/weather", NewWeatherHandler(weather).WeatherData, "/weather?key=k&q=Springfield&ambiguous=list", nil)
+			assertStatus(t, w, http.StatusForbidden)
+			if got := requests.Load(); got != tt.requests {
+				t.Errorf("upstream received %d requests, want %d", got, tt.requests)
+			}
+		})
+	}
+}
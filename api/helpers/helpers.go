@@ -1,15 +1,29 @@
 package helpers
 
 import (
+	"errors"
+	"havoAPI/internal/models"
 	"log"
 	"net/http"
+	"strconv"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 )
 
 // ServerError logs unexpected server errors and returns a generic internal server error response.
-// It ensures sensitive information about the error is not exposed to the client.
+// It ensures sensitive information about the error is not exposed to the client, whatever the Gin mode.
+// A database without its tables is a deployment mistake rather than a bug: it is logged as such
+// and answered with 503 Service Unavailable.
 func ServerError(c *gin.Context, err error) {
+	if errors.Is(err, models.ErrSchemaMissing) {
+		log.Printf("ERROR: %v", err)
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"error": "The service is not fully set up yet. Please try again later.",
+		})
+		return
+	}
+
 	// Log the error on the server for further inspection
 	log.Println(err)
 	// Send a generic error response to the client
@@ -27,9 +41,52 @@ func ClientError(c *gin.Context, code int, message string) {
 	})
 }
 
+// ClientErrorWithCode responds like ClientError, adding a machine-readable error code (e.g. "NOT_FOUND")
+// that clients can branch on without parsing the message.
+func ClientErrorWithCode(c *gin.Context, status int, code, message string) {
+	c.JSON(status, gin.H{
+		"error": message,
+		"code":  code,
+	})
+}
+
+// RateLimitInfo describes which rate limit a rejected request hit.
+// It is returned to the client so developers can tell the global and per-key limits apart.
+type RateLimitInfo struct {
+	Scope      string  `json:"scope"`               // Scope is the limit that was hit: "global", "route", "key" or "refresh".
+	Limit      float64 `json:"limit"`               // Limit is the number of requests allowed per window.
+	Window     string  `json:"window"`              // Window is the period the limit applies to (e.g. "1s").
+	Burst      int     `json:"burst"`               // Burst is the maximum number of requests allowed at once.
+	RetryAfter int     `json:"retry_after_seconds"` // RetryAfter is the number of seconds until a new request will be allowed.
+}
+
 // RateLimitExceededResponse handles the case when a user exceeds the rate limit.
-// It sends a response with a "rate limit exceeded" message and a 429 Too Many Requests status.
-func RateLimitExceededResponse(c *gin.Context) {
-	message := "rate limit exceeded"                    // The message to be sent in the response
-	ClientError(c, http.StatusTooManyRequests, message) // Send the error response with status 429
+// It sends a response with a "rate limit exceeded" message, the details of the limit that was hit
+// and a Retry-After header with a 429 Too Many Requests status,
+// and aborts the request so that no further handlers are executed.
+func RateLimitExceededResponse(c *gin.Context, info RateLimitInfo) {
+	c.Header("Retry-After", strconv.Itoa(info.RetryAfter)) // Tell the client when to retry
+	c.JSON(http.StatusTooManyRequests, gin.H{              // Send the error response with status 429
+		"error":      "rate limit exceeded",
+		"rate_limit": info,
+	})
+	c.Abort() // Stop the handler chain
+}
+
+// FormatETag quotes a content hash into a strong entity tag for the ETag header.
+func FormatETag(hash string) string {
+	return `"` + hash + `"`
+}
+
+// IfNoneMatch reports whether the request's If-None-Match header lists the given entity tag,
+// meaning the client already has this version of the resource. Weak tags match their strong counterpart,
+// as the weak comparison of RFC 9110 requires, and "*" matches any tag.
+func IfNoneMatch(c *gin.Context, etag string) bool {
+	for _, candidate := range strings.Split(c.GetHeader("If-None-Match"), ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == "*" || candidate == etag {
+			return true
+		}
+	}
+	return false
 }
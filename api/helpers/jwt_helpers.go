@@ -1,35 +1,40 @@
 package helpers
 
 import (
-	"fmt"
-	"havoAPI/api/config"
+	"havoAPI/internal/clock"
 	"net/http"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
 )
- 
+
 // CreateAndSignJWT generates a JWT token for a given user ID.
-// The token includes the user's ID (userID) and an expiration time (ttl).
-// The token is signed with a secret key stored in the environment variables.
-func CreateAndSignJWT(userID int) (string, error) {
+// The token includes the user's ID (userID), an expiration time (ttl), the issue time (iat) and a unique token ID (jti).
+// The token is signed with the provided secret key loaded from the application config,
+// and its times are taken from the given clock.
+func CreateAndSignJWT(clk clock.Clock, userID int, secretKey string, ttl time.Duration) (string, error) {
 	// Create a new JWT with claims
+	now := clk.Now()
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
-		"userID":   userID,                                // User ID included in the payload
-		"ttl":      time.Now().Add(time.Hour * 24).Unix(), // Token expiration time (1 hour)
+		"userID": userID,              // User ID included in the payload
+		"ttl":    now.Add(ttl).Unix(), // Token expiration time
+		"iat":    issuedAt(now),       // Issue time, compared against the user's tokens_valid_after
+		"jti":    uuid.New().String(), // Unique token ID, used to revoke the token on logout
 	})
 
-	// Load the JWT secret key from environment variables
-	secretKey, err := config.LoadEnvironmentVariable("JWT_SECRET_KEY")
-	if err != nil {
-		return "", fmt.Errorf("cannot get secret key while creating and signing JWT: %v", err)
-	}
-
 	// Sign the token with the secret key and return the token string
 	return token.SignedString([]byte(secretKey))
 }
 
+// issuedAt returns the "iat" claim of a token issued at the given time. It keeps millisecond precision
+// (NumericDate allows fractions), so that a token issued right after the user's sessions were invalidated,
+// e.g. the fresh token of a password change, is told apart from the ones issued earlier in the same second.
+func issuedAt(now time.Time) float64 {
+	return float64(now.UnixMilli()) / 1000
+}
+
 // SetCookie sets the JWT token as a cookie in the user's browser.
 // The cookie is named "u_auth" and will be valid for 1 week (604800 seconds).
 // The cookie is marked as HttpOnly for security and will be sent with secure HTTPS connections.
@@ -1,10 +1,13 @@
 package helpers
 
 import (
+	"errors"
 	"fmt"
+	"havoAPI/internal/units"
 	"net/http"
 	"reflect"
 	"regexp"
+	"strconv"
 	"strings"
 
 	"github.com/gin-gonic/gin"
@@ -82,32 +85,84 @@ func ValidatePassword(password string) error {
 	return nil
 }
 
-// GetParametersFromUrl extracts the API key and query parameters from the URL.
+// APIKeyProtocolPrefix prefixes an API key offered as a WebSocket subprotocol (e.g. "apikey.<key>").
+// Browsers can't set headers on WebSocket connections, but they can offer subprotocols.
+const APIKeyProtocolPrefix = "apikey."
+
+// APIKeyFromRequest returns the API key of the request, read from the X-API-Key header, an
+// "Authorization: Bearer <key>" header, an "apikey.<key>" entry of the Sec-WebSocket-Protocol header
+// or the 'key' query parameter, in that order of precedence.
+// Headers keep the key out of URLs, which end up in access logs and browser histories.
+func APIKeyFromRequest(c *gin.Context) string {
+	if apiKey := strings.TrimSpace(c.GetHeader("X-API-Key")); apiKey != "" {
+		return apiKey
+	}
+	if scheme, apiKey, ok := strings.Cut(c.GetHeader("Authorization"), " "); ok && strings.EqualFold(scheme, "Bearer") {
+		if apiKey = strings.TrimSpace(apiKey); apiKey != "" {
+			return apiKey
+		}
+	}
+	for _, protocol := range strings.Split(c.GetHeader("Sec-WebSocket-Protocol"), ",") {
+		if apiKey, ok := strings.CutPrefix(strings.TrimSpace(protocol), APIKeyProtocolPrefix); ok && apiKey != "" {
+			return apiKey
+		}
+	}
+	return c.Query("key")
+}
+
+// ErrMissingAPIKey is returned by GetParametersFromUrl when the request carries no API key.
+var ErrMissingAPIKey = errors.New("api key is missing or invalid. Please include a valid API key in your request")
+
+// GetParametersFromUrl extracts the API key (see APIKeyFromRequest) and query parameters from the request.
 // It returns the API key, query parameter, and an error if either is missing or invalid.
 func GetParametersFromUrl(c *gin.Context) (string, string, error) {
-	// Extract the 'key' parameter (API key) from the URL query string
-	apiKey := c.Query("key")
+	// Extract the API key from the headers or the URL query string
+	apiKey := APIKeyFromRequest(c)
 	if len(apiKey) == 0 || len(strings.TrimSpace(apiKey)) == 0 {
 		// If the API key is missing or invalid, return an error
-		return "", "", fmt.Errorf("api key is missing or invalid. Please include a valid API key in your request")
+		return "", "", ErrMissingAPIKey
+	}
+
+	// Extract the location query
+	query, err := GetQueryFromUrl(c)
+	if err != nil {
+		return "", "", err
+	}
+
+	// Return the API key and query if both are valid
+	return apiKey, query, nil
+}
+
+// GetQueryFromUrl extracts the location query from the 'q' parameter.
+// The 'airport' parameter is a shorthand for an airport query: airport=DXB is the same as q=iata:DXB.
+// It returns an error if neither is set, or both are.
+func GetQueryFromUrl(c *gin.Context) (string, error) {
+	// Translate the 'airport' shorthand into an airport query; the code itself is validated by the services
+	if airport, ok := c.GetQuery("airport"); ok {
+		if _, hasQuery := c.GetQuery("q"); hasQuery {
+			return "", fmt.Errorf("parameters q and airport can't be combined")
+		}
+		if strings.TrimSpace(airport) == "" {
+			return "", fmt.Errorf("parameter airport is empty")
+		}
+		return "iata:" + strings.TrimSpace(airport), nil
 	}
 
 	// Extract the 'q' parameter (query) from the URL query string
 	query := c.Query("q")
 	if len(query) == 0 || len(strings.TrimSpace(query)) == 0 {
 		// If the query is missing or invalid, return an error
-		return "", "", fmt.Errorf("parameter q is missing")
+		return "", fmt.Errorf("parameter q is missing")
 	}
 
-	// Return the API key and query if both are valid
-	return apiKey, query, nil
+	return query, nil
 }
 
 // GetParametersFromUrlForBulk extracts the API key and checks if the 'q' parameter is set to 'bulk'.
 // It returns the API key and an error if either condition is violated.
 func GetParametersFromUrlForBulk(c *gin.Context) (string, error) {
-	// Extract the 'key' parameter (API key) from the URL query string
-	apiKey := c.Query("key")
+	// Extract the API key from the headers or the URL query string
+	apiKey := APIKeyFromRequest(c)
 	if len(apiKey) == 0 || len(strings.TrimSpace(apiKey)) == 0 {
 		// If the API key is missing or invalid, return an error
 		return "", fmt.Errorf("api key is missing or invalid. Please include a valid API key in your request")
@@ -124,8 +179,21 @@ func GetParametersFromUrlForBulk(c *gin.Context) (string, error) {
 	return apiKey, nil
 }
 
+// SplitLocationList splits a comma-separated list of locations (e.g. "London,Paris"), dropping blank entries.
+func SplitLocationList(query string) []string {
+	var queries []string
+	for _, q := range strings.Split(query, ",") {
+		if q = strings.TrimSpace(q); q != "" {
+			queries = append(queries, q)
+		}
+	}
+	return queries
+}
+
 // FilterValidQValues filters the valid 'q' values from a LocationsForm or similar struct.
-// It extracts the 'Q' field from each location and returns a slice of valid non-empty strings.
+// It extracts the 'Q' field from each location and returns a slice of valid non-empty strings,
+// trimmed of surrounding whitespace. Names and coordinate pairs (e.g. "48.85,2.35") may be mixed freely;
+// each value is normalized and validated on its own by the weather service.
 func FilterValidQValues(data interface{}) []string {
 	var qValues []string
 
@@ -147,9 +215,9 @@ func FilterValidQValues(data interface{}) []string {
 			// Extract the 'Q' field from each location (the query string)
 			qField := location.FieldByName("Q")
 			if qField.IsValid() && qField.Kind() == reflect.String {
-				// If the 'Q' field is a non-empty string, append it to the result slice
-				qValue := qField.String()
-				if qValue != "" && qValue != " " {
+				// If the 'Q' field is a non-blank string, append it to the result slice
+				qValue := strings.TrimSpace(qField.String())
+				if qValue != "" {
 					qValues = append(qValues, qValue)
 				}
 			}
@@ -159,3 +227,104 @@ func FilterValidQValues(data interface{}) []string {
 	// Return the slice of valid 'q' values
 	return qValues
 }
+
+// GetPaginationFromUrl extracts the 'limit' and 'offset' query parameters from the URL.
+// A missing limit defaults to defaultLimit and a limit above maxLimit is capped to maxLimit,
+// so clients can never request an expensive full-table scan. A missing offset defaults to 0.
+// It returns an error if either parameter is not a valid number or is out of range.
+func GetPaginationFromUrl(c *gin.Context, defaultLimit, maxLimit int) (int, int, error) {
+	limit := defaultLimit
+	if value := c.Query("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed <= 0 {
+			return 0, 0, fmt.Errorf("parameter limit must be a positive integer")
+		}
+		limit = min(parsed, maxLimit)
+	}
+
+	offset := 0
+	if value := c.Query("offset"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 0 {
+			return 0, 0, fmt.Errorf("parameter offset must be a non-negative integer")
+		}
+		offset = parsed
+	}
+
+	// Return the validated pagination parameters
+	return limit, offset, nil
+}
+
+// QueryOrPreference returns the value of a query parameter if the client set it, even to an empty value.
+// Otherwise it falls back to the logged-in user's preference stored in the context under preferenceKey
+// (see middlewares.UserPreferences), and then to defaultValue.
+func QueryOrPreference(c *gin.Context, param, preferenceKey, defaultValue string) string {
+	if value, ok := c.GetQuery(param); ok {
+		return value
+	}
+	if preferred := c.GetString(preferenceKey); preferred != "" {
+		return preferred
+	}
+	return defaultValue
+}
+
+// GetUnitsFromUrl extracts the optional 'units' query parameter from the URL.
+// It defaults to the logged-in user's preferred units, then to metric units,
+// and returns an error if an unsupported unit system is requested.
+func GetUnitsFromUrl(c *gin.Context) (string, error) {
+	system := QueryOrPreference(c, "units", "preferredUnits", units.Metric)
+
+	if !units.Valid(system) {
+		return "", fmt.Errorf("parameter units must be one of '%s', '%s' or '%s'", units.Metric, units.Both, units.Kelvin)
+	}
+	return system, nil
+}
+
+// Supported values of the 'shape' query parameter.
+const (
+	ShapeFlat   = "flat"   // ShapeFlat returns location and weather fields side by side in one object (the default).
+	ShapeNested = "nested" // ShapeNested separates the location fields from the current weather, like WeatherAPI does.
+
+	// ShapeCompact returns only the name, country, temperature, wind and cloud cover under one- or two-letter keys.
+	// It is selected with compact=true rather than through the 'shape' parameter.
+	ShapeCompact = "compact"
+)
+
+// GetShapeFromUrl extracts the optional 'shape' and 'compact' query parameters from the URL.
+// It defaults to the flat shape, returns ShapeCompact for compact=true and returns an error
+// if an unsupported shape is requested or compact=true is combined with another shape.
+func GetShapeFromUrl(c *gin.Context) (string, error) {
+	shape := c.DefaultQuery("shape", ShapeFlat)
+
+	switch shape {
+	case ShapeFlat, ShapeNested:
+	default:
+		return "", fmt.Errorf("parameter shape must be either '%s' or '%s'", ShapeFlat, ShapeNested)
+	}
+
+	switch c.DefaultQuery("compact", "false") {
+	case "false":
+		return shape, nil
+	case "true":
+		if shape != ShapeFlat {
+			return "", fmt.Errorf("parameter compact=true can't be combined with shape '%s'", shape)
+		}
+		return ShapeCompact, nil
+	default:
+		return "", fmt.Errorf("parameter compact must be either 'true' or 'false'")
+	}
+}
+
+// GetMultiStatusFromUrl extracts the optional 'multi_status' query parameter of bulk requests from the URL.
+// It defaults to false, so clients expecting 200 for every bulk response keep getting it,
+// and returns an error if the value is neither 'true' nor 'false'.
+func GetMultiStatusFromUrl(c *gin.Context) (bool, error) {
+	switch c.DefaultQuery("multi_status", "false") {
+	case "false":
+		return false, nil
+	case "true":
+		return true, nil
+	default:
+		return false, fmt.Errorf("parameter multi_status must be either 'true' or 'false'")
+	}
+}
@@ -0,0 +1,56 @@
+package middlewares
+
+import (
+	"crypto/subtle"
+	"havoAPI/api/helpers"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AdminAuthorization checks that the request carries the configured admin token
+// in the "Authorization: Bearer <token>" header. If no admin token is configured,
+// all admin endpoints are disabled and every request is rejected with 403 Forbidden.
+func AdminAuthorization(adminToken string) gin.HandlerFunc {
+	return bearerTokenAuthorization(adminToken, "Admin endpoints are disabled.", "Admin token")
+}
+
+// MetricsAuthorization checks that the request carries the configured metrics token
+// in the "Authorization: Bearer <token>" header, which Prometheus sends when its scrape
+// config sets authorization credentials. If no metrics token is configured, the metrics
+// endpoint is disabled and every request is rejected with 403 Forbidden.
+func MetricsAuthorization(metricsToken string) gin.HandlerFunc {
+	return bearerTokenAuthorization(metricsToken, "The metrics endpoint is disabled.", "Metrics token")
+}
+
+// bearerTokenAuthorization checks that the request carries the given token in the "Authorization: Bearer <token>" header.
+// An empty token rejects every request with 403 Forbidden and the disabled message; tokenName prefixes the other errors.
+func bearerTokenAuthorization(expected, disabled, tokenName string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// The endpoints are disabled when no token is configured
+		if expected == "" {
+			helpers.ClientError(c, http.StatusForbidden, disabled)
+			c.Abort()
+			return
+		}
+
+		// Extract the bearer token from the Authorization header
+		token, found := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if !found {
+			helpers.ClientError(c, http.StatusUnauthorized, tokenName+" is missing.")
+			c.Abort()
+			return
+		}
+
+		// Compare the tokens in constant time to avoid leaking information through timing
+		if subtle.ConstantTimeCompare([]byte(token), []byte(expected)) != 1 {
+			helpers.ClientError(c, http.StatusUnauthorized, tokenName+" is invalid.")
+			c.Abort()
+			return
+		}
+
+		// Proceed to the next middleware or handler in the chain.
+		c.Next()
+	}
+}
@@ -0,0 +1,42 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestMetricsAuthorization(t *testing.T) {
+	tests := []struct {
+		name          string
+		metricsToken  string
+		authorization string
+		want          int
+	}{
+		{name: "valid token", metricsToken: "metrics-token", authorization: "Bearer metrics-token", want: http.StatusOK},
+		{name: "wrong token", metricsToken: "metrics-token", authorization: "Bearer admin-token", want: http.StatusUnauthorized},
+		{name: "other scheme", metricsToken: "metrics-token", authorization: "Basic metrics-token", want: http.StatusUnauthorized},
+		{name: "no token sent", metricsToken: "metrics-token", want: http.StatusUnauthorized},
+		{name: "endpoint disabled", authorization: "Bearer ", want: http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := gin.New()
+			router.GET("/metrics", MetricsAuthorization(tt.metricsToken), func(c *gin.Context) {
+				c.Status(http.StatusOK)
+			})
+
+			r := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+			if tt.authorization != "" {
+				r.Header.Set("Authorization", tt.authorization)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, r)
+			if w.Code != tt.want {
+				t.Errorf("status = %d, want %d", w.Code, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,43 @@
+package middlewares
+
+import (
+	"fmt"
+	"havoAPI/api/helpers"
+	"net/http"
+	"regexp"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// vendorMediaTypePattern matches the versioned vendor media type, e.g. "application/vnd.havoapi.v1+json".
+var vendorMediaTypePattern = regexp.MustCompile(`application/vnd\.havoapi\.v(\d+)\+json`)
+
+// APIVersion is a middleware that resolves the API version requested through the Accept header.
+// Clients may pin a version with "Accept: application/vnd.havoapi.v1+json"; requests without a
+// vendor media type default to the version of the route group. If the header asks for a version
+// the group does not serve, the request is rejected with 406 Not Acceptable.
+// The resolved version is stored in the context under "apiVersion" and echoed in the X-API-Version header.
+func APIVersion(groupVersion int) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		version := groupVersion
+
+		// Read the version from the vendor media type, if the client sent one
+		if match := vendorMediaTypePattern.FindStringSubmatch(c.GetHeader("Accept")); match != nil {
+			requested, err := strconv.Atoi(match[1])
+			if err != nil || requested != groupVersion {
+				helpers.ClientError(c, http.StatusNotAcceptable, fmt.Sprintf("API version v%s is not served at this path; use application/vnd.havoapi.v%d+json", match[1], groupVersion))
+				c.Abort()
+				return
+			}
+			version = requested
+		}
+
+		// Make the resolved version available to handlers and clients
+		c.Set("apiVersion", version)
+		c.Header("X-API-Version", strconv.Itoa(version))
+
+		// Proceed to the next middleware or handler in the chain.
+		c.Next()
+	}
+}
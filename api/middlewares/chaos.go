@@ -0,0 +1,50 @@
+package middlewares
+
+import (
+	"havoAPI/api/config"
+	"havoAPI/api/helpers"
+	"math/rand/v2"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Chaos injects faults into the requests whose route starts with pathPrefix, to test how clients cope with
+// a slow or failing service: a random fraction of them is delayed by up to the configured latency,
+// and another is answered with 500 Internal Server Error instead of reaching its handler.
+// Affected responses carry an X-Chaos header, so they can be told apart from real failures in traces.
+// It must only be installed when chaos mode is enabled, which config.Load refuses in production.
+func Chaos(settings config.Chaos, pathPrefix string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !strings.HasPrefix(c.FullPath(), pathPrefix) {
+			c.Next()
+			return
+		}
+
+		// Delay the request, giving up early if the client goes away
+		if settings.MaxLatency > 0 && rand.Float64() < settings.LatencyRate {
+			delay := rand.N(settings.MaxLatency)
+			c.Header("X-Chaos", "latency")
+			select {
+			case <-time.After(delay):
+			case <-c.Request.Context().Done():
+				c.Abort()
+				return
+			}
+		}
+
+		// Fail the request as if the handler had run into an internal error
+		if rand.Float64() < settings.ErrorRate {
+			c.Header("X-Chaos", "error")
+			// The body is the one of a real server error, so clients can't special-case injected failures
+			helpers.ClientError(c, http.StatusInternalServerError, "An unexpected server error occurred. Please try again later.")
+			c.Abort()
+			return
+		}
+
+		// Proceed to the next middleware or handler in the chain.
+		c.Next()
+	}
+}
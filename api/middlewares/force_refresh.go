@@ -0,0 +1,43 @@
+package middlewares
+
+import (
+	"fmt"
+	"havoAPI/api/helpers"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ForceRefreshLimiter guards refresh=true on weather requests, which bypasses the shared cache and spends
+// upstream quota on every call. Only logged-in users may force a refresh, so it must run after UserPreferences,
+// which identifies them from their JWT; requests authorized by API key alone get 401 Unauthorized.
+// Every user's refreshes are limited by a token bucket of their own in the given registry, separate from
+// the per-key limit, and answered with 429 Too Many Requests beyond it. Other requests pass through untouched.
+func ForceRefreshLimiter(registry *RateLimiterRegistry) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.Query("refresh") != "true" {
+			c.Next()
+			return
+		}
+
+		userID, ok := c.Get("userID")
+		if !ok {
+			helpers.ClientError(c, http.StatusUnauthorized, "parameter refresh=true requires a logged-in user. Please log in and try again.")
+			c.Abort()
+			return
+		}
+
+		// Check if this user still has refreshes left in their own bucket
+		limiter := registry.get(fmt.Sprintf("user:%v", userID))
+		if !limiter.Allow() {
+			info := rateLimitInfo("refresh", limiter)
+			if registry.logRejections {
+				logRateLimitRejection(c, info)
+			}
+			helpers.RateLimitExceededResponse(c, info)
+			return
+		}
+
+		c.Next()
+	}
+}
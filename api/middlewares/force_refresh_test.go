@@ -0,0 +1,59 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// forceRefreshRouter serves weather.current behind ForceRefreshLimiter, allowing one refresh per user.
+// Requests carrying an X-User header stand for a user logged in with a JWT.
+func forceRefreshRouter() *gin.Engine {
+	router := gin.New()
+	login := func(c *gin.Context) {
+		if user := c.GetHeader("X-User"); user != "" {
+			c.Set("userID", user)
+		}
+	}
+	router.GET("/weather.current", login, ForceRefreshLimiter(NewRateLimiterRegistry(0.001, 1, false)), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+	return router
+}
+
+func TestForceRefreshLimiter(t *testing.T) {
+	router := forceRefreshRouter()
+	send := func(target, user string) int {
+		r := httptest.NewRequest(http.MethodGet, target, nil)
+		if user != "" {
+			r.Header.Set("X-User", user)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, r)
+		return w.Code
+	}
+
+	// A key alone can't force a refresh, but can still request cached data
+	if got := send("/weather.current?key=k&q=London&refresh=true", ""); got != http.StatusUnauthorized {
+		t.Errorf("refresh without a logged-in user = %d, want 401", got)
+	}
+	if got := send("/weather.current?key=k&q=London", ""); got != http.StatusOK {
+		t.Errorf("request without a refresh = %d, want 200", got)
+	}
+
+	// Every user has a refresh allowance of their own
+	if got := send("/weather.current?key=k&q=London&refresh=true", "7"); got != http.StatusOK {
+		t.Errorf("first refresh of user 7 = %d, want 200", got)
+	}
+	if got := send("/weather.current?key=k&q=London&refresh=true", "7"); got != http.StatusTooManyRequests {
+		t.Errorf("second refresh of user 7 = %d, want 429", got)
+	}
+	if got := send("/weather.current?key=k&q=London", "7"); got != http.StatusOK {
+		t.Errorf("request without a refresh after the limit = %d, want 200", got)
+	}
+	if got := send("/weather.current?key=k&q=London&refresh=true", "8"); got != http.StatusOK {
+		t.Errorf("first refresh of user 8 = %d, want 200", got)
+	}
+}
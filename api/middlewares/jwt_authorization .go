@@ -1,74 +1,152 @@
 package middlewares
 
 import (
+	"errors"
 	"fmt"
-	"havoAPI/api/config"
 	"havoAPI/api/helpers"
+	"havoAPI/internal/clock"
+	"math"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
 )
 
+// TokenBlacklist reports whether a JWT has been revoked server-side before its expiry,
+// either individually (logout) or because all of the user's sessions were invalidated (password change).
+type TokenBlacklist interface {
+	IsTokenRevoked(jti string) (bool, error)
+	TokensValidAfter(userID int) (time.Time, error)
+}
+
+// errInvalidToken is returned by authenticateUser when the request carries no usable JWT.
+// Any other error means the token could not be checked (e.g. Redis is down).
+var errInvalidToken = errors.New("missing, invalid or revoked JWT")
+
+// userToken holds the claims of a validated JWT that downstream handlers rely on.
+type userToken struct {
+	userID    float64   // userID is the "userID" claim, stored in the context as a float64 like every JSON number.
+	jti       string    // jti is the ID of the token, used to revoke it on logout.
+	expiresAt time.Time // expiresAt is the expiry of the token from the "ttl" claim.
+}
+
 // UserAuthorizationJWT checks if the user has a valid JWT token stored in the "u_auth" cookie.
 // If the token is missing, invalid, or expired, the request is aborted with an "Unauthorized" response.
 // If the token is valid, the userID is extracted from the claims and set in the context for further use by downstream handlers.
-func UserAuthorizationJWT() gin.HandlerFunc {
+// Tokens whose ID ("jti") has been revoked through the blacklist (e.g. on logout), or that were issued ("iat")
+// before the user's sessions were last invalidated (e.g. on password change), are rejected as well.
+// Expiry is checked against the given clock.
+// Tokens signed with any of the secret keys are accepted, so that the signing secret can be rotated
+// without invalidating the tokens issued with the previous one.
+func UserAuthorizationJWT(secretKeys []string, blacklist TokenBlacklist, clk clock.Clock) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		// Retrieve the JWT token from the cookie
-		tokenStr, err := c.Cookie("u_auth")
-		if err != nil {
+		token, err := authenticateUser(c, secretKeys, blacklist, clk)
+		if errors.Is(err, errInvalidToken) {
 			helpers.UnauthorizedResponse(c)
 			return
 		}
+		if err != nil {
+			helpers.ServerError(c, err)
+			c.Abort()
+			return
+		}
 
-		// Parse and validate the JWT token using the signing method and secret key
-		token, err := jwt.Parse(tokenStr, func(token *jwt.Token) (interface{}, error) {
-			// Ensure the signing method is HMAC (symmetric encryption).
-			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-				return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-			}
+		// Set the "userID" in the context for further use in downstream handlers.
+		c.Set("userID", token.userID) // Store userID in context.
 
-			// Load the secret key from environment variables to validate the token signature.
-			secretKey, err := config.LoadEnvironmentVariable("JWT_SECRET_KEY")
-			if err != nil {
-				return nil, fmt.Errorf("cannot get secret key while creating and signing JWT: %v", err)
-			}
+		// Store the token ID and expiry so the logout handler can revoke this token.
+		c.Set("jti", token.jti)
+		c.Set("tokenExpiresAt", token.expiresAt)
 
-			// Return the secret key for token validation.
-			return []byte(secretKey), nil
-		})
+		// Proceed to the next middleware or handler in the chain.
+		c.Next()
+	}
+}
 
-		// If token parsing or validation fails, return an unauthorized response
-		if err != nil || !token.Valid {
-			helpers.UnauthorizedResponse(c)
-			return
-		}
+// authenticateUser validates the JWT stored in the "u_auth" cookie of the request and returns its claims.
+// The token may be signed with any of the secret keys.
+// It returns errInvalidToken if the token is missing, malformed, expired or revoked.
+func authenticateUser(c *gin.Context, secretKeys []string, blacklist TokenBlacklist, clk clock.Clock) (userToken, error) {
+	// Retrieve the JWT token from the cookie
+	tokenStr, err := c.Cookie("u_auth")
+	if err != nil {
+		return userToken{}, errInvalidToken
+	}
 
-		// Extract claims from the JWT token and check if they are valid
-		claims, ok := token.Claims.(jwt.MapClaims)
-		if !ok {
-			helpers.UnauthorizedResponse(c)
-			return
-		}
+	// Parse and validate the JWT token with each secret key in turn, the current one first
+	token, err := parseWithAnyKey(tokenStr, secretKeys, clk)
 
-		// Check if the token has expired based on the "ttl" claim
-		if claims["ttl"].(float64) < float64(time.Now().Unix()) {
-			helpers.UnauthorizedResponse(c)
-			return
-		}
+	// If token parsing or validation fails with every key, the token is unusable
+	if err != nil || !token.Valid {
+		return userToken{}, errInvalidToken
+	}
 
-		// Ensure the "userID" claim is valid, otherwise return unauthorized
-		userID := claims["userID"].(float64)
-		if userID == 0 {
-			helpers.UnauthorizedResponse(c)
-			return
-		}
+	// Extract claims from the JWT token and check if they are valid
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		return userToken{}, errInvalidToken
+	}
 
-		// Set the "userID" in the context for further use in downstream handlers.
-		c.Set("userID", userID) // Store userID in context.
+	// Check if the token has expired based on the "ttl" claim
+	expiresAt, ok := claims["ttl"].(float64)
+	if !ok || expiresAt < float64(clk.Now().Unix()) {
+		return userToken{}, errInvalidToken
+	}
 
-		// Proceed to the next middleware or handler in the chain.
-		c.Next()
+	// Ensure the "userID" claim is valid
+	userID, ok := claims["userID"].(float64)
+	if !ok || userID == 0 {
+		return userToken{}, errInvalidToken
+	}
+
+	// Ensure the token has an ID and has not been revoked
+	jti, ok := claims["jti"].(string)
+	if !ok || jti == "" {
+		return userToken{}, errInvalidToken
+	}
+	revoked, err := blacklist.IsTokenRevoked(jti)
+	if err != nil {
+		return userToken{}, err
+	}
+	if revoked {
+		return userToken{}, errInvalidToken
+	}
+
+	// Reject tokens issued before the user's sessions were last invalidated
+	issuedAt, ok := claims["iat"].(float64)
+	if !ok {
+		return userToken{}, errInvalidToken
+	}
+	validAfter, err := blacklist.TokensValidAfter(int(userID))
+	if err != nil {
+		return userToken{}, err
+	}
+	// Both sides have millisecond precision; tokens issued in the second of the invalidation but before it are rejected too
+	if int64(math.Round(issuedAt*1000)) < validAfter.UnixMilli() {
+		return userToken{}, errInvalidToken
+	}
+
+	return userToken{userID: userID, jti: jti, expiresAt: time.Unix(int64(expiresAt), 0)}, nil
+}
+
+// parseWithAnyKey parses and validates a JWT with each of the secret keys in turn, returning the first valid result.
+// The library's own time checks (e.g. "exp", "nbf") use the given clock.
+func parseWithAnyKey(tokenStr string, secretKeys []string, clk clock.Clock) (*jwt.Token, error) {
+	err := errInvalidToken
+	for _, secretKey := range secretKeys {
+		var token *jwt.Token
+		token, err = jwt.Parse(tokenStr, func(token *jwt.Token) (interface{}, error) {
+			// Ensure the signing method is HMAC (symmetric encryption).
+			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+				return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+			}
+
+			// Return the secret key from the application config for token validation.
+			return []byte(secretKey), nil
+		}, jwt.WithTimeFunc(clk.Now))
+		if err == nil && token.Valid {
+			return token, nil
+		}
 	}
+	return nil, err
 }
@@ -0,0 +1,118 @@
+package middlewares
+
+import (
+	"havoAPI/api/helpers"
+	"havoAPI/internal/clock"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func init() {
+	gin.SetMode(gin.TestMode)
+}
+
+// testSecret is the JWT secret the test tokens are signed with.
+const testSecret = "0123456789abcdef0123456789abcdef"
+
+// fakeBlacklist is a TokenBlacklist holding the revoked token IDs and the invalidation time of every user.
+type fakeBlacklist struct {
+	revoked    map[string]bool
+	validAfter time.Time
+}
+
+func (b *fakeBlacklist) IsTokenRevoked(jti string) (bool, error) {
+	return b.revoked[jti], nil
+}
+
+func (b *fakeBlacklist) TokensValidAfter(userID int) (time.Time, error) {
+	return b.validAfter, nil
+}
+
+// authorize sends a request carrying the given JWT through UserAuthorizationJWT and returns the response status.
+func authorize(t *testing.T, token string, secretKeys []string, blacklist TokenBlacklist, clk clock.Clock) int {
+	t.Helper()
+	router := gin.New()
+	router.GET("/me", UserAuthorizationJWT(secretKeys, blacklist, clk), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	r := httptest.NewRequest(http.MethodGet, "/me", nil)
+	r.AddCookie(&http.Cookie{Name: "u_auth", Value: token})
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, r)
+	return w.Code
+}
+
+// signToken issues a JWT for user 1 at the current time of the clock.
+func signToken(t *testing.T, clk clock.Clock, secret string) string {
+	t.Helper()
+	token, err := helpers.CreateAndSignJWT(clk, 1, secret, time.Hour)
+	if err != nil {
+		t.Fatalf("CreateAndSignJWT failed: %v", err)
+	}
+	return token
+}
+
+func TestUserAuthorizationJWTRejectsTokensIssuedBeforeInvalidation(t *testing.T) {
+	// The sessions are invalidated half-way through a second
+	invalidatedAt := time.Date(2026, 3, 14, 12, 0, 0, 500*int(time.Millisecond), time.UTC)
+	blacklist := &fakeBlacklist{validAfter: invalidatedAt}
+
+	tests := []struct {
+		name     string
+		issuedAt time.Time
+		want     int
+	}{
+		{name: "a second earlier", issuedAt: invalidatedAt.Add(-time.Second), want: http.StatusUnauthorized},
+		{name: "earlier in the same second", issuedAt: invalidatedAt.Add(-100 * time.Millisecond), want: http.StatusUnauthorized},
+		{name: "at the invalidation", issuedAt: invalidatedAt, want: http.StatusOK},
+		{name: "later in the same second", issuedAt: invalidatedAt.Add(100 * time.Millisecond), want: http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clk := clock.NewFake(tt.issuedAt)
+			token := signToken(t, clk, testSecret)
+
+			clk.Set(invalidatedAt.Add(time.Minute))
+			if got := authorize(t, token, []string{testSecret}, blacklist, clk); got != tt.want {
+				t.Errorf("status = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUserAuthorizationJWTAcceptsTokensSignedByPreviousSecrets(t *testing.T) {
+	const (
+		previousSecret = "previous-secret-0123456789abcdef"
+		retiredSecret  = "retired-secret-0123456789abcdefg"
+	)
+	secretKeys := []string{testSecret, previousSecret}
+	blacklist := &fakeBlacklist{}
+
+	tests := []struct {
+		name   string
+		secret string
+		age    time.Duration
+		want   int
+	}{
+		{name: "current secret", secret: testSecret, want: http.StatusOK},
+		{name: "previous secret", secret: previousSecret, want: http.StatusOK},
+		{name: "previous secret, expired", secret: previousSecret, age: 2 * time.Hour, want: http.StatusUnauthorized},
+		{name: "secret no longer listed", secret: retiredSecret, want: http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clk := clock.NewFake(time.Date(2026, 3, 14, 12, 0, 0, 0, time.UTC))
+			token := signToken(t, clk, tt.secret)
+
+			clk.Advance(tt.age)
+			if got := authorize(t, token, secretKeys, blacklist, clk); got != tt.want {
+				t.Errorf("status = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
@@ -1,27 +1,36 @@
 package middlewares
 
 import (
-	"fmt"
-	"havoAPI/api/helpers"
+	"log"
+	"net/http"
+	"runtime/debug"
 
 	"github.com/gin-gonic/gin"
 )
- 
+
 // RecoverPanic is a middleware that handles panics in the Gin application.
-// If a panic occurs during request processing, it will recover from the panic
-// and return a 500 Internal Server Error response to the client.
+// If a panic occurs during request processing, it will recover from the panic, log the recovered value
+// and the stack trace together with the request ID, and return a 500 Internal Server Error response to the client.
 func RecoverPanic() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// Defer function to recover from panic if any occurs during the request lifecycle
 		defer func() {
 			// Check if a panic occurred (i.e., err is not nil)
 			if err := recover(); err != nil {
+				// Log the actual panic value and where it happened, keyed by the request ID (set by the RequestID middleware)
+				log.Printf("panic recovered (request_id=%s, %s %s): %v\n%s", c.GetString("requestID"), c.Request.Method, c.Request.URL.Path, err, debug.Stack())
+
 				// Set the "Connection" header to "close" to indicate the connection should be closed after the response is sent
 				c.Header("Connection", "close")
 
-				// Log the error (optional) and send a generic server error response with status 500
-				// The error message returned is a generic message, but you could expand this based on the error
-				helpers.ServerError(c, fmt.Errorf("this is panic recovery; something went wrong %s", "..."))
+				// Send a generic server error response with status 500, unless the handler already started writing one
+				if !c.Writer.Written() {
+					c.JSON(http.StatusInternalServerError, gin.H{
+						"error":      "An unexpected server error occurred. Please try again later.",
+						"request_id": c.GetString("requestID"),
+					})
+				}
+				c.Abort()
 			}
 		}()
 
@@ -0,0 +1,53 @@
+package middlewares
+
+import (
+	"bytes"
+	"encoding/json"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestRecoverPanicLogsTheValueAndRequestID(t *testing.T) {
+	// Capture the log output
+	var logs bytes.Buffer
+	output := log.Writer()
+	log.SetOutput(&logs)
+	t.Cleanup(func() { log.SetOutput(output) })
+
+	router := gin.New()
+	router.Use(RequestID(), RecoverPanic())
+	router.GET("/boom", func(c *gin.Context) {
+		panic("nil map in weather formatter")
+	})
+
+	r := httptest.NewRequest(http.MethodGet, "/boom", nil)
+	r.Header.Set(requestIDHeader, "req-1137")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, r)
+
+	if w.Code != http.StatusInternalServerError {
+		t.Fatalf("status = %d, want 500", w.Code)
+	}
+	var body struct {
+		Error     string `json:"error"`
+		RequestID string `json:"request_id"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatalf("response is not valid JSON: %v", err)
+	}
+	if body.RequestID != "req-1137" || strings.Contains(body.Error, "nil map") {
+		t.Errorf("response = %+v, want the request ID and a generic message", body)
+	}
+
+	logged := logs.String()
+	for _, want := range []string{"nil map in weather formatter", "request_id=req-1137", "GET /boom", "panic_recovery_test.go"} {
+		if !strings.Contains(logged, want) {
+			t.Errorf("log doesn't contain %q:\n%s", want, logged)
+		}
+	}
+}
@@ -1,24 +1,65 @@
 package middlewares
 
 import (
+	"havoAPI/api/config"
 	"havoAPI/api/helpers"
+	"log"
+	"math"
+	"sync"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"golang.org/x/time/rate"
 )
 
 // RateLimiter is a middleware that limits the number of requests that can be made in a given time window.
-// It uses a token bucket algorithm to allow a certain number of requests (10 requests) every 30 seconds.
+// It uses token buckets selected by the matched route: routes listed in routeLimits get a bucket of their own,
+// and all other routes share the baseline bucket. Limits are keyed by route pattern, either for a single method
+// ("POST /api/v1/signup") or for every method of the path ("/api/v1/signup"). Either way, every method gets
+// a bucket of its own, so that e.g. GET requests never use up the allowance of POST requests to the same path.
 // If the rate limit is exceeded, it responds with a 429 Too Many Requests status.
-func RateLimiter() gin.HandlerFunc {
-	// Create a new rate limiter with a maximum of 10 requests per 30 seconds.
-	limiter := rate.NewLimiter(10, 30)
+// With logRejections set, every rejection is logged (see logRateLimitRejection).
+func RateLimiter(baseline config.RateLimit, routeLimits map[string]config.RateLimit, logRejections bool) gin.HandlerFunc {
+	// Create the shared baseline limiter; route limiters are created on the first request of each method and route.
+	limiter := rate.NewLimiter(rate.Limit(baseline.Rate), baseline.Burst)
+	var mu sync.Mutex
+	routeLimiters := make(map[string]*rate.Limiter)
+
+	// routeLimiter returns the limiter of a method and route, or nil if the route has no limit of its own.
+	routeLimiter := func(method, route string) *rate.Limiter {
+		key := method + " " + route
+		mu.Lock()
+		defer mu.Unlock()
+		if routeLimiter, ok := routeLimiters[key]; ok {
+			return routeLimiter
+		}
+
+		// A limit for the method takes precedence over one for the whole path
+		limit, ok := routeLimits[key]
+		if !ok {
+			if limit, ok = routeLimits[route]; !ok {
+				return nil
+			}
+		}
+		routeLimiters[key] = rate.NewLimiter(rate.Limit(limit.Rate), limit.Burst)
+		return routeLimiters[key]
+	}
 
 	return func(c *gin.Context) {
+		// Select the limiter of the matched method and route, falling back to the baseline
+		scope, selected := "global", limiter
+		if routeLimiter := routeLimiter(c.Request.Method, c.FullPath()); routeLimiter != nil {
+			scope, selected = "route", routeLimiter
+		}
+
 		// Check if the current request is allowed based on the rate limit
-		if !limiter.Allow() {
+		if !selected.Allow() {
 			// If the rate limit is exceeded, return a rate limit exceeded response
-			helpers.RateLimitExceededResponse(c)
+			info := rateLimitInfo(scope, selected)
+			if logRejections {
+				logRateLimitRejection(c, info)
+			}
+			helpers.RateLimitExceededResponse(c, info)
 			return
 		}
 
@@ -26,3 +67,153 @@ func RateLimiter() gin.HandlerFunc {
 		c.Next()
 	}
 }
+
+// limiterIdleTimeout is how long a per-key limiter may stay unused before it is evicted from the registry.
+const limiterIdleTimeout = 3 * time.Minute
+
+// keyLimiter pairs a token bucket with the last time it was used, so idle entries can be evicted.
+type keyLimiter struct {
+	limiter  *rate.Limiter
+	lastSeen time.Time
+}
+
+// RateLimitStatus describes the current state of a per-key token bucket.
+type RateLimitStatus struct {
+	Limit        float64 `json:"limit"`         // Limit is the number of requests per second the key may make.
+	Burst        int     `json:"burst"`         // Burst is the maximum number of requests the key may make at once.
+	Remaining    int     `json:"remaining"`     // Remaining is the number of requests the key may make right now.
+	ResetSeconds int     `json:"reset_seconds"` // ResetSeconds is the number of seconds until the bucket is full again.
+}
+
+// RateLimiterRegistry holds one token bucket per API key.
+// It is shared between the per-key rate limiting middleware and the handlers that report limiter state.
+type RateLimiterRegistry struct {
+	mu        sync.Mutex
+	limiters  map[string]*keyLimiter
+	limit     rate.Limit
+	burst     int
+	lastSweep time.Time
+
+	logRejections bool // logRejections makes the per-key middleware log every rejected request.
+}
+
+// NewRateLimiterRegistry creates a registry whose limiters allow `limit` requests per second with the given burst.
+// With logRejections set, the per-key middleware logs every request it rejects.
+func NewRateLimiterRegistry(limit float64, burst int, logRejections bool) *RateLimiterRegistry {
+	return &RateLimiterRegistry{
+		limiters:      make(map[string]*keyLimiter),
+		limit:         rate.Limit(limit),
+		burst:         burst,
+		lastSweep:     time.Now(),
+		logRejections: logRejections,
+	}
+}
+
+// get returns the limiter for the given key, creating it if necessary.
+// It also evicts limiters that have been idle for longer than limiterIdleTimeout, at most once per minute.
+func (r *RateLimiterRegistry) get(key string) *rate.Limiter {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+
+	// Periodically drop idle limiters so random or rotated keys can't grow the map forever
+	if now.Sub(r.lastSweep) > time.Minute {
+		for k, l := range r.limiters {
+			if now.Sub(l.lastSeen) > limiterIdleTimeout {
+				delete(r.limiters, k)
+			}
+		}
+		r.lastSweep = now
+	}
+
+	l, ok := r.limiters[key]
+	if !ok {
+		l = &keyLimiter{limiter: rate.NewLimiter(r.limit, r.burst)}
+		r.limiters[key] = l
+	}
+	l.lastSeen = now
+
+	return l.limiter
+}
+
+// Status returns the current state of the given key's limiter without consuming a token.
+func (r *RateLimiterRegistry) Status(key string) RateLimitStatus {
+	limiter := r.get(key)
+	tokens := limiter.Tokens()
+
+	// Time until the bucket refills completely at the configured rate
+	resetSeconds := 0
+	if missing := float64(r.burst) - tokens; missing > 0 {
+		resetSeconds = int(math.Ceil(missing / float64(r.limit)))
+	}
+
+	return RateLimitStatus{
+		Limit:        float64(r.limit),
+		Burst:        r.burst,
+		Remaining:    int(math.Max(0, math.Floor(tokens))),
+		ResetSeconds: resetSeconds,
+	}
+}
+
+// PerKeyRateLimiter is a middleware that limits requests per API key using the given registry.
+// Requests without an API key are left to the handlers, which reject them anyway.
+func PerKeyRateLimiter(registry *RateLimiterRegistry) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		apiKey := helpers.APIKeyFromRequest(c)
+		if apiKey == "" {
+			c.Next()
+			return
+		}
+
+		// Check if this key still has tokens left in its own bucket
+		limiter := registry.get(apiKey)
+		if !limiter.Allow() {
+			info := rateLimitInfo("key", limiter)
+			if registry.logRejections {
+				logRateLimitRejection(c, info)
+			}
+			helpers.RateLimitExceededResponse(c, info)
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// rateLimitInfo describes the given limiter for a 429 response, including when the next token is available.
+func rateLimitInfo(scope string, limiter *rate.Limiter) helpers.RateLimitInfo {
+	// Seconds until the bucket holds at least one full token again
+	retryAfter := 1
+	if missing := 1 - limiter.Tokens(); missing > 0 {
+		retryAfter = max(1, int(math.Ceil(missing/float64(limiter.Limit()))))
+	}
+
+	return helpers.RateLimitInfo{
+		Scope:      scope,
+		Limit:      float64(limiter.Limit()),
+		Window:     "1s",
+		Burst:      limiter.Burst(),
+		RetryAfter: retryAfter,
+	}
+}
+
+// logRateLimitRejection logs a request rejected by a rate limiter as key=value pairs, for spotting abusive clients:
+// the limit that was hit, the endpoint, the client IP and the masked API key (if any), and the request ID.
+func logRateLimitRejection(c *gin.Context, info helpers.RateLimitInfo) {
+	log.Printf("WARN: rate limit exceeded scope=%s limit=%v burst=%d method=%s route=%q ip=%s key=%s request_id=%s",
+		info.Scope, info.Limit, info.Burst, c.Request.Method, c.FullPath(), c.ClientIP(),
+		maskAPIKey(helpers.APIKeyFromRequest(c)), c.GetString("requestID"))
+}
+
+// maskAPIKey keeps only the first four characters of an API key, enough to tell keys apart in logs
+// without making them usable. An empty key is reported as "-".
+func maskAPIKey(apiKey string) string {
+	if apiKey == "" {
+		return "-"
+	}
+	if len(apiKey) <= 4 {
+		return "****"
+	}
+	return apiKey[:4] + "****"
+}
@@ -0,0 +1,65 @@
+package middlewares
+
+import (
+	"havoAPI/api/config"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// rateLimitedRouter serves GET and POST on a single path behind RateLimiter.
+func rateLimitedRouter(routeLimits map[string]config.RateLimit) *gin.Engine {
+	router := gin.New()
+	router.Use(RateLimiter(config.RateLimit{Rate: 0.001, Burst: 100}, routeLimits, false))
+	ok := func(c *gin.Context) { c.Status(http.StatusOK) }
+	router.GET("/api/v1/signup", ok)
+	router.POST("/api/v1/signup", ok)
+	return router
+}
+
+// send returns the status of a single request.
+func send(router *gin.Engine, method string) int {
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(method, "/api/v1/signup", nil))
+	return w.Code
+}
+
+func TestRateLimiterKeysRouteLimitsByMethod(t *testing.T) {
+	tests := []struct {
+		name        string
+		routeLimits map[string]config.RateLimit
+		wantGET     int
+	}{
+		{
+			name:        "limit for the path",
+			routeLimits: map[string]config.RateLimit{"/api/v1/signup": {Rate: 0.001, Burst: 1}},
+			wantGET:     http.StatusTooManyRequests,
+		},
+		{
+			name:        "limit for the method",
+			routeLimits: map[string]config.RateLimit{"POST /api/v1/signup": {Rate: 0.001, Burst: 1}},
+			wantGET:     http.StatusOK,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := rateLimitedRouter(tt.routeLimits)
+
+			// Using up the GET allowance must leave POST untouched
+			if got := send(router, http.MethodGet); got != http.StatusOK {
+				t.Fatalf("first GET = %d, want %d", got, http.StatusOK)
+			}
+			if got := send(router, http.MethodGet); got != tt.wantGET {
+				t.Errorf("second GET = %d, want %d", got, tt.wantGET)
+			}
+			if got := send(router, http.MethodPost); got != http.StatusOK {
+				t.Errorf("first POST = %d, want %d", got, http.StatusOK)
+			}
+			if got := send(router, http.MethodPost); got != http.StatusTooManyRequests {
+				t.Errorf("second POST = %d, want %d", got, http.StatusTooManyRequests)
+			}
+		})
+	}
+}
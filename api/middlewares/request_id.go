@@ -0,0 +1,32 @@
+package middlewares
+
+import (
+	"regexp"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// requestIDHeader is the header carrying the request ID in both directions.
+const requestIDHeader = "X-Request-ID"
+
+// validRequestID limits client-supplied request IDs to a safe length and charset, so they can be logged as-is.
+var validRequestID = regexp.MustCompile(`^[A-Za-z0-9._-]{1,64}$`)
+
+// RequestID is a middleware that assigns every request an ID, stored in the context under "requestID"
+// and echoed in the X-Request-ID response header so log lines can be correlated with client reports.
+// A well-formed X-Request-ID sent by the client (or a proxy in front of the API) is reused; otherwise a UUID is generated.
+func RequestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		requestID := c.GetHeader(requestIDHeader)
+		if !validRequestID.MatchString(requestID) {
+			requestID = uuid.New().String()
+		}
+
+		// Make the ID available to downstream handlers and to the client
+		c.Set("requestID", requestID)
+		c.Header(requestIDHeader, requestID)
+
+		c.Next()
+	}
+}
@@ -0,0 +1,25 @@
+package middlewares
+
+import (
+	"havoAPI/api/helpers"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequireHeaderAPIKey rejects requests that pass an API key in the 'key' query parameter with 400 Bad Request,
+// so that keys never end up in URLs, where access logs and proxies record them.
+// Keys sent in the X-API-Key or Authorization header, or as a WebSocket subprotocol, are unaffected.
+func RequireHeaderAPIKey() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if _, inQuery := c.GetQuery("key"); inQuery {
+			helpers.ClientErrorWithCode(c, http.StatusBadRequest, "API_KEY_IN_QUERY",
+				"API keys are not accepted in the query string on this service. Send the key in the X-API-Key header (or as 'Authorization: Bearer <key>', or from browser WebSockets as the subprotocol 'apikey.<key>' along with 'havo.stream') instead, and rotate any key that was sent in a URL.")
+			c.Abort()
+			return
+		}
+
+		// Proceed to the next middleware or handler in the chain.
+		c.Next()
+	}
+}
@@ -0,0 +1,34 @@
+package middlewares
+
+import (
+	"havoAPI/api/helpers"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequireJSONBody rejects requests whose body is not declared as JSON with 415 Unsupported Media Type,
+// instead of letting the JSON binding fail with a confusing validation error (e.g. on a form-encoded body).
+// "application/json" and structured "+json" types are accepted, with any parameters such as a charset.
+// Requests without a body (e.g. POST /logout) are left to their handler.
+func RequireJSONBody() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// Only requests that carry a body need a JSON content type; a length of -1 means the length is unknown
+		if c.Request.ContentLength == 0 {
+			c.Next()
+			return
+		}
+
+		mediaType, _, err := mime.ParseMediaType(c.GetHeader("Content-Type"))
+		if err != nil || (mediaType != "application/json" && !strings.HasSuffix(mediaType, "+json")) {
+			helpers.ClientError(c, http.StatusUnsupportedMediaType, "Request body must be JSON; set the Content-Type header to application/json.")
+			c.Abort()
+			return
+		}
+
+		// Proceed to the next middleware or handler in the chain.
+		c.Next()
+	}
+}
@@ -0,0 +1,47 @@
+package middlewares
+
+import (
+	"havoAPI/api/helpers"
+	"net/http"
+	"sync/atomic"
+
+	"github.com/gin-gonic/gin"
+)
+
+// StreamLimiter caps the number of concurrent long-lived streaming connections (WebSocket and Server-Sent Events).
+// Each of them holds a file descriptor for as long as the client stays connected, so without a cap
+// a burst of clients could exhaust the descriptors of the process and take down every other route with it.
+// It is shared by all streaming routes, so the cap is global, and by the metrics handler reporting the count.
+type StreamLimiter struct {
+	active atomic.Int64 // active is the number of streaming connections currently served.
+	max    int64        // max is the number of streaming connections served at once.
+}
+
+// NewStreamLimiter creates a limiter admitting at most max concurrent streaming connections.
+func NewStreamLimiter(max int) *StreamLimiter {
+	return &StreamLimiter{max: int64(max)}
+}
+
+// Active returns the number of streaming connections currently served.
+func (l *StreamLimiter) Active() int64 {
+	return l.active.Load()
+}
+
+// Limit is a middleware that admits a streaming connection if the limiter is below its cap and releases
+// the slot once the handler returns, i.e. when the client has disconnected. At capacity,
+// it responds with 503 Service Unavailable before the connection is upgraded.
+func (l *StreamLimiter) Limit() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// Take a slot first and give it back if that went over the cap, so concurrent connects can't overshoot
+		if l.active.Add(1) > l.max {
+			l.active.Add(-1)
+			helpers.ClientError(c, http.StatusServiceUnavailable, "Too many streaming connections. Please try again later.")
+			c.Abort()
+			return
+		}
+		defer l.active.Add(-1)
+
+		// Streaming handlers only return once the connection is closed
+		c.Next()
+	}
+}
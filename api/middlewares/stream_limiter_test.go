@@ -0,0 +1,67 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestStreamLimiterCapsConcurrentStreams(t *testing.T) {
+	limiter := NewStreamLimiter(2)
+	release := make(chan struct{})
+	router := gin.New()
+	router.GET("/stream", limiter.Limit(), func(c *gin.Context) {
+		// A stream is served until its client disconnects
+		<-release
+		c.Status(http.StatusOK)
+	})
+	stream := func() int {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/stream", nil))
+		return w.Code
+	}
+
+	// Fill the cap with streams that stay open
+	var wg sync.WaitGroup
+	codes := make([]int, 2)
+	for i := range codes {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			codes[i] = stream()
+		}()
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for limiter.Active() < 2 {
+		if time.Now().After(deadline) {
+			t.Fatalf("active streams = %d, want 2", limiter.Active())
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	if got := stream(); got != http.StatusServiceUnavailable {
+		t.Errorf("stream over the cap returned %d, want 503", got)
+	}
+	if got := limiter.Active(); got != 2 {
+		t.Errorf("active streams after a refused one = %d, want 2", got)
+	}
+
+	// Disconnected streams give their slots back
+	close(release)
+	wg.Wait()
+	for i, code := range codes {
+		if code != http.StatusOK {
+			t.Errorf("stream %d returned %d, want 200", i+1, code)
+		}
+	}
+	if got := limiter.Active(); got != 0 {
+		t.Errorf("active streams after every client left = %d, want 0", got)
+	}
+	if got := stream(); got != http.StatusOK {
+		t.Errorf("stream after the others closed returned %d, want 200", got)
+	}
+}
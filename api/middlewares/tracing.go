@@ -0,0 +1,51 @@
+package middlewares
+
+import (
+	"havoAPI/internal/tracing"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Tracing is a middleware that wraps every request in an OpenTelemetry server span, continuing the trace
+// of the caller if the request carries a traceparent header. The span is named after the route template
+// (e.g. "GET /api/v1/weather.current") rather than the raw path, to keep the number of span names bounded,
+// and the request context carries it so that the services add their spans as children.
+func Tracing() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// Continue the caller's trace, if any
+		ctx := otel.GetTextMapPropagator().Extract(c.Request.Context(), propagation.HeaderCarrier(c.Request.Header))
+
+		// Unknown paths have no route template
+		route := c.FullPath()
+		name := c.Request.Method
+		if route != "" {
+			name += " " + route
+		}
+
+		ctx, span := tracing.Tracer().Start(ctx, name,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				attribute.String("http.request.method", c.Request.Method),
+				attribute.String("http.route", route),
+				attribute.String("request.id", c.GetString("requestID")),
+			),
+		)
+		defer span.End()
+		c.Request = c.Request.WithContext(ctx)
+
+		c.Next()
+
+		// Server spans only fail on server errors; 4xx responses are the client's doing
+		status := c.Writer.Status()
+		span.SetAttributes(attribute.Int("http.response.status_code", status))
+		if status >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, http.StatusText(status))
+		}
+	}
+}
@@ -0,0 +1,33 @@
+package middlewares
+
+import (
+	"havoAPI/api/helpers"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// UsageRecorder counts the requests made with an API key.
+type UsageRecorder interface {
+	RecordUsage(apiKey string)
+}
+
+// UsageTracker is a middleware that counts every request made with an API key towards the key's daily usage.
+// Requests rejected before being served (bad key or rate limited) are not counted. The count is
+// written in the background once the response is complete, so tracking never delays a request.
+func UsageTracker(recorder UsageRecorder) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+
+		apiKey := helpers.APIKeyFromRequest(c)
+		if apiKey == "" {
+			return
+		}
+		switch c.Writer.Status() {
+		case http.StatusUnauthorized, http.StatusTooManyRequests:
+			return
+		}
+
+		go recorder.RecordUsage(apiKey)
+	}
+}
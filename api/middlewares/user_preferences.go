@@ -0,0 +1,49 @@
+package middlewares
+
+import (
+	"errors"
+	"havoAPI/internal/clock"
+	"log"
+
+	"github.com/gin-gonic/gin"
+)
+
+// PreferencesLookup returns the preferred units and language of a user; empty strings for unset preferences.
+type PreferencesLookup interface {
+	PreferredSettings(userID int) (string, string, error)
+}
+
+// UserPreferences applies the preferences of a logged-in user to weather requests authorized by API key.
+// If the request also carries a valid JWT in the "u_auth" cookie, the user's preferred units and language are
+// stored in the context under "preferredUnits" and "preferredLang", where the query parameter helpers pick them
+// up as defaults; explicit query parameters still win. The user's ID is stored under "userID" as well,
+// for options reserved to logged-in users (see ForceRefreshLimiter). Requests without a valid JWT are never
+// rejected here: they simply keep the system defaults, and failures to load the preferences are only logged.
+func UserPreferences(secretKeys []string, blacklist TokenBlacklist, prefs PreferencesLookup, clk clock.Clock) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		token, err := authenticateUser(c, secretKeys, blacklist, clk)
+		if err != nil {
+			if !errors.Is(err, errInvalidToken) {
+				log.Printf("failed to check JWT for preferences: %v", err)
+			}
+			c.Next()
+			return
+		}
+
+		// Identify the user, like UserAuthorizationJWT does
+		c.Set("userID", token.userID)
+
+		units, lang, err := prefs.PreferredSettings(int(token.userID))
+		if err != nil {
+			log.Printf("failed to load preferences of user %d: %v", int(token.userID), err)
+			c.Next()
+			return
+		}
+
+		// Make the preferences available to the query parameter helpers
+		c.Set("preferredUnits", units)
+		c.Set("preferredLang", lang)
+
+		c.Next()
+	}
+}
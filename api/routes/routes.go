@@ -1,8 +1,12 @@
 package routes
 
 import (
+	"fmt"
+	"havoAPI/api/config"
 	"havoAPI/api/handlers"
 	"havoAPI/api/middlewares"
+	"havoAPI/internal/clock"
+	"log"
 
 	"github.com/gin-gonic/gin"
 )
@@ -10,24 +14,88 @@ import (
 // ServeHandlerWrapper wraps the UserHandler and WeatherHandler to provide HTTP handler functionality.
 // By embedding these handlers, the wrapper allows easy access to user and weather-related routes in the application.
 type ServeHandlerWrapper struct {
-	*handlers.UserHandler    // Embeds the UserHandler to handle user-related actions (signup, login, etc.)
-	*handlers.WeatherHandler // Embeds the WeatherHandler to handle weather-related actions (weather data retrieval, bulk queries, etc.)
+	*handlers.UserHandler      // Embeds the UserHandler to handle user-related actions (signup, login, etc.)
+	*handlers.WeatherHandler   // Embeds the WeatherHandler to handle weather-related actions (weather data retrieval, bulk queries, etc.)
+	*handlers.RateLimitHandler // Embeds the RateLimitHandler to report the per-key rate limiter state
+	*handlers.HealthHandler    // Embeds the HealthHandler to serve the liveness and readiness probes
+	*handlers.AlertsHandler    // Embeds the AlertsHandler to manage weather thresholds and list triggered alerts
+	*handlers.GroupsHandler    // Embeds the GroupsHandler to manage named location groups and fetch their weather
+	*handlers.UsageHandler     // Embeds the UsageHandler to report the daily usage of API keys
+	*handlers.CacheHandler     // Embeds the CacheHandler to let admins refresh the weather cache and follow its progress
+	*handlers.MetricsHandler   // Embeds the MetricsHandler to expose the size of the weather cache to Prometheus
+
+	RateLimiters *middlewares.RateLimiterRegistry // Per-key token buckets shared by the limiter middleware and RateLimitHandler
+
+	RefreshLimiters *middlewares.RateLimiterRegistry // Per-user token buckets limiting forced cache refreshes (refresh=true)
+
+	StreamLimiter *middlewares.StreamLimiter // Cap on concurrent streaming connections shared by the streaming routes and MetricsHandler
+
+	TokenBlacklist middlewares.TokenBlacklist // Revoked-token lookup used by the JWT authorization middleware
+
+	UsageRecorder middlewares.UsageRecorder // Daily request counter of API keys used by the usage tracking middleware
+
+	Preferences middlewares.PreferencesLookup // Preferred units and language of users, applied to their weather requests
+
+	Config *config.Config // Application config shared with the middlewares (e.g. JWT secret)
+
+	Clock clock.Clock // Time source shared with the middlewares (e.g. JWT expiry)
 }
 
 // Route sets up the routes and handlers for the application.
 // It accepts a ServeHandlerWrapper, which contains the logic for user-related actions like signup, login, and logout,
 // as well as weather data retrieval and bulk requests.
-func Route(h *ServeHandlerWrapper) *gin.Engine {
+func Route(h *ServeHandlerWrapper) (*gin.Engine, error) {
 	// Create a new Gin router with default middleware (logging, recovery, etc.)
 	router := gin.Default()
 
-	// Apply middleware for panic recovery, secure headers, and rate limiting
+	// Only trust X-Forwarded-For from the configured proxies, so c.ClientIP() can't be spoofed
+	if err := router.SetTrustedProxies(h.Config.TrustedProxies); err != nil {
+		return nil, fmt.Errorf("failed to set trusted proxies: %w", err)
+	}
+
+	// Apply middleware for request IDs, panic recovery and secure headers
+	router.Use(middlewares.RequestID())     // Assigns every request an ID for log correlation
+	router.Use(middlewares.Tracing())       // Wraps every request in an OpenTelemetry span (a no-op without an exporter)
 	router.Use(middlewares.RecoverPanic())  // Handles panics during request processing
 	router.Use(middlewares.SecureHeaders()) // Adds security-related headers to the response
-	router.Use(middlewares.RateLimiter())   // Limits the rate of incoming requests
+
+	// GET /livez: Liveness probe, responds as long as the process is not wedged (no dependency checks)
+	router.GET("/livez", h.Liveness)
+
+	// GET /readyz and /healthz: Readiness probe, checks MySQL and Redis and fails during graceful shutdown
+	router.GET("/readyz", h.Readiness)
+	router.GET("/healthz", h.Readiness)
+
+	// GET /metrics: Prometheus gauges of the weather cache size, measured at most once per CACHE_METRICS_INTERVAL
+	// This route requires METRICS_TOKEN (or ADMIN_TOKEN) as a bearer token, since the gauges reveal how the service is used.
+	router.GET("/metrics", middlewares.MetricsAuthorization(h.Config.MetricsToken), h.Metrics)
+
+	// JWT authorization shared by all routes that require a logged-in user
+	userAuth := middlewares.UserAuthorizationJWT(h.Config.JWTVerificationKeys(), h.TokenBlacklist, h.Clock)
+
+	// Optional JWT check applying a logged-in user's preferred units and language to weather requests
+	preferences := middlewares.UserPreferences(h.Config.JWTVerificationKeys(), h.TokenBlacklist, h.Preferences, h.Clock)
 
 	// Define version 1 of the API routes with the /v1 prefix
-	v1 := router.Group("/api/v1")
+	// The APIVersion middleware also honors header-based versioning (Accept: application/vnd.havoapi.v1+json)
+	v1 := router.Group("/api/v1", middlewares.APIVersion(1))
+	// Limit the rate of incoming API requests
+	// It only applies to the API so that frequent load balancer probes of the health endpoints are never throttled
+	// Routes listed in RATE_LIMIT_ROUTES get their own bucket, all others share the baseline one
+	v1.Use(middlewares.RateLimiter(h.Config.RateLimit, h.Config.RouteRateLimits, h.Config.LogRateLimitRejections))
+	// Optionally refuse API keys in the query string, before their usage is counted
+	if h.Config.RequireHeaderAPIKey {
+		v1.Use(middlewares.RequireHeaderAPIKey())
+	}
+	// Count the requests of every API key per day, for the usage endpoint
+	v1.Use(middlewares.UsageTracker(h.UsageRecorder))
+	// Reject request bodies that are not JSON with 415 before any handler tries to bind them
+	v1.Use(middlewares.RequireJSONBody())
+	// Optionally inject latency and errors into the weather endpoints to test client retries (never in production)
+	if h.Config.Chaos.Enabled {
+		log.Printf("WARN: chaos mode is enabled, weather requests will be delayed and fail at random")
+		v1.Use(middlewares.Chaos(h.Config.Chaos, "/api/v1/weather."))
+	}
 	{
 		// POST /v1/signup: Route for user signup
 		// This route accepts user details, validates them, and creates a new user.
@@ -39,23 +107,149 @@ func Route(h *ServeHandlerWrapper) *gin.Engine {
 
 		// POST /v1/logout: Route for user logout, requires JWT authorization middleware
 		// This route allows the user to log out and clear their session by removing the JWT token.
-		v1.POST("/logout", middlewares.UserAuthorizationJWT(), h.Logout)
+		v1.POST("/logout", userAuth, h.Logout)
 
 		// GET /v1/user/dashboard: Route to fetch user dashboard details, requires JWT authorization
 		// This route provides user-specific data (e.g., API key) for the logged-in user.
-		v1.GET("/user/dashboard", middlewares.UserAuthorizationJWT(), h.UserDashboard)
+		v1.GET("/user/dashboard", userAuth, h.UserDashboard)
+
+		// PUT /v1/user/password: Route to change the logged-in user's password, requires JWT authorization
+		// This route invalidates every session issued before the change and issues a fresh token.
+		v1.PUT("/user/password", userAuth, h.ChangePassword)
+
+		// POST, GET /v1/user/thresholds and PUT, DELETE /v1/user/thresholds/:id: Routes to manage weather thresholds, require JWT authorization
+		// Thresholds are checked against the cached weather data after every cache refresh.
+		v1.POST("/user/thresholds", userAuth, h.CreateThreshold)
+		v1.GET("/user/thresholds", userAuth, h.ListThresholds)
+		v1.PUT("/user/thresholds/:id", userAuth, h.UpdateThreshold)
+		v1.DELETE("/user/thresholds/:id", userAuth, h.DeleteThreshold)
+
+		// GET /v1/user/alerts: Route to list the alerts triggered by the user's thresholds, requires JWT authorization
+		v1.GET("/user/alerts", userAuth, h.ListAlerts)
+
+		// PUT, DELETE /v1/user/groups/:name and GET /v1/user/groups: Routes to manage named location groups, require JWT authorization
+		// A group's weather is fetched in one call through /v1/weather.group.
+		v1.PUT("/user/groups/:name", userAuth, h.SaveGroup)
+		v1.GET("/user/groups", userAuth, h.ListGroups)
+		v1.DELETE("/user/groups/:name", userAuth, h.DeleteGroup)
+
+		// POST /v1/user/apikeys: Route to create an additional API key limited to a set of scopes, requires JWT authorization
+		// Weather endpoints outside the key's scopes answer it with 403 Forbidden.
+		v1.POST("/user/apikeys", userAuth, h.CreateAPIKey)
+
+		// DELETE /v1/user/apikeys: Route to delete several of the user's API keys in one transaction, requires JWT authorization
+		// Keys are named by ID or prefix; keys of other users are reported as not found.
+		v1.DELETE("/user/apikeys", userAuth, h.DeleteAPIKeys)
+
+		// GET /v1/user/apikeys/:key/usage: Route to fetch the daily usage of one of the user's API keys, requires JWT authorization
+		// The optional 'from' and 'to' dates (YYYY-MM-DD) select the range, defaulting to the last 30 days.
+		v1.GET("/user/apikeys/:key/usage", userAuth, h.APIKeyUsage)
+
+		// PUT /v1/user/preferences: Route to store the user's preferred units and language, requires JWT authorization
+		// Weather requests sent with the user's JWT cookie use them when the units or lang parameter is left out.
+		v1.PUT("/user/preferences", userAuth, h.UpdatePreferences)
 
 		// GET /v1/weather: Route for fetching weather data based on query parameter
-		// This route returns weather data for a given location.
-		v1.GET("/weather.current", h.WeatherData)
+		// This route returns weather data for a given location, or for a list of locations given as repeated q parameters.
+		// Logged-in users may bypass the cache with refresh=true, within their own refresh rate limit.
+		v1.GET("/weather.current", middlewares.PerKeyRateLimiter(h.RateLimiters), preferences, middlewares.ForceRefreshLimiter(h.RefreshLimiters), h.WeatherData)
+
+		// HEAD /v1/weather: Route for cheap freshness and validity checks
+		// This route authorizes the API key and reports cache state through headers only, without a body.
+		v1.HEAD("/weather.current", middlewares.PerKeyRateLimiter(h.RateLimiters), h.WeatherDataHead)
 
 		// POST /v1/weather: Route for bulk weather data requests
 		// This route accepts a list of locations and fetches weather data for each location.
-		v1.POST("/weather.current", h.BulkWeatherData)
+		v1.POST("/weather.current", middlewares.PerKeyRateLimiter(h.RateLimiters), preferences, h.BulkWeatherData)
+
+		// GET /v1/weather.stream: Route for streaming weather updates over a WebSocket
+		// This route pushes the data of the requested locations every time it is refreshed in the cache.
+		// Connections count against STREAM_MAX_CONNECTIONS until the client disconnects.
+		v1.GET("/weather.stream", middlewares.PerKeyRateLimiter(h.RateLimiters), h.StreamLimiter.Limit(), h.WeatherStream)
+
+		// GET /v1/weather.astronomy: Route for fetching sunrise, sunset and moon data
+		// This route returns astronomy data for a given location and date (defaults to today).
+		v1.GET("/weather.astronomy", middlewares.PerKeyRateLimiter(h.RateLimiters), h.AstronomyData)
+
+		// GET /v1/weather.history: Route for fetching the recorded weather of past days
+		// This route returns one daily summary per date of the requested range, within the plan's lookback window.
+		v1.GET("/weather.history", middlewares.PerKeyRateLimiter(h.RateLimiters), h.HistoryData)
+
+		// GET /v1/weather.group: Route for fetching the weather of every location of a named group
+		// This route resolves the group of the API key's user and runs its locations through the bulk lookup.
+		v1.GET("/weather.group", middlewares.PerKeyRateLimiter(h.RateLimiters), preferences, h.GroupWeatherData)
+
+		// GET /v1/weather.compare: Route for comparing the weather of several locations
+		// This route fetches a comma-separated list of locations and sorts them by temperature or wind speed.
+		v1.GET("/weather.compare", middlewares.PerKeyRateLimiter(h.RateLimiters), preferences, h.CompareWeatherData)
+
+		// GET /v1/ratelimit: Route for checking the caller's remaining per-key allowance
+		// This route does not consume a token itself so clients can poll it before making calls.
+		v1.GET("/ratelimit", h.RateLimitStatus)
+	}
+
+	// Define the admin routes, all protected by the admin token
+	admin := v1.Group("/admin", middlewares.AdminAuthorization(h.Config.AdminToken))
+	{
+		// GET /v1/admin/users: Route for listing users with pagination
+		// This route returns non-sensitive user fields and the total number of users.
+		admin.GET("/users", h.ListUsers)
+
+		// POST /v1/admin/users/import: Route for creating users in bulk, each with a generated API key
+		// This route reports the outcome of every row without aborting the import on a bad one.
+		admin.POST("/users/import", h.ImportUsers)
+
+		// POST /v1/admin/users/:id/revoke-sessions: Route for logging a user out everywhere
+		// This route rejects every JWT issued to the user before the call.
+		admin.POST("/users/:id/revoke-sessions", h.RevokeUserSessions)
+
+		// GET /v1/admin/cache/stats: Route for listing the cached locations with their TTL and hit and miss counts
+		admin.GET("/cache/stats", h.CacheStats)
+
+		// POST /v1/admin/cache/refresh: Route for starting a refresh of the weather cache
+		// This route answers once the refresh is under way, without waiting for it to finish.
+		admin.POST("/cache/refresh", h.CacheRefresh)
+
+		// GET /v1/admin/cache/refresh/stream: Route for following the progress of the refresh in progress
+		// This route never starts a refresh; it streams Server-Sent Events with the progress, then a final "done" or "error" event.
+		// Connections count against STREAM_MAX_CONNECTIONS like the weather stream.
+		admin.GET("/cache/refresh/stream", h.StreamLimiter.Limit(), h.CacheRefreshStream)
+	}
+
+	// Refuse to start with a rate limit override for a route that does not exist, which is most likely a typo
+	if err := checkRouteRateLimits(router, h.Config.RouteRateLimits); err != nil {
+		return nil, err
 	}
 
+	// Redirect minor variations of a route's path instead of answering 404: an extra or missing trailing slash,
+	// and optionally a different letter case or an uncleaned path (e.g. "/API/v1//Weather.current").
+	// GET requests are redirected with 301 and other methods with 307, which keeps the method and body.
+	// Paths matching no route even after these fixes fall through to the JSON 404 below.
+	router.RedirectTrailingSlash = h.Config.RedirectTrailingSlash
+	router.RedirectFixedPath = h.Config.CaseInsensitiveRoutes
+
+	// Answer unknown paths and unsupported methods with JSON errors, like every other route
+	router.HandleMethodNotAllowed = true
+	router.NoRoute(handlers.NotFound)
+	router.NoMethod(handlers.MethodNotAllowed)
+
 	// Return the configured router to be used by the web server
 	// This allows the Gin engine to process requests according to the defined routes and handlers.
-	return router
+	return router, nil
+}
+
+// checkRouteRateLimits returns an error if a per-route rate limit names a path, or a method and path,
+// that no route is registered under.
+func checkRouteRateLimits(router *gin.Engine, routeLimits map[string]config.RateLimit) error {
+	registered := make(map[string]bool)
+	for _, route := range router.Routes() {
+		registered[route.Path] = true
+		registered[route.Method+" "+route.Path] = true
+	}
+	for route := range routeLimits {
+		if !registered[route] {
+			return fmt.Errorf("RATE_LIMIT_ROUTES: no route is registered under %s", route)
+		}
+	}
+	return nil
 }
- 
\ No newline at end of file
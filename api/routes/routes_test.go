@@ -0,0 +1,160 @@
+package routes
+
+import (
+	"havoAPI/api/config"
+	"havoAPI/api/handlers"
+	"havoAPI/api/middlewares"
+	"havoAPI/internal/clock"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func init() {
+	gin.SetMode(gin.TestMode)
+	// gin.Default logs every request, which would drown the test output
+	gin.DefaultWriter = io.Discard
+}
+
+// newTestRouter builds the application router around a ready health handler without dependency checks.
+// The other handlers are left nil, so tests may only reach routes that answer before calling their service.
+func newTestRouter(t *testing.T, cfg *config.Config) *gin.Engine {
+	t.Helper()
+	health := handlers.NewHealthHandler(nil)
+	health.SetReady(true)
+	router, err := Route(&ServeHandlerWrapper{
+		HealthHandler: health,
+		StreamLimiter: middlewares.NewStreamLimiter(1),
+		Config:        cfg,
+		Clock:         clock.Real{},
+	})
+	if err != nil {
+		t.Fatalf("Route() failed: %v", err)
+	}
+	return router
+}
+
+// request sends a request through the router and returns the response status.
+func request(router *gin.Engine, method, target, body string) int {
+	r := httptest.NewRequest(method, target, strings.NewReader(body))
+	if body != "" {
+		r.Header.Set("Content-Type", "application/json")
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, r)
+	return w.Code
+}
+
+func TestHealthProbesAreNeverRateLimited(t *testing.T) {
+	router := newTestRouter(t, &config.Config{RateLimit: config.RateLimit{Rate: 1, Burst: 1}})
+
+	for _, path := range []string{"/healthz", "/readyz", "/livez"} {
+		for i := range 200 {
+			if got := request(router, http.MethodGet, path, ""); got != http.StatusOK {
+				t.Fatalf("request %d to %s returned %d, want 200", i+1, path, got)
+			}
+		}
+	}
+
+	// The API itself is still limited: the burst of one is used up by the first request
+	// (an invalid signup, rejected before reaching the user service)
+	if got := request(router, http.MethodPost, "/api/v1/signup", "{}"); got == http.StatusTooManyRequests {
+		t.Fatalf("first API request was rate limited")
+	}
+	if got := request(router, http.MethodPost, "/api/v1/signup", "{}"); got != http.StatusTooManyRequests {
+		t.Errorf("second API request returned %d, want 429", got)
+	}
+}
+
+func TestRouteRejectsRateLimitsForUnknownRoutes(t *testing.T) {
+	tests := []struct {
+		route   string
+		wantErr bool
+	}{
+		{route: "/api/v1/signup"},
+		{route: "POST /api/v1/signup"},
+		{route: "GET /api/v1/signup", wantErr: true},
+		{route: "/api/v1/sign-up", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.route, func(t *testing.T) {
+			health := handlers.NewHealthHandler(nil)
+			_, err := Route(&ServeHandlerWrapper{
+				HealthHandler: health,
+				StreamLimiter: middlewares.NewStreamLimiter(1),
+				Config: &config.Config{
+					RateLimit:       config.RateLimit{Rate: 1, Burst: 1},
+					RouteRateLimits: map[string]config.RateLimit{tt.route: {Rate: 1, Burst: 1}},
+				},
+				Clock: clock.Real{},
+			})
+			if gotErr := err != nil; gotErr != tt.wantErr {
+				t.Errorf("Route() error = %v, want error: %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestMetricsRequireTheMetricsToken(t *testing.T) {
+	tests := []struct {
+		name          string
+		metricsToken  string
+		authorization string
+		want          int
+	}{
+		{name: "no token configured", authorization: "Bearer anything", want: http.StatusForbidden},
+		{name: "no token sent", metricsToken: "metrics-token", want: http.StatusUnauthorized},
+		{name: "wrong token", metricsToken: "metrics-token", authorization: "Bearer admin-token", want: http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := newTestRouter(t, &config.Config{RateLimit: config.RateLimit{Rate: 1, Burst: 1}, MetricsToken: tt.metricsToken})
+			r := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+			if tt.authorization != "" {
+				r.Header.Set("Authorization", tt.authorization)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, r)
+			if w.Code != tt.want {
+				t.Errorf("status = %d, want %d", w.Code, tt.want)
+			}
+		})
+	}
+}
+
+func TestRouteRedirects(t *testing.T) {
+	tests := []struct {
+		name            string
+		trailingSlash   bool
+		caseInsensitive bool
+		method          string
+		target          string
+		status          int
+		location        string
+	}{
+		{"trailing slash", true, false, http.MethodGet, "/livez/?verbose=1", http.StatusMovedPermanently, "/livez?verbose=1"},
+		{"trailing slash keeps the method", true, false, http.MethodPost, "/api/v1/signup/", http.StatusTemporaryRedirect, "/api/v1/signup"},
+		{"trailing slash disabled", false, false, http.MethodGet, "/livez/", http.StatusNotFound, ""},
+		{"letter case", false, true, http.MethodGet, "/LiveZ?verbose=1", http.StatusMovedPermanently, "/livez?verbose=1"},
+		{"uncleaned path", false, true, http.MethodPost, "/API/v1//Signup", http.StatusTemporaryRedirect, "/api/v1/signup"},
+		{"letter case by default", true, false, http.MethodGet, "/LIVEZ", http.StatusNotFound, ""},
+		{"no matching route", true, true, http.MethodGet, "/Nowhere/", http.StatusNotFound, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := newTestRouter(t, &config.Config{RedirectTrailingSlash: tt.trailingSlash, CaseInsensitiveRoutes: tt.caseInsensitive})
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(tt.method, tt.target, nil))
+			if w.Code != tt.status {
+				t.Fatalf("%s %s returned %d, want %d", tt.method, tt.target, w.Code, tt.status)
+			}
+			if got := w.Header().Get("Location"); got != tt.location {
+				t.Errorf("%s %s redirected to %q, want %q", tt.method, tt.target, got, tt.location)
+			}
+		})
+	}
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"havoAPI/api/config"
+	"havoAPI/internal/clock"
+	"havoAPI/internal/models"
+	"havoAPI/internal/services"
+	"os"
+	"time"
+)
+
+// checkTimeout bounds each dependency check of the check command.
+const checkTimeout = 10 * time.Second
+
+// runCheck verifies a deployment without serving traffic: it connects to MySQL and Redis and checks
+// that WeatherAPI accepts the configured key, printing one line per check.
+// The config has already been loaded and validated by the caller.
+// It returns the exit code of the command: 0 if every check passed, 1 otherwise.
+func runCheck(cfg *config.Config) int {
+	failed := false
+	report := func(name string, err error) {
+		if err != nil {
+			failed = true
+			fmt.Fprintf(os.Stdout, "%-10s FAIL  %v\n", name, err)
+			return
+		}
+		fmt.Fprintf(os.Stdout, "%-10s OK\n", name)
+	}
+
+	// The config was loaded before the checks started, so it is valid
+	report("config", nil)
+
+	// Connect to MySQL and ping it, like the server does on startup
+	db, err := models.OpenDB(cfg.DSN(), cfg.SlowQueryThreshold)
+	report("mysql", err)
+	if err == nil {
+		defer db.Close()
+	}
+
+	// Ping Redis through the same client the services use, unless it is left out with the cache disabled
+	redisClient := services.NewRedisClient(cfg)
+	defer redisClient.Close()
+	if cfg.RedisAddr != "" {
+		ctx, cancel := context.WithTimeout(context.Background(), checkTimeout)
+		report("redis", redisClient.Ping(ctx).Err())
+		cancel()
+	}
+
+	// Make one lightweight call to WeatherAPI to verify the API key
+	// The service's database is not used by the call, so it runs even if MySQL is unreachable
+	weatherAPIService := services.NewWeatherAPIService(nil, redisClient, cfg, clock.Real{})
+	ctx, cancel := context.WithTimeout(context.Background(), checkTimeout)
+	report("weatherapi", weatherAPIService.CheckUpstream(ctx))
+	cancel()
+
+	if failed {
+		fmt.Fprintln(os.Stdout, "check failed")
+		return 1
+	}
+	fmt.Fprintln(os.Stdout, "all checks passed")
+	return 0
+}
@@ -1,14 +1,24 @@
 package main
 
 import (
-	"fmt"
+	"context"
+	"errors"
 	"havoAPI/api/config"
 	"havoAPI/api/handlers"
+	"havoAPI/api/middlewares"
 	"havoAPI/api/routes"
+	"havoAPI/internal/clock"
 	"havoAPI/internal/models"
 	"havoAPI/internal/services"
+	"havoAPI/internal/tracing"
 	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
+	"github.com/gin-gonic/gin"
 	"github.com/joho/godotenv"
 	"github.com/robfig/cron/v3"
 )
@@ -20,57 +30,115 @@ func main() {
 		log.Fatalf("failed to load .env file in main.go: %v", err)
 	}
 
-	// Load DB connection parameters from environment variables
-	// If any variable is missing, log the error and terminate the program
-	dbUserName, err := config.LoadEnvironmentVariable("DB_USER_NAME")
+	// Load and validate the application config once at startup
+	// If any required setting is missing or malformed, log the error and terminate the program
+	cfg, err := config.Load()
 	if err != nil {
 		log.Fatalf("%v", err)
 	}
 
-	dbUserPassword, err := config.LoadEnvironmentVariable("DB_USER_PASSWORD")
-	if err != nil {
-		log.Fatalf("%v", err)
+	// Log the effective config once, with all secrets redacted, to ease diagnosing misconfiguration
+	log.Printf("Starting havoAPI with config:\n%s", cfg.Summary())
+
+	// "havoAPI check" verifies the config and the dependencies, then exits without serving traffic
+	if len(os.Args) > 1 && os.Args[1] == "check" {
+		os.Exit(runCheck(cfg))
 	}
 
-	dbName, err := config.LoadEnvironmentVariable("DB_NAME")
+	// Export request traces to the configured OpenTelemetry collector, if any
+	shutdownTracing, err := tracing.Setup(context.Background(), cfg.OTLPEndpoint)
 	if err != nil {
-		log.Fatalf("%v", err)
+		log.Fatal(err)
 	}
 
 	// Construct the Data Source Name (DSN) for the database connection
 	// The DSN will be used to connect to the MySQL database
-	dsn := fmt.Sprintf("%v:%v@/%v?parseTime=true", dbUserName, dbUserPassword, dbName)
+	dsn := cfg.DSN()
 
 	// Open a connection to the database
 	// If the connection fails, log the error and terminate the program
-	db, err := models.OpenDB(dsn)
+	db, err := models.OpenDB(dsn, cfg.SlowQueryThreshold)
 	if err != nil {
 		log.Fatal(err)
 	}
 	defer db.Close() // Ensure that the DB connection is closed when the program exits
 
-	// Initialize the UserService with the database connection
-	usersService := services.NewUsersService(db)
-	// Initialize the UserHandler with the UserService
-	usersHandler := handlers.NewUsersHandler(usersService)
+	// Make sure the indexes the hot queries rely on exist, creating any that are missing
+	// A failure is logged but not fatal, since the service still works (slower) without them
+	if err := db.EnsureIndexes(); err != nil {
+		log.Printf("index check failed: %v", err)
+	}
+
+	// Use the system time everywhere; tests can inject a fake clock instead
+	clk := clock.Real{}
 
-	// Initialize the WeatherAPIService with the database connection
-	weatherAPIService := services.NewWeatherAPIService(db)
+	// Initialize the Redis client shared by the services
+	redisClient := services.NewRedisClient(cfg)
+
+	// Select the algorithm new passwords are hashed with; existing hashes of any algorithm keep verifying
+	passwordHasher, err := services.NewPasswordHasher(cfg.PasswordHasher, cfg.BcryptCost)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// Initialize the WeatherAPIService with the database connection and the Redis client
+	weatherAPIService := services.NewWeatherAPIService(db, redisClient, cfg, clk)
 	// Initialize the WeatherHandler with the WeatherAPIService
 	weatherapiHandler := handlers.NewWeatherHandler(weatherAPIService)
 
-	// Create the ServeHandlerWrapper to group UserHandler and WeatherHandler
-	// This will be used to route requests to the appropriate handler
-	serveHandlerWrapper := &routes.ServeHandlerWrapper{
-		UserHandler:    usersHandler,
-		WeatherHandler: weatherapiHandler,
+	// Initialize the UserService with the database connection, the Redis client, the password hasher,
+	// the WeatherAPIService, whose cached API key validations must be dropped when keys are deleted,
+	// and the maximum number of keys per user
+	usersService := services.NewUsersService(db, redisClient, passwordHasher, weatherAPIService, clk, cfg.MaxAPIKeysPerUser)
+	// Initialize the UserHandler with the UserService
+	usersHandler := handlers.NewUsersHandler(usersService, cfg, clk)
+
+	// Initialize the AlertsService with the database connection and the Redis client holding the cached weather data
+	alertsService := services.NewAlertsService(db, redisClient)
+	// Initialize the AlertsHandler with the AlertsService
+	alertsHandler := handlers.NewAlertsHandler(alertsService)
+
+	// Initialize the GroupsService with the database connection, the maximum size of a group and the maximum number of groups per user
+	groupsService := services.NewGroupsService(db, cfg.MaxGroupLocations, cfg.MaxGroupsPerUser)
+	// Initialize the GroupsHandler with the GroupsService and the WeatherAPIService fetching the groups' weather
+	groupsHandler := handlers.NewGroupsHandler(groupsService, weatherAPIService)
+
+	// Initialize the UsageService with the database connection, counting and reporting the daily requests of API keys
+	usageService := services.NewUsageService(db, clk)
+	// Initialize the UsageHandler with the UsageService
+	usageHandler := handlers.NewUsageHandler(usageService)
+
+	// Initialize the per-key rate limiter registry shared by the middleware and the RateLimitHandler
+	rateLimiters := middlewares.NewRateLimiterRegistry(cfg.RateLimitPerKey, cfg.RateLimitPerKeyBurst, cfg.LogRateLimitRejections)
+	// Initialize the per-user registry limiting forced cache refreshes, separate from the per-key limits
+	refreshLimiters := middlewares.NewRateLimiterRegistry(cfg.RefreshRateLimitPerUser, cfg.RefreshRateLimitPerUserBurst, cfg.LogRateLimitRejections)
+
+	// Initialize the RateLimitHandler with the WeatherAPIService and the limiter registry
+	rateLimitHandler := handlers.NewRateLimitHandler(weatherAPIService, rateLimiters)
+
+	// Initialize the HealthHandler with the dependency checks used by the readiness probe
+	// Redis is optional while the weather cache is disabled, so it is only checked when configured
+	healthChecks := map[string]handlers.HealthCheck{
+		"mysql": db.Ping,
 	}
+	if cfg.RedisAddr != "" {
+		healthChecks["redis"] = weatherAPIService.Ping
+	}
+	healthHandler := handlers.NewHealthHandler(healthChecks)
 
-	// Initialize a new cron job to periodically update weather data in the Redis cache every 30 minutes
-	cronJob := cron.New()
-	_, err = cronJob.AddFunc("@every 30m", func() {
+	// refreshCache updates the weather data in the Redis cache and checks the users' thresholds against it
+	// The optional progress callback is called after every location, for admins following the refresh
+	refreshCache := func(progress func(services.RefreshProgress)) error {
 		// Update the weather data in the cache
-		err := weatherAPIService.UpdateWeatherDataInTheRedisCache()
+		err := weatherAPIService.UpdateWeatherDataInTheRedisCache(progress)
+		if errors.Is(err, services.ErrCacheRefreshInProgress) {
+			// The previous refresh is still running and will check the thresholds itself
+			return err
+		}
+		if errors.Is(err, services.ErrCacheDisabled) {
+			// Without a cache there is no data to check the thresholds against
+			return err
+		}
 		if err != nil {
 			// Log the error if the update fails
 			log.Printf("Error updating weather data in cache: %v", err)
@@ -78,26 +146,126 @@ func main() {
 			// Log a success message if the update is successful
 			log.Println("Weather data updated successfully!")
 		}
-	})
-	if err != nil {
-		log.Fatal(err) // If adding the cron job fails, log the error and terminate
+
+		// Compare the freshly cached data against the users' thresholds
+		if err := alertsService.CheckThresholds(); err != nil {
+			log.Printf("Error checking weather thresholds: %v", err)
+		}
+		return err
+	}
+
+	// Initialize the CacheHandler with the refresh, so admins can trigger it and follow its progress, and the cache stats
+	cacheHandler := handlers.NewCacheHandler(refreshCache, weatherAPIService.WatchCacheRefresh, weatherAPIService.CacheStats)
+
+	// Initialize the cap on concurrent streaming connections shared by the WebSocket and Server-Sent Events routes
+	streamLimiter := middlewares.NewStreamLimiter(cfg.MaxStreamConnections)
+
+	// Initialize the MetricsHandler with the weather cache measurement and circuit breaker state of the WeatherAPIService and the stream count
+	metricsHandler := handlers.NewMetricsHandler(weatherAPIService.CacheFootprint, streamLimiter.Active, weatherAPIService.UpstreamCircuit)
+
+	// Create the ServeHandlerWrapper to group UserHandler, WeatherHandler, RateLimitHandler, HealthHandler, AlertsHandler, GroupsHandler, UsageHandler, CacheHandler and MetricsHandler
+	// This will be used to route requests to the appropriate handler
+	serveHandlerWrapper := &routes.ServeHandlerWrapper{
+		UserHandler:      usersHandler,
+		WeatherHandler:   weatherapiHandler,
+		RateLimitHandler: rateLimitHandler,
+		HealthHandler:    healthHandler,
+		AlertsHandler:    alertsHandler,
+		GroupsHandler:    groupsHandler,
+		UsageHandler:     usageHandler,
+		CacheHandler:     cacheHandler,
+		MetricsHandler:   metricsHandler,
+		TokenBlacklist:   usersService,
+		UsageRecorder:    usageService,
+		Preferences:      usersService,
+		RateLimiters:     rateLimiters,
+		RefreshLimiters:  refreshLimiters,
+		StreamLimiter:    streamLimiter,
+		Config:           cfg,
+		Clock:            clk,
+	}
+
+	// Initialize a new cron job to periodically update weather data in the Redis cache on the configured schedule
+	// Without the cache, there is nothing to refresh
+	cronJob := cron.New()
+	if cfg.CacheEnabled {
+		_, err = cronJob.AddFunc(cfg.CacheRefreshSpec, func() { refreshCache(nil) })
+		if err != nil {
+			log.Fatal(err) // If adding the cron job fails, log the error and terminate
+		}
+	} else {
+		log.Println("WARN: the weather cache is disabled, every weather request is sent to WeatherAPI")
 	}
 
 	// Start the cron job in a separate goroutine to run it periodically
 	go cronJob.Start()
 
+	// Optionally fill the cache right away, so early requests don't all miss until the first cron tick
+	// It runs in the background: readiness only waits for the basic startup below, not for the warm-up
+	if cfg.WarmCacheOnStart && cfg.CacheEnabled {
+		go func() {
+			log.Println("Warming the weather cache on startup")
+			start := time.Now()
+			refreshCache(nil)
+			log.Printf("Weather cache warm-up finished in %v", time.Since(start).Round(time.Second))
+		}()
+	}
+
+	// Select the Gin mode before building the router; Gin reads GIN_MODE before the .env file is loaded
+	gin.SetMode(cfg.GinMode)
+
 	// Initialize the Gin router with the routes defined in the ServeHandlerWrapper
-	router := routes.Route(serveHandlerWrapper)
+	router, err := routes.Route(serveHandlerWrapper)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// Create an explicit HTTP server so it can be shut down gracefully
+	// The timeouts protect against clients holding connections open indefinitely (e.g. slow-loris)
+	server := &http.Server{
+		Addr:              cfg.ServerAddr,
+		Handler:           router,
+		ReadHeaderTimeout: cfg.ReadHeaderTimeout,
+		ReadTimeout:       cfg.ReadTimeout,
+		WriteTimeout:      cfg.WriteTimeout,
+		IdleTimeout:       cfg.IdleTimeout,
+	}
 
 	// Start the HTTP server in a separate goroutine to handle incoming requests
 	go func() {
-		if err := router.Run(); err != nil {
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
 			// If there is an error starting the server, log the error and terminate
-			log.Fatal("error running the server")
+			log.Fatalf("error running the server: %v", err)
 		}
 	}()
 
-	// Block the main goroutine indefinitely so that the application keeps running
-	select {}
+	// Startup is complete: let the readiness probe report ready
+	healthHandler.SetReady(true)
+
+	// Block the main goroutine until a termination signal is received
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+	<-quit
+
+	// Fail the readiness probe first so load balancers stop routing new traffic to this instance
+	log.Printf("Shutting down: draining traffic for %v", cfg.ShutdownDrainPeriod)
+	healthHandler.SetReady(false)
+	time.Sleep(cfg.ShutdownDrainPeriod)
+
+	// Stop the cron scheduler so no new cache update starts
+	cronJob.Stop()
+
+	// Stop accepting connections and wait for in-flight requests to complete
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := server.Shutdown(ctx); err != nil {
+		log.Printf("error shutting down the server: %v", err)
+	}
+
+	// Flush the spans of the last requests to the collector
+	if err := shutdownTracing(ctx); err != nil {
+		log.Printf("error flushing traces: %v", err)
+	}
+
+	log.Println("Server stopped")
 }
- 
\ No newline at end of file
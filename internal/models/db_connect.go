@@ -1,9 +1,11 @@
 package models
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"log"
+	"time"
 
 	_ "github.com/go-sql-driver/mysql"
 )
@@ -11,11 +13,14 @@ import (
 // MySQL represents a connection to a MySQL database.
 type MySQL struct {
 	DB *sql.DB // The underlying database connection.
+
+	slowQueryThreshold time.Duration // Statements taking longer than this are logged; zero disables the logging.
 }
 
 // OpenDB initializes and opens a connection to the MySQL database using the provided DSN (Data Source Name).
+// Statements taking longer than slowQueryThreshold are logged as slow queries.
 // It returns a pointer to a MySQL instance or an error if the connection fails.
-func OpenDB(dsn string) (*MySQL, error) {
+func OpenDB(dsn string, slowQueryThreshold time.Duration) (*MySQL, error) {
 	// Attempt to open a connection to the database
 	db, err := sql.Open("mysql", dsn)
 	if err != nil {
@@ -28,7 +33,7 @@ func OpenDB(dsn string) (*MySQL, error) {
 	}
 
 	// Return a MySQL instance wrapping the connection
-	return &MySQL{DB: db}, nil
+	return &MySQL{DB: db, slowQueryThreshold: slowQueryThreshold}, nil
 }
 
 // Close attempts to close the MySQL database connection.
@@ -40,3 +45,9 @@ func (mysql *MySQL) Close() {
 		log.Fatalf("mysql close failure: %v", err) // Fatal log if closing fails
 	}
 }
+
+// Ping verifies that the MySQL database is still reachable.
+// It is used by the readiness probe.
+func (mysql *MySQL) Ping(ctx context.Context) error {
+	return mysql.DB.PingContext(ctx)
+}
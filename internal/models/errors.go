@@ -2,17 +2,46 @@ package models
 
 import "errors"
 
+// The errors below that have a counterpart in the services package are shared with it:
+// the services package declares its sentinel as the very same value, so errors.Is matches
+// across the handler, service and model layers. Their messages therefore carry no layer prefix.
+
+// ErrSchemaMissing is returned when a statement refers to a table that doesn't exist (MySQL error 1146),
+// which means the database was created without applying the migrations.
+var ErrSchemaMissing = errors.New("database schema is missing, apply the migrations in ./migrations")
+
 // ErrUserNotFound is returned when a user cannot be found in the database.
 // This error is useful when attempting to retrieve a user by their ID or username,
 // but the user does not exist in the database.
-var ErrUserNotFound = errors.New("models: User not found")
+var ErrUserNotFound = errors.New("user not found")
 
 // ErrDuplicatedUsername is returned when a username already exists in the database.
 // This error occurs when a new user attempts to register with a username
 // that is already taken by another user in the system.
-var ErrDuplicatedUsername = errors.New("models: Username already exists")
+var ErrDuplicatedUsername = errors.New("username already exists")
+
+// ErrDuplicatedEmail is returned when an inserted user collides with the unique index of another user's email.
+// It is told apart from ErrDuplicatedUsername by the name of the index in MySQL's duplicate entry error.
+var ErrDuplicatedEmail = errors.New("email already exists")
 
 // ErrAPIKeyNotFound is returned when an API key cannot be found.
 // This error occurs when an API request is made with an invalid or missing API key,
 // and the application cannot locate a valid API key for the user.
-var ErrAPIKeyNotFound = errors.New("models: API Key not found")
+var ErrAPIKeyNotFound = errors.New("API key not found")
+
+// ErrDuplicatedAPIKey is returned when an inserted API key collides with an existing one.
+// This can only happen on a UUID collision, so callers should retry with a freshly generated key.
+var ErrDuplicatedAPIKey = errors.New("API key already exists")
+
+// ErrUserAPIKeyExists is returned when a user already has an API key and the
+// per-user uniqueness constraint prevents inserting a second one.
+var ErrUserAPIKeyExists = errors.New("user already has an API key")
+
+// ErrAmbiguousAPIKeyPrefix is returned when an API key prefix matches more than one of the user's keys.
+var ErrAmbiguousAPIKeyPrefix = errors.New("API key prefix matches several keys")
+
+// ErrThresholdNotFound is returned when a threshold does not exist or belongs to another user.
+var ErrThresholdNotFound = errors.New("threshold not found")
+
+// ErrGroupNotFound is returned when a location group does not exist for the user.
+var ErrGroupNotFound = errors.New("location group not found")
@@ -0,0 +1,29 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/go-sql-driver/mysql"
+)
+
+// newMockDB returns a MySQL model backed by sqlmock, checking on cleanup that every expected statement ran.
+func newMockDB(t *testing.T) (*MySQL, sqlmock.Sqlmock) {
+	t.Helper()
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	t.Cleanup(func() {
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("unmet database expectations: %v", err)
+		}
+		db.Close()
+	})
+	return &MySQL{DB: db}, mock
+}
+
+// duplicateEntry returns the error MySQL 8 reports for a duplicate value of the given unique index.
+func duplicateEntry(table, index string) *mysql.MySQLError {
+	return &mysql.MySQLError{Number: 1062, Message: "Duplicate entry 'x' for key '" + table + "." + index + "'"}
+}
@@ -0,0 +1,132 @@
+package models
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// DBContractGroups defines the contract (interface) for database operations
+// related to the named location groups of users.
+type DBContractGroups interface {
+	SaveGroup(userID int, name string, locations []string) error     // Create a group or replace the locations of an existing one
+	ListGroups(userID int) ([]LocationGroup, error)                  // Retrieve all groups of a user
+	DeleteGroup(userID int, name string) error                       // Delete a group owned by the user
+	GetGroupLocationsByAPIKey(apiKey, name string) ([]string, error) // Retrieve the locations of a group owned by the API key's user
+}
+
+// LocationGroup is a single row of the `location_groups` table.
+type LocationGroup struct {
+	ID        int       // ID is the primary key of the group.
+	UserID    int       // UserID is the owner of the group.
+	Name      string    // Name identifies the group among the user's groups.
+	Locations []string  // Locations lists the location queries of the group, in order.
+	CreatedAt time.Time // CreatedAt is the time the group was first saved.
+}
+
+// SaveGroup stores the group under its name for the user, replacing the locations of an existing group with the same name.
+func (msql *MySQL) SaveGroup(userID int, name string, locations []string) error {
+	// The locations are stored as a JSON array
+	encoded, err := json.Marshal(locations)
+	if err != nil {
+		return fmt.Errorf("failed to marshal group locations: %w", err)
+	}
+
+	// SQL query to insert the group, or update it if the user already has a group with that name
+	stmt := `INSERT INTO location_groups (user_id, name, locations) VALUES (?, ?, ?)
+	ON DUPLICATE KEY UPDATE locations = VALUES(locations)`
+
+	// Execute the query with the provided values
+	if _, err := msql.exec("SaveGroup", stmt, userID, name, encoded); err != nil {
+		return fmt.Errorf("failed to save location group: %w", err)
+	}
+
+	return nil
+}
+
+// ListGroups retrieves all location groups of the user, ordered by name.
+func (msql *MySQL) ListGroups(userID int) ([]LocationGroup, error) {
+	// SQL query to retrieve the groups of the user
+	stmt := `SELECT id, user_id, name, locations, created_at FROM location_groups WHERE user_id = ? ORDER BY name`
+
+	// Execute the query with the user ID
+	rows, err := msql.query("ListGroups", stmt, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list location groups: %w", err)
+	}
+	defer rows.Close()
+
+	// Scan each row into a LocationGroup
+	groups := []LocationGroup{}
+	for rows.Next() {
+		var g LocationGroup
+		var encoded []byte
+		if err := rows.Scan(&g.ID, &g.UserID, &g.Name, &encoded, &g.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan location group row: %w", err)
+		}
+		if err := json.Unmarshal(encoded, &g.Locations); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal locations of group %d: %w", g.ID, err)
+		}
+		groups = append(groups, g)
+	}
+
+	// Check for errors that occurred during iteration
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate over location group rows: %w", err)
+	}
+
+	return groups, nil
+}
+
+// DeleteGroup deletes a location group owned by the user.
+// It returns ErrGroupNotFound if the user has no group with that name.
+func (msql *MySQL) DeleteGroup(userID int, name string) error {
+	// SQL query to delete the group, scoped to its owner
+	stmt := `DELETE FROM location_groups WHERE user_id = ? AND name = ?`
+
+	// Execute the query with the provided values
+	result, err := msql.exec("DeleteGroup", stmt, userID, name)
+	if err != nil {
+		return fmt.Errorf("failed to delete location group: %w", err)
+	}
+
+	// If no row was deleted, the user has no such group
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to retrieve affected rows: %w", err)
+	}
+	if affected == 0 {
+		return ErrGroupNotFound
+	}
+
+	return nil
+}
+
+// GetGroupLocationsByAPIKey retrieves the locations of the named group owned by the user of the API key.
+// It returns ErrGroupNotFound if the key's user has no group with that name.
+func (msql *MySQL) GetGroupLocationsByAPIKey(apiKey, name string) ([]string, error) {
+	// SQL query resolving the API key to its user and the group name to its locations in one step
+	stmt := `SELECT g.locations FROM location_groups g
+	JOIN api_keys k ON k.user_id = g.user_id
+	WHERE k.api_key = ? AND g.name = ?`
+
+	// Execute the query and scan the encoded locations
+	var encoded []byte
+	err := msql.queryRow("GetGroupLocationsByAPIKey", stmt, apiKey, name).Scan(&encoded)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrGroupNotFound
+		}
+		return nil, fmt.Errorf("failed to get location group: %w", err)
+	}
+
+	// Decode the JSON array of locations
+	var locations []string
+	if err := json.Unmarshal(encoded, &locations); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal group locations: %w", err)
+	}
+
+	return locations, nil
+}
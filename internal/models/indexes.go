@@ -0,0 +1,56 @@
+package models
+
+import (
+	"fmt"
+	"log"
+)
+
+// requiredIndex describes an index the queries of the models rely on to avoid full-table scans.
+type requiredIndex struct {
+	table  string // table is the indexed table.
+	column string // column is the column that must lead an index.
+	name   string // name is the name of the index created if none exists.
+	unique bool   // unique creates a UNIQUE index, for columns whose uniqueness the models depend on.
+}
+
+// requiredIndexes lists the indexes created by the migrations that the hot queries depend on:
+// CheckUserAPIKey filters on api_keys.api_key, RetriveUserAPIKey on api_keys.user_id,
+// and RetrieveUserCredentials on users.username.
+var requiredIndexes = []requiredIndex{
+	{table: "api_keys", column: "api_key", name: "idx_api_key", unique: true},
+	{table: "api_keys", column: "user_id", name: "idx_user_id"},
+	{table: "users", column: "username", name: "idx_username"},
+}
+
+// EnsureIndexes checks that every required index exists and creates the missing ones,
+// logging what it found and created. It protects databases that were set up without running every migration.
+func (msql *MySQL) EnsureIndexes() error {
+	// SQL query to check whether any index of the table starts with the column
+	stmt := `SELECT COUNT(*) FROM information_schema.statistics
+	WHERE table_schema = DATABASE() AND table_name = ? AND column_name = ? AND seq_in_index = 1`
+
+	for _, index := range requiredIndexes {
+		var count int
+		if err := msql.queryRow("EnsureIndexes", stmt, index.table, index.column).Scan(&count); err != nil {
+			return fmt.Errorf("failed to check index on %s.%s: %w", index.table, index.column, err)
+		}
+
+		if count > 0 {
+			log.Printf("index check: %s.%s is indexed", index.table, index.column)
+			continue
+		}
+
+		// Create the missing index; table, column and index names come from the fixed list above
+		kind := "INDEX"
+		if index.unique {
+			kind = "UNIQUE INDEX"
+		}
+		create := fmt.Sprintf("CREATE %s %s ON %s (%s)", kind, index.name, index.table, index.column)
+		if _, err := msql.exec("EnsureIndexes", create); err != nil {
+			return fmt.Errorf("failed to create index %s on %s.%s: %w", index.name, index.table, index.column, err)
+		}
+		log.Printf("index check: created missing index %s on %s.%s", index.name, index.table, index.column)
+	}
+
+	return nil
+}
@@ -0,0 +1,55 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// The helpers below wrap the database calls of the models with timing, so that slow statements
+// (e.g. a full-table scan caused by a missing index) are logged before they turn into an outage.
+// Every statement is identified by the name of the model method issuing it.
+
+// exec runs a statement that returns no rows and logs it if it was slow.
+func (msql *MySQL) exec(name, stmt string, args ...any) (sql.Result, error) {
+	defer msql.logIfSlow(name, time.Now())
+	res, err := msql.DB.Exec(stmt, args...)
+	return res, missingTableError(err)
+}
+
+// query runs a statement that returns rows and logs it if it was slow.
+// Only the execution is timed; reading the rows afterwards is not.
+func (msql *MySQL) query(name, stmt string, args ...any) (*sql.Rows, error) {
+	defer msql.logIfSlow(name, time.Now())
+	rows, err := msql.DB.Query(stmt, args...)
+	return rows, missingTableError(err)
+}
+
+// queryRow runs a statement that returns at most one row and logs it if it was slow.
+// Its errors are only reported by Scan, so callers pass them through missingTableError themselves.
+func (msql *MySQL) queryRow(name, stmt string, args ...any) *sql.Row {
+	defer msql.logIfSlow(name, time.Now())
+	return msql.DB.QueryRow(stmt, args...)
+}
+
+// logIfSlow logs a warning if the statement started at start took longer than the slow query threshold.
+func (msql *MySQL) logIfSlow(name string, start time.Time) {
+	if elapsed := time.Since(start); msql.slowQueryThreshold > 0 && elapsed > msql.slowQueryThreshold {
+		log.Printf("WARN: slow query %s took %v (threshold %v)", name, elapsed.Round(time.Millisecond), msql.slowQueryThreshold)
+	}
+}
+
+// missingTableError marks a "table doesn't exist" error (MySQL error 1146) as ErrSchemaMissing, so that a
+// database set up without the migrations is reported as such instead of as a vague internal error.
+// The MySQL error stays in the chain; any other error is returned unchanged.
+func missingTableError(err error) error {
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) && mysqlErr.Number == 1146 { // 1146 is MySQL's error code for a missing table
+		return fmt.Errorf("%w: %w", ErrSchemaMissing, err)
+	}
+	return err
+}
@@ -0,0 +1,63 @@
+package models
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/go-sql-driver/mysql"
+)
+
+// noSuchTable returns the error MySQL reports for a statement on a table that doesn't exist.
+func noSuchTable(table string) *mysql.MySQLError {
+	return &mysql.MySQLError{Number: 1146, Message: "Table 'havo." + table + "' doesn't exist"}
+}
+
+func TestMissingTableError(t *testing.T) {
+	tests := []struct {
+		name          string
+		err           error
+		schemaMissing bool
+	}{
+		{"missing table", noSuchTable("api_keys"), true},
+		{"other MySQL error", duplicateEntry("api_keys", "idx_api_key"), false},
+		{"other error", errors.New("connection refused"), false},
+		{"no error", nil, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := missingTableError(tt.err)
+			if got := errors.Is(err, ErrSchemaMissing); got != tt.schemaMissing {
+				t.Errorf("missingTableError(%v) is ErrSchemaMissing = %v, want %v", tt.err, got, tt.schemaMissing)
+			}
+			// The original error stays in the chain
+			if tt.err != nil && !errors.Is(err, tt.err) {
+				t.Errorf("missingTableError(%v) = %v, which no longer wraps the original error", tt.err, err)
+			}
+		})
+	}
+}
+
+func TestStatementsOnMissingTablesReportTheMissingSchema(t *testing.T) {
+	t.Run("exec", func(t *testing.T) {
+		db, mock := newMockDB(t)
+		mock.ExpectExec("INSERT INTO api_keys").
+			WithArgs(7, "key", sqlmock.AnyArg()).
+			WillReturnError(noSuchTable("api_keys"))
+
+		if err := db.InsertUserAPIKey(7, "key", nil); !errors.Is(err, ErrSchemaMissing) {
+			t.Errorf("InsertUserAPIKey() = %v, want ErrSchemaMissing", err)
+		}
+	})
+
+	t.Run("single row", func(t *testing.T) {
+		db, mock := newMockDB(t)
+		mock.ExpectQuery("SELECT scopes FROM api_keys").
+			WithArgs("key").
+			WillReturnError(noSuchTable("api_keys"))
+
+		if _, err := db.CheckUserAPIKey("key"); !errors.Is(err, ErrSchemaMissing) {
+			t.Errorf("CheckUserAPIKey() = %v, want ErrSchemaMissing", err)
+		}
+	})
+}
@@ -0,0 +1,213 @@
+package models
+
+import (
+	"fmt"
+	"time"
+)
+
+// DBContractThresholds defines the contract (interface) for database operations
+// related to user-defined weather thresholds and the alerts they trigger.
+type DBContractThresholds interface {
+	InsertThreshold(userID int, location, metric, operator string, value float64) (int, error)                    // Insert a new threshold and return its ID
+	ListThresholds(userID int) ([]Threshold, error)                                                               // Retrieve all thresholds of a user
+	ListAllThresholds() ([]Threshold, error)                                                                      // Retrieve the thresholds of every user (used by the checker)
+	UpdateThreshold(userID, thresholdID int, location, metric, operator string, value float64) (Threshold, error) // Replace a threshold owned by the user
+	DeleteThreshold(userID, thresholdID int) error                                                                // Delete a threshold owned by the user
+	SetThresholdTriggered(thresholdID int, triggered bool) error                                                  // Record whether a threshold is currently crossed
+	InsertAlert(threshold Threshold, observedValue float64) error                                                 // Record that a threshold was triggered
+	ListAlerts(userID, limit, offset int) ([]Alert, error)                                                        // Retrieve a page of the most recent alerts of a user
+}
+
+// Threshold is a single row of the `thresholds` table.
+type Threshold struct {
+	ID        int       // ID is the primary key of the threshold.
+	UserID    int       // UserID is the owner of the threshold.
+	Location  string    // Location is the location query the threshold watches.
+	Metric    string    // Metric is the weather field compared (e.g. "temp_c").
+	Operator  string    // Operator is the comparison, either ">" or "<".
+	Value     float64   // Value is the threshold the metric is compared against.
+	CreatedAt time.Time // CreatedAt is the time the threshold was registered.
+	Triggered bool      // Triggered is whether the threshold was crossed when it was last checked.
+}
+
+// Alert is a single row of the `alerts` table.
+type Alert struct {
+	ID             int       // ID is the primary key of the alert.
+	ThresholdID    int       // ThresholdID is the threshold that triggered the alert.
+	Location       string    // Location is the location query of the threshold.
+	Metric         string    // Metric is the weather field that crossed the threshold.
+	Operator       string    // Operator is the comparison of the threshold.
+	ThresholdValue float64   // ThresholdValue is the value of the threshold when it triggered.
+	ObservedValue  float64   // ObservedValue is the value of the metric that triggered the alert.
+	TriggeredAt    time.Time // TriggeredAt is the time the alert was recorded.
+}
+
+// InsertThreshold inserts a new threshold for the user and returns its ID.
+func (msql *MySQL) InsertThreshold(userID int, location, metric, operator string, value float64) (int, error) {
+	// SQL query to insert a new threshold
+	stmt := `INSERT INTO thresholds (user_id, location, metric, operator, value) VALUES (?, ?, ?, ?, ?)`
+
+	// Execute the query with the provided values
+	result, err := msql.exec("InsertThreshold", stmt, userID, location, metric, operator, value)
+	if err != nil {
+		return 0, fmt.Errorf("failed to insert threshold: %w", err)
+	}
+
+	// Retrieve the ID of the newly inserted threshold
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, fmt.Errorf("failed to retrieve inserted threshold ID: %w", err)
+	}
+
+	return int(id), nil
+}
+
+// ListThresholds retrieves all thresholds registered by the user, oldest first.
+func (msql *MySQL) ListThresholds(userID int) ([]Threshold, error) {
+	stmt := `SELECT id, user_id, location, metric, operator, value, created_at, triggered FROM thresholds WHERE user_id = ? ORDER BY id`
+	return msql.queryThresholds("ListThresholds", stmt, userID)
+}
+
+// ListAllThresholds retrieves the thresholds of every user, used by the periodic threshold checker.
+func (msql *MySQL) ListAllThresholds() ([]Threshold, error) {
+	stmt := `SELECT id, user_id, location, metric, operator, value, created_at, triggered FROM thresholds ORDER BY id`
+	return msql.queryThresholds("ListAllThresholds", stmt)
+}
+
+// queryThresholds executes a query selecting full threshold rows and scans the result.
+// The name identifies the calling model method in slow query logs.
+func (msql *MySQL) queryThresholds(name, stmt string, args ...any) ([]Threshold, error) {
+	// Execute the query with the provided arguments
+	rows, err := msql.query(name, stmt, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list thresholds: %w", err)
+	}
+	defer rows.Close()
+
+	// Scan each row into a Threshold
+	thresholds := []Threshold{}
+	for rows.Next() {
+		var t Threshold
+		if err := rows.Scan(&t.ID, &t.UserID, &t.Location, &t.Metric, &t.Operator, &t.Value, &t.CreatedAt, &t.Triggered); err != nil {
+			return nil, fmt.Errorf("failed to scan threshold row: %w", err)
+		}
+		thresholds = append(thresholds, t)
+	}
+
+	// Check for errors that occurred during iteration
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate over threshold rows: %w", err)
+	}
+
+	return thresholds, nil
+}
+
+// UpdateThreshold replaces the location, metric, operator and value of a threshold owned by the user and returns it.
+// The triggered state is reset, so the changed threshold raises an alert as soon as it is crossed.
+// It returns ErrThresholdNotFound if no such threshold exists for the user.
+func (msql *MySQL) UpdateThreshold(userID, thresholdID int, location, metric, operator string, value float64) (Threshold, error) {
+	// SQL query to update the threshold, scoped to its owner
+	stmt := `UPDATE thresholds SET location = ?, metric = ?, operator = ?, value = ?, triggered = FALSE WHERE id = ? AND user_id = ?`
+
+	// Execute the query with the new values
+	if _, err := msql.exec("UpdateThreshold", stmt, location, metric, operator, value, thresholdID, userID); err != nil {
+		return Threshold{}, fmt.Errorf("failed to update threshold: %w", err)
+	}
+
+	// Read the threshold back; MySQL reports no affected rows for an unchanged row,
+	// so a missing row is the only reliable sign that the threshold doesn't exist or belongs to another user
+	stmt = `SELECT id, user_id, location, metric, operator, value, created_at, triggered FROM thresholds WHERE id = ? AND user_id = ?`
+	thresholds, err := msql.queryThresholds("UpdateThreshold", stmt, thresholdID, userID)
+	if err != nil {
+		return Threshold{}, err
+	}
+	if len(thresholds) == 0 {
+		return Threshold{}, ErrThresholdNotFound
+	}
+
+	return thresholds[0], nil
+}
+
+// DeleteThreshold deletes a threshold owned by the user.
+// It returns ErrThresholdNotFound if no such threshold exists for the user.
+func (msql *MySQL) DeleteThreshold(userID, thresholdID int) error {
+	// SQL query to delete the threshold, scoped to its owner
+	stmt := `DELETE FROM thresholds WHERE id = ? AND user_id = ?`
+
+	// Execute the query with the provided IDs
+	result, err := msql.exec("DeleteThreshold", stmt, thresholdID, userID)
+	if err != nil {
+		return fmt.Errorf("failed to delete threshold: %w", err)
+	}
+
+	// If no row was deleted, the threshold does not exist or belongs to another user
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to retrieve affected rows: %w", err)
+	}
+	if affected == 0 {
+		return ErrThresholdNotFound
+	}
+
+	return nil
+}
+
+// SetThresholdTriggered records whether a threshold was crossed when it was last checked,
+// so that an alert is only recorded when the threshold becomes crossed.
+func (msql *MySQL) SetThresholdTriggered(thresholdID int, triggered bool) error {
+	// SQL query to update the triggered state of the threshold
+	stmt := `UPDATE thresholds SET triggered = ? WHERE id = ?`
+
+	// Execute the query with the new state
+	if _, err := msql.exec("SetThresholdTriggered", stmt, triggered, thresholdID); err != nil {
+		return fmt.Errorf("failed to update threshold state: %w", err)
+	}
+
+	return nil
+}
+
+// InsertAlert records that the given threshold was triggered by the observed value.
+// The threshold's fields are copied so the alert stays meaningful if the threshold is changed later.
+func (msql *MySQL) InsertAlert(threshold Threshold, observedValue float64) error {
+	// SQL query to insert a new alert
+	stmt := `INSERT INTO alerts (threshold_id, user_id, location, metric, operator, threshold_value, observed_value) VALUES (?, ?, ?, ?, ?, ?, ?)`
+
+	// Execute the query with the threshold's fields and the observed value
+	_, err := msql.exec("InsertAlert", stmt, threshold.ID, threshold.UserID, threshold.Location, threshold.Metric, threshold.Operator, threshold.Value, observedValue)
+	if err != nil {
+		return fmt.Errorf("failed to insert alert: %w", err)
+	}
+
+	return nil
+}
+
+// ListAlerts retrieves a page of the user's alerts, newest first.
+func (msql *MySQL) ListAlerts(userID, limit, offset int) ([]Alert, error) {
+	// SQL query to retrieve the latest alerts of the user
+	stmt := `SELECT id, threshold_id, location, metric, operator, threshold_value, observed_value, triggered_at
+	FROM alerts WHERE user_id = ? ORDER BY triggered_at DESC, id DESC LIMIT ? OFFSET ?`
+
+	// Execute the query with the user ID and the pagination parameters
+	rows, err := msql.query("ListAlerts", stmt, userID, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list alerts: %w", err)
+	}
+	defer rows.Close()
+
+	// Scan each row into an Alert
+	alerts := []Alert{}
+	for rows.Next() {
+		var a Alert
+		if err := rows.Scan(&a.ID, &a.ThresholdID, &a.Location, &a.Metric, &a.Operator, &a.ThresholdValue, &a.ObservedValue, &a.TriggeredAt); err != nil {
+			return nil, fmt.Errorf("failed to scan alert row: %w", err)
+		}
+		alerts = append(alerts, a)
+	}
+
+	// Check for errors that occurred during iteration
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate over alert rows: %w", err)
+	}
+
+	return alerts, nil
+}
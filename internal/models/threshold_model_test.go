@@ -0,0 +1,44 @@
+package models
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+// thresholdColumns are the columns selected for full threshold rows.
+var thresholdColumns = []string{"id", "user_id", "location", "metric", "operator", "value", "created_at", "triggered"}
+
+func TestUpdateThresholdResetsTheTriggeredState(t *testing.T) {
+	db, mock := newMockDB(t)
+	createdAt := time.Date(2026, 3, 1, 8, 0, 0, 0, time.UTC)
+	mock.ExpectExec(`UPDATE thresholds SET location = \?, metric = \?, operator = \?, value = \?, triggered = FALSE WHERE id = \? AND user_id = \?`).
+		WithArgs("London", "temp_c", "<", 0.0, 3, 7).
+		WillReturnResult(sqlmock.NewResult(0, 0)) // unchanged values affect no rows
+	mock.ExpectQuery("SELECT .* FROM thresholds WHERE id = \\? AND user_id = \\?").
+		WithArgs(3, 7).
+		WillReturnRows(sqlmock.NewRows(thresholdColumns).AddRow(3, 7, "London", "temp_c", "<", 0.0, createdAt, false))
+
+	threshold, err := db.UpdateThreshold(7, 3, "London", "temp_c", "<", 0)
+	if err != nil {
+		t.Fatalf("UpdateThreshold failed: %v", err)
+	}
+	want := Threshold{ID: 3, UserID: 7, Location: "London", Metric: "temp_c", Operator: "<", Value: 0, CreatedAt: createdAt}
+	if threshold != want {
+		t.Errorf("UpdateThreshold = %+v, want %+v", threshold, want)
+	}
+}
+
+func TestUpdateThresholdOfAnotherUser(t *testing.T) {
+	db, mock := newMockDB(t)
+	mock.ExpectExec("UPDATE thresholds").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("SELECT .* FROM thresholds WHERE id = \\? AND user_id = \\?").
+		WithArgs(3, 8).
+		WillReturnRows(sqlmock.NewRows(thresholdColumns))
+
+	if _, err := db.UpdateThreshold(8, 3, "London", "temp_c", "<", 0); !errors.Is(err, ErrThresholdNotFound) {
+		t.Errorf("UpdateThreshold = %v, want ErrThresholdNotFound", err)
+	}
+}
@@ -0,0 +1,81 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// DBContractUsage defines the contract (interface) for database operations
+// related to the daily request counts of API keys.
+type DBContractUsage interface {
+	RecordAPIKeyUsage(apiKey string, day time.Time) error                                    // Count one request of the API key on the given day
+	GetAPIKeyDailyUsage(userID int, apiKey string, from, to time.Time) ([]DailyUsage, error) // Retrieve the daily totals of a key owned by the user
+}
+
+// DailyUsage is the number of requests made with an API key on a single day.
+type DailyUsage struct {
+	Day      time.Time // Day is the UTC date the requests were made on.
+	Requests int       // Requests is the number of requests made that day.
+}
+
+// RecordAPIKeyUsage increments the request count of the API key for the given day, creating the day's row if needed.
+// Unknown API keys are ignored, since they can't be attributed to a user.
+func (msql *MySQL) RecordAPIKeyUsage(apiKey string, day time.Time) error {
+	// SQL query resolving the key to its ID and counting the request in that key's row for the day
+	stmt := `INSERT INTO api_key_usage (api_key_id, day, requests)
+	SELECT id, ?, 1 FROM api_keys WHERE api_key = ?
+	ON DUPLICATE KEY UPDATE requests = requests + 1`
+
+	// Execute the query with the day truncated to a date
+	if _, err := msql.exec("RecordAPIKeyUsage", stmt, day.Format(time.DateOnly), apiKey); err != nil {
+		return fmt.Errorf("failed to record API key usage: %w", err)
+	}
+
+	return nil
+}
+
+// GetAPIKeyDailyUsage retrieves the request totals per day of an API key owned by the user, for the days
+// between from and to (both inclusive), ordered by day. Days without requests have no entry.
+// It returns ErrAPIKeyNotFound if the user has no such API key.
+func (msql *MySQL) GetAPIKeyDailyUsage(userID int, apiKey string, from, to time.Time) ([]DailyUsage, error) {
+	// Make sure the key belongs to the user, so users can't read the usage of other keys
+	var apiKeyID int
+	err := msql.queryRow("GetAPIKeyDailyUsage", `SELECT id FROM api_keys WHERE api_key = ? AND user_id = ?`, apiKey, userID).Scan(&apiKeyID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrAPIKeyNotFound
+		}
+		return nil, fmt.Errorf("failed to get API key: %w", err)
+	}
+
+	// SQL query summing the requests of the key per day within the range
+	stmt := `SELECT day, SUM(requests) FROM api_key_usage
+	WHERE api_key_id = ? AND day BETWEEN ? AND ?
+	GROUP BY day ORDER BY day`
+
+	// Execute the query with the key ID and the date range
+	rows, err := msql.query("GetAPIKeyDailyUsage", stmt, apiKeyID, from.Format(time.DateOnly), to.Format(time.DateOnly))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get API key usage: %w", err)
+	}
+	defer rows.Close()
+
+	// Scan each row into a DailyUsage
+	usage := []DailyUsage{}
+	for rows.Next() {
+		var u DailyUsage
+		if err := rows.Scan(&u.Day, &u.Requests); err != nil {
+			return nil, fmt.Errorf("failed to scan API key usage row: %w", err)
+		}
+		usage = append(usage, u)
+	}
+
+	// Check for errors that occurred during iteration
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate over API key usage rows: %w", err)
+	}
+
+	return usage, nil
+}
@@ -4,6 +4,9 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/go-sql-driver/mysql"
 )
@@ -14,9 +17,30 @@ import (
 type DBContractUsers interface {
 	InsertUser(name, surname, username string, password_hash []byte) (int, error)
 	RetrieveUserCredentials(username string) (int, string, error)
-	InsertUserAPIKey(userID int, apiKey string) error
-	CheckUserAPIKey(apiKey string) (bool, error)
+	InsertUserAPIKey(userID int, apiKey string, scopes []string) error
+	InsertUserWithAPIKey(name, surname, username string, password_hash []byte, apiKey string) (int, error)
+	CheckUserAPIKey(apiKey string) ([]string, error)
 	RetriveUserAPIKey(userID int) (string, error)
+	CountUserAPIKeys(userID int) (int, error)
+	DeleteUserAPIKeys(userID int, identifiers []string) ([]APIKeyDeletion, error)
+	ListUsers(limit, offset int) ([]User, error)
+	CountUsers() (int, error)
+	RetrievePasswordHash(userID int) (string, error)
+	UpdateUserPassword(userID int, password_hash []byte) error
+	RetrieveTokensValidAfter(userID int) (time.Time, error)
+	UpdateTokensValidAfter(userID int, validAfter time.Time) error
+	RetrieveUserPreferences(userID int) (string, string, error)
+	UpdateUserPreferences(userID int, units, lang string) error
+}
+
+// User represents the non-sensitive fields of a row in the `users` table.
+// The password hash is intentionally left out so it can never leak through listings.
+type User struct {
+	ID        int       // ID is the primary key of the user.
+	Name      string    // Name is the user's first name.
+	Surname   string    // Surname is the user's last name.
+	Username  string    // Username is the unique login name of the user.
+	CreatedAt time.Time // CreatedAt is the time the user signed up.
 }
 
 // UsersModel represents the struct that holds the database connection
@@ -40,17 +64,10 @@ func (msql *MySQL) InsertUser(name, surname, username string, password_hash []by
 	stmt := `INSERT INTO users (name, surname, username, password_hash) VALUES(?, ?, ?, ?)`
 
 	// Execute the insert operation, returning an error if it fails
-	req, err := msql.DB.Exec(stmt, name, surname, username, password_hash)
+	req, err := msql.exec("InsertUser", stmt, name, surname, username, password_hash)
 	if err != nil {
-		// Check for MySQL-specific error: duplicate username
-		if mysqlErr, ok := err.(*mysql.MySQLError); ok {
-			if mysqlErr.Number == 1062 { // 1062 is MySQL's error code for duplicate entry
-				// Return a custom error indicating the username already exists
-				return 0, ErrDuplicatedUsername
-			}
-		}
-		// Return a wrapped error with the original failure reason
-		return 0, fmt.Errorf("failed to insert the new user to the database: %w", err)
+		// Map a duplicate entry to the error of the column that collided
+		return 0, userInsertError(err)
 	}
 
 	// Retrieve the ID of the newly inserted user
@@ -64,6 +81,45 @@ func (msql *MySQL) InsertUser(name, surname, username string, password_hash []by
 	return int(userId), nil
 }
 
+// InsertUserWithAPIKey inserts a new user together with their API key in a single transaction,
+// so a failure never leaves a user without a key. It returns ErrDuplicatedUsername if the username is taken,
+// or ErrDuplicatedAPIKey if the API key collides with an existing one.
+func (msql *MySQL) InsertUserWithAPIKey(name, surname, username string, password_hash []byte, apiKey string) (int, error) {
+	defer msql.logIfSlow("InsertUserWithAPIKey", time.Now())
+
+	// Start the transaction; it is rolled back unless both inserts succeed
+	tx, err := msql.DB.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Insert the user
+	res, err := tx.Exec(`INSERT INTO users (name, surname, username, password_hash) VALUES(?, ?, ?, ?)`, name, surname, username, password_hash)
+	if err != nil {
+		return 0, userInsertError(missingTableError(err))
+	}
+	userID, err := res.LastInsertId()
+	if err != nil {
+		return 0, fmt.Errorf("failed to retrieve last inserted user id: %w", err)
+	}
+
+	// Insert the user's API key
+	if _, err := tx.Exec(`INSERT INTO api_keys (user_id, api_key) VALUES (?, ?)`, userID, apiKey); err != nil {
+		if mysqlErr, ok := err.(*mysql.MySQLError); ok && mysqlErr.Number == 1062 {
+			return 0, ErrDuplicatedAPIKey
+		}
+		return 0, fmt.Errorf("failed to insert new API key into the database: %w", missingTableError(err))
+	}
+
+	// Commit both inserts
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit the new user: %w", err)
+	}
+
+	return int(userID), nil
+}
+
 // RetrieveUserCredentials retrieves the credentials (user ID and password hash)
 // for a given username. If the user is not found, it returns an error.
 // This method assumes the 'users' table contains 'id' and 'password_hash' columns.
@@ -76,14 +132,14 @@ func (msql *MySQL) RetrieveUserCredentials(username string) (int, string, error)
 	var password_hash string
 
 	// Query the database and scan the result into userID and password_hash
-	err := msql.DB.QueryRow(stmt, username).Scan(&userID, &password_hash)
+	err := msql.queryRow("RetrieveUserCredentials", stmt, username).Scan(&userID, &password_hash)
 	if err != nil {
 		// If no rows are returned (user not found), return a custom error
 		if errors.Is(err, sql.ErrNoRows) {
 			return 0, "", ErrUserNotFound
 		}
 		// Return a wrapped error if any other error occurs during the query
-		return 0, "", fmt.Errorf("failed to scan user credentials: %w", err)
+		return 0, "", fmt.Errorf("failed to scan user credentials: %w", missingTableError(err))
 	}
 
 	// Return the user ID and password hash if found
@@ -91,14 +147,24 @@ func (msql *MySQL) RetrieveUserCredentials(username string) (int, string, error)
 }
 
 // InsertUserAPIKey inserts a new API key into the `api_keys` table for the specified user.
-// It associates the provided user ID with the given API key in the database.
-func (msql *MySQL) InsertUserAPIKey(userID int, apiKey string) error {
-	// SQL query to insert the user ID and API key into the api_keys table
-	stmt := `INSERT INTO api_keys (user_id, api_key) VALUES (?, ?)`
+// It associates the provided user ID with the given API key in the database, limited to the given scopes
+// (nil leaves the key unrestricted).
+func (msql *MySQL) InsertUserAPIKey(userID int, apiKey string, scopes []string) error {
+	// SQL query to insert the user ID, API key and scopes into the api_keys table
+	stmt := `INSERT INTO api_keys (user_id, api_key, scopes) VALUES (?, ?, ?)`
 
-	// Execute the insert statement with the userID and apiKey values
-	_, err := msql.DB.Exec(stmt, userID, apiKey)
+	// Execute the insert statement with the userID, apiKey and scopes values
+	_, err := msql.exec("InsertUserAPIKey", stmt, userID, apiKey, joinScopes(scopes))
 	if err != nil {
+		// Check for MySQL-specific error: duplicate entry on one of the unique indexes
+		if mysqlErr, ok := err.(*mysql.MySQLError); ok && mysqlErr.Number == 1062 {
+			// A collision on the api_key index means the generated key itself is taken
+			if duplicateKeyName(mysqlErr) == "idx_api_key" {
+				return ErrDuplicatedAPIKey
+			}
+			// Any other duplicate is the per-user constraint of databases not yet migrated to multiple keys
+			return ErrUserAPIKeyExists
+		}
 		// Return a wrapped error indicating failure to insert the API key
 		return fmt.Errorf("failed to insert new API key into the database: %w", err)
 	}
@@ -107,22 +173,337 @@ func (msql *MySQL) InsertUserAPIKey(userID int, apiKey string) error {
 	return nil
 }
 
+// CountUserAPIKeys returns the number of API keys owned by the user with the given ID.
+func (msql *MySQL) CountUserAPIKeys(userID int) (int, error) {
+	// SQL query to count the keys of the user
+	stmt := `SELECT COUNT(*) FROM api_keys WHERE user_id = ?`
+
+	// Execute the query and scan the result into count
+	var count int
+	err := msql.queryRow("CountUserAPIKeys", stmt, userID).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count API keys: %w", err)
+	}
+
+	// Return the number of keys of the user
+	return count, nil
+}
+
 // RetriveUserAPIKey retrieves the API key for a given user ID from the `api_keys` table.
+// Users may own several keys; the first one, created on signup, is returned.
 // If no API key is found for the user, it returns an error.
 func (msql *MySQL) RetriveUserAPIKey(userID int) (string, error) {
-	// SQL query to retrieve the API key for the given user ID
-	stmt := `SELECT api_key FROM api_keys WHERE user_id = ?`
+	// SQL query to retrieve the first API key for the given user ID
+	stmt := `SELECT api_key FROM api_keys WHERE user_id = ? ORDER BY id LIMIT 1`
 
 	// Variable to store the retrieved API key
 	var apiKey string
 
 	// Query the database and scan the result into apiKey
-	err := msql.DB.QueryRow(stmt, userID).Scan(&apiKey)
+	err := msql.queryRow("RetriveUserAPIKey", stmt, userID).Scan(&apiKey)
 	if err != nil {
 		// Return a wrapped error if the retrieval fails
-		return "", fmt.Errorf("failed to retrieve user API key: %w", err)
+		return "", fmt.Errorf("failed to retrieve user API key: %w", missingTableError(err))
 	}
 
 	// Return the retrieved API key
 	return apiKey, nil
 }
+
+// MinAPIKeyPrefixLength is the shortest API key prefix accepted to identify a key, so that
+// a handful of characters can't accidentally match (and delete) a key.
+const MinAPIKeyPrefixLength = 8
+
+// APIKeyDeletion is the outcome of deleting the API key named by one identifier.
+type APIKeyDeletion struct {
+	Identifier string // Identifier is the key ID or key prefix as requested.
+	APIKey     string // APIKey is the deleted key; empty if nothing was deleted.
+	Err        error  // Err is ErrAPIKeyNotFound or ErrAmbiguousAPIKeyPrefix if nothing was deleted.
+}
+
+// DeleteUserAPIKeys deletes the user's API keys named by the identifiers in a single transaction.
+// An identifier is either the numeric ID of a key or a prefix (at least MinAPIKeyPrefixLength characters)
+// of the key itself. Only the user's own keys are matched, so the keys of other users are reported
+// as not found, exactly like keys that don't exist. Their daily usage is deleted along with them.
+// It returns one outcome per identifier, in order.
+func (msql *MySQL) DeleteUserAPIKeys(userID int, identifiers []string) ([]APIKeyDeletion, error) {
+	defer msql.logIfSlow("DeleteUserAPIKeys", time.Now())
+
+	// Start the transaction; the user's keys are locked so the matches can't change before they are deleted
+	tx, err := msql.DB.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Load the user's own keys; nothing else can be matched
+	rows, err := tx.Query(`SELECT id, api_key FROM api_keys WHERE user_id = ? FOR UPDATE`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve user API keys: %w", missingTableError(err))
+	}
+	keys := make(map[int]string)
+	for rows.Next() {
+		var id int
+		var apiKey string
+		if err := rows.Scan(&id, &apiKey); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan user API key: %w", err)
+		}
+		keys[id] = apiKey
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate user API keys: %w", err)
+	}
+
+	// Resolve every identifier to one of the keys, deleting each key at most once
+	deletions := make([]APIKeyDeletion, len(identifiers))
+	deleted := make(map[int]bool)
+	for i, identifier := range identifiers {
+		deletions[i] = APIKeyDeletion{Identifier: identifier}
+
+		id, err := matchAPIKey(keys, identifier)
+		if err == nil && deleted[id] {
+			err = ErrAPIKeyNotFound
+		}
+		if err != nil {
+			deletions[i].Err = err
+			continue
+		}
+
+		if _, err := tx.Exec(`DELETE FROM api_keys WHERE id = ? AND user_id = ?`, id, userID); err != nil {
+			return nil, fmt.Errorf("failed to delete API key: %w", err)
+		}
+		deleted[id] = true
+		deletions[i].APIKey = keys[id]
+	}
+
+	// Commit all deletions at once
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit API key deletions: %w", err)
+	}
+
+	return deletions, nil
+}
+
+// matchAPIKey returns the ID of the key named by the identifier: a numeric key ID or a key prefix.
+// A numeric identifier that is no key ID is still tried as a prefix, since keys may start with digits.
+// It returns ErrAPIKeyNotFound if no key matches and ErrAmbiguousAPIKeyPrefix if a prefix matches several.
+func matchAPIKey(keys map[int]string, identifier string) (int, error) {
+	if id, err := strconv.Atoi(identifier); err == nil {
+		if _, ok := keys[id]; ok {
+			return id, nil
+		}
+	}
+
+	if len(identifier) < MinAPIKeyPrefixLength {
+		return 0, ErrAPIKeyNotFound
+	}
+
+	match := 0
+	for id, apiKey := range keys {
+		if strings.HasPrefix(apiKey, identifier) {
+			if match != 0 {
+				return 0, ErrAmbiguousAPIKeyPrefix
+			}
+			match = id
+		}
+	}
+	if match == 0 {
+		return 0, ErrAPIKeyNotFound
+	}
+	return match, nil
+}
+
+// ListUsers retrieves a page of users ordered by ID, using LIMIT and OFFSET for pagination.
+// Only non-sensitive columns are selected.
+func (msql *MySQL) ListUsers(limit, offset int) ([]User, error) {
+	// SQL query to retrieve a page of users
+	stmt := `SELECT id, name, surname, username, created_at FROM users ORDER BY id LIMIT ? OFFSET ?`
+
+	// Execute the query with the pagination parameters
+	rows, err := msql.query("ListUsers", stmt, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list users: %w", err)
+	}
+	defer rows.Close()
+
+	// Scan each row into a User
+	users := []User{}
+	for rows.Next() {
+		var user User
+		if err := rows.Scan(&user.ID, &user.Name, &user.Surname, &user.Username, &user.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan user row: %w", err)
+		}
+		users = append(users, user)
+	}
+
+	// Check for errors that occurred during iteration
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate over user rows: %w", err)
+	}
+
+	// Return the page of users
+	return users, nil
+}
+
+// CountUsers returns the total number of users in the `users` table.
+func (msql *MySQL) CountUsers() (int, error) {
+	// SQL query to count all users
+	stmt := `SELECT COUNT(*) FROM users`
+
+	// Execute the query and scan the result into count
+	var count int
+	err := msql.queryRow("CountUsers", stmt).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count users: %w", err)
+	}
+
+	// Return the total number of users
+	return count, nil
+}
+
+// RetrievePasswordHash retrieves the password hash of the user with the given ID.
+// If the user is not found, it returns ErrUserNotFound.
+func (msql *MySQL) RetrievePasswordHash(userID int) (string, error) {
+	// SQL query to retrieve the password hash based on the user ID
+	stmt := `SELECT password_hash FROM users WHERE id = ?`
+
+	// Query the database and scan the result into password_hash
+	var password_hash string
+	err := msql.queryRow("RetrievePasswordHash", stmt, userID).Scan(&password_hash)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", ErrUserNotFound
+		}
+		return "", fmt.Errorf("failed to scan user password hash: %w", err)
+	}
+
+	// Return the password hash if found
+	return password_hash, nil
+}
+
+// UpdateUserPassword replaces the password hash of the user with the given ID.
+// If the user is not found, it returns ErrUserNotFound.
+func (msql *MySQL) UpdateUserPassword(userID int, password_hash []byte) error {
+	// SQL query to update the password hash of the user
+	stmt := `UPDATE users SET password_hash = ? WHERE id = ?`
+
+	// Execute the update statement
+	result, err := msql.exec("UpdateUserPassword", stmt, password_hash, userID)
+	if err != nil {
+		return fmt.Errorf("failed to update user password: %w", err)
+	}
+
+	// Make sure a user was actually updated
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to retrieve affected rows: %w", err)
+	}
+	if affected == 0 {
+		return ErrUserNotFound
+	}
+
+	return nil
+}
+
+// RetrieveTokensValidAfter retrieves the time before which all JWTs of the user are rejected.
+// A zero time means the user's sessions were never invalidated.
+func (msql *MySQL) RetrieveTokensValidAfter(userID int) (time.Time, error) {
+	// SQL query to retrieve the tokens_valid_after timestamp of the user
+	stmt := `SELECT tokens_valid_after FROM users WHERE id = ?`
+
+	// Query the database; the column is NULL until the sessions are first invalidated
+	var validAfter sql.NullTime
+	err := msql.queryRow("RetrieveTokensValidAfter", stmt, userID).Scan(&validAfter)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return time.Time{}, ErrUserNotFound
+		}
+		return time.Time{}, fmt.Errorf("failed to scan tokens_valid_after: %w", err)
+	}
+
+	// Return the timestamp, or the zero time if it was never set
+	return validAfter.Time, nil
+}
+
+// UpdateTokensValidAfter sets the time before which all JWTs of the user are rejected.
+// Affected rows are not checked: MySQL reports 0 when the timestamp is unchanged within the same second.
+func (msql *MySQL) UpdateTokensValidAfter(userID int, validAfter time.Time) error {
+	// SQL query to update the tokens_valid_after timestamp of the user
+	stmt := `UPDATE users SET tokens_valid_after = ? WHERE id = ?`
+
+	// Execute the update statement
+	_, err := msql.exec("UpdateTokensValidAfter", stmt, validAfter, userID)
+	if err != nil {
+		return fmt.Errorf("failed to update tokens_valid_after: %w", err)
+	}
+
+	return nil
+}
+
+// RetrieveUserPreferences retrieves the preferred units and language of the user.
+// Preferences that were never set are returned as empty strings.
+func (msql *MySQL) RetrieveUserPreferences(userID int) (string, string, error) {
+	// SQL query to retrieve the preferences of the user
+	stmt := `SELECT preferred_units, preferred_lang FROM users WHERE id = ?`
+
+	// Query the database; both columns are NULL until the user sets them
+	var units, lang sql.NullString
+	err := msql.queryRow("RetrieveUserPreferences", stmt, userID).Scan(&units, &lang)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", "", ErrUserNotFound
+		}
+		return "", "", fmt.Errorf("failed to scan user preferences: %w", err)
+	}
+
+	return units.String, lang.String, nil
+}
+
+// UpdateUserPreferences stores the preferred units and language of the user; empty values clear a preference.
+// Affected rows are not checked: MySQL reports 0 when the preferences are unchanged.
+func (msql *MySQL) UpdateUserPreferences(userID int, units, lang string) error {
+	// SQL query to update the preferences of the user, storing NULL for cleared ones
+	stmt := `UPDATE users SET preferred_units = NULLIF(?, ''), preferred_lang = NULLIF(?, '') WHERE id = ?`
+
+	// Execute the update statement
+	_, err := msql.exec("UpdateUserPreferences", stmt, units, lang, userID)
+	if err != nil {
+		return fmt.Errorf("failed to update user preferences: %w", err)
+	}
+
+	return nil
+}
+
+// userInsertError maps the error of an insert into the `users` table.
+// A duplicate entry (MySQL error 1062) is reported by the unique index that collided: "username" is a taken
+// username and "email" a taken email, so that the two are never mistaken for one another.
+func userInsertError(err error) error {
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) && mysqlErr.Number == 1062 { // 1062 is MySQL's error code for duplicate entry
+		switch duplicateKeyName(mysqlErr) {
+		case "username":
+			return ErrDuplicatedUsername
+		case "email":
+			return ErrDuplicatedEmail
+		}
+	}
+	// Return a wrapped error with the original failure reason
+	return fmt.Errorf("failed to insert the new user to the database: %w", err)
+}
+
+// duplicateKeyName extracts the name of the unique index from a duplicate entry error, whose message reads
+// "Duplicate entry '<value>' for key '<index>'". MySQL 8 qualifies the index with its table ("users.username"),
+// older versions do not, so the table is stripped. It returns "" if the message does not name an index.
+func duplicateKeyName(mysqlErr *mysql.MySQLError) string {
+	_, key, found := strings.Cut(mysqlErr.Message, "for key '")
+	if !found {
+		return ""
+	}
+	key = strings.TrimSuffix(key, "'")
+	if i := strings.LastIndex(key, "."); i >= 0 {
+		key = key[i+1:]
+	}
+	return key
+}
@@ -0,0 +1,131 @@
+package models
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/go-sql-driver/mysql"
+)
+
+func TestInsertUserAPIKeyMapsDuplicates(t *testing.T) {
+	tests := []struct {
+		name  string
+		index string
+		want  error
+	}{
+		{"generated key collides", "idx_api_key", ErrDuplicatedAPIKey},
+		{"user already has a key", "user_id", ErrUserAPIKeyExists},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock := newMockDB(t)
+			mock.ExpectExec("INSERT INTO api_keys").
+				WithArgs(7, "key", sqlmock.AnyArg()).
+				WillReturnError(duplicateEntry("api_keys", tt.index))
+
+			if err := db.InsertUserAPIKey(7, "key", nil); !errors.Is(err, tt.want) {
+				t.Errorf("InsertUserAPIKey() = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestCountUserAPIKeys(t *testing.T) {
+	db, mock := newMockDB(t)
+	mock.ExpectQuery(`SELECT COUNT\(\*\) FROM api_keys WHERE user_id = \?`).
+		WithArgs(7).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(4))
+
+	count, err := db.CountUserAPIKeys(7)
+	if err != nil {
+		t.Fatalf("CountUserAPIKeys() failed: %v", err)
+	}
+	if count != 4 {
+		t.Errorf("CountUserAPIKeys() = %d, want 4", count)
+	}
+}
+
+func TestDuplicateKeyName(t *testing.T) {
+	tests := []struct {
+		message string
+		want    string
+	}{
+		{"Duplicate entry 'alice' for key 'users.username'", "username"},
+		{"Duplicate entry 'alice' for key 'username'", "username"},
+		{"Duplicate entry 'a@example.com' for key 'users.email'", "email"},
+		{"Duplicate entry 'a@example.com' for key 'email'", "email"},
+		{"Duplicate entry '42'", ""},
+	}
+	for _, tt := range tests {
+		if got := duplicateKeyName(&mysql.MySQLError{Number: 1062, Message: tt.message}); got != tt.want {
+			t.Errorf("duplicateKeyName(%q) = %q, want %q", tt.message, got, tt.want)
+		}
+	}
+}
+
+func TestInsertUserMapsDuplicates(t *testing.T) {
+	tests := []struct {
+		name  string
+		err   error
+		want  error
+		other bool
+	}{
+		{name: "username taken", err: duplicateEntry("users", "username"), want: ErrDuplicatedUsername},
+		{name: "email taken", err: duplicateEntry("users", "email"), want: ErrDuplicatedEmail},
+		{name: "other index", err: duplicateEntry("users", "PRIMARY"), other: true},
+		{name: "other error", err: &mysql.MySQLError{Number: 1205, Message: "Lock wait timeout exceeded"}, other: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock := newMockDB(t)
+			mock.ExpectExec("INSERT INTO users").
+				WithArgs("Ada", "Lovelace", "ada", []byte("hash")).
+				WillReturnError(tt.err)
+
+			_, err := db.InsertUser("Ada", "Lovelace", "ada", []byte("hash"))
+			if tt.other {
+				if errors.Is(err, ErrDuplicatedUsername) || errors.Is(err, ErrDuplicatedEmail) || !errors.Is(err, tt.err) {
+					t.Errorf("InsertUser() = %v, want the original error wrapped", err)
+				}
+				return
+			}
+			if !errors.Is(err, tt.want) {
+				t.Errorf("InsertUser() = %v, want %v", err, tt.want)
+			}
+			// Each collision maps to its own error only
+			if errors.Is(err, ErrDuplicatedUsername) && errors.Is(err, ErrDuplicatedEmail) {
+				t.Errorf("InsertUser() = %v matches both duplicate errors", err)
+			}
+		})
+	}
+}
+
+func TestDeleteUserAPIKeysOnlyMatchesTheUsersOwnKeys(t *testing.T) {
+	db, mock := newMockDB(t)
+	mock.ExpectBegin()
+	// Only the keys of user 7 are loaded; key 3 ("bbbbbbbb...") belongs to another user
+	mock.ExpectQuery(`SELECT id, api_key FROM api_keys WHERE user_id = \? FOR UPDATE`).
+		WithArgs(7).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "api_key"}).
+			AddRow(1, "aaaaaaaa1111").
+			AddRow(2, "aaaaaaaa2222"))
+	// The only deletion is the user's own key, and it is still guarded by the user ID
+	mock.ExpectExec(`DELETE FROM api_keys WHERE id = \? AND user_id = \?`).
+		WithArgs(1, 7).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	deletions, err := db.DeleteUserAPIKeys(7, []string{"3", "bbbbbbbb", "bbbbbbbb2222", "1"})
+	if err != nil {
+		t.Fatalf("DeleteUserAPIKeys() failed: %v", err)
+	}
+	for _, deletion := range deletions[:3] {
+		if !errors.Is(deletion.Err, ErrAPIKeyNotFound) || deletion.APIKey != "" {
+			t.Errorf("deletion of another user's key %q = %+v, want ErrAPIKeyNotFound", deletion.Identifier, deletion)
+		}
+	}
+	if own := deletions[3]; own.Err != nil || own.APIKey != "aaaaaaaa1111" {
+		t.Errorf("deletion of the user's own key = %+v, want key aaaaaaaa1111 deleted", own)
+	}
+}
@@ -1,12 +1,17 @@
 package models
 
-import "fmt"
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+)
 
 // DBContractWeatherapi defines the contract (interface) for database operations
 // related to weather API keys. This ensures that any struct implementing this
 // interface must provide an implementation for checking the validity of an API key.
 type DBContractWeatherapi interface {
-	CheckUserAPIKey(apiKey string) (bool, error) // Check if the provided API key exists in the database
+	CheckUserAPIKey(apiKey string) ([]string, error) // Check if the provided API key exists in the database and retrieve its scopes
 }
 
 // WeatherapiModel represents the struct that holds the database connection
@@ -23,26 +28,40 @@ func NewWeatherapiModel(db DBContractWeatherapi) *WeatherapiModel {
 }
 
 // CheckUserAPIKey checks if the provided API key exists in the `api_keys` table in the database.
-// It returns true if the API key is valid, or false if not. If an error occurs, it returns the error.
-func (msql *MySQL) CheckUserAPIKey(apiKey string) (bool, error) {
-	// SQL query to count how many rows in the api_keys table have the provided api_key
-	stmt := `SELECT COUNT(*) FROM api_keys WHERE api_key=?`
+// It returns the scopes the key is limited to, or nil if the key may call every endpoint.
+// If the key does not exist, it returns ErrAPIKeyNotFound.
+func (msql *MySQL) CheckUserAPIKey(apiKey string) ([]string, error) {
+	// SQL query to retrieve the scopes of the key; the column is NULL for unrestricted keys
+	stmt := `SELECT scopes FROM api_keys WHERE api_key = ?`
 
-	// Variable to store the count of matching rows
-	var count int
-
-	// Execute the query and scan the result into the 'count' variable
-	err := msql.DB.QueryRow(stmt, apiKey).Scan(&count)
+	// Execute the query and scan the result into the 'scopes' variable
+	var scopes sql.NullString
+	err := msql.queryRow("CheckUserAPIKey", stmt, apiKey).Scan(&scopes)
 	if err != nil {
+		// If no matching row is found, return the custom error indicating the API key is not found
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrAPIKeyNotFound
+		}
 		// Return a wrapped error if something goes wrong during the query
-		return false, fmt.Errorf("failed to scan count of api key in the database: %w", err)
+		return nil, fmt.Errorf("failed to scan scopes of api key in the database: %w", missingTableError(err))
+	}
+
+	// Return the comma-separated scopes as a list
+	return splitScopes(scopes), nil
+}
+
+// joinScopes encodes a list of scopes for the `scopes` column, where NULL means unrestricted.
+func joinScopes(scopes []string) sql.NullString {
+	if len(scopes) == 0 {
+		return sql.NullString{}
 	}
+	return sql.NullString{String: strings.Join(scopes, ","), Valid: true}
+}
 
-	// If the count is greater than 0, the API key is valid and exists in the database
-	if count > 0 {
-		return true, nil
+// splitScopes decodes the `scopes` column into a list of scopes, or nil if the key is unrestricted.
+func splitScopes(scopes sql.NullString) []string {
+	if !scopes.Valid || scopes.String == "" {
+		return nil
 	}
-	// If no matching rows are found, return the custom error indicating the API key is not found
-	return false, ErrAPIKeyNotFound
+	return strings.Split(scopes.String, ",")
 }
- 
\ No newline at end of file
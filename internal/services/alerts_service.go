@@ -0,0 +1,260 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"havoAPI/internal/models"
+	"log"
+	"strings"
+)
+
+// thresholdMetrics maps every metric a threshold may watch to its value in the formatted weather data.
+var thresholdMetrics = map[string]func(FormattedWeatherData) float64{
+	"temp_c":   func(d FormattedWeatherData) float64 { return d.TempC },
+	"wind_kph": func(d FormattedWeatherData) float64 { return d.WindKph },
+	"cloud":    func(d FormattedWeatherData) float64 { return float64(d.Cloud) },
+}
+
+// thresholdOperators maps every supported comparison to its implementation.
+var thresholdOperators = map[string]func(observed, threshold float64) bool{
+	">": func(observed, threshold float64) bool { return observed > threshold },
+	"<": func(observed, threshold float64) bool { return observed < threshold },
+}
+
+// AlertsServiceInterface defines the methods for managing weather thresholds and the alerts they trigger.
+type AlertsServiceInterface interface {
+	// CreateThreshold registers a new threshold for the user and returns it.
+	// It returns an error wrapping ErrInvalidThreshold if the metric, operator or location is invalid.
+	CreateThreshold(userID int, location, metric, operator string, value float64) (Threshold, error)
+
+	// ListThresholds retrieves all thresholds registered by the user.
+	ListThresholds(userID int) ([]Threshold, error)
+
+	// UpdateThreshold replaces the location, metric, operator and value of a threshold of the user and returns it.
+	// It returns an error wrapping ErrInvalidThreshold if the new values are invalid,
+	// and ErrThresholdNotFound if the threshold does not exist or belongs to another user.
+	UpdateThreshold(userID, thresholdID int, location, metric, operator string, value float64) (Threshold, error)
+
+	// DeleteThreshold deletes a threshold of the user.
+	// It returns ErrThresholdNotFound if the threshold does not exist or belongs to another user.
+	DeleteThreshold(userID, thresholdID int) error
+
+	// ListAlerts retrieves a page of the most recent alerts triggered by the user's thresholds.
+	ListAlerts(userID, limit, offset int) ([]Alert, error)
+
+	// CheckThresholds compares the cached weather data against every threshold and records an alert for every
+	// threshold that became crossed since the last check. It never calls the upstream: locations that are not cached
+	// are skipped, so only the thresholds of the locations fetched by every refresh are reliably evaluated.
+	CheckThresholds() error
+}
+
+// AlertsService is a concrete implementation of the AlertsServiceInterface.
+// It stores thresholds and alerts in the database and reads weather data from the Redis cache.
+type AlertsService struct {
+	// db is an instance of the DBContractThresholds interface which handles threshold-related database operations.
+	db models.DBContractThresholds
+
+	// redisClient is the Redis client holding the cached weather data.
+	redisClient *RedisClient
+}
+
+// NewAlertsService initializes a new instance of AlertsService.
+func NewAlertsService(db models.DBContractThresholds, redisClient *RedisClient) *AlertsService {
+	return &AlertsService{
+		db:          db,
+		redisClient: redisClient,
+	}
+}
+
+// CreateThreshold validates and registers a new threshold for the user.
+func (s *AlertsService) CreateThreshold(userID int, location, metric, operator string, value float64) (Threshold, error) {
+	location, err := validateThreshold(location, metric, operator)
+	if err != nil {
+		return Threshold{}, err
+	}
+
+	// Store the threshold.
+	id, err := s.db.InsertThreshold(userID, location, metric, operator, value)
+	if err != nil {
+		return Threshold{}, fmt.Errorf("error occurred while inserting threshold: %w", err)
+	}
+
+	return Threshold{ID: id, Location: location, Metric: metric, Operator: operator, Value: value, Evaluated: isRefreshedLocation(location)}, nil
+}
+
+// UpdateThreshold validates the new values and replaces a threshold of the user with them.
+// The changed threshold starts over as not crossed, so it raises an alert as soon as the new condition holds.
+func (s *AlertsService) UpdateThreshold(userID, thresholdID int, location, metric, operator string, value float64) (Threshold, error) {
+	location, err := validateThreshold(location, metric, operator)
+	if err != nil {
+		return Threshold{}, err
+	}
+
+	// Replace the threshold, which must belong to the user.
+	row, err := s.db.UpdateThreshold(userID, thresholdID, location, metric, operator, value)
+	if err != nil {
+		if errors.Is(err, models.ErrThresholdNotFound) {
+			return Threshold{}, ErrThresholdNotFound
+		}
+		return Threshold{}, fmt.Errorf("error occurred while updating threshold: %w", err)
+	}
+
+	return thresholdFromRow(row), nil
+}
+
+// validateThreshold checks the fields of a threshold and returns the trimmed location.
+// It returns an error wrapping ErrInvalidThreshold if the metric, operator or location is invalid.
+func validateThreshold(location, metric, operator string) (string, error) {
+	// Only metrics and operators the checker knows how to evaluate are accepted.
+	if _, ok := thresholdMetrics[metric]; !ok {
+		return "", fmt.Errorf("%w: 'metric' must be one of temp_c, wind_kph, cloud", ErrInvalidThreshold)
+	}
+	if _, ok := thresholdOperators[operator]; !ok {
+		return "", fmt.Errorf("%w: 'operator' must be one of >, <", ErrInvalidThreshold)
+	}
+
+	// The location must be a query the cache can resolve.
+	location = strings.TrimSpace(location)
+	if _, err := normalizeQuery(location); err != nil {
+		return "", fmt.Errorf("%w: %v", ErrInvalidThreshold, err)
+	}
+
+	return location, nil
+}
+
+// isRefreshedLocation reports whether a location is fetched by every cache refresh, which is what
+// guarantees that its thresholds are evaluated: other locations are only checked while a client keeps them cached.
+func isRefreshedLocation(location string) bool {
+	// Compare the cache keys, which is what decides whether the refreshed data is found.
+	normalized, err := normalizeQuery(location)
+	if err != nil {
+		return false
+	}
+	key := weatherCacheKey(normalized)
+	for _, refreshed := range refreshedLocations {
+		if candidate, err := normalizeQuery(refreshed); err == nil && weatherCacheKey(candidate) == key {
+			return true
+		}
+	}
+	return false
+}
+
+// thresholdFromRow maps a database row to the service-level representation of a threshold.
+func thresholdFromRow(row models.Threshold) Threshold {
+	return Threshold{
+		ID:        row.ID,
+		Location:  row.Location,
+		Metric:    row.Metric,
+		Operator:  row.Operator,
+		Value:     row.Value,
+		CreatedAt: row.CreatedAt,
+		Triggered: row.Triggered,
+		Evaluated: isRefreshedLocation(row.Location),
+	}
+}
+
+// ListThresholds retrieves all thresholds registered by the user.
+func (s *AlertsService) ListThresholds(userID int) ([]Threshold, error) {
+	rows, err := s.db.ListThresholds(userID)
+	if err != nil {
+		return nil, fmt.Errorf("error occurred while listing thresholds: %w", err)
+	}
+
+	// Map the database rows to the service-level representation.
+	thresholds := make([]Threshold, 0, len(rows))
+	for _, row := range rows {
+		thresholds = append(thresholds, thresholdFromRow(row))
+	}
+
+	return thresholds, nil
+}
+
+// DeleteThreshold deletes a threshold of the user.
+func (s *AlertsService) DeleteThreshold(userID, thresholdID int) error {
+	if err := s.db.DeleteThreshold(userID, thresholdID); err != nil {
+		if errors.Is(err, models.ErrThresholdNotFound) {
+			return ErrThresholdNotFound
+		}
+		return fmt.Errorf("error occurred while deleting threshold: %w", err)
+	}
+	return nil
+}
+
+// ListAlerts retrieves a page of the most recent alerts of the user.
+func (s *AlertsService) ListAlerts(userID, limit, offset int) ([]Alert, error) {
+	rows, err := s.db.ListAlerts(userID, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("error occurred while listing alerts: %w", err)
+	}
+
+	// Map the database rows to the service-level representation.
+	alerts := make([]Alert, 0, len(rows))
+	for _, row := range rows {
+		alerts = append(alerts, Alert{
+			ID:             row.ID,
+			ThresholdID:    row.ThresholdID,
+			Location:       row.Location,
+			Metric:         row.Metric,
+			Operator:       row.Operator,
+			ThresholdValue: row.ThresholdValue,
+			ObservedValue:  row.ObservedValue,
+			TriggeredAt:    row.TriggeredAt,
+		})
+	}
+
+	return alerts, nil
+}
+
+// CheckThresholds compares the freshly cached weather data against every registered threshold
+// and records an alert for each one that became crossed. It is meant to run right after the cache refresh.
+// Alerts are edge-triggered: a threshold that stays crossed over several refreshes raises a single alert,
+// and raises the next one only after it was seen not crossed in between.
+func (s *AlertsService) CheckThresholds() error {
+	thresholds, err := s.db.ListAllThresholds()
+	if err != nil {
+		return fmt.Errorf("error occurred while listing thresholds: %w", err)
+	}
+
+	for _, threshold := range thresholds {
+		metric, okMetric := thresholdMetrics[threshold.Metric]
+		compare, okOperator := thresholdOperators[threshold.Operator]
+		if !okMetric || !okOperator {
+			log.Printf("skipping threshold %d with unsupported metric %q or operator %q", threshold.ID, threshold.Metric, threshold.Operator)
+			continue
+		}
+
+		// Read the location from the cache; uncached locations are skipped rather than fetched.
+		normalized, err := normalizeQuery(threshold.Location)
+		if err != nil {
+			continue
+		}
+		data, err := readCachedWeatherData(s.redisClient, weatherCacheKey(normalized))
+		if err != nil {
+			if !errors.Is(err, ErrNoDataCache) {
+				log.Printf("failed to read cached weather data for threshold %d: %v", threshold.ID, err)
+			}
+			continue
+		}
+
+		// Only a change of state is acted upon.
+		observed := metric(data)
+		crossed := compare(observed, threshold.Value)
+		if crossed == threshold.Triggered {
+			continue
+		}
+
+		// Record an alert when the threshold becomes crossed; the state is kept unchanged if that fails,
+		// so the alert is retried on the next check.
+		if crossed {
+			if err := s.db.InsertAlert(threshold, observed); err != nil {
+				log.Printf("failed to record alert for threshold %d: %v", threshold.ID, err)
+				continue
+			}
+		}
+		if err := s.db.SetThresholdTriggered(threshold.ID, crossed); err != nil {
+			log.Printf("failed to update state of threshold %d: %v", threshold.ID, err)
+		}
+	}
+
+	return nil
+}
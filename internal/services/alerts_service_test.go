@@ -0,0 +1,96 @@
+package services
+
+import (
+	"context"
+	"havoAPI/internal/models"
+	"net/http"
+	"testing"
+)
+
+// fakeThresholdsDB is an in-memory thresholds table recording the alerts inserted.
+type fakeThresholdsDB struct {
+	models.DBContractThresholds
+
+	thresholds []models.Threshold
+	alerts     []float64
+}
+
+func (db *fakeThresholdsDB) ListAllThresholds() ([]models.Threshold, error) {
+	return append([]models.Threshold(nil), db.thresholds...), nil
+}
+
+func (db *fakeThresholdsDB) InsertAlert(threshold models.Threshold, observedValue float64) error {
+	db.alerts = append(db.alerts, observedValue)
+	return nil
+}
+
+func (db *fakeThresholdsDB) SetThresholdTriggered(thresholdID int, triggered bool) error {
+	for i := range db.thresholds {
+		if db.thresholds[i].ID == thresholdID {
+			db.thresholds[i].Triggered = triggered
+		}
+	}
+	return nil
+}
+
+func TestCheckThresholdsAlertsOnceWhileCrossed(t *testing.T) {
+	ts := newTestService(t, nil)
+	db := &fakeThresholdsDB{thresholds: []models.Threshold{{ID: 1, UserID: 1, Location: "Spain", Metric: "temp_c", Operator: ">", Value: 35}}}
+	alerts := NewAlertsService(db, ts.redisClient)
+
+	// Each step caches a new temperature for the location, as a refresh would, and checks the thresholds
+	steps := []struct {
+		tempC      float64
+		wantAlerts int
+	}{
+		{tempC: 36, wantAlerts: 1}, // becomes crossed
+		{tempC: 38, wantAlerts: 1}, // stays crossed
+		{tempC: 30, wantAlerts: 1}, // goes back
+		{tempC: 37, wantAlerts: 2}, // crossed again
+	}
+	for i, step := range steps {
+		ts.upstream.handle(func(w http.ResponseWriter, r *http.Request) {
+			writeCurrentWeather(w, "Spain", step.tempC)
+		})
+		ts.advance(ts.cfg.CacheTTL + ts.cfg.MinClientMaxAge)
+		if _, err := ts.FetchWeatherData(context.Background(), "Spain", WeatherOptions{}); err != nil {
+			t.Fatalf("step %d: FetchWeatherData failed: %v", i+1, err)
+		}
+
+		if err := alerts.CheckThresholds(); err != nil {
+			t.Fatalf("step %d: CheckThresholds failed: %v", i+1, err)
+		}
+		if len(db.alerts) != step.wantAlerts {
+			t.Errorf("step %d (%v °C): %d alerts recorded, want %d", i+1, step.tempC, len(db.alerts), step.wantAlerts)
+		}
+		if crossed := step.tempC > 35; db.thresholds[0].Triggered != crossed {
+			t.Errorf("step %d (%v °C): triggered = %v, want %v", i+1, step.tempC, db.thresholds[0].Triggered, crossed)
+		}
+	}
+}
+
+func TestCheckThresholdsSkipsUncachedLocations(t *testing.T) {
+	ts := newTestService(t, nil)
+	db := &fakeThresholdsDB{thresholds: []models.Threshold{{ID: 1, UserID: 1, Location: "Tashkent", Metric: "temp_c", Operator: ">", Value: -100}}}
+
+	if err := NewAlertsService(db, ts.redisClient).CheckThresholds(); err != nil {
+		t.Fatalf("CheckThresholds failed: %v", err)
+	}
+	if len(db.alerts) != 0 || ts.upstream.count() != 0 {
+		t.Errorf("uncached location: %d alerts and %d upstream requests, want none", len(db.alerts), ts.upstream.count())
+	}
+}
+
+func TestIsRefreshedLocation(t *testing.T) {
+	tests := map[string]bool{
+		"Spain":            true,
+		"  united kingdom": true,
+		"Tashkent":         false,
+		"41.3,69.2":        false,
+	}
+	for location, want := range tests {
+		if got := isRefreshedLocation(location); got != want {
+			t.Errorf("isRefreshedLocation(%q) = %v, want %v", location, got, want)
+		}
+	}
+}
@@ -0,0 +1,57 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// anonymousCounterTTL keeps a daily counter longer than the day it counts, so it never expires early,
+// even on the 25-hour day at the end of daylight saving time.
+const anonymousCounterTTL = 26 * time.Hour
+
+// anonymousCounterKey derives the Redis key counting the requests without an API key made by an IP on a day.
+// The day is the calendar date of the given time in its own location, so the key changes exactly once
+// per local day, at local midnight, whatever the daylight saving transitions in between.
+func anonymousCounterKey(ip string, day time.Time) string {
+	return "anonymous:" + day.Format(time.DateOnly) + ":" + ip
+}
+
+// AllowAnonymousRequest counts a request without an API key against the daily allowance of the client IP
+// (ANONYMOUS_DAILY_LIMIT) and returns the number of requests left today. The allowance resets at midnight
+// in the QUOTA_RESET_TZ time zone (UTC by default).
+// It returns ErrAnonymousAccessDisabled if no allowance is configured and ErrAnonymousLimitExceeded once it is used up.
+func (s *WeatherAPIService) AllowAnonymousRequest(ip string) (int, error) {
+	limit := s.cfg.AnonymousDailyLimit
+	if limit == 0 {
+		return 0, ErrAnonymousAccessDisabled
+	}
+
+	// Count the request and (re)set the counter's expiry atomically. Since the key is scoped to the day,
+	// pushing the expiry back on every request only keeps a finished day's counter around a little longer.
+	// Without Redis, the counters are kept in process memory.
+	now := s.clk.Now()
+	key := anonymousCounterKey(ip, now.In(s.cfg.QuotaResetLocation))
+	var count int64
+	if s.redisClient.configured() {
+		var incr *redis.IntCmd
+		_, err := s.redisClient.TxPipelined(context.Background(), func(pipe redis.Pipeliner) error {
+			incr = pipe.Incr(context.Background(), s.redisClient.prefixed(key))
+			pipe.Expire(context.Background(), s.redisClient.prefixed(key), anonymousCounterTTL)
+			return nil
+		})
+		if err != nil {
+			return 0, fmt.Errorf("failed to count anonymous request: %w", err)
+		}
+		count = incr.Val()
+	} else {
+		count = s.redisClient.local.incr(key, anonymousCounterTTL, now)
+	}
+
+	if count > int64(limit) {
+		return 0, ErrAnonymousLimitExceeded
+	}
+	return limit - int(count), nil
+}
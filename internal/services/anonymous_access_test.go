@@ -0,0 +1,64 @@
+package services
+
+import (
+	"errors"
+	"havoAPI/api/config"
+	"testing"
+	"time"
+
+	// The daylight saving rules must not depend on the zone database of the machine running the tests
+	_ "time/tzdata"
+)
+
+func TestAnonymousAllowanceResetsAtLocalMidnightAcrossDaylightSavingTime(t *testing.T) {
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatalf("LoadLocation failed: %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		start     time.Time     // start is when the allowance is used up, shortly after local midnight
+		untilLate time.Duration // untilLate leads from start to shortly before the next local midnight
+	}{
+		// Clocks jump from 2:00 to 3:00, so the day lasts 23 hours
+		{name: "spring forward", start: time.Date(2026, 3, 8, 0, 30, 0, 0, newYork), untilLate: 22*time.Hour + 29*time.Minute},
+		// Clocks fall back from 2:00 to 1:00, so the day lasts 25 hours
+		{name: "fall back", start: time.Date(2026, 11, 1, 0, 30, 0, 0, newYork), untilLate: 24*time.Hour + 29*time.Minute},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts := newTestService(t, func(cfg *config.Config) {
+				cfg.AnonymousDailyLimit = 2
+				cfg.QuotaResetLocation = newYork
+			})
+			ts.clk.Set(tt.start)
+			ts.redis.SetTime(tt.start)
+
+			for i := range 2 {
+				if _, err := ts.AllowAnonymousRequest("203.0.113.7"); err != nil {
+					t.Fatalf("request %d failed: %v", i+1, err)
+				}
+			}
+
+			// The allowance stays used up until the end of the local day, however long it lasts
+			ts.advance(tt.untilLate)
+			if local := ts.clk.Now().In(newYork); local.Day() != tt.start.Day() || local.Hour() != 23 {
+				t.Fatalf("clock reads %v, want 23:59 on the day the allowance was used up", local)
+			}
+			if _, err := ts.AllowAnonymousRequest("203.0.113.7"); !errors.Is(err, ErrAnonymousLimitExceeded) {
+				t.Errorf("request late on the same local day returned %v, want ErrAnonymousLimitExceeded", err)
+			}
+
+			// At local midnight it starts over
+			ts.advance(2 * time.Minute)
+			remaining, err := ts.AllowAnonymousRequest("203.0.113.7")
+			if err != nil {
+				t.Fatalf("request after local midnight failed: %v", err)
+			}
+			if remaining != 1 {
+				t.Errorf("remaining after local midnight = %d, want 1", remaining)
+			}
+		})
+	}
+}
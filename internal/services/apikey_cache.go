@@ -0,0 +1,66 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// validAPIKeyCacheTTL is how long a successfully validated API key is remembered in Redis.
+// It bounds how long a key keeps working after being disabled if its cache entry could not be invalidated.
+const validAPIKeyCacheTTL = 60 * time.Second
+
+// validAPIKeyCacheKey derives the Redis key remembering that an API key is valid.
+// The key is hashed so that plain API keys never end up in Redis.
+func validAPIKeyCacheKey(apiKey string) string {
+	sum := sha256.Sum256([]byte(apiKey))
+	return "apikey_valid:" + hex.EncodeToString(sum[:])
+}
+
+// unrestrictedScopesValue is the cached scopes value of a key limited to no scope.
+const unrestrictedScopesValue = "*"
+
+// cachedAPIKeyScopes returns the scopes of the API key if it was validated within the last validAPIKeyCacheTTL.
+// Redis errors are logged and treated as a miss so that the check falls through to the database.
+func cachedAPIKeyScopes(redisClient *RedisClient, apiKey string) (APIKeyScopes, bool) {
+	value, err := redisClient.Get(context.Background(), redisClient.prefixed(validAPIKeyCacheKey(apiKey))).Result()
+	if err != nil {
+		if !errors.Is(err, redis.Nil) {
+			log.Printf("failed to check API key cache: %v", err)
+		}
+		return nil, false
+	}
+	if value == unrestrictedScopesValue {
+		return nil, true
+	}
+	return APIKeyScopes(strings.Split(value, ",")), true
+}
+
+// rememberValidAPIKey caches a successful API key validation and the key's scopes for validAPIKeyCacheTTL.
+// Failing to store it only costs an extra database query later, so errors are logged and ignored.
+func rememberValidAPIKey(redisClient *RedisClient, apiKey string, scopes APIKeyScopes) {
+	value := unrestrictedScopesValue
+	if len(scopes) > 0 {
+		value = strings.Join(scopes, ",")
+	}
+	err := redisClient.Set(context.Background(), redisClient.prefixed(validAPIKeyCacheKey(apiKey)), value, validAPIKeyCacheTTL).Err()
+	if err != nil {
+		log.Printf("failed to cache API key validation: %v", err)
+	}
+}
+
+// invalidateAPIKeyCache drops the cached validation of an API key, so that a disabled or deleted key
+// stops working immediately instead of after validAPIKeyCacheTTL.
+func invalidateAPIKeyCache(redisClient *RedisClient, apiKey string) error {
+	if err := redisClient.Del(context.Background(), redisClient.prefixed(validAPIKeyCacheKey(apiKey))).Err(); err != nil {
+		return fmt.Errorf("failed to invalidate cached API key: %w", err)
+	}
+	return nil
+}
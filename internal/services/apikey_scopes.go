@@ -0,0 +1,29 @@
+package services
+
+import "slices"
+
+// Scopes an API key can be limited to. Each one grants access to a group of weather endpoints.
+const (
+	ScopeCurrent   = "current"   // ScopeCurrent grants the single-location lookups of weather.current (GET and HEAD).
+	ScopeBulk      = "bulk"      // ScopeBulk grants the multi-location lookups: bulk weather.current (including repeated 'q') and weather.group.
+	ScopeStream    = "stream"    // ScopeStream grants the weather.stream WebSocket.
+	ScopeAstronomy = "astronomy" // ScopeAstronomy grants weather.astronomy.
+	ScopeHistory   = "history"   // ScopeHistory grants weather.history.
+)
+
+// allScopes lists every scope a key can be limited to.
+var allScopes = []string{ScopeCurrent, ScopeBulk, ScopeStream, ScopeAstronomy, ScopeHistory}
+
+// ValidScope reports whether the scope is one of the supported scopes.
+func ValidScope(scope string) bool {
+	return slices.Contains(allScopes, scope)
+}
+
+// APIKeyScopes is the set of scopes an API key is limited to. An empty set means the key is unrestricted,
+// which is the case for every key created on signup or before scopes existed.
+type APIKeyScopes []string
+
+// Allows reports whether a key with these scopes may call the endpoints of the given scope.
+func (s APIKeyScopes) Allows(scope string) bool {
+	return len(s) == 0 || slices.Contains(s, scope)
+}
@@ -0,0 +1,189 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// AstronomyDateLayout is the layout of the 'date' option of astronomy requests (YYYY-MM-DD).
+const AstronomyDateLayout = "2006-01-02"
+
+// astronomyResponse is the response of WeatherAPI's astronomy endpoint.
+type astronomyResponse struct {
+	Location  Location `json:"location"` // Location contains geographical details like name, country, and coordinates.
+	Astronomy struct {
+		Astro struct {
+			Sunrise          string      `json:"sunrise"`           // Sunrise is the local time of sunrise (e.g., "05:43 AM").
+			Sunset           string      `json:"sunset"`            // Sunset is the local time of sunset.
+			Moonrise         string      `json:"moonrise"`          // Moonrise is the local time of moonrise.
+			Moonset          string      `json:"moonset"`           // Moonset is the local time of moonset.
+			MoonPhase        string      `json:"moon_phase"`        // MoonPhase is the name of the moon phase (e.g., "Waxing Gibbous").
+			MoonIllumination json.Number `json:"moon_illumination"` // MoonIllumination is a percentage, sent either as a number or a string.
+		} `json:"astro"`
+	} `json:"astronomy"`
+}
+
+// astronomyTodayKey stands in for the date in the cache key of requests for today's astronomy data,
+// since "today" depends on the time zone of the location, which is only known once it is resolved.
+const astronomyTodayKey = "today"
+
+// FetchAstronomyData retrieves sunrise, sunset, moonrise, moonset and the moon phase for a location on a date
+// (YYYY-MM-DD, today at the location if empty), either from the Redis cache or by querying the weather API.
+// Astronomy data for a date never changes, so it is cached until the end of the current day
+// (at the location for today's data, in UTC otherwise).
+func (s *WeatherAPIService) FetchAstronomyData(q, date string) (AstronomyData, error) {
+	// Normalize the location for consistent formatting, validating coordinate queries.
+	q, err := normalizeQuery(q)
+	if err != nil {
+		return AstronomyData{}, err
+	}
+
+	// Reject locations that can never pass the allowlist before any upstream call.
+	if !s.allowlist.mayAllowQuery(q) {
+		return AstronomyData{}, ErrLocationNotAllowed
+	}
+
+	// Today's data is cached apart from explicit dates, since the date it covers depends on the location.
+	now := s.clk.Now()
+	today := date == ""
+	key := astronomyCacheKey(q, date)
+	if today {
+		key = astronomyCacheKey(q, astronomyTodayKey)
+	}
+
+	// Serve the data from the cache when possible; without Redis, it is always requested.
+	if s.redisClient.configured() {
+		cached, err := s.redisClient.Get(context.Background(), s.redisClient.prefixed(key)).Result()
+		if err == nil {
+			var data AstronomyData
+			if err := json.Unmarshal([]byte(cached), &data); err == nil {
+				if !s.allowlist.allowsLocation(q, FormattedWeatherData{Name: data.Name, Country: data.Country}) {
+					return AstronomyData{}, ErrLocationNotAllowed
+				}
+				return s.attributeAstronomy(data), nil
+			}
+		} else if !errors.Is(err, redis.Nil) {
+			log.Printf("failed to read cached astronomy data for %s: %v", key, err)
+		}
+	}
+
+	// Request the astronomy data from the weather API.
+	var response astronomyResponse
+	if today {
+		response, date, err = s.requestAstronomyToday(q, now)
+	} else {
+		response, err = s.requestAstronomy(q, date)
+	}
+	if err != nil {
+		return AstronomyData{}, err
+	}
+	data := formatAstronomyData(response, date)
+
+	// Cache the data until the end of the current day.
+	zone := time.UTC
+	if today {
+		zone = timezoneOrUTC(response.Location.TzID)
+	}
+	local := now.In(zone)
+	endOfDay := time.Date(local.Year(), local.Month(), local.Day()+1, 0, 0, 0, 0, zone)
+	if jsonData, err := json.Marshal(data); err == nil && s.redisClient.configured() {
+		if err := s.redisClient.Set(context.Background(), s.redisClient.prefixed(key), jsonData, endOfDay.Sub(now)).Err(); err != nil {
+			log.Printf("failed to cache astronomy data for %s: %v", key, err)
+		}
+	}
+
+	// Country-level allowlist entries can only be checked once the location is resolved.
+	if !s.allowlist.allowsLocation(q, FormattedWeatherData{Name: data.Name, Country: data.Country}) {
+		return AstronomyData{}, ErrLocationNotAllowed
+	}
+
+	return s.attributeAstronomy(data), nil
+}
+
+// requestAstronomyToday requests the astronomy data of the current date at the location and returns it with that date.
+// The time zone is taken from the cached weather data of the location when there is some. Otherwise the UTC date
+// is requested first, and, if the response reveals that the date at the location is another one, that date is
+// requested again, which only happens for the hours around midnight in which the two dates differ.
+func (s *WeatherAPIService) requestAstronomyToday(q string, now time.Time) (astronomyResponse, string, error) {
+	if weather, err := readCachedWeatherData(s.redisClient, weatherCacheKey(q)); err == nil && weather.TzID != "" {
+		date := now.In(timezoneOrUTC(weather.TzID)).Format(AstronomyDateLayout)
+		response, err := s.requestAstronomy(q, date)
+		return response, date, err
+	}
+
+	date := now.UTC().Format(AstronomyDateLayout)
+	response, err := s.requestAstronomy(q, date)
+	if err != nil {
+		return astronomyResponse{}, "", err
+	}
+	if localDate := now.In(timezoneOrUTC(response.Location.TzID)).Format(AstronomyDateLayout); localDate != date {
+		response, err = s.requestAstronomy(q, localDate)
+		return response, localDate, err
+	}
+	return response, date, nil
+}
+
+// requestAstronomy requests the astronomy data of a location on a date (YYYY-MM-DD) from the weather API.
+func (s *WeatherAPIService) requestAstronomy(q, date string) (astronomyResponse, error) {
+	apiURL := fmt.Sprintf("%s/astronomy.json?key=%s&q=%s&dt=%s", s.cfg.WeatherAPIBaseURL, s.cfg.WeatherAPIKey, url.QueryEscape(q), date)
+	resBody, err := s.requestToWeatherApi(context.Background(), apiURL)
+	if err != nil {
+		return astronomyResponse{}, err
+	}
+
+	// Parse the response body.
+	var response astronomyResponse
+	if err := json.Unmarshal(resBody, &response); err != nil {
+		if _, ok := err.(*json.SyntaxError); ok {
+			return astronomyResponse{}, ErrUnexpectedEndOfJSONInput
+		}
+		return astronomyResponse{}, fmt.Errorf("error occurred while unmarshaling astronomy JSON: %w", err)
+	}
+	if strings.TrimSpace(response.Location.Name) == "" {
+		return astronomyResponse{}, fmt.Errorf("%w: astronomy response has no location name", ErrUpstreamUnavailable)
+	}
+	return response, nil
+}
+
+// formatAstronomyData flattens WeatherAPI's astronomy response into AstronomyData.
+func formatAstronomyData(response astronomyResponse, date string) AstronomyData {
+	astro := response.Astronomy.Astro
+
+	// The illumination is informative only, so an unparsable value is reported as 0.
+	illumination, _ := astro.MoonIllumination.Int64()
+
+	return AstronomyData{
+		Name:             response.Location.Name,
+		Region:           response.Location.Region,
+		Country:          response.Location.Country,
+		Lat:              response.Location.Lat,
+		Lon:              response.Location.Lon,
+		TzID:             response.Location.TzID,
+		Date:             date,
+		Sunrise:          astro.Sunrise,
+		Sunset:           astro.Sunset,
+		Moonrise:         astro.Moonrise,
+		Moonset:          astro.Moonset,
+		MoonPhase:        astro.MoonPhase,
+		MoonIllumination: int(illumination),
+	}
+}
+
+// attributeAstronomy sets the data source attribution on the astronomy data, like attribute does for weather data.
+func (s *WeatherAPIService) attributeAstronomy(data AstronomyData) AstronomyData {
+	data.Source = s.cfg.Attribution
+	return data
+}
+
+// astronomyCacheKey derives the Redis key under which astronomy data for a location and date is stored.
+func astronomyCacheKey(location, date string) string {
+	return "astronomy:" + date + ":" + capitalizeFirstLetter(strings.TrimSpace(location))
+}
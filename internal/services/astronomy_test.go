@@ -0,0 +1,130 @@
+package services
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// astronomyUpstream answers astronomy.json requests for locations in the given time zones, recording the dates requested.
+type astronomyUpstream struct {
+	mu    sync.Mutex
+	dates []string
+}
+
+func (u *astronomyUpstream) handler(zones map[string]string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasSuffix(r.URL.Path, "/astronomy.json") {
+			writeCurrentWeather(w, r.URL.Query().Get("q"), 20)
+			return
+		}
+		u.mu.Lock()
+		u.dates = append(u.dates, r.URL.Query().Get("dt"))
+		u.mu.Unlock()
+
+		var response astronomyResponse
+		response.Location.Name = r.URL.Query().Get("q")
+		response.Location.TzID = zones[response.Location.Name]
+		response.Astronomy.Astro.Sunrise = "06:00 AM"
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(response)
+	}
+}
+
+func (u *astronomyUpstream) requested() []string {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return append([]string(nil), u.dates...)
+}
+
+func TestFetchAstronomyDataDefaultsToTodayAtTheLocation(t *testing.T) {
+	// 20:00 UTC on March 14th is already March 15th in Tokyo, and still March 14th in London
+	evening := time.Date(2026, 3, 14, 20, 0, 0, 0, time.UTC)
+	zones := map[string]string{"Tokyo": "Asia/Tokyo", "London": "Europe/London"}
+
+	tests := []struct {
+		name          string
+		location      string
+		cacheWeather  bool
+		wantDate      string
+		wantRequested []string
+		wantTTL       time.Duration
+	}{
+		{
+			name:          "ahead of UTC, time zone unknown",
+			location:      "Tokyo",
+			wantDate:      "2026-03-15",
+			wantRequested: []string{"2026-03-14", "2026-03-15"},
+			wantTTL:       19 * time.Hour, // until midnight in Tokyo, 15:00 UTC on March 15th
+		},
+		{
+			name:          "ahead of UTC, time zone of the cached weather",
+			location:      "Tokyo",
+			cacheWeather:  true,
+			wantDate:      "2026-03-15",
+			wantRequested: []string{"2026-03-15"},
+			wantTTL:       19 * time.Hour,
+		},
+		{
+			name:          "same date as UTC",
+			location:      "London",
+			wantDate:      "2026-03-14",
+			wantRequested: []string{"2026-03-14"},
+			wantTTL:       4 * time.Hour,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts := newTestService(t, nil)
+			ts.clk.Set(evening)
+			ts.redis.SetTime(evening)
+			upstream := &astronomyUpstream{}
+			ts.upstream.handle(upstream.handler(zones))
+
+			if tt.cacheWeather {
+				weather := FormattedWeatherData{Name: tt.location, TzID: zones[tt.location]}
+				data, _ := json.Marshal(weather)
+				ts.redis.Set(weatherCacheKey(tt.location), string(data))
+			}
+
+			data, err := ts.FetchAstronomyData(tt.location, "")
+			if err != nil {
+				t.Fatalf("FetchAstronomyData failed: %v", err)
+			}
+			if data.Date != tt.wantDate {
+				t.Errorf("date = %s, want %s", data.Date, tt.wantDate)
+			}
+			if got := upstream.requested(); strings.Join(got, ",") != strings.Join(tt.wantRequested, ",") {
+				t.Errorf("requested dates %v, want %v", got, tt.wantRequested)
+			}
+			if got := ts.redis.TTL(astronomyCacheKey(tt.location, astronomyTodayKey)); got != tt.wantTTL {
+				t.Errorf("cached for %v, want %v", got, tt.wantTTL)
+			}
+
+			// The next request for today is served from the cache
+			if _, err := ts.FetchAstronomyData(tt.location, ""); err != nil {
+				t.Fatalf("second FetchAstronomyData failed: %v", err)
+			}
+			if got := len(upstream.requested()); got != len(tt.wantRequested) {
+				t.Errorf("upstream received %d astronomy requests after a cached lookup, want %d", got, len(tt.wantRequested))
+			}
+		})
+	}
+}
+
+func TestFetchAstronomyDataForAnExplicitDate(t *testing.T) {
+	ts := newTestService(t, nil)
+	upstream := &astronomyUpstream{}
+	ts.upstream.handle(upstream.handler(map[string]string{"Tokyo": "Asia/Tokyo"}))
+
+	data, err := ts.FetchAstronomyData("Tokyo", "2026-01-20")
+	if err != nil {
+		t.Fatalf("FetchAstronomyData failed: %v", err)
+	}
+	if data.Date != "2026-01-20" || strings.Join(upstream.requested(), ",") != "2026-01-20" {
+		t.Errorf("date = %s after requesting %v, want only 2026-01-20", data.Date, upstream.requested())
+	}
+}
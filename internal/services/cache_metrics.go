@@ -0,0 +1,128 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// cacheFootprintSampleSize is the number of weather keys whose memory usage is measured per collection.
+// The total is extrapolated from the sample, so measuring the memory costs the same few commands however big the cache is.
+const cacheFootprintSampleSize = 50
+
+// CacheFootprint describes the size of the weather cache, for operators sizing Redis.
+// A number of keys far above the number of distinct locations served points at fragmented cache keys.
+type CacheFootprint struct {
+	Keys           int64     // Keys is the number of weather keys in Redis.
+	SampledKeys    int       // SampledKeys is the number of keys whose memory usage was measured.
+	EstimatedBytes int64     // EstimatedBytes extrapolates the sampled memory usage to all keys; -1 if Redis can't report it.
+	CollectedAt    time.Time // CollectedAt is when the footprint was measured.
+}
+
+// cacheFootprintCache keeps the last measured footprint, so metric scrapes never scan Redis themselves.
+type cacheFootprintCache struct {
+	mu        sync.Mutex
+	last      CacheFootprint
+	err       error         // err is the error of the last measurement, if it failed.
+	measuring chan struct{} // measuring is closed when the measurement in progress ends; nil if none is running.
+}
+
+// CacheFootprint reports the number of weather keys and their approximate memory usage.
+// It returns the last measurement without waiting, and starts a new one in the background once the last one
+// is older than the configured CACHE_METRICS_INTERVAL, so that at most one SCAN runs at a time however often
+// it is called. Only the very first call waits for a measurement. If the last measurement failed, its error is returned.
+// With the cache disabled, the cache is reported as empty without asking Redis.
+func (s *WeatherAPIService) CacheFootprint(ctx context.Context) (CacheFootprint, error) {
+	if !s.cfg.CacheEnabled {
+		return CacheFootprint{CollectedAt: s.clk.Now()}, nil
+	}
+
+	s.footprint.mu.Lock()
+	stale := s.footprint.last.CollectedAt.IsZero() || s.clk.Now().Sub(s.footprint.last.CollectedAt) >= s.cfg.CacheMetricsInterval
+	if (stale || s.footprint.err != nil) && s.footprint.measuring == nil {
+		s.footprint.measuring = make(chan struct{})
+		go s.remeasureCacheFootprint(s.footprint.measuring)
+	}
+	last, err, measuring := s.footprint.last, s.footprint.err, s.footprint.measuring
+	s.footprint.mu.Unlock()
+
+	if err != nil {
+		return CacheFootprint{}, err
+	}
+	if !last.CollectedAt.IsZero() {
+		return last, nil
+	}
+
+	// Nothing was measured yet: wait for the first measurement rather than reporting an empty cache
+	select {
+	case <-measuring:
+	case <-ctx.Done():
+		return CacheFootprint{}, ctx.Err()
+	}
+	s.footprint.mu.Lock()
+	defer s.footprint.mu.Unlock()
+	return s.footprint.last, s.footprint.err
+}
+
+// remeasureCacheFootprint measures the weather cache and stores the result for CacheFootprint, then closes done.
+// It is detached from the request that started it, so a scrape timing out doesn't waste the scan.
+func (s *WeatherAPIService) remeasureCacheFootprint(done chan struct{}) {
+	defer close(done)
+	footprint, err := s.measureCacheFootprint(context.Background())
+
+	s.footprint.mu.Lock()
+	defer s.footprint.mu.Unlock()
+	if err != nil {
+		s.footprint.err = err
+	} else {
+		s.footprint.last, s.footprint.err = footprint, nil
+	}
+	s.footprint.measuring = nil
+}
+
+// measureCacheFootprint counts the weather keys with SCAN, keeping a uniform random sample of them
+// (reservoir sampling), and extrapolates the memory usage of the sample to the whole cache.
+func (s *WeatherAPIService) measureCacheFootprint(ctx context.Context) (CacheFootprint, error) {
+	footprint := CacheFootprint{EstimatedBytes: -1, CollectedAt: s.clk.Now()}
+
+	sample := make([]string, 0, cacheFootprintSampleSize)
+	iter := s.redisClient.Scan(ctx, 0, s.redisClient.prefixed("weather:*"), 500).Iterator()
+	for iter.Next(ctx) {
+		footprint.Keys++
+		if len(sample) < cacheFootprintSampleSize {
+			sample = append(sample, iter.Val())
+		} else if i := rand.Int64N(footprint.Keys); i < cacheFootprintSampleSize {
+			sample[i] = iter.Val()
+		}
+	}
+	if err := iter.Err(); err != nil {
+		return CacheFootprint{}, fmt.Errorf("failed to scan weather cache keys: %w", err)
+	}
+
+	// MEMORY USAGE is missing on some managed Redis offerings; the key count is still worth reporting then.
+	var sampledBytes int64
+	for _, key := range sample {
+		bytes, err := s.redisClient.MemoryUsage(ctx, key).Result()
+		if err != nil {
+			// Keys may expire between the scan and the measurement; any other error means the command is unavailable.
+			if errors.Is(err, redis.Nil) {
+				continue
+			}
+			return footprint, nil
+		}
+		sampledBytes += bytes
+		footprint.SampledKeys++
+	}
+
+	if footprint.SampledKeys > 0 {
+		footprint.EstimatedBytes = sampledBytes * footprint.Keys / int64(footprint.SampledKeys)
+	} else if footprint.Keys == 0 {
+		footprint.EstimatedBytes = 0
+	}
+	return footprint, nil
+}
@@ -0,0 +1,86 @@
+package services
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+// waitForFootprint waits for the cache measurement running in the background, if any.
+func (ts *testService) waitForFootprint() {
+	ts.footprint.mu.Lock()
+	measuring := ts.footprint.measuring
+	ts.footprint.mu.Unlock()
+	if measuring != nil {
+		<-measuring
+	}
+}
+
+func TestCacheFootprintNeverScansOnEveryCall(t *testing.T) {
+	ts := newTestService(t, nil)
+	ts.redis.Set("weather:London", "{}")
+	ts.redis.Set("weather:Paris", "{}")
+	commands := ts.countCommands()
+
+	// Concurrent first calls share a single measurement
+	var wg sync.WaitGroup
+	for range 10 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			footprint, err := ts.CacheFootprint(context.Background())
+			if err != nil || footprint.Keys != 2 {
+				t.Errorf("CacheFootprint() = %+v, %v, want 2 keys", footprint, err)
+			}
+		}()
+	}
+	wg.Wait()
+	if got := commands.count("scan"); got != 1 {
+		t.Fatalf("10 concurrent calls ran %d scans, want 1", got)
+	}
+
+	// Within the interval, the measurement is reused as is
+	ts.redis.Set("weather:Tashkent", "{}")
+	for range 10 {
+		if _, err := ts.CacheFootprint(context.Background()); err != nil {
+			t.Fatalf("CacheFootprint failed: %v", err)
+		}
+	}
+	if got := commands.count("scan"); got != 1 {
+		t.Fatalf("calls within the interval ran %d scans, want 1", got)
+	}
+
+	// Once the interval is over, the old measurement is returned at once while a new one runs in the background
+	ts.advance(time.Minute)
+	footprint, err := ts.CacheFootprint(context.Background())
+	if err != nil || footprint.Keys != 2 {
+		t.Errorf("CacheFootprint() after the interval = %+v, %v, want the previous 2 keys", footprint, err)
+	}
+	ts.waitForFootprint()
+	footprint, err = ts.CacheFootprint(context.Background())
+	if err != nil || footprint.Keys != 3 || !footprint.CollectedAt.Equal(ts.clk.Now()) {
+		t.Errorf("CacheFootprint() after the new measurement = %+v, %v, want 3 keys measured now", footprint, err)
+	}
+	if got := commands.count("scan"); got != 2 {
+		t.Errorf("scans = %d, want 2", got)
+	}
+}
+
+func TestCacheFootprintReportsAFailedMeasurement(t *testing.T) {
+	ts := newTestService(t, nil)
+	if _, err := ts.CacheFootprint(context.Background()); err != nil {
+		t.Fatalf("CacheFootprint failed: %v", err)
+	}
+
+	// Redis goes away: the next measurement fails, and so do the calls after it until one succeeds
+	ts.redis.Close()
+	ts.advance(time.Minute)
+	if _, err := ts.CacheFootprint(context.Background()); err != nil {
+		t.Fatalf("CacheFootprint() while measuring in the background = %v, want the previous measurement", err)
+	}
+	ts.waitForFootprint()
+	if _, err := ts.CacheFootprint(context.Background()); err == nil {
+		t.Error("CacheFootprint() after a failed measurement succeeded, want its error")
+	}
+}
@@ -0,0 +1,112 @@
+package services
+
+import (
+	"errors"
+	"net/http"
+	"sync"
+	"testing"
+)
+
+// refreshOnly limits the cache refresh to the given locations for the duration of a test.
+func refreshOnly(t *testing.T, locations ...string) {
+	t.Helper()
+	previous := refreshedLocations
+	refreshedLocations = locations
+	t.Cleanup(func() { refreshedLocations = previous })
+}
+
+func TestUpdateWeatherDataInTheRedisCacheNeverOverlaps(t *testing.T) {
+	refreshOnly(t, "London")
+	ts := newTestService(t, nil)
+
+	// Hold the first refresh in its upstream request until the concurrent ones are done
+	started := make(chan struct{})
+	release := make(chan struct{})
+	ts.upstream.handle(func(w http.ResponseWriter, r *http.Request) {
+		if ts.upstream.count() == 1 {
+			close(started)
+			<-release
+		}
+		writeCurrentWeather(w, r.URL.Query().Get("q"), 20)
+	})
+
+	first := make(chan error)
+	go func() { first <- ts.UpdateWeatherDataInTheRedisCache(nil) }()
+	<-started
+
+	var wg sync.WaitGroup
+	errs := make([]error, 5)
+	for i := range errs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = ts.UpdateWeatherDataInTheRedisCache(nil)
+		}()
+	}
+	wg.Wait()
+	for i, err := range errs {
+		if !errors.Is(err, ErrCacheRefreshInProgress) {
+			t.Errorf("concurrent refresh %d returned %v, want ErrCacheRefreshInProgress", i, err)
+		}
+	}
+
+	close(release)
+	if err := <-first; err != nil {
+		t.Fatalf("first refresh failed: %v", err)
+	}
+	if got := ts.upstream.count(); got != 1 {
+		t.Errorf("upstream received %d requests, want 1 from the only refresh that ran", got)
+	}
+
+	// The guard is released once the refresh is over
+	if err := ts.UpdateWeatherDataInTheRedisCache(nil); err != nil {
+		t.Errorf("refresh after the first one finished failed: %v", err)
+	}
+}
+
+func TestWatchCacheRefresh(t *testing.T) {
+	refreshOnly(t, "London", "Paris")
+	ts := newTestService(t, nil)
+
+	if _, _, err := ts.WatchCacheRefresh(); !errors.Is(err, ErrNoCacheRefreshInProgress) {
+		t.Fatalf("WatchCacheRefresh() without a refresh returned %v, want ErrNoCacheRefreshInProgress", err)
+	}
+
+	// Hold the refresh in its first upstream request until the watcher has subscribed
+	started := make(chan struct{})
+	release := make(chan struct{})
+	ts.upstream.handle(func(w http.ResponseWriter, r *http.Request) {
+		if ts.upstream.count() == 1 {
+			close(started)
+			<-release
+		}
+		writeCurrentWeather(w, r.URL.Query().Get("q"), 20)
+	})
+	result := make(chan error)
+	go func() { result <- ts.UpdateWeatherDataInTheRedisCache(nil) }()
+	<-started
+
+	events, stop, err := ts.WatchCacheRefresh()
+	if err != nil {
+		t.Fatalf("WatchCacheRefresh() during a refresh failed: %v", err)
+	}
+	defer stop()
+	close(release)
+
+	// The watcher may skip progress, but always ends with the final counts
+	var last RefreshEvent
+	for event := range events {
+		last = event
+	}
+	if err := <-result; err != nil {
+		t.Fatalf("refresh failed: %v", err)
+	}
+	want := RefreshProgress{Location: "Paris", Done: 2, Total: 2, Succeeded: 2}
+	if !last.Done || last.Err != nil || last.Progress != want {
+		t.Errorf("last event = %+v, want the done event with %+v", last, want)
+	}
+
+	if _, _, err := ts.WatchCacheRefresh(); !errors.Is(err, ErrNoCacheRefreshInProgress) {
+		t.Errorf("WatchCacheRefresh() after the refresh returned %v, want ErrNoCacheRefreshInProgress", err)
+	}
+}
@@ -0,0 +1,169 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// maxTrackedCacheKeys bounds the number of cache keys with hit and miss counters of their own.
+// Keys beyond it still count towards the totals, so a flood of distinct queries can't grow the counters without limit.
+const maxTrackedCacheKeys = 10000
+
+// cacheStatsBatchSize is the number of cached entries whose TTL and data are read with a single pipeline.
+const cacheStatsBatchSize = 500
+
+// cacheKeyCounters counts the weather cache lookups of a single key.
+type cacheKeyCounters struct {
+	hits   uint64
+	misses uint64
+}
+
+// cacheStats counts the weather cache lookups of client requests since startup, in total and per cache key.
+// The counters live in memory, so every instance of the service reports its own traffic.
+type cacheStats struct {
+	mu     sync.Mutex
+	since  time.Time
+	hits   uint64
+	misses uint64
+	keys   map[string]*cacheKeyCounters
+}
+
+// newCacheStats creates empty counters starting at the given time.
+func newCacheStats(since time.Time) *cacheStats {
+	return &cacheStats{since: since, keys: make(map[string]*cacheKeyCounters)}
+}
+
+// record counts a lookup of the given cache key as a hit or a miss.
+func (c *cacheStats) record(key string, hit bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	counters, ok := c.keys[key]
+	if !ok && len(c.keys) < maxTrackedCacheKeys {
+		counters = &cacheKeyCounters{}
+		c.keys[key] = counters
+	}
+
+	if hit {
+		c.hits++
+		if counters != nil {
+			counters.hits++
+		}
+	} else {
+		c.misses++
+		if counters != nil {
+			counters.misses++
+		}
+	}
+}
+
+// CachedLocationStats describes a single entry of the weather cache.
+type CachedLocationStats struct {
+	Key                 string     `json:"key"`                   // Key is the cache key, without the configured prefix (e.g. "weather:london:lang=fr").
+	TTLRemainingSeconds int        `json:"ttl_remaining_seconds"` // TTLRemainingSeconds is how long the entry still lives.
+	CachedAt            *time.Time `json:"cached_at"`             // CachedAt is when the entry was last refreshed, as stored with it; nil for entries cached before the time was stored.
+	Hits                uint64     `json:"hits"`                  // Hits counts the lookups served from the entry since startup.
+	Misses              uint64     `json:"misses"`                // Misses counts the lookups that found the key missing since startup.
+}
+
+// CacheStats reports the traffic of the weather cache, for operators tuning the warm list and the cache TTL.
+type CacheStats struct {
+	Since     time.Time             `json:"since"`     // Since is when the counters started, i.e. the startup of the instance.
+	Hits      uint64                `json:"hits"`      // Hits counts all lookups served from the cache.
+	Misses    uint64                `json:"misses"`    // Misses counts all lookups that had to go to WeatherAPI.
+	Locations []CachedLocationStats `json:"locations"` // Locations lists every cached entry, most hit first.
+}
+
+// CacheStats lists every entry of the weather cache with its remaining TTL and last refresh time,
+// along with the hit and miss counts of this instance since startup. It scans all weather keys,
+// so it is meant for occasional admin use rather than frequent polling.
+// It returns ErrCacheDisabled when the cache is disabled.
+func (s *WeatherAPIService) CacheStats(ctx context.Context) (CacheStats, error) {
+	if !s.cfg.CacheEnabled {
+		return CacheStats{}, ErrCacheDisabled
+	}
+
+	// Read the TTL and the data of a batch of keys with a single round trip.
+	locations := []CachedLocationStats{}
+	collect := func(keys []string) error {
+		ttls := make([]*redis.DurationCmd, len(keys))
+		values := make([]*redis.StringCmd, len(keys))
+		_, err := s.redisClient.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+			for i, key := range keys {
+				ttls[i] = pipe.TTL(ctx, key)
+				values[i] = pipe.Get(ctx, key)
+			}
+			return nil
+		})
+		// A key expiring between the scan and the read reports redis.Nil, which is no failure.
+		if err != nil && !errors.Is(err, redis.Nil) {
+			return fmt.Errorf("failed to get cached weather data: %w", err)
+		}
+		for i, key := range keys {
+			ttl := ttls[i].Val()
+			if ttl <= 0 || values[i].Err() != nil {
+				// The entry expired between the scan and the read.
+				continue
+			}
+			location := CachedLocationStats{
+				Key:                 strings.TrimPrefix(key, s.redisClient.prefix),
+				TTLRemainingSeconds: int(ttl.Seconds()),
+			}
+			// The cache time is the one stored with the entry, which a changed CACHE_TTL can't shift.
+			if weatherData, err := decodeCachedWeatherData([]byte(values[i].Val())); err == nil && !weatherData.CachedAt.IsZero() {
+				cachedAt := weatherData.CachedAt.UTC().Truncate(time.Second)
+				location.CachedAt = &cachedAt
+			}
+			locations = append(locations, location)
+		}
+		return nil
+	}
+
+	var batch []string
+	iter := s.redisClient.Scan(ctx, 0, s.redisClient.prefixed("weather:*"), 500).Iterator()
+	for iter.Next(ctx) {
+		batch = append(batch, iter.Val())
+		if len(batch) == cacheStatsBatchSize {
+			if err := collect(batch); err != nil {
+				return CacheStats{}, err
+			}
+			batch = batch[:0]
+		}
+	}
+	if err := iter.Err(); err != nil {
+		return CacheStats{}, fmt.Errorf("failed to scan weather cache keys: %w", err)
+	}
+	if len(batch) > 0 {
+		if err := collect(batch); err != nil {
+			return CacheStats{}, err
+		}
+	}
+
+	// Attach the counters of every listed key.
+	s.stats.mu.Lock()
+	stats := CacheStats{Since: s.stats.since.UTC(), Hits: s.stats.hits, Misses: s.stats.misses}
+	for i := range locations {
+		if counters, ok := s.stats.keys[locations[i].Key]; ok {
+			locations[i].Hits = counters.hits
+			locations[i].Misses = counters.misses
+		}
+	}
+	s.stats.mu.Unlock()
+
+	// List the hottest locations first; SCAN returns keys in no particular order.
+	sort.Slice(locations, func(i, j int) bool {
+		if locations[i].Hits != locations[j].Hits {
+			return locations[i].Hits > locations[j].Hits
+		}
+		return locations[i].Key < locations[j].Key
+	})
+	stats.Locations = locations
+	return stats, nil
+}
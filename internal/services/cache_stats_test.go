@@ -0,0 +1,57 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"havoAPI/api/config"
+	"testing"
+	"time"
+)
+
+func TestCacheStatsReportTheStoredCacheTime(t *testing.T) {
+	ts := newTestService(t, func(cfg *config.Config) {
+		cfg.CacheTTL = 30 * time.Minute
+	})
+	if _, err := ts.FetchWeatherData(context.Background(), "London", WeatherOptions{}); err != nil {
+		t.Fatalf("FetchWeatherData failed: %v", err)
+	}
+	// An entry cached before the time was stored has none to report
+	jsonData, err := json.Marshal(FormattedWeatherData{Name: "Paris"})
+	if err != nil {
+		t.Fatalf("json.Marshal failed: %v", err)
+	}
+	ts.redis.Set(weatherCacheKey("Paris"), string(jsonData))
+	ts.redis.SetTTL(weatherCacheKey("Paris"), time.Hour)
+
+	// A CACHE_TTL changed since the entry was written must not shift its cache time
+	ts.advance(10 * time.Minute)
+	ts.cfg.CacheTTL = time.Hour
+
+	stats, err := ts.CacheStats(context.Background())
+	if err != nil {
+		t.Fatalf("CacheStats failed: %v", err)
+	}
+	locations := make(map[string]CachedLocationStats)
+	for _, location := range stats.Locations {
+		locations[location.Key] = location
+	}
+
+	london, ok := locations[weatherCacheKey("London")]
+	if !ok {
+		t.Fatalf("locations = %+v, want London listed", stats.Locations)
+	}
+	if london.CachedAt == nil || !london.CachedAt.Equal(testNow) {
+		t.Errorf("cached_at of London = %v, want %v", london.CachedAt, testNow)
+	}
+	if want := int((20 * time.Minute).Seconds()); london.TTLRemainingSeconds != want {
+		t.Errorf("ttl_remaining_seconds of London = %d, want %d", london.TTLRemainingSeconds, want)
+	}
+
+	paris, ok := locations[weatherCacheKey("Paris")]
+	if !ok {
+		t.Fatalf("locations = %+v, want Paris listed", stats.Locations)
+	}
+	if paris.CachedAt != nil {
+		t.Errorf("cached_at of an entry without a stored time = %v, want none", paris.CachedAt)
+	}
+}
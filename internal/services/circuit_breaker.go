@@ -0,0 +1,126 @@
+package services
+
+import (
+	"fmt"
+	"havoAPI/internal/clock"
+	"log"
+	"sync"
+	"time"
+)
+
+// ErrUpstreamCircuitOpen is returned without contacting WeatherAPI while the circuit breaker is open.
+// It wraps ErrUpstreamUnavailable, so callers serve stale data or respond with 503 as for any outage.
+var ErrUpstreamCircuitOpen = fmt.Errorf("%w: circuit breaker is open", ErrUpstreamUnavailable)
+
+// CircuitState is the state of the circuit breaker guarding WeatherAPI, as reported by the metrics endpoint.
+type CircuitState int
+
+// States of the circuit breaker.
+const (
+	CircuitClosed   CircuitState = 0 // CircuitClosed lets every request through.
+	CircuitHalfOpen CircuitState = 1 // CircuitHalfOpen lets a single probe through after the cooldown.
+	CircuitOpen     CircuitState = 2 // CircuitOpen fails every request fast until the cooldown has passed.
+)
+
+// circuitBreaker stops sending requests to WeatherAPI after a number of consecutive transient failures,
+// so that an outage fails requests at once instead of making each of them wait for its timeouts and retries.
+// Once the cooldown has passed, a single probe request is let through: its success closes the circuit again,
+// its failure reopens it for another cooldown. A threshold of 0 disables the breaker.
+type circuitBreaker struct {
+	mu        sync.Mutex
+	state     CircuitState
+	failures  int       // failures counts the consecutive transient failures.
+	openedAt  time.Time // openedAt is when the circuit last opened.
+	probing   bool      // probing is set while the probe of a half-open circuit is in flight.
+	threshold int
+	cooldown  time.Duration
+	clk       clock.Clock
+}
+
+// newCircuitBreaker creates a closed circuit breaker opening after threshold consecutive failures for cooldown.
+func newCircuitBreaker(threshold int, cooldown time.Duration, clk clock.Clock) *circuitBreaker {
+	return &circuitBreaker{threshold: threshold, cooldown: cooldown, clk: clk}
+}
+
+// allow reports whether a request may be sent. Every allowed request must be followed by record or release.
+func (b *circuitBreaker) allow() bool {
+	if b.threshold == 0 {
+		return true
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case CircuitOpen:
+		if b.clk.Now().Sub(b.openedAt) < b.cooldown {
+			return false
+		}
+		// The cooldown has passed: this request becomes the probe.
+		b.state = CircuitHalfOpen
+		b.probing = true
+		return true
+	case CircuitHalfOpen:
+		// Only one probe at a time; the others keep failing fast until it has answered.
+		if b.probing {
+			return false
+		}
+		b.probing = true
+		return true
+	}
+	return true
+}
+
+// record reports the outcome of an allowed request. Only transient failures (network errors, 5xx and 429)
+// count: any other answer, such as an unknown location, shows that WeatherAPI is up.
+func (b *circuitBreaker) record(failed bool) {
+	if b.threshold == 0 {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.probing = false
+
+	if !failed {
+		if b.state != CircuitClosed {
+			log.Println("WeatherAPI circuit breaker closed, the probe request succeeded")
+		}
+		b.state = CircuitClosed
+		b.failures = 0
+		return
+	}
+
+	b.failures++
+	if b.state == CircuitHalfOpen || b.failures >= b.threshold {
+		if b.state != CircuitOpen {
+			log.Printf("WARN: WeatherAPI circuit breaker opened after %d consecutive failures, failing fast for %v", b.failures, b.cooldown)
+		}
+		b.state = CircuitOpen
+		b.openedAt = b.clk.Now()
+	}
+}
+
+// release ends an allowed request that tells nothing about WeatherAPI's health, e.g. one canceled by its client,
+// so that a half-open circuit can send another probe.
+func (b *circuitBreaker) release() {
+	if b.threshold == 0 {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.probing = false
+}
+
+// snapshot returns the current state and number of consecutive failures.
+func (b *circuitBreaker) snapshot() (CircuitState, int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.state, b.failures
+}
+
+// UpstreamCircuit reports the state of the circuit breaker guarding WeatherAPI and its number of consecutive failures.
+func (s *WeatherAPIService) UpstreamCircuit() (CircuitState, int) {
+	return s.breaker.snapshot()
+}
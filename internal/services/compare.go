@@ -0,0 +1,89 @@
+package services
+
+import (
+	"math"
+	"slices"
+)
+
+// Metrics a weather comparison can be sorted by.
+const (
+	CompareByTemp = "temp_c"   // CompareByTemp sorts locations by temperature.
+	CompareByWind = "wind_kph" // CompareByWind sorts locations by wind speed.
+)
+
+// ValidCompareMetric reports whether locations can be compared by the metric.
+func ValidCompareMetric(metric string) bool {
+	return metric == CompareByTemp || metric == CompareByWind
+}
+
+// MetricSummary holds simple aggregates of a metric across the compared locations.
+type MetricSummary struct {
+	Min float64 `json:"min"` // Min is the lowest value.
+	Max float64 `json:"max"` // Max is the highest value.
+	Avg float64 `json:"avg"` // Avg is the mean value, rounded to one decimal.
+}
+
+// WeatherComparison holds the weather of several locations sorted by a metric, with aggregates of the main metrics.
+type WeatherComparison struct {
+	Sort      string                 `json:"sort"`                // Sort is the metric the locations are sorted by, highest first.
+	Locations []FormattedWeatherData `json:"locations"`           // Locations holds the weather data of every found location, sorted.
+	TempC     *MetricSummary         `json:"temp_c"`              // TempC summarizes the temperatures; null when no location was found.
+	WindKph   *MetricSummary         `json:"wind_kph"`            // WindKph summarizes the wind speeds; null when no location was found.
+	NotFound  []string               `json:"not_found,omitempty"` // NotFound lists the locations that were not found.
+}
+
+// CompareWeatherData sorts the weather data of several locations by the metric, highest first, and computes
+// the min, max and average temperature and wind speed. Locations with equal values keep their requested order.
+func CompareWeatherData(data []FormattedWeatherData, metric string) WeatherComparison {
+	value := compareMetricValue(metric)
+
+	sorted := slices.Clone(data)
+	if sorted == nil {
+		sorted = []FormattedWeatherData{}
+	}
+	slices.SortStableFunc(sorted, func(a, b FormattedWeatherData) int {
+		// Descending order: the hottest or windiest location comes first
+		switch va, vb := value(a), value(b); {
+		case va > vb:
+			return -1
+		case va < vb:
+			return 1
+		default:
+			return 0
+		}
+	})
+
+	return WeatherComparison{
+		Sort:      metric,
+		Locations: sorted,
+		TempC:     summarizeMetric(sorted, compareMetricValue(CompareByTemp)),
+		WindKph:   summarizeMetric(sorted, compareMetricValue(CompareByWind)),
+	}
+}
+
+// compareMetricValue returns the accessor of the metric's value; unknown metrics fall back to the temperature.
+func compareMetricValue(metric string) func(FormattedWeatherData) float64 {
+	if metric == CompareByWind {
+		return func(d FormattedWeatherData) float64 { return d.WindKph }
+	}
+	return func(d FormattedWeatherData) float64 { return d.TempC }
+}
+
+// summarizeMetric computes the aggregates of a metric, or returns nil if there is no data.
+func summarizeMetric(data []FormattedWeatherData, value func(FormattedWeatherData) float64) *MetricSummary {
+	if len(data) == 0 {
+		return nil
+	}
+
+	summary := MetricSummary{Min: math.Inf(1), Max: math.Inf(-1)}
+	var sum float64
+	for _, d := range data {
+		v := value(d)
+		summary.Min = min(summary.Min, v)
+		summary.Max = max(summary.Max, v)
+		sum += v
+	}
+	summary.Avg = math.Round(sum/float64(len(data))*10) / 10
+
+	return &summary
+}
@@ -0,0 +1,95 @@
+package services
+
+import "testing"
+
+// weatherOf returns weather data of a named location with the given temperature and wind speed.
+func weatherOf(name string, tempC, windKph float64) FormattedWeatherData {
+	return FormattedWeatherData{Name: name, TempC: tempC, WindKph: windKph}
+}
+
+// names returns the location names of the weather data, in order.
+func names(data []FormattedWeatherData) []string {
+	result := make([]string, len(data))
+	for i, d := range data {
+		result[i] = d.Name
+	}
+	return result
+}
+
+func TestCompareWeatherDataSorts(t *testing.T) {
+	data := []FormattedWeatherData{
+		weatherOf("London", 12, 30),
+		weatherOf("Tokyo", 24.5, 8),
+		weatherOf("Oslo", -3, 30),
+		weatherOf("Cairo", 24.5, 15),
+	}
+
+	tests := []struct {
+		metric string
+		want   []string
+	}{
+		// Tokyo and Cairo tie on temperature, London and Oslo on wind: both keep their requested order
+		{metric: CompareByTemp, want: []string{"Tokyo", "Cairo", "London", "Oslo"}},
+		{metric: CompareByWind, want: []string{"London", "Oslo", "Cairo", "Tokyo"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.metric, func(t *testing.T) {
+			comparison := CompareWeatherData(data, tt.metric)
+			if comparison.Sort != tt.metric {
+				t.Errorf("Sort = %q, want %q", comparison.Sort, tt.metric)
+			}
+			got := names(comparison.Locations)
+			for i := range tt.want {
+				if got[i] != tt.want[i] {
+					t.Fatalf("locations sorted as %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+
+	// The input keeps its order
+	if got := names(data); got[0] != "London" || got[3] != "Cairo" {
+		t.Errorf("CompareWeatherData reordered its input: %v", got)
+	}
+}
+
+func TestCompareWeatherDataAggregates(t *testing.T) {
+	comparison := CompareWeatherData([]FormattedWeatherData{
+		weatherOf("London", 12, 30),
+		weatherOf("Tokyo", 24.5, 8),
+		weatherOf("Oslo", -3, 10.2),
+	}, CompareByTemp)
+
+	tests := []struct {
+		name    string
+		summary *MetricSummary
+		want    MetricSummary
+	}{
+		// 33.5 / 3 = 11.1666... is rounded to one decimal
+		{name: "temp_c", summary: comparison.TempC, want: MetricSummary{Min: -3, Max: 24.5, Avg: 11.2}},
+		{name: "wind_kph", summary: comparison.WindKph, want: MetricSummary{Min: 8, Max: 30, Avg: 16.1}},
+	}
+	for _, tt := range tests {
+		if tt.summary == nil || *tt.summary != tt.want {
+			t.Errorf("%s summary = %+v, want %+v", tt.name, tt.summary, tt.want)
+		}
+	}
+}
+
+func TestCompareWeatherDataSingleLocation(t *testing.T) {
+	comparison := CompareWeatherData([]FormattedWeatherData{weatherOf("Oslo", -3, 10)}, CompareByWind)
+	want := MetricSummary{Min: -3, Max: -3, Avg: -3}
+	if comparison.TempC == nil || *comparison.TempC != want {
+		t.Errorf("temp_c summary = %+v, want %+v", comparison.TempC, want)
+	}
+}
+
+func TestCompareWeatherDataWithoutLocations(t *testing.T) {
+	comparison := CompareWeatherData(nil, CompareByTemp)
+	if comparison.Locations == nil || len(comparison.Locations) != 0 {
+		t.Errorf("Locations = %#v, want an empty list rather than null", comparison.Locations)
+	}
+	if comparison.TempC != nil || comparison.WindKph != nil {
+		t.Errorf("summaries = %+v, %+v, want none without data", comparison.TempC, comparison.WindKph)
+	}
+}
@@ -0,0 +1,72 @@
+package services
+
+// Coarse categories of the WeatherAPI condition codes, for clients that don't need the granular conditions.
+const (
+	ConditionClear  = "clear"  // ConditionClear is a sunny or clear sky.
+	ConditionCloudy = "cloudy" // ConditionCloudy is a partly cloudy to overcast sky without precipitation.
+	ConditionFog    = "fog"    // ConditionFog is mist or fog.
+	ConditionRain   = "rain"   // ConditionRain is drizzle, rain or freezing rain, including showers.
+	ConditionSnow   = "snow"   // ConditionSnow is snow, sleet or ice pellets, including showers.
+	ConditionStorm  = "storm"  // ConditionStorm is any condition with thunder.
+)
+
+// conditionCategories maps every WeatherAPI condition code (see https://www.weatherapi.com/docs/weather_conditions.json)
+// to its coarse category. Sleet and ice pellets count as snow and freezing drizzle or rain as rain,
+// since clients mostly tell wet from frozen precipitation; anything with thunder is a storm.
+var conditionCategories = map[int]string{
+	1000: ConditionClear,  // Sunny / Clear
+	1003: ConditionCloudy, // Partly cloudy
+	1006: ConditionCloudy, // Cloudy
+	1009: ConditionCloudy, // Overcast
+	1030: ConditionFog,    // Mist
+	1063: ConditionRain,   // Patchy rain possible
+	1066: ConditionSnow,   // Patchy snow possible
+	1069: ConditionSnow,   // Patchy sleet possible
+	1072: ConditionRain,   // Patchy freezing drizzle possible
+	1087: ConditionStorm,  // Thundery outbreaks possible
+	1114: ConditionSnow,   // Blowing snow
+	1117: ConditionSnow,   // Blizzard
+	1135: ConditionFog,    // Fog
+	1147: ConditionFog,    // Freezing fog
+	1150: ConditionRain,   // Patchy light drizzle
+	1153: ConditionRain,   // Light drizzle
+	1168: ConditionRain,   // Freezing drizzle
+	1171: ConditionRain,   // Heavy freezing drizzle
+	1180: ConditionRain,   // Patchy light rain
+	1183: ConditionRain,   // Light rain
+	1186: ConditionRain,   // Moderate rain at times
+	1189: ConditionRain,   // Moderate rain
+	1192: ConditionRain,   // Heavy rain at times
+	1195: ConditionRain,   // Heavy rain
+	1198: ConditionRain,   // Light freezing rain
+	1201: ConditionRain,   // Moderate or heavy freezing rain
+	1204: ConditionSnow,   // Light sleet
+	1207: ConditionSnow,   // Moderate or heavy sleet
+	1210: ConditionSnow,   // Patchy light snow
+	1213: ConditionSnow,   // Light snow
+	1216: ConditionSnow,   // Patchy moderate snow
+	1219: ConditionSnow,   // Moderate snow
+	1222: ConditionSnow,   // Patchy heavy snow
+	1225: ConditionSnow,   // Heavy snow
+	1237: ConditionSnow,   // Ice pellets
+	1240: ConditionRain,   // Light rain shower
+	1243: ConditionRain,   // Moderate or heavy rain shower
+	1246: ConditionRain,   // Torrential rain shower
+	1249: ConditionSnow,   // Light sleet showers
+	1252: ConditionSnow,   // Moderate or heavy sleet showers
+	1255: ConditionSnow,   // Light snow showers
+	1258: ConditionSnow,   // Moderate or heavy snow showers
+	1261: ConditionSnow,   // Light showers of ice pellets
+	1264: ConditionSnow,   // Moderate or heavy showers of ice pellets
+	1273: ConditionStorm,  // Patchy light rain with thunder
+	1276: ConditionStorm,  // Moderate or heavy rain with thunder
+	1279: ConditionStorm,  // Patchy light snow with thunder
+	1282: ConditionStorm,  // Moderate or heavy snow with thunder
+}
+
+// conditionCategory classifies a WeatherAPI condition code into a coarse category such as "rain".
+// Unknown codes (including a missing code) return an empty string, so the category is left out of the response
+// rather than guessed.
+func conditionCategory(code int) string {
+	return conditionCategories[code]
+}
@@ -0,0 +1,53 @@
+package services
+
+import "testing"
+
+func TestConditionCategory(t *testing.T) {
+	tests := []struct {
+		code int
+		want string
+	}{
+		{1000, ConditionClear},  // Sunny / Clear
+		{1003, ConditionCloudy}, // Partly cloudy
+		{1009, ConditionCloudy}, // Overcast
+		{1030, ConditionFog},    // Mist
+		{1147, ConditionFog},    // Freezing fog is fog, not frozen precipitation
+		{1063, ConditionRain},   // Patchy rain possible
+		{1072, ConditionRain},   // Freezing drizzle counts as rain
+		{1201, ConditionRain},   // Moderate or heavy freezing rain
+		{1246, ConditionRain},   // Torrential rain shower
+		{1066, ConditionSnow},   // Patchy snow possible
+		{1069, ConditionSnow},   // Sleet counts as snow
+		{1117, ConditionSnow},   // Blizzard
+		{1237, ConditionSnow},   // Ice pellets count as snow
+		{1264, ConditionSnow},   // Moderate or heavy showers of ice pellets
+		{1087, ConditionStorm},  // Thundery outbreaks possible
+		{1273, ConditionStorm},  // Rain with thunder is a storm
+		{1282, ConditionStorm},  // Snow with thunder is a storm too
+		{0, ""},                 // A missing code is left out rather than guessed
+		{1001, ""},              // So is a code WeatherAPI doesn't document
+		{-1, ""},
+	}
+	for _, tt := range tests {
+		if got := conditionCategory(tt.code); got != tt.want {
+			t.Errorf("conditionCategory(%d) = %q, want %q", tt.code, got, tt.want)
+		}
+	}
+}
+
+func TestConditionCategoriesCoverEveryWeatherAPICode(t *testing.T) {
+	// The codes of https://www.weatherapi.com/docs/weather_conditions.json
+	codes := []int{
+		1000, 1003, 1006, 1009, 1030, 1063, 1066, 1069, 1072, 1087, 1114, 1117, 1135, 1147, 1150, 1153,
+		1168, 1171, 1180, 1183, 1186, 1189, 1192, 1195, 1198, 1201, 1204, 1207, 1210, 1213, 1216, 1219,
+		1222, 1225, 1237, 1240, 1243, 1246, 1249, 1252, 1255, 1258, 1261, 1264, 1273, 1276, 1279, 1282,
+	}
+	for _, code := range codes {
+		if conditionCategory(code) == "" {
+			t.Errorf("condition code %d has no category", code)
+		}
+	}
+	if len(conditionCategories) != len(codes) {
+		t.Errorf("%d condition codes are categorized, want the %d WeatherAPI documents", len(conditionCategories), len(codes))
+	}
+}
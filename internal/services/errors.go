@@ -1,22 +1,36 @@
 package services
 
-import "errors"
+import (
+	"errors"
+	"havoAPI/internal/models"
+)
+
+// Errors that also exist in the models package are declared as the same values as their models counterparts
+// rather than redefined, so that errors.Is holds across the handler, service and model layers
+// even where a model error reaches a handler without being mapped.
 
 // ErrUserNotFound is returned when the requested user cannot be found in the system.
 // This is typically used when a user attempts to log in with a non-existent account.
-var ErrUserNotFound = errors.New("services: User not found")
+var ErrUserNotFound = models.ErrUserNotFound
 
 // ErrUsernameExists is returned when an attempt is made to create a user with a username
 // that already exists in the database. This helps in enforcing unique usernames.
-var ErrUsernameExists = errors.New("services: Username already exists")
+var ErrUsernameExists = models.ErrDuplicatedUsername
+
+// ErrEmailExists is returned when an attempt is made to create a user with an email
+// that already belongs to another user.
+var ErrEmailExists = models.ErrDuplicatedEmail
+
+// ErrSchemaMissing is returned when the database tables don't exist because the migrations were never applied.
+var ErrSchemaMissing = models.ErrSchemaMissing
 
 // ErrInvalidUserCredentials is returned when the provided user credentials (username/password)
 // do not match any existing records in the system. It indicates failed authentication.
-var ErrInvalidUserCredentials = errors.New("services: Invalid user credentials")
+var ErrInvalidUserCredentials = errors.New("invalid user credentials")
 
 // ErrAPIKeyNotFound is returned when the provided API key does not exist in the database.
 // This can occur when a user provides an invalid or expired API key during authentication.
-var ErrAPIKeyNotFound = errors.New("models: API Key not found")
+var ErrAPIKeyNotFound = models.ErrAPIKeyNotFound
 
 // ErrNoLocationFound is returned when no matching location is found for a weather query.
 // This helps indicate that the location provided by the user does not exist or is not recognized.
@@ -29,3 +43,106 @@ var ErrUnexpectedEndOfJSONInput = errors.New("unexpected end of JSON input")
 // ErrNoDataCache is returned when a request for cached weather data cannot find any available data
 // for the specified location. This may happen if the data has expired or hasn't been cached yet.
 var ErrNoDataCache = errors.New("no data in cache for location")
+
+// ErrAnonymousAccessDisabled is returned when a request without an API key is made while ANONYMOUS_DAILY_LIMIT is 0.
+var ErrAnonymousAccessDisabled = errors.New("anonymous access is disabled")
+
+// ErrAnonymousLimitExceeded is returned when a client IP has used up its daily allowance of requests without an API key.
+var ErrAnonymousLimitExceeded = errors.New("daily limit of anonymous requests exceeded")
+
+// ErrAPIKeyAlreadyExists is returned when a user already has an API key and a second one
+// would violate the one-key-per-user constraint.
+var ErrAPIKeyAlreadyExists = models.ErrUserAPIKeyExists
+
+// ErrInvalidCoordinates is returned when a coordinate query ("lat,lon") has a latitude outside [-90, 90]
+// or a longitude outside [-180, 180]. It is detected before any upstream call is made.
+var ErrInvalidCoordinates = errors.New("invalid coordinates: latitude must be within [-90, 90] and longitude within [-180, 180]")
+
+// ErrMaxAgeTooShort is returned when a client asks for a cache tolerance (max_age) shorter than MIN_CLIENT_MAX_AGE.
+// It is detected before any cache lookup or upstream call is made.
+var ErrMaxAgeTooShort = errors.New("max_age is shorter than the minimum cache tolerance")
+
+// ErrInvalidIATACode is returned when an airport query ("iata:XXX") doesn't name a three-letter IATA code.
+// It is detected before any upstream call is made.
+var ErrInvalidIATACode = errors.New("invalid airport code: an IATA code must be exactly three letters (e.g. 'iata:DXB')")
+
+// ErrAPIKeyLimitReached is returned when a user who already owns MAX_API_KEYS_PER_USER keys asks for another one.
+var ErrAPIKeyLimitReached = errors.New("maximum number of API keys reached")
+
+// ErrAmbiguousAPIKeyPrefix is returned when an API key prefix given for deletion matches several of the user's keys.
+var ErrAmbiguousAPIKeyPrefix = models.ErrAmbiguousAPIKeyPrefix
+
+// ErrInvalidAPIKeyDeletion is returned when a bulk API key deletion names no key, too many keys or a blank identifier.
+var ErrInvalidAPIKeyDeletion = errors.New("invalid API key deletion")
+
+// ErrThresholdNotFound is returned when a user tries to access a threshold that does not exist
+// or that belongs to another user.
+var ErrThresholdNotFound = models.ErrThresholdNotFound
+
+// ErrInvalidThreshold is returned when a threshold uses an unknown metric or operator.
+// It is wrapped with a description of the offending field.
+var ErrInvalidThreshold = errors.New("invalid threshold")
+
+// ErrUpstreamUnauthorized is returned when WeatherAPI rejects the service's API key as missing or invalid
+// (error codes 1002 and 2006). It points to a misconfigured API_KEY_FOR_WEATHERAPI.
+var ErrUpstreamUnauthorized = errors.New("weatherapi rejected the service API key")
+
+// ErrUpstreamKeyDisabled is returned when the service's WeatherAPI key has been disabled (error code 2008).
+var ErrUpstreamKeyDisabled = errors.New("weatherapi API key has been disabled")
+
+// ErrUpstreamQuotaExceeded is returned when the service's WeatherAPI key has exceeded its monthly quota (error code 2007).
+var ErrUpstreamQuotaExceeded = errors.New("weatherapi monthly quota exceeded")
+
+// ErrUpstreamMissingQuery is returned when WeatherAPI reports that no location query was provided (error code 1003).
+var ErrUpstreamMissingQuery = errors.New("weatherapi: location query not provided")
+
+// ErrUpstreamInvalidRequest is returned when WeatherAPI reports that the request URL is invalid (error code 1005).
+var ErrUpstreamInvalidRequest = errors.New("weatherapi: invalid request url")
+
+// ErrUpstreamInternal is returned when WeatherAPI reports an internal application error (error code 9999).
+var ErrUpstreamInternal = errors.New("weatherapi internal error")
+
+// ErrUpstreamUnavailable is returned when WeatherAPI answers with an empty, oversized or obviously bogus body
+// (e.g. 200 with "{}" during an incident). Such a response is never cached or returned as weather data.
+var ErrUpstreamUnavailable = errors.New("weatherapi returned an unusable response")
+
+// ErrLocationNotAllowed is returned when a location is outside the configured allowlist.
+var ErrLocationNotAllowed = errors.New("location is not allowed on this service")
+
+// ErrGroupNotFound is returned when a location group does not exist for the user.
+var ErrGroupNotFound = models.ErrGroupNotFound
+
+// ErrInvalidGroup is returned when a location group has an invalid name or location list.
+// It is wrapped with a description of the offending field.
+var ErrInvalidGroup = errors.New("invalid location group")
+
+// ErrGroupLimitReached is returned when a user who already owns MAX_GROUPS_PER_USER groups saves a new one.
+var ErrGroupLimitReached = errors.New("maximum number of location groups reached")
+
+// ErrCacheRefreshInProgress is returned when a cache refresh is requested while the previous one is still running.
+var ErrCacheRefreshInProgress = errors.New("cache refresh already in progress")
+
+// ErrNoCacheRefreshInProgress is returned when following a cache refresh while none is running.
+var ErrNoCacheRefreshInProgress = errors.New("no cache refresh in progress")
+
+// ErrCacheDisabled is returned when a cache refresh is requested while caching is disabled (CACHE_ENABLED=false).
+var ErrCacheDisabled = errors.New("weather cache is disabled")
+
+// ErrRedisNotConfigured is returned by the commands of a Redis client without a server address (see NewRedisClient).
+var ErrRedisNotConfigured = errors.New("redis is not configured")
+
+// ErrInvalidUsageRange is returned when a usage query has a malformed, reversed or too long date range.
+// It is wrapped with a description of the offending parameter.
+var ErrInvalidUsageRange = errors.New("invalid usage date range")
+
+// ErrInvalidHistoryRange is returned when a history request has a malformed or too long date range,
+// or one outside the lookback window of the WeatherAPI plan. It is wrapped with a description of the problem.
+var ErrInvalidHistoryRange = errors.New("invalid history date range")
+
+// ErrInvalidPreferences is returned when a user tries to store an unsupported unit system or language.
+// It is wrapped with a description of the offending field.
+var ErrInvalidPreferences = errors.New("invalid preferences")
+
+// ErrInvalidScope is returned when an API key is requested with an unsupported scope.
+// It is wrapped with the offending scope.
+var ErrInvalidScope = errors.New("invalid API key scope")
@@ -0,0 +1,56 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// etagCacheKey derives the Redis key of the content hash of the weather data cached under key.
+// The hash is written together with the data and expires with it, so conditional requests can be
+// answered with a single GET of this small value instead of fetching and decoding the whole entry.
+func etagCacheKey(key string) string {
+	return "etag:" + key
+}
+
+// weatherContentHash returns the hash of weather data serialized as cached, used as its entity tag.
+// The data must be the JSON encoding of a FormattedWeatherData: its fields are encoded in declaration order
+// (and encoding/json sorts the keys of any map), so the same data always hashes to the same value.
+func weatherContentHash(jsonData []byte) string {
+	sum := sha256.Sum256(jsonData)
+	return hex.EncodeToString(sum[:16])
+}
+
+// CachedWeatherDataHash returns the content hash of the weather data cached for a location with the given options,
+// reading only its companion key. It returns ErrNoDataCache if no data is currently cached for the location.
+func (s *WeatherAPIService) CachedWeatherDataHash(q string, opts WeatherOptions) (string, error) {
+	// Use the same normalization and key derivation as the read and write paths.
+	q, err := normalizeQuery(q)
+	if err != nil {
+		return "", err
+	}
+	// Locations that can never pass the allowlist are never cached.
+	if !s.allowlist.mayAllowQuery(q) {
+		return "", ErrLocationNotAllowed
+	}
+	key := opts.cacheKey(q)
+
+	// Nothing is cached while caching is disabled.
+	if !s.cfg.CacheEnabled {
+		return "", ErrNoDataCache
+	}
+
+	hash, err := s.redisClient.Get(context.Background(), s.redisClient.prefixed(etagCacheKey(key))).Result()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return "", ErrNoDataCache
+		}
+		return "", fmt.Errorf("failed to get content hash from Redis: %w", err)
+	}
+
+	return hash, nil
+}
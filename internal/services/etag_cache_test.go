@@ -0,0 +1,98 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+// BenchmarkConditionalLookup compares answering a conditional request from the stored content hash alone
+// with fetching and decoding the whole cached entry to hash it again.
+func BenchmarkConditionalLookup(b *testing.B) {
+	ts := newTestService(b, nil)
+	if _, err := ts.FetchWeatherData(context.Background(), "London", WeatherOptions{}); err != nil {
+		b.Fatalf("FetchWeatherData failed: %v", err)
+	}
+
+	b.Run("hash only", func(b *testing.B) {
+		for range b.N {
+			if _, err := ts.CachedWeatherDataHash("London", WeatherOptions{}); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+
+	b.Run("full entry", func(b *testing.B) {
+		for range b.N {
+			if _, err := readCachedWeatherData(ts.redisClient, weatherCacheKey("London")); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func TestCachedWeatherDataHashMatchesTheCachedEntry(t *testing.T) {
+	ts := newTestService(t, nil)
+	data, err := ts.FetchWeatherData(context.Background(), "London", WeatherOptions{})
+	if err != nil {
+		t.Fatalf("FetchWeatherData failed: %v", err)
+	}
+
+	hash, err := ts.CachedWeatherDataHash("london", WeatherOptions{})
+	if err != nil {
+		t.Fatalf("CachedWeatherDataHash failed: %v", err)
+	}
+	cached, err := readCachedWeatherData(ts.redisClient, weatherCacheKey("London"))
+	if err != nil {
+		t.Fatalf("readCachedWeatherData failed: %v", err)
+	}
+	if hash == "" || hash != data.ContentHash || hash != cached.ContentHash {
+		t.Errorf("stored hash %q, fetched hash %q and hash of the cached entry %q differ", hash, data.ContentHash, cached.ContentHash)
+	}
+}
+
+func TestWeatherContentHashIsStable(t *testing.T) {
+	localTime := testNow.Add(5 * time.Hour)
+	tempF := 68.0
+	data := FormattedWeatherData{
+		Name: "London", Region: "City of London, Greater London", Country: "United Kingdom",
+		Lat: 51.52, Lon: -0.11, TzID: "Europe/London", LocalTime: &localTime, LastUpdated: testNow,
+		TempC: 20, TempF: &tempF, TempTrend: "rising", WindKph: 12.5, Cloud: 75, Humidity: 60,
+		Comfort: "comfortable", Condition: "Partly cloudy", ConditionCategory: "cloudy",
+		AirQuality: &AirQuality{CO: 230.3, PM2_5: 8.1, USEPAIndex: 1, GBDefra: 1},
+	}
+
+	// Every marshal of the same data, and of the data decoded from the cache, must hash alike
+	var want string
+	for i := range 100 {
+		jsonData, err := json.Marshal(data)
+		if err != nil {
+			t.Fatalf("json.Marshal failed: %v", err)
+		}
+		hash := weatherContentHash(jsonData)
+		if i == 0 {
+			want = hash
+		} else if hash != want {
+			t.Fatalf("marshal %d hashed to %q, want %q", i+1, hash, want)
+		}
+
+		data = FormattedWeatherData{}
+		if err := json.Unmarshal(jsonData, &data); err != nil {
+			t.Fatalf("json.Unmarshal failed: %v", err)
+		}
+	}
+
+	// Caching the same data again keeps the stored tag, although the entry records another cache time
+	ts := newTestService(t, nil)
+	for i := range 100 {
+		ts.advance(time.Second)
+		hash, err := ts.cacheTheWeatherDataToRedis(weatherCacheKey("London"), data)
+		if err != nil {
+			t.Fatalf("cacheTheWeatherDataToRedis failed: %v", err)
+		}
+		if hash != want {
+			t.Fatalf("caching %d stored hash %q, want %q", i+1, hash, want)
+		}
+	}
+}
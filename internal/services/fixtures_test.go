@@ -0,0 +1,242 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"havoAPI/api/config"
+	"havoAPI/internal/clock"
+	"havoAPI/internal/models"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+// testNow is the time the fake clock of the test services starts at.
+var testNow = time.Date(2026, 3, 14, 12, 0, 0, 0, time.UTC)
+
+// fakeUpstream stands in for WeatherAPI, counting the requests it receives.
+// By default it answers current.json with weather data named after the query.
+type fakeUpstream struct {
+	*httptest.Server
+
+	requests atomic.Int32
+
+	mu      sync.Mutex
+	queries []string
+	handler http.HandlerFunc
+}
+
+// newFakeUpstream starts a fake WeatherAPI that is closed when the test ends.
+func newFakeUpstream(t testing.TB) *fakeUpstream {
+	t.Helper()
+	upstream := &fakeUpstream{}
+	upstream.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		upstream.requests.Add(1)
+		upstream.mu.Lock()
+		upstream.queries = append(upstream.queries, r.URL.Query().Get("q"))
+		handler := upstream.handler
+		upstream.mu.Unlock()
+
+		if handler != nil {
+			handler(w, r)
+			return
+		}
+		writeCurrentWeather(w, r.URL.Query().Get("q"), 20)
+	}))
+	t.Cleanup(upstream.Close)
+	return upstream
+}
+
+// handle replaces the responses of the fake upstream.
+func (u *fakeUpstream) handle(handler http.HandlerFunc) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.handler = handler
+}
+
+// count returns the number of requests received so far.
+func (u *fakeUpstream) count() int {
+	return int(u.requests.Load())
+}
+
+// lastQuery returns the q parameter of the last request received.
+func (u *fakeUpstream) lastQuery() string {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if len(u.queries) == 0 {
+		return ""
+	}
+	return u.queries[len(u.queries)-1]
+}
+
+// writeCurrentWeather answers a current.json request with weather data for the named location.
+func writeCurrentWeather(w http.ResponseWriter, name string, tempC float64) {
+	var weather Weather
+	weather.Location.Name = name
+	weather.Location.Country = "Testland"
+	weather.Location.TzID = "UTC"
+	weather.Location.LocalTimeEpoch = testNow.Unix()
+	weather.Current.TempC = tempC
+	weather.Current.LastUpdatedEpoch = testNow.Unix()
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(weather)
+}
+
+// writeUpstreamError answers a request with a WeatherAPI error body.
+func writeUpstreamError(w http.ResponseWriter, status, code int) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	fmt.Fprintf(w, `{"error":{"code":%d,"message":"error %d"}}`, code, code)
+}
+
+// fakeAPIKeyDB is an in-memory api_keys table, counting the lookups it serves.
+type fakeAPIKeyDB struct {
+	mu      sync.Mutex
+	keys    map[string][]string
+	lookups int
+}
+
+// CheckUserAPIKey returns the scopes of a known key, or ErrAPIKeyNotFound.
+func (db *fakeAPIKeyDB) CheckUserAPIKey(apiKey string) ([]string, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	db.lookups++
+	scopes, ok := db.keys[apiKey]
+	if !ok {
+		return nil, models.ErrAPIKeyNotFound
+	}
+	return scopes, nil
+}
+
+// remove deletes a key, as disabling it in the database would.
+func (db *fakeAPIKeyDB) remove(apiKey string) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	delete(db.keys, apiKey)
+}
+
+// testConfig returns the settings of a test service talking to the given upstream, with the cache enabled.
+func testConfig(baseURL string) *config.Config {
+	return &config.Config{
+		WeatherAPIKey:              "upstream-secret",
+		WeatherAPIBaseURL:          baseURL,
+		WeatherAPITimeout:          5 * time.Second,
+		WeatherAPIUserAgent:        "havoAPI-test",
+		WeatherAPIMaxResponseBytes: 4 << 20,
+		WeatherAPIHistoryDays:      7,
+		UpstreamBreakerCooldown:    30 * time.Second,
+		Attribution:                "Powered by WeatherAPI.com",
+		CacheEnabled:               true,
+		CacheTTL:                   30 * time.Minute,
+		NegativeCacheTTL:           2 * time.Minute,
+		MinClientMaxAge:            time.Minute,
+		NearestCacheRadiusKm:       10,
+		CacheMetricsInterval:       time.Minute,
+		QuotaResetLocation:         time.UTC,
+	}
+}
+
+// testService bundles a WeatherAPIService with the fakes behind it.
+type testService struct {
+	*WeatherAPIService
+
+	upstream *fakeUpstream
+	redis    *miniredis.Miniredis
+	db       *fakeAPIKeyDB
+	clk      *clock.Fake
+}
+
+// newTestService creates a WeatherAPIService backed by an in-memory Redis, a fake upstream and a fake clock.
+// The configure function, if any, adjusts the settings before the service is created.
+func newTestService(t testing.TB, configure func(cfg *config.Config)) *testService {
+	t.Helper()
+	upstream := newFakeUpstream(t)
+	cfg := testConfig(upstream.URL)
+	if configure != nil {
+		configure(cfg)
+	}
+
+	mr := miniredis.RunT(t)
+	mr.SetTime(testNow)
+	redisClient := &RedisClient{Client: redis.NewClient(&redis.Options{Addr: mr.Addr()}), prefix: cfg.RedisKeyPrefix}
+	t.Cleanup(func() { redisClient.Close() })
+
+	db := &fakeAPIKeyDB{keys: map[string][]string{"valid-key": nil}}
+	clk := clock.NewFake(testNow)
+	return &testService{
+		WeatherAPIService: NewWeatherAPIService(db, redisClient, cfg, clk),
+		upstream:          upstream,
+		redis:             mr,
+		db:                db,
+		clk:               clk,
+	}
+}
+
+// advance moves both the service clock and the Redis clock, expiring entries whose TTL ran out.
+func (ts *testService) advance(d time.Duration) {
+	ts.clk.Advance(d)
+	ts.redis.SetTime(ts.clk.Now())
+	ts.redis.FastForward(d)
+}
+
+// commandCounter is a Redis hook counting the commands sent, by name, alone or in a pipeline.
+type commandCounter struct {
+	mu     sync.Mutex
+	counts map[string]int
+}
+
+// countCommands installs a commandCounter on the Redis client of the service.
+func (ts *testService) countCommands() *commandCounter {
+	counter := &commandCounter{counts: make(map[string]int)}
+	ts.redisClient.AddHook(counter)
+	return counter
+}
+
+// count returns the number of commands with the given name sent so far.
+func (cc *commandCounter) count(name string) int {
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+	return cc.counts[name]
+}
+
+// total returns the number of commands sent so far.
+func (cc *commandCounter) total() int {
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+	total := 0
+	for _, n := range cc.counts {
+		total += n
+	}
+	return total
+}
+
+func (cc *commandCounter) record(cmds ...redis.Cmder) {
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+	for _, cmd := range cmds {
+		cc.counts[cmd.Name()]++
+	}
+}
+
+func (cc *commandCounter) DialHook(next redis.DialHook) redis.DialHook { return next }
+
+func (cc *commandCounter) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		cc.record(cmd)
+		return next(ctx, cmd)
+	}
+}
+
+func (cc *commandCounter) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []redis.Cmder) error {
+		cc.record(cmds...)
+		return next(ctx, cmds)
+	}
+}
@@ -0,0 +1,140 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"havoAPI/internal/models"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// groupNamePattern restricts group names to URL-friendly slugs such as "european-offices".
+var groupNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,63}$`)
+
+// GroupsServiceInterface defines the methods for managing the named location groups of users.
+type GroupsServiceInterface interface {
+	// SaveGroup creates the named group for the user, or replaces its locations if it already exists.
+	// It returns an error wrapping ErrInvalidGroup if the name or a location is invalid, or if there are too many locations,
+	// and an error wrapping ErrGroupLimitReached if a new group would exceed the user's number of groups.
+	SaveGroup(userID int, name string, locations []string) (LocationGroup, error)
+
+	// ListGroups retrieves all groups of the user.
+	ListGroups(userID int) ([]LocationGroup, error)
+
+	// DeleteGroup deletes a group of the user.
+	// It returns ErrGroupNotFound if the user has no group with that name.
+	DeleteGroup(userID int, name string) error
+
+	// GroupLocations retrieves the locations of the named group owned by the user of the API key.
+	// It returns ErrGroupNotFound if the key's user has no group with that name.
+	GroupLocations(apiKey, name string) ([]string, error)
+}
+
+// GroupsService is a concrete implementation of the GroupsServiceInterface.
+type GroupsService struct {
+	// db is an instance of the DBContractGroups interface which handles group-related database operations.
+	db models.DBContractGroups
+
+	// maxLocations is the maximum number of locations in a single group.
+	maxLocations int
+
+	// maxGroups is the maximum number of groups a user can own.
+	maxGroups int
+}
+
+// NewGroupsService initializes a new instance of GroupsService accepting up to maxGroups groups per user,
+// each of up to maxLocations locations.
+func NewGroupsService(db models.DBContractGroups, maxLocations, maxGroups int) *GroupsService {
+	return &GroupsService{db: db, maxLocations: maxLocations, maxGroups: maxGroups}
+}
+
+// SaveGroup validates and stores the named group of the user.
+func (s *GroupsService) SaveGroup(userID int, name string, locations []string) (LocationGroup, error) {
+	// The name appears in URLs, so it must be a simple slug.
+	if !groupNamePattern.MatchString(name) {
+		return LocationGroup{}, fmt.Errorf("%w: 'name' must be 1-64 lowercase letters, digits, '-' or '_'", ErrInvalidGroup)
+	}
+
+	// Every location must be a query the weather service can resolve; blank entries are dropped.
+	cleaned := make([]string, 0, len(locations))
+	for _, location := range locations {
+		location = strings.TrimSpace(location)
+		if location == "" {
+			continue
+		}
+		if _, err := normalizeQuery(location); err != nil {
+			return LocationGroup{}, fmt.Errorf("%w: '%s': %v", ErrInvalidGroup, location, err)
+		}
+		cleaned = append(cleaned, location)
+	}
+	if len(cleaned) == 0 {
+		return LocationGroup{}, fmt.Errorf("%w: 'locations' must contain at least one location", ErrInvalidGroup)
+	}
+	// Every fetch of the group spends upstream quota on each location, so its size is capped.
+	if len(cleaned) > s.maxLocations {
+		return LocationGroup{}, fmt.Errorf("%w: 'locations' must contain at most %d locations, got %d", ErrInvalidGroup, s.maxLocations, len(cleaned))
+	}
+
+	// Fetching every group of a user costs as much quota as one huge group, so the number of groups is capped too.
+	// Replacing an existing group is always allowed. Two concurrent requests may both pass the check,
+	// which at most exceeds the limit by one group each.
+	groups, err := s.db.ListGroups(userID)
+	if err != nil {
+		return LocationGroup{}, fmt.Errorf("error occurred while counting location groups: %w", err)
+	}
+	exists := slices.ContainsFunc(groups, func(group models.LocationGroup) bool { return group.Name == name })
+	if !exists && len(groups) >= s.maxGroups {
+		return LocationGroup{}, fmt.Errorf("%w (at most %d per user, delete one first)", ErrGroupLimitReached, s.maxGroups)
+	}
+
+	// Store the group.
+	if err := s.db.SaveGroup(userID, name, cleaned); err != nil {
+		return LocationGroup{}, fmt.Errorf("error occurred while saving location group: %w", err)
+	}
+
+	return LocationGroup{Name: name, Locations: cleaned}, nil
+}
+
+// ListGroups retrieves all groups of the user.
+func (s *GroupsService) ListGroups(userID int) ([]LocationGroup, error) {
+	rows, err := s.db.ListGroups(userID)
+	if err != nil {
+		return nil, fmt.Errorf("error occurred while listing location groups: %w", err)
+	}
+
+	// Map the database rows to the service-level representation.
+	groups := make([]LocationGroup, 0, len(rows))
+	for _, row := range rows {
+		groups = append(groups, LocationGroup{
+			Name:      row.Name,
+			Locations: row.Locations,
+			CreatedAt: row.CreatedAt,
+		})
+	}
+
+	return groups, nil
+}
+
+// DeleteGroup deletes a group of the user.
+func (s *GroupsService) DeleteGroup(userID int, name string) error {
+	if err := s.db.DeleteGroup(userID, name); err != nil {
+		if errors.Is(err, models.ErrGroupNotFound) {
+			return ErrGroupNotFound
+		}
+		return fmt.Errorf("error occurred while deleting location group: %w", err)
+	}
+	return nil
+}
+
+// GroupLocations retrieves the locations of the named group owned by the user of the API key.
+func (s *GroupsService) GroupLocations(apiKey, name string) ([]string, error) {
+	locations, err := s.db.GetGroupLocationsByAPIKey(apiKey, name)
+	if err != nil {
+		if errors.Is(err, models.ErrGroupNotFound) {
+			return nil, ErrGroupNotFound
+		}
+		return nil, fmt.Errorf("error occurred while retrieving location group: %w", err)
+	}
+	return locations, nil
+}
@@ -0,0 +1,94 @@
+package services
+
+import (
+	"errors"
+	"havoAPI/internal/models"
+	"testing"
+)
+
+// fakeGroupsDB stands in for the location_groups table, keeping the groups of every user by name.
+// Calling a method it doesn't implement panics through the embedded nil interface.
+type fakeGroupsDB struct {
+	models.DBContractGroups
+
+	groups map[int]map[string][]string
+}
+
+func newFakeGroupsDB() *fakeGroupsDB {
+	return &fakeGroupsDB{groups: make(map[int]map[string][]string)}
+}
+
+func (db *fakeGroupsDB) SaveGroup(userID int, name string, locations []string) error {
+	if db.groups[userID] == nil {
+		db.groups[userID] = make(map[string][]string)
+	}
+	db.groups[userID][name] = locations
+	return nil
+}
+
+func (db *fakeGroupsDB) ListGroups(userID int) ([]models.LocationGroup, error) {
+	groups := []models.LocationGroup{}
+	for name, locations := range db.groups[userID] {
+		groups = append(groups, models.LocationGroup{UserID: userID, Name: name, Locations: locations})
+	}
+	return groups, nil
+}
+
+func (db *fakeGroupsDB) DeleteGroup(userID int, name string) error {
+	if _, ok := db.groups[userID][name]; !ok {
+		return models.ErrGroupNotFound
+	}
+	delete(db.groups[userID], name)
+	return nil
+}
+
+func TestSaveGroupCapsTheLocations(t *testing.T) {
+	service := NewGroupsService(newFakeGroupsDB(), 3, 10)
+
+	// Blank entries don't count towards the limit
+	group, err := service.SaveGroup(7, "offices", []string{"Berlin", " ", "Paris", "", "Madrid"})
+	if err != nil {
+		t.Fatalf("SaveGroup with 3 locations failed: %v", err)
+	}
+	if len(group.Locations) != 3 {
+		t.Errorf("saved %q, want the 3 locations", group.Locations)
+	}
+
+	_, err = service.SaveGroup(7, "offices", []string{"Berlin", "Paris", "Madrid", "Rome"})
+	if !errors.Is(err, ErrInvalidGroup) {
+		t.Errorf("SaveGroup with 4 locations = %v, want ErrInvalidGroup", err)
+	}
+}
+
+func TestSaveGroupCapsTheGroupsPerUser(t *testing.T) {
+	db := newFakeGroupsDB()
+	service := NewGroupsService(db, 50, 2)
+
+	for _, name := range []string{"offices", "homes"} {
+		if _, err := service.SaveGroup(7, name, []string{"Berlin"}); err != nil {
+			t.Fatalf("SaveGroup(%s) failed: %v", name, err)
+		}
+	}
+	if _, err := service.SaveGroup(7, "trips", []string{"Rome"}); !errors.Is(err, ErrGroupLimitReached) {
+		t.Fatalf("SaveGroup beyond the limit = %v, want ErrGroupLimitReached", err)
+	}
+	if _, ok := db.groups[7]["trips"]; ok {
+		t.Error("the group beyond the limit was stored")
+	}
+
+	// Replacing an existing group is always allowed, and every user has a limit of their own
+	if _, err := service.SaveGroup(7, "offices", []string{"Paris"}); err != nil {
+		t.Errorf("replacing a group at the limit failed: %v", err)
+	}
+	if _, err := service.SaveGroup(8, "trips", []string{"Rome"}); err != nil {
+		t.Errorf("SaveGroup for another user failed: %v", err)
+	}
+
+	// Deleting a group makes room for a new one
+	if err := service.DeleteGroup(7, "homes"); err != nil {
+		t.Fatalf("DeleteGroup failed: %v", err)
+	}
+	if _, err := service.SaveGroup(7, "trips", []string{"Rome"}); err != nil {
+		t.Errorf("SaveGroup after a deletion failed: %v", err)
+	}
+}
@@ -1,6 +1,13 @@
 package services
 
 import (
+	"havoAPI/internal/units"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
 	"golang.org/x/text/cases"
 	"golang.org/x/text/language"
 )
@@ -13,10 +20,19 @@ func formatWeatherData(weatherData Weather) FormattedWeatherData {
 
 	// Extract location details from the weather data.
 	formattedData.Name = weatherData.Location.Name
+	formattedData.Region = weatherData.Location.Region
 	formattedData.Country = weatherData.Location.Country
 	formattedData.Lat = weatherData.Location.Lat
 	formattedData.Lon = weatherData.Location.Lon
 
+	// Set the time zone and the local time at the location.
+	formattedData.TzID = weatherData.Location.TzID
+	// The local time is left out rather than reported as the zero time when WeatherAPI omits it.
+	if localTime := localTimeAt(weatherData.Location.LocalTimeEpoch, weatherData.Location.TzID); !localTime.IsZero() {
+		formattedData.LocalTime = &localTime
+	}
+	formattedData.LastUpdated = localTimeAt(weatherData.Current.LastUpdatedEpoch, weatherData.Location.TzID)
+
 	// Set temperature and corresponding color code based on the temperature.
 	formattedData.TempC = weatherData.Current.TempC
 	formattedData.TempColor = getTempColor(formattedData.TempC)
@@ -29,10 +45,95 @@ func formatWeatherData(weatherData Weather) FormattedWeatherData {
 	formattedData.Cloud = weatherData.Current.Cloud
 	formattedData.CloudColor = getCloudColor(formattedData.Cloud)
 
+	// Pass through the visibility, the atmospheric pressure and the humidity.
+	formattedData.VisibilityKm = weatherData.Current.VisKm
+	formattedData.PressureMb = weatherData.Current.PressureMb
+	formattedData.Humidity = weatherData.Current.Humidity
+
+	// Summarize how the weather feels from the temperature, humidity and wind.
+	formattedData.Comfort = comfortIndex(formattedData.TempC, formattedData.Humidity, formattedData.WindKph)
+
+	// Pass through the condition text with its coarse category and, when requested, the air quality data.
+	formattedData.Condition = weatherData.Current.Condition.Text
+	formattedData.ConditionCategory = conditionCategory(weatherData.Current.Condition.Code)
+	formattedData.AirQuality = weatherData.Current.AirQuality
+
 	// Return the fully formatted weather data.
 	return formattedData
 }
 
+// ApplyUnits adds the fields required by the requested unit system to the formatted weather data.
+// Imperial and Kelvin values are always derived from the metric ones rather than fetched from the upstream,
+// and the color codes keep deriving from the metric values.
+func ApplyUnits(data FormattedWeatherData, system string) FormattedWeatherData {
+	switch system {
+	case units.Both:
+		tempF := units.CelsiusToFahrenheit(data.TempC)
+		windMph := units.KphToMph(data.WindKph)
+		data.TempF = &tempF
+		data.WindMph = &windMph
+	case units.Kelvin:
+		tempK := units.CelsiusToKelvin(data.TempC)
+		data.TempK = &tempK
+	}
+	return data
+}
+
+// Categories of the comfort index.
+const (
+	ComfortWindyChill  = "windy-chill" // ComfortWindyChill is cold weather made colder by the wind.
+	ComfortCold        = "cold"        // ComfortCold is cold weather with little wind.
+	ComfortCool        = "cool"        // ComfortCool is mild but cool weather.
+	ComfortComfortable = "comfortable" // ComfortComfortable is pleasant weather.
+	ComfortHumid       = "humid"       // ComfortHumid is pleasant temperatures with muggy air.
+	ComfortWarm        = "warm"        // ComfortWarm is warm, fairly dry weather.
+	ComfortHot         = "hot"         // ComfortHot is hot weather, or warm weather made oppressive by humidity.
+)
+
+// Thresholds of the comfort index. Lower bounds are inclusive: 10 °C is cool, not cold.
+const (
+	comfortColdBelowC         = 10.0 // comfortColdBelowC is the temperature below which the weather is cold.
+	comfortCoolBelowC         = 18.0 // comfortCoolBelowC is the temperature below which the weather is cool.
+	comfortWarmFromC          = 26.0 // comfortWarmFromC is the temperature from which the weather is warm.
+	comfortHotFromC           = 30.0 // comfortHotFromC is the temperature from which the weather is hot whatever the humidity.
+	comfortChillWindKph       = 20.0 // comfortChillWindKph is the wind speed from which cold weather is a windy chill.
+	comfortMuggyHumidity      = 70   // comfortMuggyHumidity is the humidity from which pleasant temperatures feel humid.
+	comfortOppressiveHumidity = 60   // comfortOppressiveHumidity is the humidity from which warm weather feels hot.
+)
+
+// comfortIndex combines the temperature (°C), the relative humidity (%) and the wind speed (km/h) into a
+// human-readable category, checked in this order:
+//   - below 10 °C: "windy-chill" with wind of 20 km/h or more, otherwise "cold";
+//   - below 18 °C: "cool";
+//   - 30 °C or more: "hot";
+//   - 26 °C or more: "hot" with humidity of 60% or more, otherwise "warm";
+//   - humidity of 70% or more: "humid";
+//   - otherwise: "comfortable".
+//
+// Like the color codes, it always derives from the metric values.
+func comfortIndex(tempC float64, humidity int, windKph float64) string {
+	switch {
+	case tempC < comfortColdBelowC:
+		if windKph >= comfortChillWindKph {
+			return ComfortWindyChill
+		}
+		return ComfortCold
+	case tempC < comfortCoolBelowC:
+		return ComfortCool
+	case tempC >= comfortHotFromC:
+		return ComfortHot
+	case tempC >= comfortWarmFromC:
+		if humidity >= comfortOppressiveHumidity {
+			return ComfortHot
+		}
+		return ComfortWarm
+	case humidity >= comfortMuggyHumidity:
+		return ComfortHumid
+	default:
+		return ComfortComfortable
+	}
+}
+
 // getTempColor determines the color associated with the temperature.
 // The color changes based on the temperature value to visually represent different temperature ranges.
 func getTempColor(tempC float64) string {
@@ -102,3 +203,194 @@ func capitalizeFirstLetter(s string) string {
 	caser := cases.Title(language.Und)
 	return caser.String(s)
 }
+
+// coordinatesPattern matches coordinate queries such as "48.85,2.35" or "-33.87, 151.21".
+var coordinatesPattern = regexp.MustCompile(`^\s*(-?\d+(?:\.\d+)?)\s*,\s*(-?\d+(?:\.\d+)?)\s*$`)
+
+// Postal code shapes accepted by WeatherAPI. They all contain a digit, so no place name matches them.
+var (
+	usZipPattern      = regexp.MustCompile(`^\d{5}(?:-\d{4})?$`)                           // e.g. "90210" or "90210-1234"
+	ukPostcodePattern = regexp.MustCompile(`^([A-Z]{1,2}\d[A-Z\d]?)(?:\s*(\d[A-Z]{2}))?$`) // e.g. "SW1", "SW1A 1AA"
+	caPostalPattern   = regexp.MustCompile(`^([A-Z]\d[A-Z])(?:\s*(\d[A-Z]\d))?$`)          // e.g. "K1A" or "K1A 0B1"
+)
+
+// normalizePostalCode reports whether the query is shaped like a US zip code, a UK postcode or a Canadian
+// postal code, and returns it in canonical form: upper-case, with a single space between the outward and
+// inward parts (e.g. "sw1a1aa" becomes "SW1A 1AA"). Postal codes must never be title-cased like place names.
+func normalizePostalCode(q string) (string, bool) {
+	code := strings.ToUpper(strings.TrimSpace(q))
+
+	if usZipPattern.MatchString(code) {
+		return code, true
+	}
+	for _, pattern := range []*regexp.Regexp{caPostalPattern, ukPostcodePattern} {
+		if match := pattern.FindStringSubmatch(code); match != nil {
+			if match[2] == "" {
+				return match[1], true
+			}
+			return match[1] + " " + match[2], true
+		}
+	}
+
+	return "", false
+}
+
+// iataPrefix marks a query naming an airport by its IATA code (e.g. "iata:DXB"), which WeatherAPI resolves itself.
+const iataPrefix = "iata:"
+
+// iataCodePattern matches a three-letter IATA airport code, in any case.
+var iataCodePattern = regexp.MustCompile(`^[A-Za-z]{3}$`)
+
+// normalizeIATAQuery reports whether the query has the "iata:" prefix (in any case), and returns it in
+// canonical form with an upper-case code (e.g. "IATA: dxb" becomes "iata:DXB"). Like postal codes,
+// airport codes must never be title-cased like place names.
+// It returns ErrInvalidIATACode if the code is not made of three letters.
+func normalizeIATAQuery(q string) (string, bool, error) {
+	q = strings.TrimSpace(q)
+	if len(q) < len(iataPrefix) || !strings.EqualFold(q[:len(iataPrefix)], iataPrefix) {
+		return "", false, nil
+	}
+
+	code := strings.TrimSpace(q[len(iataPrefix):])
+	if !iataCodePattern.MatchString(code) {
+		return "", true, ErrInvalidIATACode
+	}
+	return iataPrefix + strings.ToUpper(code), true, nil
+}
+
+// normalizeQuery brings a location query into the canonical form used for upstream requests and cache keys.
+// Coordinate queries are validated and reformatted as "lat,lon"; postal codes are upper-cased;
+// airport queries are passed through as "iata:XXX"; named locations are title-cased.
+// It returns ErrInvalidCoordinates if a coordinate query is out of range
+// and ErrInvalidIATACode if an airport query has a malformed code.
+func normalizeQuery(q string) (string, error) {
+	if airport, ok, err := normalizeIATAQuery(q); ok {
+		return airport, err
+	}
+
+	if lat, lon, ok := parseCoordinates(q); ok {
+		if !validCoordinates(lat, lon) {
+			return "", ErrInvalidCoordinates
+		}
+		return strconv.FormatFloat(lat, 'f', -1, 64) + "," + strconv.FormatFloat(lon, 'f', -1, 64), nil
+	}
+
+	if code, ok := normalizePostalCode(q); ok {
+		return code, nil
+	}
+
+	return capitalizeFirstLetter(q), nil
+}
+
+// parseCoordinates parses a "lat,lon" query. The boolean result reports whether the query has
+// the shape of a coordinate pair at all; the values are not range-checked.
+func parseCoordinates(q string) (float64, float64, bool) {
+	match := coordinatesPattern.FindStringSubmatch(q)
+	if match == nil {
+		return 0, 0, false
+	}
+
+	lat, errLat := strconv.ParseFloat(match[1], 64)
+	lon, errLon := strconv.ParseFloat(match[2], 64)
+	if errLat != nil || errLon != nil {
+		return 0, 0, false
+	}
+
+	return lat, lon, true
+}
+
+// validCoordinates reports whether the latitude is within [-90, 90] and the longitude within [-180, 180].
+func validCoordinates(lat, lon float64) bool {
+	return lat >= -90 && lat <= 90 && lon >= -180 && lon <= 180
+}
+
+// withMatchInfo records the client's query in the weather data and whether WeatherAPI resolved it to
+// a location with a different name, e.g. the nearest larger station of a small town.
+// Only the part before the first comma is compared, so "Portland, Maine" matches "Portland";
+// coordinate, postal code and airport queries have no name to compare and are never reported as differing.
+func withMatchInfo(query string, data FormattedWeatherData) FormattedWeatherData {
+	data.Query = strings.TrimSpace(query)
+	if _, ok, _ := normalizeIATAQuery(query); ok {
+		data.MatchedNameDiffers = false
+		return data
+	}
+	if _, _, ok := parseCoordinates(query); ok {
+		data.MatchedNameDiffers = false
+		return data
+	}
+	if _, ok := normalizePostalCode(query); ok {
+		data.MatchedNameDiffers = false
+		return data
+	}
+
+	name, _, _ := strings.Cut(data.Query, ",")
+	data.MatchedNameDiffers = !strings.EqualFold(strings.TrimSpace(name), data.Name)
+	return data
+}
+
+// weatherCacheKey derives the Redis key under which weather data for a location is stored.
+// Both the cache read and write paths must use it so that multi-word locations like "New York"
+// resolve to the same key regardless of how the query was URL-encoded for the upstream request.
+// The key follows the query rather than the resolved location, so a query naming the region
+// (e.g. "Portland, Maine") never shares an entry with a bare "Portland" that WeatherAPI may resolve elsewhere;
+// the "region" field of the response tells clients which place a query was resolved to.
+func weatherCacheKey(location string) string {
+	if airport, ok, err := normalizeIATAQuery(location); ok && err == nil {
+		return "weather:" + airport
+	}
+	if code, ok := normalizePostalCode(location); ok {
+		return "weather:" + code
+	}
+	return "weather:" + capitalizeFirstLetter(strings.TrimSpace(location))
+}
+
+// negativeCacheKey derives the Redis key of the marker remembering that a location does not exist.
+// It is built from the regular cache key so both always refer to the same normalized location.
+func negativeCacheKey(key string) string {
+	return "notfound:" + key
+}
+
+// sensitiveQueryParams lists the URL query parameters whose values must never appear in logs.
+// Add new entries here when upstream requests start carrying other secrets or user PII.
+var sensitiveQueryParams = []string{"key"}
+
+// redactURL masks the values of all sensitive query parameters in the given URL
+// so that it can be safely logged or wrapped into an error message.
+// If the URL cannot be parsed, a placeholder is returned instead of the raw string.
+func redactURL(rawURL string) string {
+	parsedURL, err := url.Parse(rawURL)
+	if err != nil {
+		return "[unparseable URL redacted]"
+	}
+
+	// Replace the value of each sensitive parameter that is present in the query string.
+	query := parsedURL.Query()
+	for _, param := range sensitiveQueryParams {
+		if query.Has(param) {
+			query.Set(param, "REDACTED")
+		}
+	}
+	parsedURL.RawQuery = query.Encode()
+
+	return parsedURL.String()
+}
+
+// localTimeAt converts a Unix timestamp into a time expressed in the given IANA time zone.
+// If the time zone is unknown, the time is expressed in UTC instead.
+func localTimeAt(epoch int64, tzID string) time.Time {
+	// A zero epoch means the upstream did not report the local time.
+	if epoch == 0 {
+		return time.Time{}
+	}
+
+	return time.Unix(epoch, 0).In(timezoneOrUTC(tzID))
+}
+
+// timezoneOrUTC loads the given IANA time zone, falling back to UTC if it is empty or unknown.
+func timezoneOrUTC(tzID string) *time.Location {
+	location, err := time.LoadLocation(tzID)
+	if err != nil || tzID == "" {
+		return time.UTC
+	}
+	return location
+}
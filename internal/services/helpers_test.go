@@ -0,0 +1,194 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRedactURLMasksTheAPIKey(t *testing.T) {
+	tests := []string{
+		"https://api.weatherapi.com/v1/current.json?key=upstream-secret&q=London&aqi=no",
+		"https://api.weatherapi.com/v1/current.json?q=London&key=upstream-secret",
+		"https://api.weatherapi.com/v1/search.json?key=upstream-secret",
+		"http://[::1]:namedport/current.json?key=upstream-secret",
+	}
+	for _, rawURL := range tests {
+		redacted := redactURL(rawURL)
+		if strings.Contains(redacted, "upstream-secret") {
+			t.Errorf("redactURL(%q) = %q, still contains the key", rawURL, redacted)
+		}
+	}
+
+	// Other parameters are kept for diagnostics
+	if redacted := redactURL("https://api.weatherapi.com/v1/current.json?key=upstream-secret&q=London"); !strings.Contains(redacted, "q=London") {
+		t.Errorf("redactURL dropped the query: %q", redacted)
+	}
+}
+
+func TestUpstreamErrorsNeverContainTheAPIKey(t *testing.T) {
+	ts := newTestService(t, nil)
+
+	// A transport error embeds the request URL
+	ts.upstream.Close()
+	_, err := ts.FetchWeatherData(context.Background(), "London", WeatherOptions{})
+	if err == nil {
+		t.Fatal("FetchWeatherData succeeded against a closed upstream")
+	}
+	if strings.Contains(err.Error(), "upstream-secret") {
+		t.Errorf("error contains the API key: %v", err)
+	}
+}
+
+func TestFormatWeatherDataLocalTime(t *testing.T) {
+	var weather Weather
+	weather.Location.Name = "Tashkent"
+	weather.Location.TzID = "Asia/Tashkent"
+
+	// Without a local time from the upstream, the field is left out rather than set to year 1
+	data, err := json.Marshal(formatWeatherData(weather))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), `"localtime"`) {
+		t.Errorf("zero local time is serialized: %s", data)
+	}
+
+	weather.Location.LocalTimeEpoch = time.Date(2025, 1, 20, 10, 30, 0, 0, time.UTC).Unix()
+	formatted := formatWeatherData(weather)
+	if formatted.LocalTime == nil {
+		t.Fatal("LocalTime is nil despite localtime_epoch")
+	}
+	if got := formatted.LocalTime.Format(time.RFC3339); got != "2025-01-20T15:30:00+05:00" {
+		t.Errorf("LocalTime = %s, want 2025-01-20T15:30:00+05:00", got)
+	}
+}
+
+func TestNormalizeQueryCoordinates(t *testing.T) {
+	tests := []struct {
+		q       string
+		want    string
+		wantErr error
+	}{
+		{q: "48.85,2.35", want: "48.85,2.35"},
+		{q: " -33.87 , 151.21 ", want: "-33.87,151.21"},
+		{q: "90,180", want: "90,180"},
+		{q: "-90,-180", want: "-90,-180"},
+		{q: "0,0", want: "0,0"},
+		{q: "90.0001,0", wantErr: ErrInvalidCoordinates},
+		{q: "-90.5,0", wantErr: ErrInvalidCoordinates},
+		{q: "0,180.01", wantErr: ErrInvalidCoordinates},
+		{q: "0,-181", wantErr: ErrInvalidCoordinates},
+		{q: "999,999", wantErr: ErrInvalidCoordinates},
+	}
+	for _, tt := range tests {
+		got, err := normalizeQuery(tt.q)
+		if !errors.Is(err, tt.wantErr) {
+			t.Errorf("normalizeQuery(%q) error = %v, want %v", tt.q, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("normalizeQuery(%q) = %q, want %q", tt.q, got, tt.want)
+		}
+	}
+}
+
+func TestNormalizeQueryPostalCodes(t *testing.T) {
+	tests := []struct {
+		name string
+		q    string
+		want string
+	}{
+		{name: "US zip", q: "90210", want: "90210"},
+		{name: "US zip+4", q: " 90210-1234 ", want: "90210-1234"},
+		{name: "UK postcode", q: "sw1a 1aa", want: "SW1A 1AA"},
+		{name: "UK postcode without space", q: "sw1a1aa", want: "SW1A 1AA"},
+		{name: "UK outward code", q: "sw1", want: "SW1"},
+		{name: "UK single-letter area", q: "m1  1ae", want: "M1 1AE"},
+		{name: "Canadian postal code", q: "k1a 0b1", want: "K1A 0B1"},
+		{name: "Canadian postal code without space", q: "K1A0B1", want: "K1A 0B1"},
+		{name: "Canadian forward sortation area", q: "k1a", want: "K1A"},
+		{name: "place name", q: "new york", want: "New York"},
+		{name: "place name with digits", q: "district 9", want: "District 9"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := normalizeQuery(tt.q)
+			if err != nil {
+				t.Fatalf("normalizeQuery(%q) failed: %v", tt.q, err)
+			}
+			if got != tt.want {
+				t.Errorf("normalizeQuery(%q) = %q, want %q", tt.q, got, tt.want)
+			}
+			if key := weatherCacheKey(tt.q); key != "weather:"+tt.want {
+				t.Errorf("weatherCacheKey(%q) = %q, want %q", tt.q, key, "weather:"+tt.want)
+			}
+		})
+	}
+}
+
+func TestFormattersCopyTheRegion(t *testing.T) {
+	var weather Weather
+	weather.Location.Name = "Portland"
+	weather.Location.Region = "Maine"
+	if got := formatWeatherData(weather).Region; got != "Maine" {
+		t.Errorf("formatWeatherData region = %q, want Maine", got)
+	}
+
+	var astronomy astronomyResponse
+	astronomy.Location = weather.Location
+	if got := formatAstronomyData(astronomy, "2026-03-14").Region; got != "Maine" {
+		t.Errorf("formatAstronomyData region = %q, want Maine", got)
+	}
+
+	var history historyResponse
+	history.Location = weather.Location
+	if got := formatHistoryData(history).Region; got != "Maine" {
+		t.Errorf("formatHistoryData region = %q, want Maine", got)
+	}
+}
+
+func TestComfortIndexBoundaries(t *testing.T) {
+	tests := []struct {
+		name     string
+		tempC    float64
+		humidity int
+		windKph  float64
+		want     string
+	}{
+		// Cold, with and without wind
+		{name: "just below cold, calm", tempC: 9.9, humidity: 50, windKph: 19.9, want: ComfortCold},
+		{name: "just below cold, chill wind", tempC: 9.9, humidity: 50, windKph: 20, want: ComfortWindyChill},
+		{name: "freezing, strong wind", tempC: -15, humidity: 90, windKph: 45, want: ComfortWindyChill},
+		{name: "cold ignores humidity", tempC: 5, humidity: 100, windKph: 0, want: ComfortCold},
+
+		// Cool starts at 10 °C, where the wind no longer matters
+		{name: "cool from 10", tempC: 10, humidity: 50, windKph: 30, want: ComfortCool},
+		{name: "just below comfortable", tempC: 17.9, humidity: 90, windKph: 0, want: ComfortCool},
+
+		// Comfortable and humid between 18 and 26 °C
+		{name: "comfortable from 18", tempC: 18, humidity: 50, windKph: 0, want: ComfortComfortable},
+		{name: "just below muggy", tempC: 22, humidity: 69, windKph: 0, want: ComfortComfortable},
+		{name: "muggy from 70%", tempC: 22, humidity: 70, windKph: 0, want: ComfortHumid},
+		{name: "just below warm, muggy", tempC: 25.9, humidity: 95, windKph: 0, want: ComfortHumid},
+
+		// Warm from 26 °C, hot when humid
+		{name: "warm from 26", tempC: 26, humidity: 59, windKph: 0, want: ComfortWarm},
+		{name: "warm and oppressive from 60%", tempC: 26, humidity: 60, windKph: 0, want: ComfortHot},
+		{name: "just below hot, dry", tempC: 29.9, humidity: 10, windKph: 0, want: ComfortWarm},
+
+		// Hot from 30 °C whatever the humidity
+		{name: "hot from 30", tempC: 30, humidity: 10, windKph: 0, want: ComfortHot},
+		{name: "hot and windy", tempC: 35, humidity: 0, windKph: 50, want: ComfortHot},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := comfortIndex(tt.tempC, tt.humidity, tt.windKph); got != tt.want {
+				t.Errorf("comfortIndex(%v, %d, %v) = %q, want %q", tt.tempC, tt.humidity, tt.windKph, got, tt.want)
+			}
+		})
+	}
+}
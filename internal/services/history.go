@@ -0,0 +1,205 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// MaxHistoryDays is the maximum number of days a single history request may span.
+// Every uncached day costs one upstream call, so the range is kept short.
+const MaxHistoryDays = 7
+
+// historyResponse is the response of WeatherAPI's history endpoint for a single date.
+type historyResponse struct {
+	Location Location `json:"location"` // Location contains geographical details like name, country, and coordinates.
+	Forecast struct {
+		ForecastDay []struct {
+			Date string `json:"date"` // Date is the date (YYYY-MM-DD) the summary applies to.
+			Day  struct {
+				MaxTempC      float64 `json:"maxtemp_c"`      // MaxTempC is the highest temperature of the day in Celsius.
+				MinTempC      float64 `json:"mintemp_c"`      // MinTempC is the lowest temperature of the day in Celsius.
+				AvgTempC      float64 `json:"avgtemp_c"`      // AvgTempC is the average temperature of the day in Celsius.
+				MaxWindKph    float64 `json:"maxwind_kph"`    // MaxWindKph is the highest wind speed of the day.
+				TotalPrecipMm float64 `json:"totalprecip_mm"` // TotalPrecipMm is the total precipitation of the day in millimeters.
+				AvgHumidity   float64 `json:"avghumidity"`    // AvgHumidity is the average humidity of the day in percent.
+				Condition     struct {
+					Text string `json:"text"` // Text describes the prevailing weather condition (e.g. "Patchy rain possible").
+				} `json:"condition"`
+			} `json:"day"`
+		} `json:"forecastday"`
+	} `json:"forecast"`
+}
+
+// FetchHistoryData retrieves the daily weather summaries of a location from date to endDate (both YYYY-MM-DD and inclusive;
+// endDate defaults to date). Every day is served from the Redis cache when possible and otherwise fetched from the weather API.
+// It returns ErrInvalidHistoryRange if the range is malformed, too long or outside the configured lookback window.
+func (s *WeatherAPIService) FetchHistoryData(q, date, endDate string) (HistoryData, error) {
+	// Validate the range before any upstream call.
+	dates, err := s.historyDates(date, endDate)
+	if err != nil {
+		return HistoryData{}, err
+	}
+
+	// Normalize the location for consistent formatting, validating coordinate queries.
+	q, err = normalizeQuery(q)
+	if err != nil {
+		return HistoryData{}, err
+	}
+
+	// Reject locations that can never pass the allowlist before any upstream call.
+	if !s.allowlist.mayAllowQuery(q) {
+		return HistoryData{}, ErrLocationNotAllowed
+	}
+
+	// Collect every day of the range, keeping the location of the first one.
+	var history HistoryData
+	for _, day := range dates {
+		dayData, err := s.fetchHistoryDay(q, day)
+		if err != nil {
+			return HistoryData{}, err
+		}
+		if len(history.Days) == 0 {
+			history = dayData
+			continue
+		}
+		history.Days = append(history.Days, dayData.Days...)
+	}
+
+	// Country-level allowlist entries can only be checked once the location is resolved.
+	if !s.allowlist.allowsLocation(q, FormattedWeatherData{Name: history.Name, Country: history.Country}) {
+		return HistoryData{}, ErrLocationNotAllowed
+	}
+
+	history.Source = s.cfg.Attribution
+	return history, nil
+}
+
+// historyDates validates a history range and returns every date it covers, in order.
+// The range must lie between the start of the lookback window and today (UTC) and span at most MaxHistoryDays.
+func (s *WeatherAPIService) historyDates(date, endDate string) ([]string, error) {
+	start, err := time.Parse(AstronomyDateLayout, date)
+	if err != nil {
+		return nil, fmt.Errorf("%w: parameter date must be a valid date in the format YYYY-MM-DD", ErrInvalidHistoryRange)
+	}
+	end := start
+	if endDate != "" {
+		if end, err = time.Parse(AstronomyDateLayout, endDate); err != nil {
+			return nil, fmt.Errorf("%w: parameter end_date must be a valid date in the format YYYY-MM-DD", ErrInvalidHistoryRange)
+		}
+	}
+
+	now := s.clk.Now().UTC()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	earliest := today.AddDate(0, 0, -s.cfg.WeatherAPIHistoryDays)
+
+	switch {
+	case end.Before(start):
+		return nil, fmt.Errorf("%w: parameter end_date must not be before date", ErrInvalidHistoryRange)
+	case start.Before(earliest):
+		return nil, fmt.Errorf("%w: history is only available for the last %d days (since %s)", ErrInvalidHistoryRange, s.cfg.WeatherAPIHistoryDays, earliest.Format(AstronomyDateLayout))
+	case end.After(today):
+		return nil, fmt.Errorf("%w: history is not available for future dates", ErrInvalidHistoryRange)
+	case int(end.Sub(start).Hours()/24) >= MaxHistoryDays:
+		return nil, fmt.Errorf("%w: a history request may span at most %d days", ErrInvalidHistoryRange, MaxHistoryDays)
+	}
+
+	var dates []string
+	for day := start; !day.After(end); day = day.AddDate(0, 0, 1) {
+		dates = append(dates, day.Format(AstronomyDateLayout))
+	}
+	return dates, nil
+}
+
+// fetchHistoryDay retrieves the weather summary of a single day, from the cache or the weather API.
+// Past days never change, so they stay cached until they leave the lookback window; today is still
+// being recorded and is only cached for the regular cache TTL. With the cache disabled (CACHE_ENABLED=false),
+// every day is fetched from the weather API and Redis is never touched.
+func (s *WeatherAPIService) fetchHistoryDay(q, date string) (HistoryData, error) {
+	// Serve the day from the cache when possible.
+	key := historyCacheKey(q, date)
+	if s.cfg.CacheEnabled {
+		cached, err := s.redisClient.Get(context.Background(), s.redisClient.prefixed(key)).Result()
+		if err == nil {
+			var data HistoryData
+			if err := json.Unmarshal([]byte(cached), &data); err == nil {
+				return data, nil
+			}
+		} else if !errors.Is(err, redis.Nil) {
+			log.Printf("failed to read cached history data for %s: %v", key, err)
+		}
+	}
+
+	// Request the day from the weather API.
+	apiURL := fmt.Sprintf("%s/history.json?key=%s&q=%s&dt=%s", s.cfg.WeatherAPIBaseURL, s.cfg.WeatherAPIKey, url.QueryEscape(q), date)
+	resBody, err := s.requestToWeatherApi(context.Background(), apiURL)
+	if err != nil {
+		return HistoryData{}, err
+	}
+
+	// Parse the response body.
+	var response historyResponse
+	if err := json.Unmarshal(resBody, &response); err != nil {
+		if _, ok := err.(*json.SyntaxError); ok {
+			return HistoryData{}, ErrUnexpectedEndOfJSONInput
+		}
+		return HistoryData{}, fmt.Errorf("error occurred while unmarshaling history JSON: %w", err)
+	}
+	if strings.TrimSpace(response.Location.Name) == "" || len(response.Forecast.ForecastDay) == 0 {
+		return HistoryData{}, fmt.Errorf("%w: history response has no data for %s on %s", ErrUpstreamUnavailable, q, date)
+	}
+	data := formatHistoryData(response)
+	if !s.cfg.CacheEnabled {
+		return data, nil
+	}
+
+	// Cache the day: until it leaves the lookback window if it is over, for the regular TTL if it is today.
+	ttl := s.cfg.CacheTTL
+	if day, _ := time.Parse(AstronomyDateLayout, date); day.AddDate(0, 0, 1).Before(s.clk.Now()) {
+		ttl = day.AddDate(0, 0, s.cfg.WeatherAPIHistoryDays+1).Sub(s.clk.Now())
+	}
+	if jsonData, err := json.Marshal(data); err == nil {
+		if err := s.redisClient.Set(context.Background(), s.redisClient.prefixed(key), jsonData, ttl).Err(); err != nil {
+			log.Printf("failed to cache history data for %s: %v", key, err)
+		}
+	}
+
+	return data, nil
+}
+
+// formatHistoryData flattens WeatherAPI's history response into HistoryData.
+func formatHistoryData(response historyResponse) HistoryData {
+	data := HistoryData{
+		Name:    response.Location.Name,
+		Region:  response.Location.Region,
+		Country: response.Location.Country,
+		Lat:     response.Location.Lat,
+		Lon:     response.Location.Lon,
+		TzID:    response.Location.TzID,
+	}
+	for _, forecastDay := range response.Forecast.ForecastDay {
+		data.Days = append(data.Days, HistoryDay{
+			Date:          forecastDay.Date,
+			MaxTempC:      forecastDay.Day.MaxTempC,
+			MinTempC:      forecastDay.Day.MinTempC,
+			AvgTempC:      forecastDay.Day.AvgTempC,
+			MaxWindKph:    forecastDay.Day.MaxWindKph,
+			TotalPrecipMm: forecastDay.Day.TotalPrecipMm,
+			AvgHumidity:   forecastDay.Day.AvgHumidity,
+			Condition:     forecastDay.Day.Condition.Text,
+		})
+	}
+	return data
+}
+
+// historyCacheKey derives the Redis key under which the history of a location for a date is stored.
+func historyCacheKey(location, date string) string {
+	return "history:" + date + ":" + capitalizeFirstLetter(strings.TrimSpace(location))
+}
@@ -0,0 +1,53 @@
+package services
+
+import (
+	"fmt"
+	"havoAPI/api/config"
+	"net/http"
+	"testing"
+)
+
+// writeHistory answers a history.json request with a summary of the requested day.
+func writeHistory(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	fmt.Fprintf(w, `{"location": {"name": %q, "country": "Testland"}, "forecast": {"forecastday": [{"date": %q, "day": {"avgtemp_c": 15}}]}}`,
+		r.URL.Query().Get("q"), r.URL.Query().Get("dt"))
+}
+
+func TestFetchHistoryDataCache(t *testing.T) {
+	tests := []struct {
+		name          string
+		cacheEnabled  bool
+		wantRequests  int
+		wantCacheKeys bool
+	}{
+		{name: "enabled", cacheEnabled: true, wantRequests: 2, wantCacheKeys: true},
+		{name: "disabled", cacheEnabled: false, wantRequests: 4},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts := newTestService(t, func(cfg *config.Config) {
+				cfg.CacheEnabled = tt.cacheEnabled
+			})
+			ts.upstream.handle(writeHistory)
+
+			// The same two days twice: the second time they are cached, unless caching is disabled
+			for range 2 {
+				history, err := ts.FetchHistoryData("London", "2026-03-10", "2026-03-11")
+				if err != nil {
+					t.Fatalf("FetchHistoryData failed: %v", err)
+				}
+				if len(history.Days) != 2 {
+					t.Fatalf("got %d days, want 2", len(history.Days))
+				}
+			}
+
+			if got := ts.upstream.count(); got != tt.wantRequests {
+				t.Errorf("upstream received %d requests, want %d", got, tt.wantRequests)
+			}
+			if keys := ts.redis.Keys(); (len(keys) > 0) != tt.wantCacheKeys {
+				t.Errorf("Redis keys %v, want keys stored: %v", keys, tt.wantCacheKeys)
+			}
+		})
+	}
+}
@@ -0,0 +1,76 @@
+package services
+
+import (
+	"sync"
+	"time"
+)
+
+// localStoreSweepInterval is how often a localStore drops its expired entries.
+const localStoreSweepInterval = time.Minute
+
+// localStore keeps the state that must outlive a request in process memory, for deployments without Redis
+// (CACHE_ENABLED=false without REDIS_ADDR): revoked tokens and the anonymous allowance counters.
+// Like Redis keys, its entries expire; unlike them, they are lost on restart and not shared between instances,
+// which is acceptable for the single-instance debugging and low-traffic setups that run without Redis.
+type localStore struct {
+	mu        sync.Mutex
+	entries   map[string]localEntry
+	lastSweep time.Time
+}
+
+// localEntry is a counter of a localStore with its expiry.
+type localEntry struct {
+	value     int64
+	expiresAt time.Time
+}
+
+// newLocalStore creates an empty store.
+func newLocalStore() *localStore {
+	return &localStore{entries: make(map[string]localEntry)}
+}
+
+// incr increments the counter under key, starting from 0 if it is missing or expired, and makes it expire ttl after now,
+// like INCR followed by EXPIRE. It returns the new value.
+func (l *localStore) incr(key string, ttl time.Duration, now time.Time) int64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.sweep(now)
+
+	entry := l.entries[key]
+	if !now.Before(entry.expiresAt) {
+		entry.value = 0
+	}
+	entry.value++
+	entry.expiresAt = now.Add(ttl)
+	l.entries[key] = entry
+	return entry.value
+}
+
+// set stores a flag under key that expires ttl after now.
+func (l *localStore) set(key string, ttl time.Duration, now time.Time) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.sweep(now)
+	l.entries[key] = localEntry{value: 1, expiresAt: now.Add(ttl)}
+}
+
+// exists reports whether an unexpired entry is stored under key.
+func (l *localStore) exists(key string, now time.Time) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	entry, ok := l.entries[key]
+	return ok && now.Before(entry.expiresAt)
+}
+
+// sweep drops the expired entries, at most once per localStoreSweepInterval. The caller must hold l.mu.
+func (l *localStore) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < localStoreSweepInterval {
+		return
+	}
+	l.lastSweep = now
+	for key, entry := range l.entries {
+		if !now.Before(entry.expiresAt) {
+			delete(l.entries, key)
+		}
+	}
+}
@@ -0,0 +1,46 @@
+package services
+
+import "strings"
+
+// locationAllowlist restricts which locations may be queried.
+// Entries are either exact location names or whole countries; an empty allowlist permits every location.
+type locationAllowlist struct {
+	names     map[string]bool // names holds the allowed location names, lowercased.
+	countries map[string]bool // countries holds the allowed country names, lowercased.
+}
+
+// newLocationAllowlist builds an allowlist from the configured location and country names.
+// Matching is case-insensitive.
+func newLocationAllowlist(names, countries []string) locationAllowlist {
+	allowlist := locationAllowlist{names: make(map[string]bool), countries: make(map[string]bool)}
+	for _, name := range names {
+		allowlist.names[strings.ToLower(strings.TrimSpace(name))] = true
+	}
+	for _, country := range countries {
+		allowlist.countries[strings.ToLower(strings.TrimSpace(country))] = true
+	}
+	return allowlist
+}
+
+// enabled reports whether the allowlist restricts anything.
+func (a locationAllowlist) enabled() bool {
+	return len(a.names) > 0 || len(a.countries) > 0
+}
+
+// mayAllowQuery reports whether a query could be allowed before it is resolved, so that queries
+// that can never pass are rejected without an upstream call. Without country entries, only exact
+// name matches can pass; with them, the resolved country has to be checked with allowsLocation.
+func (a locationAllowlist) mayAllowQuery(q string) bool {
+	return !a.enabled() || len(a.countries) > 0 || a.names[strings.ToLower(strings.TrimSpace(q))]
+}
+
+// allowsLocation reports whether a resolved location is allowed, either because the query or the
+// resolved name is listed or because its country is.
+func (a locationAllowlist) allowsLocation(q string, data FormattedWeatherData) bool {
+	if !a.enabled() {
+		return true
+	}
+	return a.names[strings.ToLower(strings.TrimSpace(q))] ||
+		a.names[strings.ToLower(data.Name)] ||
+		a.countries[strings.ToLower(data.Country)]
+}
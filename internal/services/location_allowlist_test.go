@@ -0,0 +1,126 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"havoAPI/api/config"
+	"net/http"
+	"slices"
+	"testing"
+)
+
+func TestLocationAllowlist(t *testing.T) {
+	allowlist := newLocationAllowlist([]string{" New York ", "tashkent"}, []string{"United Kingdom"})
+	tests := []struct {
+		name  string
+		query string
+		data  FormattedWeatherData
+		want  bool
+	}{
+		{name: "listed name", query: "Tashkent", data: FormattedWeatherData{Name: "Tashkent", Country: "Uzbekistan"}, want: true},
+		{name: "listed name in another case", query: "NEW york", data: FormattedWeatherData{Name: "New York", Country: "United States of America"}, want: true},
+		{name: "query resolving to a listed name", query: "40.71,-74.01", data: FormattedWeatherData{Name: "New York", Country: "United States of America"}, want: true},
+		{name: "listed country", query: "London", data: FormattedWeatherData{Name: "London", Country: "United Kingdom"}, want: true},
+		{name: "listed country in another case", query: "Leeds", data: FormattedWeatherData{Name: "Leeds", Country: "united kingdom"}, want: true},
+		{name: "unlisted location", query: "Paris", data: FormattedWeatherData{Name: "Paris", Country: "France"}, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := allowlist.allowsLocation(tt.query, tt.data); got != tt.want {
+				t.Errorf("allowsLocation(%q, %s/%s) = %v, want %v", tt.query, tt.data.Name, tt.data.Country, got, tt.want)
+			}
+		})
+	}
+
+	if empty := newLocationAllowlist(nil, nil); !empty.mayAllowQuery("Paris") || !empty.allowsLocation("Paris", FormattedWeatherData{Name: "Paris"}) {
+		t.Error("an empty allowlist must permit every location")
+	}
+}
+
+func TestFetchWeatherDataEnforcesTheAllowlist(t *testing.T) {
+	t.Run("names only", func(t *testing.T) {
+		ts := newTestService(t, func(cfg *config.Config) {
+			cfg.AllowedLocations = []string{"tashkent"}
+		})
+
+		if _, err := ts.FetchWeatherData(context.Background(), "TASHKENT", WeatherOptions{}); err != nil {
+			t.Errorf("allowed location: %v", err)
+		}
+
+		// Without country entries, an unlisted name can never pass, so the upstream is spared
+		before := ts.upstream.count()
+		if _, err := ts.FetchWeatherData(context.Background(), "Paris", WeatherOptions{}); !errors.Is(err, ErrLocationNotAllowed) {
+			t.Errorf("denied location: err = %v, want ErrLocationNotAllowed", err)
+		}
+		if ts.upstream.count() != before {
+			t.Error("a denied location reached the upstream")
+		}
+	})
+
+	t.Run("countries", func(t *testing.T) {
+		// The fake upstream places every location in Testland
+		ts := newTestService(t, func(cfg *config.Config) {
+			cfg.AllowedCountries = []string{"TESTLAND"}
+		})
+		if _, err := ts.FetchWeatherData(context.Background(), "Paris", WeatherOptions{}); err != nil {
+			t.Errorf("location in an allowed country: %v", err)
+		}
+
+		ts = newTestService(t, func(cfg *config.Config) {
+			cfg.AllowedCountries = []string{"France"}
+		})
+		for i := 0; i < 2; i++ {
+			// Denied both when resolved and when served from the cache
+			if _, err := ts.FetchWeatherData(context.Background(), "Paris", WeatherOptions{}); !errors.Is(err, ErrLocationNotAllowed) {
+				t.Errorf("lookup %d of a location in another country: err = %v, want ErrLocationNotAllowed", i+1, err)
+			}
+		}
+	})
+}
+
+func TestSearchLocationsEnforcesTheAllowlist(t *testing.T) {
+	t.Run("names only", func(t *testing.T) {
+		ts := newTestService(t, func(cfg *config.Config) {
+			cfg.AllowedLocations = []string{"London"}
+		})
+
+		// An unlisted name can never pass, so the upstream is spared
+		if _, err := ts.SearchLocations(context.Background(), "Springfield"); !errors.Is(err, ErrLocationNotAllowed) {
+			t.Errorf("denied location: err = %v, want ErrLocationNotAllowed", err)
+		}
+		if ts.upstream.count() != 0 {
+			t.Error("a denied location reached the upstream")
+		}
+	})
+
+	t.Run("countries", func(t *testing.T) {
+		ts := newTestService(t, func(cfg *config.Config) {
+			cfg.AllowedCountries = []string{"United States of America"}
+		})
+		ts.upstream.handle(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(`[
+				{"name": "Springfield", "region": "Illinois", "country": "United States of America"},
+				{"name": "Springfield", "region": "Saint Andrew", "country": "Jamaica"},
+				{"name": "Springfield", "region": "Missouri", "country": "United States of America"}
+			]`))
+		})
+
+		candidates, err := ts.SearchLocations(context.Background(), "Springfield")
+		if err != nil {
+			t.Fatalf("SearchLocations failed: %v", err)
+		}
+		var regions []string
+		for _, candidate := range candidates {
+			regions = append(regions, candidate.Region)
+		}
+		if !slices.Equal(regions, []string{"Illinois", "Missouri"}) {
+			t.Errorf("candidates in %q, want only those in the allowed country", regions)
+		}
+
+		// No candidate in an allowed country denies the query as a whole
+		ts.allowlist = newLocationAllowlist(nil, []string{"France"})
+		if _, err := ts.SearchLocations(context.Background(), "Springfield"); !errors.Is(err, ErrLocationNotAllowed) {
+			t.Errorf("no allowed candidate: err = %v, want ErrLocationNotAllowed", err)
+		}
+	})
+}
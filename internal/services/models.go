@@ -1,5 +1,7 @@
 package services
 
+import "time"
+
 // Weather holds the location and current weather data.
 // It represents the full weather report for a specific location.
 type Weather struct {
@@ -12,30 +14,217 @@ type Weather struct {
 // It is used to represent the geographical information for the weather data.
 type Location struct {
 	Name    string  `json:"name"`    // Name represents the name of the location (e.g., city, town, etc.).
+	Region  string  `json:"region"`  // Region represents the state or province of the location.
 	Country string  `json:"country"` // Country represents the country of the location.
 	Lat     float64 `json:"lat"`     // Using float64 for better precision.
 	Lon     float64 `json:"lon"`     // Using float64 for better precision.
+
+	TzID           string `json:"tz_id"`           // TzID is the IANA time zone of the location (e.g., "Asia/Tashkent").
+	LocalTimeEpoch int64  `json:"localtime_epoch"` // LocalTimeEpoch is the local time at the location as a Unix timestamp.
 }
 
 // Current holds the essential weather details for the current conditions.
 // It represents data such as temperature, wind speed, and cloud coverage.
 type Current struct {
-	TempC   float64 `json:"temp_c"`   // Temperature in Celsius.
-	WindKph float64 `json:"wind_kph"` // Wind speed in kilometers per hour.
-	Cloud   int     `json:"cloud"`    // Cloud cover percentage.
+	TempC      float64 `json:"temp_c"`      // Temperature in Celsius.
+	WindKph    float64 `json:"wind_kph"`    // Wind speed in kilometers per hour.
+	Cloud      int     `json:"cloud"`       // Cloud cover percentage.
+	VisKm      float64 `json:"vis_km"`      // Visibility in kilometers.
+	PressureMb float64 `json:"pressure_mb"` // Atmospheric pressure in millibars.
+	Humidity   int     `json:"humidity"`    // Relative humidity in percent.
+
+	LastUpdatedEpoch int64 `json:"last_updated_epoch"` // LastUpdatedEpoch is the time of the upstream observation as a Unix timestamp.
+
+	Condition struct {
+		Text string `json:"text"` // Text describes the weather condition in the requested language (e.g. "Partly cloudy").
+		Code int    `json:"code"` // Code identifies the weather condition independently of the language (e.g. 1003).
+	} `json:"condition"`
+	AirQuality *AirQuality `json:"air_quality"` // AirQuality is only returned by WeatherAPI when it is requested with aqi=yes.
+}
+
+// AirQuality holds the air quality measurements of a location.
+// Concentrations are in micrograms per cubic meter.
+type AirQuality struct {
+	CO         float64 `json:"co"`             // CO is the carbon monoxide concentration.
+	NO2        float64 `json:"no2"`            // NO2 is the nitrogen dioxide concentration.
+	O3         float64 `json:"o3"`             // O3 is the ozone concentration.
+	SO2        float64 `json:"so2"`            // SO2 is the sulphur dioxide concentration.
+	PM2_5      float64 `json:"pm2_5"`          // PM2_5 is the concentration of particulate matter below 2.5 microns.
+	PM10       float64 `json:"pm10"`           // PM10 is the concentration of particulate matter below 10 microns.
+	USEPAIndex int     `json:"us-epa-index"`   // USEPAIndex is the US EPA air quality index (1 good to 6 hazardous).
+	GBDefra    int     `json:"gb-defra-index"` // GBDefra is the UK Defra air quality index (1 low to 10 very high).
 }
 
 // FormattedWeatherData holds the weather data after it has been processed and formatted,
 // including additional properties such as color codes for visual representation.
 type FormattedWeatherData struct {
-	Name       string  `json:"name"`        // Name represents the name of the location (e.g., city, town, etc.).
-	Country    string  `json:"country"`     // Country represents the country of the location.
-	Lat        float64 `json:"lat"`         // Using float64 for better precision.
-	Lon        float64 `json:"lon"`         // Using float64 for better precision.
-	TempC      float64 `json:"temp_c"`      // Temperature in Celsius.
-	TempColor  string  `json:"temp_color"`  // TempColor represents the color code associated with the current temperature.
-	WindKph    float64 `json:"wind_kph"`    // Wind speed in kilometers per hour.
-	WindColor  string  `json:"wind_color"`  // WindColor represents the color code associated with the wind speed.
-	Cloud      int     `json:"cloud"`       // Cloud cover percentage.
-	CloudColor string  `json:"cloud_color"` // This can be used for visual representation of different cloud cover levels.
+	Name               string       `json:"name"`                         // Name represents the name of the location (e.g., city, town, etc.).
+	Region             string       `json:"region"`                       // Region represents the state or province of the location, telling apart places like "Portland, Oregon" and "Portland, Maine".
+	Country            string       `json:"country"`                      // Country represents the country of the location.
+	Lat                float64      `json:"lat"`                          // Using float64 for better precision.
+	Lon                float64      `json:"lon"`                          // Using float64 for better precision.
+	TzID               string       `json:"tz_id"`                        // TzID is the IANA time zone of the location.
+	LocalTime          *time.Time   `json:"localtime,omitempty"`          // LocalTime is the local time at the location when the data was fetched; nil if WeatherAPI did not report it.
+	LastUpdated        time.Time    `json:"last_updated"`                 // LastUpdated is the local time of the upstream observation; it only changes when new data is published.
+	TempC              float64      `json:"temp_c"`                       // Temperature in Celsius.
+	TempColor          string       `json:"temp_color"`                   // TempColor represents the color code associated with the current temperature.
+	TempF              *float64     `json:"temp_f,omitempty"`             // Temperature in Fahrenheit, derived from TempC when imperial units are requested.
+	TempK              *float64     `json:"temp_k,omitempty"`             // Temperature in Kelvin, derived from TempC when Kelvin units are requested.
+	TempTrend          string       `json:"temp_trend"`                   // TempTrend compares TempC to the previous snapshot: rising, falling, steady or unknown.
+	WindKph            float64      `json:"wind_kph"`                     // Wind speed in kilometers per hour.
+	WindMph            *float64     `json:"wind_mph,omitempty"`           // Wind speed in miles per hour, derived from WindKph when imperial units are requested.
+	WindColor          string       `json:"wind_color"`                   // WindColor represents the color code associated with the wind speed.
+	Cloud              int          `json:"cloud"`                        // Cloud cover percentage.
+	CloudColor         string       `json:"cloud_color"`                  // This can be used for visual representation of different cloud cover levels.
+	VisibilityKm       float64      `json:"vis_km"`                       // Visibility in kilometers, passed through from the upstream.
+	PressureMb         float64      `json:"pressure_mb"`                  // Atmospheric pressure in millibars, passed through from the upstream.
+	Humidity           int          `json:"humidity"`                     // Relative humidity in percent, passed through from the upstream.
+	Comfort            string       `json:"comfort"`                      // Comfort summarizes temperature, humidity and wind in a category such as "comfortable" (see comfortIndex).
+	Condition          string       `json:"condition,omitempty"`          // Condition describes the weather condition in the requested language.
+	ConditionCategory  string       `json:"condition_category,omitempty"` // ConditionCategory classifies the condition as clear, cloudy, fog, rain, snow or storm (see conditionCategory).
+	AirQuality         *AirQuality  `json:"air_quality,omitempty"`        // AirQuality is only set when air quality data was requested.
+	Source             string       `json:"source"`                       // Source attributes the data to the provider that served it, as required by its terms.
+	Query              string       `json:"query,omitempty"`              // Query is the location query as sent by the client.
+	MatchedNameDiffers bool         `json:"matched_name_differs"`         // MatchedNameDiffers is set when WeatherAPI resolved the query to a location with another name.
+	NearestMatch       bool         `json:"nearest_match,omitempty"`      // NearestMatch is set when a nearby cached location is served in place of the queried coordinates; it is never cached.
+	Stale              bool         `json:"-"`                            // Stale is set when the data is an expired copy served because WeatherAPI failed; it is never cached.
+	ContentHash        string       `json:"-"`                            // ContentHash is the hash of the data as cached, used as its entity tag; it is empty for uncached data.
+	CachedAt           time.Time    `json:"-"`                            // CachedAt is when the data was stored in the cache, as stored with it; it is zero for uncached data.
+	Meta               *WeatherMeta `json:"meta,omitempty"`               // Meta tells how fresh the data is; it is only set when requested and never cached.
+}
+
+// WeatherMeta describes the freshness of weather data, gathering in one place what clients need to tell its age.
+type WeatherMeta struct {
+	Cached              bool       `json:"cached"`                // Cached is set when the data was served from the cache rather than fetched for this request.
+	CachedAt            *time.Time `json:"cached_at"`             // CachedAt is when the data was stored in the cache (UTC); null for data that is never cached.
+	TTLRemainingSeconds int        `json:"ttl_remaining_seconds"` // TTLRemainingSeconds is how long the cache entry still lives; 0 for expired or uncached data.
+	UpstreamObservedAt  time.Time  `json:"upstream_observed_at"`  // UpstreamObservedAt is the local time of the upstream observation ("last_updated").
+}
+
+// AstronomyData holds the sun and moon data of a location for a single date.
+type AstronomyData struct {
+	Name             string  `json:"name"`              // Name represents the name of the location (e.g., city, town, etc.).
+	Region           string  `json:"region"`            // Region represents the state or province of the location.
+	Country          string  `json:"country"`           // Country represents the country of the location.
+	Lat              float64 `json:"lat"`               // Using float64 for better precision.
+	Lon              float64 `json:"lon"`               // Using float64 for better precision.
+	TzID             string  `json:"tz_id"`             // TzID is the IANA time zone in which the times are expressed.
+	Date             string  `json:"date"`              // Date is the date (YYYY-MM-DD) the data applies to.
+	Sunrise          string  `json:"sunrise"`           // Sunrise is the local time of sunrise (e.g., "05:43 AM").
+	Sunset           string  `json:"sunset"`            // Sunset is the local time of sunset.
+	Moonrise         string  `json:"moonrise"`          // Moonrise is the local time of moonrise.
+	Moonset          string  `json:"moonset"`           // Moonset is the local time of moonset.
+	MoonPhase        string  `json:"moon_phase"`        // MoonPhase is the name of the moon phase (e.g., "Waxing Gibbous").
+	MoonIllumination int     `json:"moon_illumination"` // MoonIllumination is the illuminated percentage of the moon.
+	Source           string  `json:"source"`            // Source attributes the data to the provider that served it.
+}
+
+// HistoryData holds the daily weather summaries of a location over a range of past days.
+type HistoryData struct {
+	Name    string       `json:"name"`    // Name represents the name of the location (e.g., city, town, etc.).
+	Region  string       `json:"region"`  // Region represents the state or province of the location.
+	Country string       `json:"country"` // Country represents the country of the location.
+	Lat     float64      `json:"lat"`     // Using float64 for better precision.
+	Lon     float64      `json:"lon"`     // Using float64 for better precision.
+	TzID    string       `json:"tz_id"`   // TzID is the IANA time zone of the location.
+	Days    []HistoryDay `json:"days"`    // Days holds one summary per requested day, oldest first.
+	Source  string       `json:"source"`  // Source attributes the data to the provider that served it.
+}
+
+// HistoryDay summarizes the recorded weather of a location on a single day.
+type HistoryDay struct {
+	Date          string  `json:"date"`            // Date is the date (YYYY-MM-DD) the summary applies to.
+	MaxTempC      float64 `json:"max_temp_c"`      // MaxTempC is the highest temperature of the day in Celsius.
+	MinTempC      float64 `json:"min_temp_c"`      // MinTempC is the lowest temperature of the day in Celsius.
+	AvgTempC      float64 `json:"avg_temp_c"`      // AvgTempC is the average temperature of the day in Celsius.
+	MaxWindKph    float64 `json:"max_wind_kph"`    // MaxWindKph is the highest wind speed of the day.
+	TotalPrecipMm float64 `json:"total_precip_mm"` // TotalPrecipMm is the total precipitation of the day in millimeters.
+	AvgHumidity   float64 `json:"avg_humidity"`    // AvgHumidity is the average humidity of the day in percent.
+	Condition     string  `json:"condition"`       // Condition describes the prevailing weather condition.
+}
+
+// LocationCandidate represents a single match returned by WeatherAPI's search endpoint.
+// It is used to let clients disambiguate queries that match several places (e.g. "Springfield").
+type LocationCandidate struct {
+	Name    string  `json:"name"`    // Name represents the name of the matched location.
+	Region  string  `json:"region"`  // Region represents the state or province of the matched location.
+	Country string  `json:"country"` // Country represents the country of the matched location.
+	Lat     float64 `json:"lat"`     // Using float64 for better precision.
+	Lon     float64 `json:"lon"`     // Using float64 for better precision.
+}
+
+// User holds the non-sensitive details of a registered user, as exposed to administrators.
+type User struct {
+	ID        int       `json:"id"`         // ID is the unique identifier of the user.
+	Name      string    `json:"name"`       // Name is the user's first name.
+	Surname   string    `json:"surname"`    // Surname is the user's last name.
+	Username  string    `json:"username"`   // Username is the unique login name of the user.
+	CreatedAt time.Time `json:"created_at"` // CreatedAt is the time the user signed up.
+}
+
+// APIKey is an API key of a user together with the scopes it is limited to.
+type APIKey struct {
+	Key    string   `json:"api_key"` // Key is the API key itself.
+	Scopes []string `json:"scopes"`  // Scopes lists the scopes the key is limited to; empty means unrestricted.
+}
+
+// APIKeyDeletionResult reports whether the API key named by one identifier of a bulk deletion was deleted.
+type APIKeyDeletionResult struct {
+	ID      string `json:"id"`              // ID is the key ID or key prefix as requested.
+	Deleted bool   `json:"deleted"`         // Deleted reports whether a key was deleted.
+	Error   string `json:"error,omitempty"` // Error tells why nothing was deleted (e.g. "API key not found").
+}
+
+// Preferences holds the defaults a user wants applied to weather requests that don't set them explicitly.
+type Preferences struct {
+	Units string `json:"units"` // Units is the preferred unit system (units.Metric, units.Both or units.Kelvin); empty for the system default.
+	Lang  string `json:"lang"`  // Lang is the preferred WeatherAPI language code; empty for English.
+}
+
+// Threshold is a user-defined condition on a location's weather, e.g. "London temp_c > 35".
+type Threshold struct {
+	ID        int       `json:"id"`         // ID is the unique identifier of the threshold.
+	Location  string    `json:"location"`   // Location is the location query the threshold watches.
+	Metric    string    `json:"metric"`     // Metric is the weather field compared (temp_c, wind_kph or cloud).
+	Operator  string    `json:"operator"`   // Operator is the comparison, either ">" or "<".
+	Value     float64   `json:"value"`      // Value is the threshold the metric is compared against.
+	CreatedAt time.Time `json:"created_at"` // CreatedAt is the time the threshold was registered.
+	Triggered bool      `json:"triggered"`  // Triggered is whether the threshold was crossed when it was last checked.
+	// Evaluated is whether the location is fetched by every cache refresh. The thresholds of other locations
+	// are only checked while a client happens to keep the location cached, so they may never trigger.
+	Evaluated bool `json:"evaluated"`
+}
+
+// Alert records that a threshold was crossed during a cache refresh.
+type Alert struct {
+	ID             int       `json:"id"`              // ID is the unique identifier of the alert.
+	ThresholdID    int       `json:"threshold_id"`    // ThresholdID is the threshold that triggered the alert.
+	Location       string    `json:"location"`        // Location is the location query of the threshold.
+	Metric         string    `json:"metric"`          // Metric is the weather field that crossed the threshold.
+	Operator       string    `json:"operator"`        // Operator is the comparison of the threshold.
+	ThresholdValue float64   `json:"threshold_value"` // ThresholdValue is the value of the threshold when it triggered.
+	ObservedValue  float64   `json:"observed_value"`  // ObservedValue is the value of the metric that triggered the alert.
+	TriggeredAt    time.Time `json:"triggered_at"`    // TriggeredAt is the time the alert was recorded.
+}
+
+// LocationGroup is a named list of locations saved by a user, e.g. "european-offices".
+type LocationGroup struct {
+	Name      string    `json:"name"`                 // Name identifies the group among the user's groups.
+	Locations []string  `json:"locations"`            // Locations lists the location queries of the group, in order.
+	CreatedAt time.Time `json:"created_at,omitempty"` // CreatedAt is the time the group was first saved.
+}
+
+// DailyUsage is the number of requests made with an API key on a single day.
+type DailyUsage struct {
+	Date     string `json:"date"`     // Date is the UTC day, formatted as YYYY-MM-DD.
+	Requests int    `json:"requests"` // Requests is the number of requests made that day.
+}
+
+// RefreshProgress reports how far a cache refresh has come, after each location.
+type RefreshProgress struct {
+	Location  string `json:"location"`  // Location is the location that was just refreshed.
+	Done      int    `json:"done"`      // Done is the number of locations processed so far, including Location.
+	Total     int    `json:"total"`     // Total is the number of locations of the refresh.
+	Succeeded int    `json:"succeeded"` // Succeeded is the number of locations cached successfully so far.
+	Failed    int    `json:"failed"`    // Failed is the number of locations that could not be fetched so far.
 }
@@ -0,0 +1,126 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"strings"
+)
+
+// earthRadiusKm is the mean radius of the Earth used for great-circle distances.
+const earthRadiusKm = 6371.0
+
+// nearestCacheBatchSize is the number of cached entries read with a single MGET while searching for the nearest one.
+const nearestCacheBatchSize = 500
+
+// fallBackToNearestCached serves the cached location nearest to a coordinate query when the upstream request
+// failed with upstreamErr and the exact coordinates aren't cached, trading precision for availability.
+// Only entries cached with the same options within NearestCacheRadiusKm qualify, and the result is marked
+// with NearestMatch. It returns the data with the cache key it was read from, or upstreamErr unchanged
+// if the fallback is disabled, the query has no coordinates, the upstream failure is not an outage
+// or no cached location is close enough.
+func (s *WeatherAPIService) fallBackToNearestCached(q string, opts WeatherOptions, upstreamErr error) (FormattedWeatherData, string, error) {
+	if !s.cfg.CacheEnabled || !s.cfg.NearestCacheFallback || !canServeStale(upstreamErr) {
+		return FormattedWeatherData{}, "", upstreamErr
+	}
+	lat, lon, ok := parseCoordinates(q)
+	if !ok {
+		return FormattedWeatherData{}, "", upstreamErr
+	}
+
+	weatherData, key, err := s.nearestCachedWeatherData(context.Background(), lat, lon, opts)
+	if err != nil {
+		log.Printf("failed to search the nearest cached location to %s: %v", q, err)
+		return FormattedWeatherData{}, "", upstreamErr
+	}
+	if key == "" {
+		return FormattedWeatherData{}, "", upstreamErr
+	}
+
+	log.Println(fmt.Errorf("serving nearest cached location %s for %s: %w", key, q, upstreamErr))
+	weatherData.NearestMatch = true
+	return weatherData, key, nil
+}
+
+// nearestCachedWeatherData scans the weather cache for the entry closest to the given coordinates within
+// NearestCacheRadiusKm, using the coordinates stored with every entry. It returns an empty key if none is close enough.
+// The scan reads every weather entry, which is acceptable for an outage fallback but too costly for regular lookups.
+func (s *WeatherAPIService) nearestCachedWeatherData(ctx context.Context, lat, lon float64, opts WeatherOptions) (FormattedWeatherData, string, error) {
+	var nearest FormattedWeatherData
+	var nearestKey string
+	nearestDistance := s.cfg.NearestCacheRadiusKm
+
+	// Compare a batch of entries read with MGET against the closest one so far.
+	compare := func(keys []string) error {
+		values, err := s.redisClient.MGet(ctx, keys...).Result()
+		if err != nil {
+			return fmt.Errorf("failed to get cached weather data: %w", err)
+		}
+		for i, value := range values {
+			jsonData, ok := value.(string)
+			if !ok {
+				// The entry expired between the scan and the read.
+				continue
+			}
+			weatherData, err := decodeCachedWeatherData([]byte(jsonData))
+			if err != nil {
+				continue
+			}
+			if distance := distanceKm(lat, lon, weatherData.Lat, weatherData.Lon); distance <= nearestDistance {
+				nearest, nearestKey, nearestDistance = weatherData, strings.TrimPrefix(keys[i], s.redisClient.prefix), distance
+			}
+		}
+		return nil
+	}
+
+	// Only entries fetched with the same options carry the requested language and air quality data.
+	suffix := cacheKeyOptionsSuffix(opts.cacheKey(""))
+
+	var batch []string
+	iter := s.redisClient.Scan(ctx, 0, s.redisClient.prefixed("weather:*"), 500).Iterator()
+	for iter.Next(ctx) {
+		if cacheKeyOptionsSuffix(iter.Val()) != suffix {
+			continue
+		}
+		batch = append(batch, iter.Val())
+		if len(batch) == nearestCacheBatchSize {
+			if err := compare(batch); err != nil {
+				return FormattedWeatherData{}, "", err
+			}
+			batch = batch[:0]
+		}
+	}
+	if err := iter.Err(); err != nil {
+		return FormattedWeatherData{}, "", fmt.Errorf("failed to scan weather cache keys: %w", err)
+	}
+	if len(batch) > 0 {
+		if err := compare(batch); err != nil {
+			return FormattedWeatherData{}, "", err
+		}
+	}
+
+	return nearest, nearestKey, nil
+}
+
+// cacheKeyOptionsSuffix returns the part of a weather cache key added by WeatherOptions.cacheKey
+// (e.g. ":lang=fr:aqi"), or an empty string for an entry fetched with the default options.
+func cacheKeyOptionsSuffix(key string) string {
+	if i := strings.Index(key, ":lang="); i >= 0 {
+		return key[i:]
+	}
+	if strings.HasSuffix(key, ":aqi") {
+		return ":aqi"
+	}
+	return ""
+}
+
+// distanceKm returns the great-circle distance between two coordinates in kilometers (haversine formula).
+func distanceKm(lat1, lon1, lat2, lon2 float64) float64 {
+	toRadians := func(degrees float64) float64 { return degrees * math.Pi / 180 }
+
+	dLat := toRadians(lat2 - lat1)
+	dLon := toRadians(lon2 - lon1)
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) + math.Cos(toRadians(lat1))*math.Cos(toRadians(lat2))*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2 * earthRadiusKm * math.Asin(math.Sqrt(a))
+}
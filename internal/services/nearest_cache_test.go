@@ -0,0 +1,64 @@
+package services
+
+import (
+	"context"
+	"math"
+	"testing"
+)
+
+func TestDistanceKm(t *testing.T) {
+	tests := []struct {
+		name                   string
+		lat1, lon1, lat2, lon2 float64
+		want                   float64
+	}{
+		{name: "same point", lat1: 48.8566, lon1: 2.3522, lat2: 48.8566, lon2: 2.3522, want: 0},
+		{name: "one degree of latitude", lat1: 0, lon1: 0, lat2: 1, lon2: 0, want: 111.19},
+		{name: "Paris to London", lat1: 48.8566, lon1: 2.3522, lat2: 51.5072, lon2: -0.1276, want: 343.53},
+		{name: "across the antimeridian", lat1: 0, lon1: 179.95, lat2: 0, lon2: -179.95, want: 11.12},
+		{name: "antipodes", lat1: 0, lon1: 0, lat2: 0, lon2: 180, want: math.Pi * earthRadiusKm},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := distanceKm(tt.lat1, tt.lon1, tt.lat2, tt.lon2)
+			if math.Abs(got-tt.want) > 0.01 {
+				t.Errorf("distanceKm() = %.3f km, want %.2f km", got, tt.want)
+			}
+			if back := distanceKm(tt.lat2, tt.lon2, tt.lat1, tt.lon1); math.Abs(back-got) > 1e-9 {
+				t.Errorf("distanceKm() is %.3f km one way and %.3f km back", got, back)
+			}
+		})
+	}
+}
+
+func TestNearestCachedWeatherDataHonorsTheRadius(t *testing.T) {
+	ts := newTestService(t, nil)
+	if _, err := ts.cacheTheWeatherDataToRedis(weatherCacheKey("Paris"), FormattedWeatherData{Name: "Paris", Lat: 48.8566, Lon: 2.3522}); err != nil {
+		t.Fatalf("cacheTheWeatherDataToRedis failed: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		lat, lon float64
+		wantKey  string
+	}{
+		{name: "same coordinates", lat: 48.8566, lon: 2.3522, wantKey: weatherCacheKey("Paris")},
+		{name: "within the radius", lat: 48.90, lon: 2.40, wantKey: weatherCacheKey("Paris")}, // about 6 km away
+		{name: "outside the radius", lat: 48.95, lon: 2.50, wantKey: ""},                      // about 15 km away
+		{name: "far away", lat: 51.5072, lon: -0.1276, wantKey: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, key, err := ts.nearestCachedWeatherData(context.Background(), tt.lat, tt.lon, WeatherOptions{})
+			if err != nil {
+				t.Fatalf("nearestCachedWeatherData failed: %v", err)
+			}
+			if key != tt.wantKey {
+				t.Errorf("nearest key = %q, want %q", key, tt.wantKey)
+			}
+			if tt.wantKey != "" && data.Name != "Paris" {
+				t.Errorf("nearest location = %q, want Paris", data.Name)
+			}
+		})
+	}
+}
@@ -0,0 +1,158 @@
+package services
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Names of the supported password hashing algorithms, as selected with PASSWORD_HASHER.
+const (
+	PasswordHasherBcrypt   = "bcrypt"   // PasswordHasherBcrypt hashes passwords with bcrypt (the default).
+	PasswordHasherArgon2id = "argon2id" // PasswordHasherArgon2id hashes passwords with argon2id, as some compliance regimes require.
+)
+
+// errPasswordMismatch is returned by PasswordHasher.Compare when the password does not match the hash.
+var errPasswordMismatch = errors.New("password does not match")
+
+// PasswordHasher hashes passwords and verifies them against stored hashes.
+// Every hash starts with a prefix naming its algorithm (e.g. "$2a$" or "$argon2id$"),
+// so hashes of every algorithm keep verifying after switching to another one.
+type PasswordHasher interface {
+	// Hash returns the hash of the password, including its algorithm prefix and parameters.
+	Hash(password string) (string, error)
+
+	// Compare checks the password against a hash produced by this algorithm.
+	// It returns errPasswordMismatch if the password is wrong.
+	Compare(hash, password string) error
+
+	// Owns reports whether the hash was produced by this algorithm.
+	Owns(hash string) bool
+}
+
+// NewPasswordHasher returns the hasher of the named algorithm, used to hash new passwords.
+// The bcrypt cost only applies to the bcrypt hasher.
+func NewPasswordHasher(name string, bcryptCost int) (PasswordHasher, error) {
+	switch name {
+	case PasswordHasherBcrypt:
+		return BcryptHasher{Cost: bcryptCost}, nil
+	case PasswordHasherArgon2id:
+		return defaultArgon2idHasher, nil
+	default:
+		return nil, fmt.Errorf("unsupported password hasher %q", name)
+	}
+}
+
+// passwordVerifiers lists a hasher of every supported algorithm, used to verify existing hashes.
+var passwordVerifiers = []PasswordHasher{BcryptHasher{Cost: bcrypt.DefaultCost}, defaultArgon2idHasher}
+
+// verifyPassword checks the password against a hash of any supported algorithm.
+// It returns errPasswordMismatch if the password is wrong or the hash has an unknown algorithm.
+func verifyPassword(hash, password string) error {
+	for _, verifier := range passwordVerifiers {
+		if verifier.Owns(hash) {
+			return verifier.Compare(hash, password)
+		}
+	}
+	return errPasswordMismatch
+}
+
+// BcryptHasher hashes passwords with bcrypt. Its hashes start with "$2a$", "$2b$" or "$2y$".
+type BcryptHasher struct {
+	Cost int // Cost is the bcrypt work factor.
+}
+
+// Hash returns the bcrypt hash of the password.
+func (h BcryptHasher) Hash(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), h.Cost)
+	if err != nil {
+		return "", fmt.Errorf("failed to hash password with bcrypt: %w", err)
+	}
+	return string(hash), nil
+}
+
+// Compare checks the password against a bcrypt hash.
+func (h BcryptHasher) Compare(hash, password string) error {
+	if err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)); err != nil {
+		return errPasswordMismatch
+	}
+	return nil
+}
+
+// Owns reports whether the hash is a bcrypt hash.
+func (h BcryptHasher) Owns(hash string) bool {
+	return strings.HasPrefix(hash, "$2a$") || strings.HasPrefix(hash, "$2b$") || strings.HasPrefix(hash, "$2y$")
+}
+
+// argon2idPrefix starts every argon2id hash, in the PHC string format used by the reference implementation:
+// $argon2id$v=19$m=<memory KiB>,t=<iterations>,p=<threads>$<salt>$<key>, with unpadded base64 salt and key.
+const argon2idPrefix = "$argon2id$"
+
+// defaultArgon2idHasher uses the parameters recommended by RFC 9106 for memory-constrained environments.
+var defaultArgon2idHasher = Argon2idHasher{Time: 3, Memory: 64 * 1024, Threads: 4, SaltLen: 16, KeyLen: 32}
+
+// Argon2idHasher hashes passwords with argon2id.
+type Argon2idHasher struct {
+	Time    uint32 // Time is the number of passes over the memory.
+	Memory  uint32 // Memory is the memory used in KiB.
+	Threads uint8  // Threads is the degree of parallelism.
+	SaltLen int    // SaltLen is the length of the random salt in bytes.
+	KeyLen  uint32 // KeyLen is the length of the derived key in bytes.
+}
+
+// Hash returns the argon2id hash of the password with a random salt.
+func (h Argon2idHasher) Hash(password string) (string, error) {
+	salt := make([]byte, h.SaltLen)
+	if _, err := rand.Read(salt); err != nil {
+		return "", fmt.Errorf("failed to generate password salt: %w", err)
+	}
+
+	key := argon2.IDKey([]byte(password), salt, h.Time, h.Memory, h.Threads, h.KeyLen)
+	return fmt.Sprintf("%sv=%d$m=%d,t=%d,p=%d$%s$%s", argon2idPrefix, argon2.Version, h.Memory, h.Time, h.Threads,
+		base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key)), nil
+}
+
+// Compare checks the password against an argon2id hash, using the parameters stored in the hash.
+func (h Argon2idHasher) Compare(hash, password string) error {
+	// "$argon2id$v=19$m=...,t=...,p=...$salt$key" splits into "", "argon2id", version, params, salt and key.
+	parts := strings.Split(hash, "$")
+	if len(parts) != 6 {
+		return errPasswordMismatch
+	}
+
+	var version int
+	var memory, time uint32
+	var threads uint8
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return errPasswordMismatch
+	}
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &memory, &time, &threads); err != nil {
+		return errPasswordMismatch
+	}
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return errPasswordMismatch
+	}
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil {
+		return errPasswordMismatch
+	}
+
+	// Compare in constant time so the comparison does not leak how much of the key matched.
+	candidate := argon2.IDKey([]byte(password), salt, time, memory, threads, uint32(len(key)))
+	if subtle.ConstantTimeCompare(candidate, key) != 1 {
+		return errPasswordMismatch
+	}
+	return nil
+}
+
+// Owns reports whether the hash is an argon2id hash.
+func (h Argon2idHasher) Owns(hash string) bool {
+	return strings.HasPrefix(hash, argon2idPrefix)
+}
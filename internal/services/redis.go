@@ -0,0 +1,93 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"havoAPI/api/config"
+	"net"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// RedisClient is the Redis client shared by the services.
+// Every key it stores is scoped to the configured prefix, so that several environments
+// (e.g. staging and production) can share a Redis server without colliding.
+// Redis may be left unconfigured while the weather cache is disabled; every command must then be skipped
+// (see configured), and the state that can't be skipped is kept in the local fallback store instead.
+type RedisClient struct {
+	*redis.Client
+
+	// prefix is prepended to every key written or read through the client.
+	prefix string
+
+	// local holds revoked tokens and anonymous counters when no Redis server is configured; nil otherwise.
+	local *localStore
+}
+
+// NewRedisClient creates the Redis client shared by the services, using the credentials,
+// database index and key prefix from the provided config. Without an address, go-redis would fall back
+// to localhost:6379, so the client refuses to connect instead and commands fail with ErrRedisNotConfigured.
+func NewRedisClient(cfg *config.Config) *RedisClient {
+	options := &redis.Options{
+		Addr:        cfg.RedisAddr,
+		Password:    cfg.RedisPass,
+		DB:          cfg.RedisDB,
+		DialTimeout: 5 * time.Second,
+	}
+	var local *localStore
+	if cfg.RedisAddr == "" {
+		options.Dialer = func(context.Context, string, string) (net.Conn, error) {
+			return nil, ErrRedisNotConfigured
+		}
+		local = newLocalStore()
+	}
+
+	return &RedisClient{
+		Client: redis.NewClient(options),
+		prefix: cfg.RedisKeyPrefix,
+		local:  local,
+	}
+}
+
+// configured reports whether a Redis server is configured. It is always the case while the weather cache
+// is enabled; features that keep other state in Redis must check it before sending a command.
+func (r *RedisClient) configured() bool {
+	return r.local == nil
+}
+
+// prefixed scopes a key derived by one of the *Key helpers to the configured prefix.
+// It must wrap every key passed to a Redis command.
+func (r *RedisClient) prefixed(key string) string {
+	return r.prefix + key
+}
+
+// deleteByPattern deletes every key of this client's prefix matching the given pattern (e.g. "weather:*").
+// Keys are found with SCAN rather than KEYS so that large caches don't block Redis.
+func (r *RedisClient) deleteByPattern(ctx context.Context, pattern string) error {
+	iter := r.Scan(ctx, 0, r.prefixed(pattern), 500).Iterator()
+
+	var batch []string
+	for iter.Next(ctx) {
+		batch = append(batch, iter.Val())
+
+		// Delete in batches to bound the size of each DEL command.
+		if len(batch) == 500 {
+			if err := r.Del(ctx, batch...).Err(); err != nil {
+				return fmt.Errorf("failed to delete keys matching %s: %w", pattern, err)
+			}
+			batch = batch[:0]
+		}
+	}
+	if err := iter.Err(); err != nil {
+		return fmt.Errorf("failed to scan keys matching %s: %w", pattern, err)
+	}
+
+	if len(batch) > 0 {
+		if err := r.Del(ctx, batch...).Err(); err != nil {
+			return fmt.Errorf("failed to delete keys matching %s: %w", pattern, err)
+		}
+	}
+
+	return nil
+}
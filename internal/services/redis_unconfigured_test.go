@@ -0,0 +1,141 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"havoAPI/api/config"
+	"havoAPI/internal/clock"
+	"havoAPI/internal/models"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestNoRedisCommandsWithoutRedis(t *testing.T) {
+	ts := newTestService(t, func(cfg *config.Config) {
+		cfg.CacheEnabled = false
+		cfg.RedisAddr = ""
+		cfg.AnonymousDailyLimit = 1
+	})
+	ts.redisClient = NewRedisClient(ts.cfg)
+	t.Cleanup(func() { ts.redisClient.Close() })
+	counter := ts.countCommands()
+	upstream := &astronomyUpstream{}
+	ts.upstream.handle(upstream.handler(map[string]string{"London": "Europe/London"}))
+
+	// Weather lookups, with every option touching the cache
+	ctx := context.Background()
+	for _, opts := range []WeatherOptions{{}, {Meta: true}, {MaxAge: time.Minute}, {ForceRefresh: true}} {
+		if _, err := ts.FetchWeatherData(ctx, "London", opts); err != nil {
+			t.Fatalf("FetchWeatherData(%+v) failed: %v", opts, err)
+		}
+	}
+	if _, err := ts.FetchBulkWeatherData([]string{"London", "48.85,2.35"}, nil); err != nil {
+		t.Fatalf("FetchBulkWeatherData failed: %v", err)
+	}
+	if _, err := ts.FetchAstronomyData("London", ""); err != nil {
+		t.Fatalf("FetchAstronomyData failed: %v", err)
+	}
+	if _, err := ts.CachedWeatherDataHash("London", WeatherOptions{}); !errors.Is(err, ErrNoDataCache) {
+		t.Errorf("CachedWeatherDataHash returned %v, want ErrNoDataCache", err)
+	}
+
+	// API keys and the anonymous allowance, which falls back to counting in memory
+	if _, err := ts.APIKeyAuthorization(ctx, "valid-key"); err != nil {
+		t.Fatalf("APIKeyAuthorization failed: %v", err)
+	}
+	if err := ts.InvalidateAPIKey("valid-key"); err != nil {
+		t.Fatalf("InvalidateAPIKey failed: %v", err)
+	}
+	if _, err := ts.AllowAnonymousRequest("203.0.113.7"); err != nil {
+		t.Fatalf("first anonymous request failed: %v", err)
+	}
+	if _, err := ts.AllowAnonymousRequest("203.0.113.7"); !errors.Is(err, ErrAnonymousLimitExceeded) {
+		t.Errorf("anonymous request over the allowance returned %v, want ErrAnonymousLimitExceeded", err)
+	}
+
+	// Cache administration and metrics
+	if _, err := ts.CacheFootprint(ctx); err != nil {
+		t.Fatalf("CacheFootprint failed: %v", err)
+	}
+	if _, err := ts.CacheStats(ctx); !errors.Is(err, ErrCacheDisabled) {
+		t.Errorf("CacheStats returned %v, want ErrCacheDisabled", err)
+	}
+	if err := ts.UpdateWeatherDataInTheRedisCache(nil); !errors.Is(err, ErrCacheDisabled) {
+		t.Errorf("UpdateWeatherDataInTheRedisCache returned %v, want ErrCacheDisabled", err)
+	}
+	thresholds := &fakeThresholdsDB{thresholds: []models.Threshold{{ID: 1, UserID: 1, Location: "London", Metric: "temp_c", Operator: ">", Value: -100}}}
+	if err := NewAlertsService(thresholds, ts.redisClient).CheckThresholds(); err != nil {
+		t.Fatalf("CheckThresholds failed: %v", err)
+	}
+
+	// Sessions and preferences, with revocations kept in memory
+	db := &fakeUsersDB{tokensValidAfter: map[int]time.Time{7: {}}, preferences: map[int]Preferences{7: {Units: "metric"}}}
+	users := NewUsersService(db, ts.redisClient, BcryptHasher{Cost: bcrypt.MinCost}, &fakeAPIKeyInvalidator{}, clock.NewFake(testNow), testMaxAPIKeys)
+	if err := users.RevokeToken("jti-1", testNow.Add(time.Hour)); err != nil {
+		t.Fatalf("RevokeToken failed: %v", err)
+	}
+	for jti, want := range map[string]bool{"jti-1": true, "jti-2": false} {
+		if revoked, err := users.IsTokenRevoked(jti); err != nil || revoked != want {
+			t.Errorf("IsTokenRevoked(%s) = %v, %v, want %v", jti, revoked, err, want)
+		}
+	}
+	if err := users.InvalidateSessions(7); err != nil {
+		t.Fatalf("InvalidateSessions failed: %v", err)
+	}
+	if validAfter, err := users.TokensValidAfter(7); err != nil || !validAfter.Equal(testNow) {
+		t.Errorf("TokensValidAfter = %v, %v, want %v", validAfter, err, testNow)
+	}
+	if err := users.SetPreferences(7, Preferences{Lang: "fr"}); err != nil {
+		t.Fatalf("SetPreferences failed: %v", err)
+	}
+	if _, lang, err := users.PreferredSettings(7); err != nil || lang != "fr" {
+		t.Errorf("PreferredSettings = %q, %v, want fr", lang, err)
+	}
+
+	if got := counter.total(); got != 0 {
+		t.Errorf("%d Redis commands were sent without a Redis server configured, want none (%v)", got, counter.counts)
+	}
+}
+
+func TestUnconfiguredRedisNeverDialsTheDefaultAddress(t *testing.T) {
+	redisClient := NewRedisClient(&config.Config{})
+	defer redisClient.Close()
+
+	if err := redisClient.Ping(context.Background()).Err(); !errors.Is(err, ErrRedisNotConfigured) {
+		t.Errorf("Ping returned %v, want ErrRedisNotConfigured", err)
+	}
+}
+
+func TestLocalStoreExpiresEntries(t *testing.T) {
+	store := newLocalStore()
+	if got := store.incr("counter", time.Hour, testNow); got != 1 {
+		t.Errorf("first incr = %d, want 1", got)
+	}
+	if got := store.incr("counter", time.Hour, testNow.Add(59*time.Minute)); got != 2 {
+		t.Errorf("incr before the expiry = %d, want 2", got)
+	}
+	// The second incr pushed the expiry back, like EXPIRE does
+	if got := store.incr("counter", time.Hour, testNow.Add(90*time.Minute)); got != 3 {
+		t.Errorf("incr within the renewed expiry = %d, want 3", got)
+	}
+	if got := store.incr("counter", time.Hour, testNow.Add(4*time.Hour)); got != 1 {
+		t.Errorf("incr after the expiry = %d, want 1", got)
+	}
+
+	later := testNow.Add(5 * time.Hour)
+	store.set("flag", time.Minute, later)
+	if !store.exists("flag", later.Add(59*time.Second)) {
+		t.Error("flag expired before its TTL")
+	}
+	if store.exists("flag", later.Add(time.Minute)) {
+		t.Error("flag outlived its TTL")
+	}
+
+	// Expired entries don't pile up
+	store.set("other", time.Minute, later.Add(time.Hour))
+	if _, ok := store.entries["flag"]; ok {
+		t.Error("expired flag was kept after a sweep")
+	}
+}
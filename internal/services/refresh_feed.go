@@ -0,0 +1,104 @@
+package services
+
+import (
+	"sync"
+)
+
+// RefreshEvent is an update of a cache refresh, delivered to the clients following it (see WatchCacheRefresh).
+type RefreshEvent struct {
+	Progress RefreshProgress // Progress is the state of the refresh after the last location processed.
+	Done     bool            // Done is set on the last event of the refresh, after which the channel is closed.
+	Err      error           // Err is the error the refresh failed with, set only on the last event.
+}
+
+// refreshFeed fans out the progress of the running cache refresh to the clients following it.
+// Every subscriber holds only the latest event: a slow subscriber skips intermediate progress,
+// which is cumulative anyway, but always receives the last event. The refresh never blocks on a subscriber.
+type refreshFeed struct {
+	mu          sync.Mutex
+	running     bool
+	last        *RefreshEvent // last is the latest event of the running refresh, nil before its first location
+	subscribers map[chan RefreshEvent]struct{}
+}
+
+// newRefreshFeed creates a feed without a running refresh.
+func newRefreshFeed() *refreshFeed {
+	return &refreshFeed{subscribers: make(map[chan RefreshEvent]struct{})}
+}
+
+// begin marks the start of a refresh.
+func (f *refreshFeed) begin() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.running = true
+	f.last = nil
+}
+
+// publish hands the progress of the running refresh to every subscriber.
+func (f *refreshFeed) publish(progress RefreshProgress) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.send(RefreshEvent{Progress: progress})
+}
+
+// finish hands the last event of the refresh to every subscriber and ends their subscriptions.
+func (f *refreshFeed) finish(err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	event := RefreshEvent{Done: true, Err: err}
+	if f.last != nil {
+		event.Progress = f.last.Progress
+	}
+	f.send(event)
+	for ch := range f.subscribers {
+		close(ch)
+		delete(f.subscribers, ch)
+	}
+	f.running = false
+	f.last = nil
+}
+
+// send replaces the pending event of every subscriber with event. The caller must hold f.mu.
+func (f *refreshFeed) send(event RefreshEvent) {
+	f.last = &event
+	for ch := range f.subscribers {
+		// Only send holds the lock, so the buffer has room once the pending event is dropped
+		select {
+		case <-ch:
+		default:
+		}
+		ch <- event
+	}
+}
+
+// subscribe follows the running refresh, starting with its latest progress, if any.
+// It returns ErrNoCacheRefreshInProgress if no refresh is running. The returned function ends the
+// subscription early; it must be called once the subscriber is done, even after the channel was closed.
+func (f *refreshFeed) subscribe() (<-chan RefreshEvent, func(), error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if !f.running {
+		return nil, nil, ErrNoCacheRefreshInProgress
+	}
+
+	ch := make(chan RefreshEvent, 1)
+	if f.last != nil {
+		ch <- *f.last
+	}
+	f.subscribers[ch] = struct{}{}
+
+	unsubscribe := func() {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		delete(f.subscribers, ch)
+	}
+	return ch, unsubscribe, nil
+}
+
+// WatchCacheRefresh follows the cache refresh in progress, whoever started it (an admin or the cron job).
+// The returned channel delivers its latest progress, then a final event with Done set, and is closed.
+// It returns ErrNoCacheRefreshInProgress if no refresh is running. The returned function ends the subscription.
+func (s *WeatherAPIService) WatchCacheRefresh() (<-chan RefreshEvent, func(), error) {
+	return s.refreshFeed.subscribe()
+}
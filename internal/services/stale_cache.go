@@ -0,0 +1,77 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+)
+
+// staleCacheKey derives the Redis key of the long-lived copy of the weather data cached under key.
+// It lives outside the "weather:" namespace so that the periodic refresh, which deletes every
+// weather entry before fetching new data, keeps the copy to fall back on when WeatherAPI fails.
+func staleCacheKey(key string) string {
+	return "stale:" + key
+}
+
+// rememberStaleCopy stores a copy of freshly fetched weather data, cached at cachedAt, that outlives the regular cache entry
+// by the configured maximum staleness. It does nothing when serving stale data is disabled.
+// Failing to store it only loses the fallback, so errors are logged and ignored.
+func (s *WeatherAPIService) rememberStaleCopy(key string, weatherData FormattedWeatherData, cachedAt time.Time) {
+	if !s.cfg.CacheEnabled || s.cfg.StaleCacheMaxAge <= 0 {
+		return
+	}
+
+	jsonData, _, err := encodeCachedWeatherData(weatherData, cachedAt)
+	if err != nil {
+		log.Printf("failed to marshal stale copy for %s: %v", key, err)
+		return
+	}
+
+	ttl := s.cfg.CacheTTL + s.cfg.StaleCacheMaxAge
+	if err := s.redisClient.Set(context.Background(), s.redisClient.prefixed(staleCacheKey(key)), jsonData, ttl).Err(); err != nil {
+		log.Printf("failed to set stale copy for %s: %v", key, err)
+	}
+}
+
+// retrieveStaleCopy returns the long-lived copy of the weather data cached under key, marked as stale.
+// It returns ErrNoDataCache if caching or serving stale data is disabled or no copy is left.
+func (s *WeatherAPIService) retrieveStaleCopy(key string) (FormattedWeatherData, error) {
+	if !s.cfg.CacheEnabled || s.cfg.StaleCacheMaxAge <= 0 {
+		return FormattedWeatherData{}, ErrNoDataCache
+	}
+
+	weatherData, err := readCachedWeatherData(s.redisClient, staleCacheKey(key))
+	if err != nil {
+		return FormattedWeatherData{}, err
+	}
+
+	weatherData.Stale = true
+	return weatherData, nil
+}
+
+// canServeStale reports whether an upstream error means WeatherAPI failed, rather than that the query was wrong.
+// Only then may a stale copy be served: an unknown location must keep failing even if it was once cached.
+func canServeStale(err error) bool {
+	return !errors.Is(err, ErrNoLocationFound) && !errors.Is(err, ErrUpstreamMissingQuery)
+}
+
+// fallBackToStaleCopy serves the stale copy of the weather data cached under key when the upstream
+// request failed with upstreamErr. It returns upstreamErr unchanged if no stale copy can be served.
+func (s *WeatherAPIService) fallBackToStaleCopy(key string, upstreamErr error) (FormattedWeatherData, error) {
+	if !canServeStale(upstreamErr) {
+		return FormattedWeatherData{}, upstreamErr
+	}
+
+	weatherData, err := s.retrieveStaleCopy(key)
+	if err != nil {
+		if !errors.Is(err, ErrNoDataCache) {
+			log.Printf("failed to read stale copy for %s: %v", key, err)
+		}
+		return FormattedWeatherData{}, upstreamErr
+	}
+
+	log.Println(fmt.Errorf("serving stale data for %s: %w", key, upstreamErr))
+	return weatherData, nil
+}
@@ -0,0 +1,60 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"havoAPI/api/config"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestFetchWeatherDataServesStaleDataOnUpstreamFailure(t *testing.T) {
+	ts := newTestService(t, func(cfg *config.Config) {
+		cfg.StaleCacheMaxAge = time.Hour
+	})
+	ts.upstream.handle(func(w http.ResponseWriter, r *http.Request) {
+		writeCurrentWeather(w, "London", 12)
+	})
+	if _, err := ts.FetchWeatherData(context.Background(), "London", WeatherOptions{}); err != nil {
+		t.Fatalf("FetchWeatherData failed: %v", err)
+	}
+
+	// The regular entry expires while WeatherAPI is down
+	ts.upstream.handle(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	})
+	ts.advance(ts.cfg.CacheTTL + time.Minute)
+
+	data, err := ts.FetchWeatherData(context.Background(), "London", WeatherOptions{})
+	if err != nil {
+		t.Fatalf("FetchWeatherData with a stale copy failed: %v", err)
+	}
+	if !data.Stale || data.TempC != 12 {
+		t.Errorf("served stale = %v with %v °C, want the stale copy at 12 °C", data.Stale, data.TempC)
+	}
+
+	// Past the maximum staleness, the failure is reported
+	ts.advance(ts.cfg.StaleCacheMaxAge)
+	if _, err := ts.FetchWeatherData(context.Background(), "London", WeatherOptions{}); err == nil {
+		t.Error("FetchWeatherData served data older than the maximum staleness")
+	}
+}
+
+func TestFetchWeatherDataDoesNotServeStaleDataForUnknownLocations(t *testing.T) {
+	ts := newTestService(t, func(cfg *config.Config) {
+		cfg.StaleCacheMaxAge = time.Hour
+	})
+	if _, err := ts.FetchWeatherData(context.Background(), "London", WeatherOptions{}); err != nil {
+		t.Fatalf("FetchWeatherData failed: %v", err)
+	}
+
+	// A location that no longer resolves must fail even though a copy exists
+	ts.upstream.handle(func(w http.ResponseWriter, r *http.Request) {
+		writeUpstreamError(w, http.StatusBadRequest, 1006)
+	})
+	ts.advance(ts.cfg.CacheTTL + time.Minute)
+	if _, err := ts.FetchWeatherData(context.Background(), "London", WeatherOptions{}); !errors.Is(err, ErrNoLocationFound) {
+		t.Errorf("err = %v, want ErrNoLocationFound", err)
+	}
+}
@@ -0,0 +1,70 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log"
+	"math"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Values of the temp_trend field of the formatted weather data.
+const (
+	TempTrendRising  = "rising"  // TempTrendRising means the temperature went up since the previous snapshot.
+	TempTrendFalling = "falling" // TempTrendFalling means the temperature went down since the previous snapshot.
+	TempTrendSteady  = "steady"  // TempTrendSteady means the temperature changed by less than tempTrendThreshold.
+	TempTrendUnknown = "unknown" // TempTrendUnknown means there is no previous snapshot to compare against.
+)
+
+// tempTrendThreshold is the smallest temperature change, in °C, reported as rising or falling.
+const tempTrendThreshold = 0.5
+
+// tempSnapshotTTL is how long the last known temperature of a location is kept for trend computation.
+// It outlives the weather cache entry so that the next fetch still has something to compare against.
+const tempSnapshotTTL = 24 * time.Hour
+
+// tempSnapshotKey derives the Redis key holding the last known temperature for a cache key.
+func tempSnapshotKey(key string) string {
+	return "snapshot:" + key
+}
+
+// tempTrend classifies the change from the previous temperature to the current one.
+func tempTrend(previous, current float64) string {
+	switch delta := current - previous; {
+	case math.Abs(delta) < tempTrendThreshold:
+		return TempTrendSteady
+	case delta > 0:
+		return TempTrendRising
+	default:
+		return TempTrendFalling
+	}
+}
+
+// withTempTrend compares freshly fetched data against the previous temperature snapshot of the location,
+// sets its temp_trend accordingly and stores the new temperature as the next snapshot.
+// Redis errors are logged and yield an unknown trend, since the trend is only informative.
+// With the cache disabled no snapshots are kept, so the trend is always unknown.
+func (s *WeatherAPIService) withTempTrend(key string, weatherData FormattedWeatherData) FormattedWeatherData {
+	ctx := context.Background()
+	weatherData.TempTrend = TempTrendUnknown
+	if !s.cfg.CacheEnabled {
+		return weatherData
+	}
+
+	// Compare against the previous snapshot, if any.
+	previous, err := s.redisClient.Get(ctx, s.redisClient.prefixed(tempSnapshotKey(key))).Float64()
+	if err == nil {
+		weatherData.TempTrend = tempTrend(previous, weatherData.TempC)
+	} else if !errors.Is(err, redis.Nil) {
+		log.Printf("failed to read temperature snapshot for %s: %v", key, err)
+	}
+
+	// Store the current temperature for the next comparison.
+	if err := s.redisClient.Set(ctx, s.redisClient.prefixed(tempSnapshotKey(key)), weatherData.TempC, tempSnapshotTTL).Err(); err != nil {
+		log.Printf("failed to store temperature snapshot for %s: %v", key, err)
+	}
+
+	return weatherData
+}
@@ -0,0 +1,80 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// WeatherAPI error codes, returned in the "error.code" field of failed responses.
+// See https://www.weatherapi.com/docs/#intro-error-codes.
+const (
+	upstreamKeyNotProvidedCode   = 1002 // API key not provided.
+	upstreamQueryNotProvidedCode = 1003 // Parameter 'q' not provided.
+	upstreamInvalidURLCode       = 1005 // API request url is invalid.
+	upstreamNoLocationFoundCode  = 1006 // No location found matching parameter 'q'.
+	upstreamInvalidKeyCode       = 2006 // API key provided is invalid.
+	upstreamQuotaExceededCode    = 2007 // API key has exceeded calls per month quota.
+	upstreamKeyDisabledCode      = 2008 // API key has been disabled.
+	upstreamInternalErrorCode    = 9999 // Internal application error.
+)
+
+// upstreamErrorResponse is the body WeatherAPI returns along with a non-200 status.
+type upstreamErrorResponse struct {
+	Error struct {
+		Code    int    `json:"code"`    // Code is the WeatherAPI error code.
+		Message string `json:"message"` // Message is the human-readable error description.
+	} `json:"error"`
+}
+
+// upstreamErrorForCode maps a WeatherAPI error code to the matching sentinel error, so handlers can tell
+// a misconfigured service key apart from an unknown location. Unknown codes yield a generic error.
+func upstreamErrorForCode(code int, message string) error {
+	switch code {
+	case upstreamNoLocationFoundCode:
+		return ErrNoLocationFound
+	case upstreamKeyNotProvidedCode, upstreamInvalidKeyCode:
+		return ErrUpstreamUnauthorized
+	case upstreamKeyDisabledCode:
+		return ErrUpstreamKeyDisabled
+	case upstreamQuotaExceededCode:
+		return ErrUpstreamQuotaExceeded
+	case upstreamQueryNotProvidedCode:
+		return ErrUpstreamMissingQuery
+	case upstreamInvalidURLCode:
+		return ErrUpstreamInvalidRequest
+	case upstreamInternalErrorCode:
+		return fmt.Errorf("%w: %s", ErrUpstreamInternal, message)
+	default:
+		return fmt.Errorf("weatherapi error %d: %s", code, message)
+	}
+}
+
+// parseUpstreamError turns a failed WeatherAPI response into an error.
+// The error body is parsed for its code; if it cannot be parsed, a 400 is still treated as an unknown location
+// (as WeatherAPI does for most invalid queries) and any other status yields a generic error.
+func parseUpstreamError(statusCode int, body []byte, redactedURL string) error {
+	var response upstreamErrorResponse
+	if err := json.Unmarshal(body, &response); err == nil && response.Error.Code != 0 {
+		return upstreamErrorForCode(response.Error.Code, response.Error.Message)
+	}
+
+	if statusCode == http.StatusBadRequest {
+		return ErrNoLocationFound
+	}
+	return fmt.Errorf("error occurred: weatherapi response status code for %s is %d", redactedURL, statusCode)
+}
+
+// validateUpstreamWeather rejects weather data that WeatherAPI returned without an error but without content either,
+// such as a "{}" body: unmarshaling it succeeds into a zero value that would be cached as a nameless location at 0°C.
+// Data without a location name or without any timestamp is reported as ErrUpstreamUnavailable.
+func validateUpstreamWeather(weather Weather) error {
+	if strings.TrimSpace(weather.Location.Name) == "" {
+		return fmt.Errorf("%w: response has no location name", ErrUpstreamUnavailable)
+	}
+	if weather.Location.LocalTimeEpoch == 0 && weather.Current.LastUpdatedEpoch == 0 {
+		return fmt.Errorf("%w: response for %s has no timestamps", ErrUpstreamUnavailable, weather.Location.Name)
+	}
+	return nil
+}
@@ -0,0 +1,39 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+func TestFetchWeatherDataRejectsEmptyUpstreamResponses(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+	}{
+		{name: "empty body", body: ""},
+		{name: "whitespace", body: "  \n"},
+		{name: "empty object", body: "{}"},
+		{name: "empty location", body: `{"location": {}, "current": {"temp_c": 0}}`},
+		{name: "no timestamps", body: `{"location": {"name": "London", "country": "Testland"}, "current": {"temp_c": 0}}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts := newTestService(t, nil)
+			ts.upstream.handle(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				fmt.Fprint(w, tt.body)
+			})
+
+			data, err := ts.FetchWeatherData(context.Background(), "London", WeatherOptions{})
+			if !errors.Is(err, ErrUpstreamUnavailable) {
+				t.Fatalf("FetchWeatherData returned %+v, %v, want ErrUpstreamUnavailable", data, err)
+			}
+			if ts.redis.Exists("weather:London") {
+				t.Error("the bogus response was cached")
+			}
+		})
+	}
+}
@@ -0,0 +1,53 @@
+package services
+
+import (
+	"context"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Backoff between retries of WeatherAPI requests: the wait doubles after every attempt, up to the cap.
+const (
+	retryBaseDelay     = 200 * time.Millisecond // retryBaseDelay is the wait before the first retry.
+	retryMaxDelay      = 5 * time.Second        // retryMaxDelay caps the wait between attempts, including Retry-After.
+	retryJitterDivisor = 2                      // Up to 1/retryJitterDivisor of the wait is added at random.
+)
+
+// retryableStatus reports whether a failed response status is worth retrying.
+// Server errors and rate limiting are transient, while other client errors (e.g. an unknown location) are not.
+func retryableStatus(status int) bool {
+	return status >= http.StatusInternalServerError || status == http.StatusTooManyRequests
+}
+
+// retryBackoff returns the wait before the retry following the given attempt (counted from 0),
+// with random jitter so that many clients failing together don't retry in lockstep.
+func retryBackoff(attempt int) time.Duration {
+	delay := min(retryBaseDelay<<attempt, retryMaxDelay)
+	return delay + rand.N(delay/retryJitterDivisor+1)
+}
+
+// parseRetryAfter parses a Retry-After header given in seconds, capped to retryMaxDelay.
+// It returns 0 if the header is missing or not a number of seconds.
+func parseRetryAfter(value string) time.Duration {
+	seconds, err := strconv.Atoi(strings.TrimSpace(value))
+	if err != nil || seconds <= 0 {
+		return 0
+	}
+	return min(time.Duration(seconds)*time.Second, retryMaxDelay)
+}
+
+// sleepContext waits for the given duration. It returns false if the context is canceled first.
+func sleepContext(ctx context.Context, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
@@ -0,0 +1,164 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"havoAPI/api/config"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newRetryingTestService returns a test service retrying failed upstream requests twice.
+func newRetryingTestService(t *testing.T) *testService {
+	t.Helper()
+	return newTestService(t, func(cfg *config.Config) {
+		cfg.WeatherAPIMaxRetries = 2
+	})
+}
+
+func TestUpstreamRequestsRetryTransientFailures(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+	}{
+		{"server error", http.StatusInternalServerError},
+		{"unavailable", http.StatusServiceUnavailable},
+		{"rate limited", http.StatusTooManyRequests},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts := newRetryingTestService(t)
+			ts.upstream.handle(func(w http.ResponseWriter, r *http.Request) {
+				if ts.upstream.count() == 1 {
+					w.WriteHeader(tt.status)
+					return
+				}
+				writeCurrentWeather(w, "London", 12)
+			})
+
+			data, err := ts.FetchWeatherData(context.Background(), "London", WeatherOptions{})
+			if err != nil {
+				t.Fatalf("FetchWeatherData failed: %v", err)
+			}
+			if data.TempC != 12 || ts.upstream.count() != 2 {
+				t.Errorf("got %v °C after %d requests, want 12 °C after 2", data.TempC, ts.upstream.count())
+			}
+		})
+	}
+}
+
+func TestUpstreamRequestsGiveUpAfterTheMaximumRetries(t *testing.T) {
+	ts := newRetryingTestService(t)
+	ts.upstream.handle(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	})
+
+	if _, err := ts.FetchWeatherData(context.Background(), "London", WeatherOptions{}); err == nil {
+		t.Fatal("FetchWeatherData succeeded against a failing upstream")
+	}
+	if got := ts.upstream.count(); got != 3 {
+		t.Errorf("upstream received %d requests, want 3 (one attempt and two retries)", got)
+	}
+}
+
+func TestUpstreamRequestsDoNotRetryClientErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		respond http.HandlerFunc
+	}{
+		{"unknown location", func(w http.ResponseWriter, r *http.Request) { writeUpstreamError(w, http.StatusBadRequest, 1006) }},
+		{"bad request without a body", func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusBadRequest) }},
+		{"not found", func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusNotFound) }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts := newRetryingTestService(t)
+			ts.upstream.handle(tt.respond)
+
+			if _, err := ts.FetchWeatherData(context.Background(), "London", WeatherOptions{}); err == nil {
+				t.Fatal("FetchWeatherData succeeded against a failing upstream")
+			}
+			if got := ts.upstream.count(); got != 1 {
+				t.Errorf("upstream received %d requests, want 1", got)
+			}
+		})
+	}
+}
+
+func TestUpstreamRequestsHonorRetryAfter(t *testing.T) {
+	ts := newRetryingTestService(t)
+	var retriedAt time.Time
+	ts.upstream.handle(func(w http.ResponseWriter, r *http.Request) {
+		if ts.upstream.count() == 1 {
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		retriedAt = time.Now()
+		writeCurrentWeather(w, "London", 12)
+	})
+
+	start := time.Now()
+	if _, err := ts.FetchWeatherData(context.Background(), "London", WeatherOptions{}); err != nil {
+		t.Fatalf("FetchWeatherData failed: %v", err)
+	}
+	if waited := retriedAt.Sub(start); waited < time.Second {
+		t.Errorf("retried after %v, want at least the 1s asked for by Retry-After", waited)
+	}
+}
+
+func TestUpstreamRetriesStopWhenTheContextIsCanceled(t *testing.T) {
+	ts := newRetryingTestService(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ts.upstream.handle(func(w http.ResponseWriter, r *http.Request) {
+		// The client goes away while the first attempt fails
+		cancel()
+		w.WriteHeader(http.StatusServiceUnavailable)
+	})
+
+	start := time.Now()
+	_, err := ts.FetchWeatherData(ctx, "London", WeatherOptions{})
+	if err == nil {
+		t.Fatal("FetchWeatherData succeeded after its context was canceled")
+	}
+	if got := ts.upstream.count(); got != 1 {
+		t.Errorf("upstream received %d requests, want 1", got)
+	}
+	if elapsed := time.Since(start); elapsed >= retryBaseDelay {
+		t.Errorf("FetchWeatherData returned after %v, want it to stop without waiting for a retry", elapsed)
+	}
+}
+
+func TestUpstreamRequestsDoNotRetryOversizedBodies(t *testing.T) {
+	ts := newTestService(t, func(cfg *config.Config) {
+		cfg.WeatherAPIMaxRetries = 2
+		cfg.WeatherAPIMaxResponseBytes = 64
+	})
+	ts.upstream.handle(func(w http.ResponseWriter, r *http.Request) {
+		writeCurrentWeather(w, "London", 12)
+	})
+
+	_, err := ts.FetchWeatherData(context.Background(), "London", WeatherOptions{})
+	if !errors.Is(err, ErrUpstreamUnavailable) {
+		t.Fatalf("FetchWeatherData() = %v, want ErrUpstreamUnavailable", err)
+	}
+	if got := ts.upstream.count(); got != 1 {
+		t.Errorf("upstream received %d requests, want 1", got)
+	}
+
+	// A body of exactly the limit is read in full
+	ts.upstream.handle(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(strings.Repeat(" ", 63) + "x"))
+	})
+	request, err := http.NewRequest(http.MethodGet, ts.upstream.URL, nil)
+	if err != nil {
+		t.Fatalf("http.NewRequest failed: %v", err)
+	}
+	body, _, err := ts.attemptRequestToWeatherApi(request)
+	if err != nil || len(body) != 64 {
+		t.Errorf("read %d bytes with error %v, want the 64-byte body", len(body), err)
+	}
+}
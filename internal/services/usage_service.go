@@ -0,0 +1,104 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"havoAPI/internal/clock"
+	"havoAPI/internal/models"
+	"log"
+	"time"
+)
+
+// Limits of the usage date range.
+const (
+	defaultUsageDays = 30  // defaultUsageDays is the number of days returned when no range is given, ending today.
+	maxUsageDays     = 366 // maxUsageDays is the longest range that can be requested at once.
+)
+
+// UsageServiceInterface defines the methods for tracking and reporting the daily usage of API keys.
+type UsageServiceInterface interface {
+	// RecordUsage counts one request made with the API key today (UTC).
+	// Failures are only logged, since usage tracking must never fail a request.
+	RecordUsage(apiKey string)
+
+	// DailyUsage retrieves the daily request totals of an API key owned by the user, between the from and to
+	// dates (YYYY-MM-DD, both inclusive). Empty dates default to the last 30 days; days without requests report 0.
+	// It returns ErrAPIKeyNotFound if the user has no such key, or an error wrapping ErrInvalidUsageRange.
+	DailyUsage(userID int, apiKey, from, to string) ([]DailyUsage, error)
+}
+
+// UsageService is a concrete implementation of the UsageServiceInterface.
+type UsageService struct {
+	// db is an instance of the DBContractUsage interface which handles usage-related database operations.
+	db models.DBContractUsage
+
+	// clk tells the current day.
+	clk clock.Clock
+}
+
+// NewUsageService initializes a new instance of UsageService.
+func NewUsageService(db models.DBContractUsage, clk clock.Clock) *UsageService {
+	return &UsageService{db: db, clk: clk}
+}
+
+// RecordUsage counts one request made with the API key today.
+func (s *UsageService) RecordUsage(apiKey string) {
+	if err := s.db.RecordAPIKeyUsage(apiKey, s.clk.Now().UTC()); err != nil {
+		log.Printf("failed to record usage of API key: %v", err)
+	}
+}
+
+// DailyUsage retrieves the daily request totals of an API key owned by the user.
+func (s *UsageService) DailyUsage(userID int, apiKey, from, to string) ([]DailyUsage, error) {
+	// Resolve the date range, defaulting to the last 30 days.
+	today := s.clk.Now().UTC().Truncate(24 * time.Hour)
+	toDay, err := parseUsageDate("to", to, today)
+	if err != nil {
+		return nil, err
+	}
+	fromDay, err := parseUsageDate("from", from, toDay.AddDate(0, 0, -(defaultUsageDays-1)))
+	if err != nil {
+		return nil, err
+	}
+	if fromDay.After(toDay) {
+		return nil, fmt.Errorf("%w: 'from' must not be after 'to'", ErrInvalidUsageRange)
+	}
+	days := int(toDay.Sub(fromDay)/(24*time.Hour)) + 1
+	if days > maxUsageDays {
+		return nil, fmt.Errorf("%w: the range may span at most %d days", ErrInvalidUsageRange, maxUsageDays)
+	}
+
+	// Fetch the days with requests.
+	rows, err := s.db.GetAPIKeyDailyUsage(userID, apiKey, fromDay, toDay)
+	if err != nil {
+		if errors.Is(err, models.ErrAPIKeyNotFound) {
+			return nil, ErrAPIKeyNotFound
+		}
+		return nil, fmt.Errorf("error occurred while retrieving API key usage: %w", err)
+	}
+	requests := make(map[string]int, len(rows))
+	for _, row := range rows {
+		requests[row.Day.Format(time.DateOnly)] = row.Requests
+	}
+
+	// Report every day of the range, so the series can be graphed without gaps.
+	usage := make([]DailyUsage, 0, days)
+	for day := fromDay; !day.After(toDay); day = day.AddDate(0, 0, 1) {
+		date := day.Format(time.DateOnly)
+		usage = append(usage, DailyUsage{Date: date, Requests: requests[date]})
+	}
+
+	return usage, nil
+}
+
+// parseUsageDate parses a YYYY-MM-DD date parameter, returning the fallback if it is empty.
+func parseUsageDate(name, value string, fallback time.Time) (time.Time, error) {
+	if value == "" {
+		return fallback, nil
+	}
+	day, err := time.Parse(time.DateOnly, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%w: '%s' must be a date formatted as YYYY-MM-DD", ErrInvalidUsageRange, name)
+	}
+	return day, nil
+}
@@ -1,12 +1,20 @@
 package services
 
 import (
+	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"havoAPI/internal/clock"
 	"havoAPI/internal/models"
+	"havoAPI/internal/units"
+	"log"
+	"slices"
+	"strings"
+	"time"
 
 	"github.com/google/uuid"
-	"golang.org/x/crypto/bcrypt"
+	"github.com/redis/go-redis/v9"
 )
 
 // UsersServiceInterface defines the methods that a user service should implement.
@@ -17,13 +25,54 @@ type UsersServiceInterface interface {
 	// It returns an error if there is an issue with the database or password hashing.
 	InsertNewUser(name, surname, username, password string) error
 
+	// ImportUser creates a user together with a generated API key in a single transaction, for the admin import.
+	// It returns the API key, or ErrUsernameExists or ErrEmailExists if the username or email is taken.
+	ImportUser(name, surname, username, password string) (string, error)
+
 	// UserAuthentication authenticates a user by verifying their username and password.
 	// It returns the user ID if authentication is successful, or an error if the credentials are invalid.
 	UserAuthentication(username, password string) (int, error)
 
+	// CreateAPIKey generates an additional API key for the user, limited to the given scopes (none for unrestricted).
+	// It returns an error wrapping ErrInvalidScope if a scope is not supported.
+	CreateAPIKey(userID int, scopes []string) (APIKey, error)
+
+	// DeleteAPIKeys deletes the user's own API keys named by key IDs or key prefixes in one transaction.
+	// It returns one result per identifier, or an error wrapping ErrInvalidAPIKeyDeletion if the list is invalid.
+	DeleteAPIKeys(userID int, identifiers []string) ([]APIKeyDeletionResult, error)
+
 	// FetchUserAPIKey retrieves the API key for a given user by user ID.
 	// It returns the API key or an error if the retrieval fails.
 	FetchUserAPIKey(userID int) (string, error)
+
+	// ListUsers retrieves a page of users together with the total number of users.
+	// It returns an error if the retrieval fails.
+	ListUsers(limit, offset int) ([]User, int, error)
+
+	// RevokeToken blacklists the JWT with the given ID until it expires.
+	// It returns an error if the blacklist cannot be updated.
+	RevokeToken(jti string, expiresAt time.Time) error
+
+	// IsTokenRevoked reports whether the JWT with the given ID has been blacklisted.
+	IsTokenRevoked(jti string) (bool, error)
+
+	// ChangePassword verifies the user's current password, stores the new one and invalidates all existing sessions.
+	// It returns ErrInvalidUserCredentials if the current password is wrong.
+	ChangePassword(userID int, currentPassword, newPassword string) error
+
+	// InvalidateSessions rejects every JWT issued to the user before now (e.g. after an account compromise).
+	// It returns ErrUserNotFound if the user does not exist.
+	InvalidateSessions(userID int) error
+
+	// TokensValidAfter returns the time before which the user's JWTs are rejected; zero if never set.
+	TokensValidAfter(userID int) (time.Time, error)
+
+	// SetPreferences stores the user's preferred units and language, applied to weather requests that don't set them.
+	// It returns an error wrapping ErrInvalidPreferences if a value is not supported.
+	SetPreferences(userID int, prefs Preferences) error
+
+	// PreferredSettings returns the user's preferred units and language; empty strings for unset preferences.
+	PreferredSettings(userID int) (string, string, error)
 }
 
 // UsersService is a concrete implementation of the UsersServiceInterface.
@@ -31,32 +80,58 @@ type UsersServiceInterface interface {
 type UsersService struct {
 	// db is an instance of the DBContractUsers interface which handles user-related database operations.
 	db models.DBContractUsers
+
+	// redisClient is a Redis client used to store revoked JWTs.
+	redisClient *RedisClient
+
+	// hasher hashes new passwords; hashes of other algorithms are still verified and upgraded on login.
+	hasher PasswordHasher
+
+	// apiKeys drops the cached validation of deleted API keys.
+	apiKeys APIKeyInvalidator
+
+	// clk is the time source of session invalidations.
+	clk clock.Clock
+
+	// maxAPIKeys is the maximum number of API keys a user can own.
+	maxAPIKeys int
+}
+
+// APIKeyInvalidator drops the cached validation of API keys. It is implemented by WeatherAPIService,
+// which caches the validations, so that a deleted key stops working at once.
+type APIKeyInvalidator interface {
+	InvalidateAPIKey(apiKey string) error
 }
 
 // NewUsersService initializes and returns a new instance of the UsersService struct.
-// This function is used to create a new UsersService instance with the provided database interface.
-func NewUsersService(db models.DBContractUsers) *UsersService {
-	return &UsersService{db: db}
+// This function is used to create a new UsersService instance with the provided database interface, Redis client,
+// password hasher, the service caching API key validations, the clock timing session invalidations
+// and the maximum number of API keys per user.
+func NewUsersService(db models.DBContractUsers, redisClient *RedisClient, hasher PasswordHasher, apiKeys APIKeyInvalidator, clk clock.Clock, maxAPIKeys int) *UsersService {
+	return &UsersService{db: db, redisClient: redisClient, hasher: hasher, apiKeys: apiKeys, clk: clk, maxAPIKeys: maxAPIKeys}
 }
 
 // InsertNewUser inserts a new user into the database after hashing the password.
 // It returns an error if there's an issue with the password hashing or database insertion.
 // This function also generates a new API key for the user after successful insertion.
 func (s *UsersService) InsertNewUser(name, surname, username, password string) error {
-	// Hash the user's password using bcrypt to ensure secure storage.
-	hashed_password, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	// Hash the user's password with the configured algorithm to ensure secure storage.
+	hashed_password, err := s.hasher.Hash(password)
 	if err != nil {
 		// Return an error if password hashing fails
 		return fmt.Errorf("error occurred while hashing password in the service section: %w", err)
 	}
 
 	// Insert the new user into the database, and get the generated user ID.
-	userID, err := s.db.InsertUser(name, surname, username, hashed_password)
+	userID, err := s.db.InsertUser(name, surname, username, []byte(hashed_password))
 	if err != nil {
-		// Check if the error is due to a duplicated username.
+		// Check if the error is due to a duplicated username or email.
 		if errors.Is(err, models.ErrDuplicatedUsername) {
 			return ErrUsernameExists
 		}
+		if errors.Is(err, models.ErrDuplicatedEmail) {
+			return ErrEmailExists
+		}
 		// Return any other error that occurred during user insertion.
 		return fmt.Errorf("error occurred while inserting user: %w", err)
 	}
@@ -71,6 +146,40 @@ func (s *UsersService) InsertNewUser(name, surname, username, password string) e
 	return nil
 }
 
+// ImportUser hashes the password and inserts the user together with a freshly generated API key.
+// On the (unlikely) collision with an existing key it retries with a fresh UUID.
+func (s *UsersService) ImportUser(name, surname, username, password string) (string, error) {
+	// Hash the user's password with the configured algorithm to ensure secure storage.
+	hashed_password, err := s.hasher.Hash(password)
+	if err != nil {
+		return "", fmt.Errorf("error occurred while hashing password: %w", err)
+	}
+
+	for attempt := 1; attempt <= maxAPIKeyGenerationAttempts; attempt++ {
+		// Insert the user and the new key together, so a failed row leaves nothing behind.
+		apiKey := uuid.New().String()
+		_, err = s.db.InsertUserWithAPIKey(name, surname, username, []byte(hashed_password), apiKey)
+		if err == nil {
+			return apiKey, nil
+		}
+
+		if errors.Is(err, models.ErrDuplicatedUsername) {
+			return "", ErrUsernameExists
+		}
+		if errors.Is(err, models.ErrDuplicatedEmail) {
+			return "", ErrEmailExists
+		}
+
+		// Anything other than a key collision is a real failure.
+		if !errors.Is(err, models.ErrDuplicatedAPIKey) {
+			return "", fmt.Errorf("error occurred while importing user: %w", err)
+		}
+	}
+
+	// Return an error if every attempt collided.
+	return "", fmt.Errorf("error occurred while importing user after %d attempts: %w", maxAPIKeyGenerationAttempts, err)
+}
+
 // UserAuthentication authenticates a user by checking the provided username and password.
 // It returns the user ID if the credentials are valid, or an error if the credentials are invalid.
 func (s *UsersService) UserAuthentication(username, password string) (int, error) {
@@ -85,31 +194,151 @@ func (s *UsersService) UserAuthentication(username, password string) (int, error
 		return 0, fmt.Errorf("error occurred while retrieving user credentials: %w", err)
 	}
 
-	// Compare the provided password with the stored password hash.
-	if err := bcrypt.CompareHashAndPassword([]byte(passwordHash), []byte(password)); err != nil {
+	// Compare the provided password with the stored password hash, whatever algorithm produced it.
+	if err := verifyPassword(passwordHash, password); err != nil {
 		// Return an error if the passwords do not match.
 		return 0, ErrInvalidUserCredentials
 	}
 
+	// Rehash passwords stored with another algorithm than the configured one, now that the plain password is known.
+	if !s.hasher.Owns(passwordHash) {
+		s.upgradePasswordHash(userID, password)
+	}
+
 	// Return the user ID if authentication is successful.
 	return userID, nil
 }
 
-// GenerateNewApiKey generates a new API key for the user using UUID and inserts it into the database.
-// It returns an error if the API key insertion fails.
+// upgradePasswordHash replaces the stored hash of the user's password with one of the configured algorithm.
+// A failure only delays the upgrade to the next login, so it is logged rather than failing the login.
+func (s *UsersService) upgradePasswordHash(userID int, password string) {
+	hashed_password, err := s.hasher.Hash(password)
+	if err != nil {
+		log.Printf("failed to upgrade password hash of user %d: %v", userID, err)
+		return
+	}
+	if err := s.db.UpdateUserPassword(userID, []byte(hashed_password)); err != nil {
+		log.Printf("failed to upgrade password hash of user %d: %v", userID, err)
+	}
+}
+
+// maxAPIKeyGenerationAttempts bounds how many times a new API key is regenerated after a UUID collision.
+const maxAPIKeyGenerationAttempts = 3
+
+// GenerateNewApiKey generates a new unrestricted API key for the user and inserts it into the database.
+// It returns ErrAPIKeyAlreadyExists if the user already has a key, or an error if the insertion fails.
 func (s *UsersService) GenerateNewApiKey(userID int) error {
-	// Generate a new unique API key using UUID for the user.
-	newAPIKey := uuid.New().String()
+	_, err := s.insertNewAPIKey(userID, nil)
+	return err
+}
+
+// CreateAPIKey generates an additional API key for the user, limited to the given scopes
+// (an empty list leaves the key unrestricted). Duplicate scopes are dropped.
+// It returns an error wrapping ErrInvalidScope if a scope is not supported.
+func (s *UsersService) CreateAPIKey(userID int, scopes []string) (APIKey, error) {
+	// Validate the requested scopes, keeping their order.
+	cleaned := []string{}
+	for _, scope := range scopes {
+		if !ValidScope(scope) {
+			return APIKey{}, fmt.Errorf("%w: '%s' (supported scopes: %s)", ErrInvalidScope, scope, strings.Join(allScopes, ", "))
+		}
+		if !slices.Contains(cleaned, scope) {
+			cleaned = append(cleaned, scope)
+		}
+	}
+
+	// Refuse the key if the user already owns as many as allowed.
+	// Two concurrent requests may both pass the check, which at most exceeds the limit by one key each.
+	count, err := s.db.CountUserAPIKeys(userID)
+	if err != nil {
+		return APIKey{}, fmt.Errorf("error occurred while counting API keys: %w", err)
+	}
+	if count >= s.maxAPIKeys {
+		return APIKey{}, fmt.Errorf("%w (at most %d per user, delete one first)", ErrAPIKeyLimitReached, s.maxAPIKeys)
+	}
 
-	// Insert the generated API key into the database for the user.
-	err := s.db.InsertUserAPIKey(userID, newAPIKey)
+	// Generate and store the key.
+	apiKey, err := s.insertNewAPIKey(userID, cleaned)
 	if err != nil {
-		// Return an error if inserting the API key into the database fails.
-		return fmt.Errorf("error occurred while inserting new API key: %w", err)
+		return APIKey{}, err
 	}
 
-	// Return nil if the API key is successfully generated and inserted.
-	return nil
+	return APIKey{Key: apiKey, Scopes: cleaned}, nil
+}
+
+// insertNewAPIKey generates a new API key for the user using UUID and inserts it into the database with the given scopes.
+// On the (unlikely) collision with an existing key it retries with a fresh UUID.
+// It returns ErrAPIKeyAlreadyExists if the database still allows a single key per user and the user already has one.
+func (s *UsersService) insertNewAPIKey(userID int, scopes []string) (string, error) {
+	var err error
+
+	for attempt := 1; attempt <= maxAPIKeyGenerationAttempts; attempt++ {
+		// Generate a new unique API key using UUID for the user.
+		newAPIKey := uuid.New().String()
+
+		// Insert the generated API key into the database for the user.
+		err = s.db.InsertUserAPIKey(userID, newAPIKey, scopes)
+		if err == nil {
+			// Return the key if it is successfully generated and inserted.
+			return newAPIKey, nil
+		}
+
+		// The user already has a key: retrying would not help.
+		if errors.Is(err, models.ErrUserAPIKeyExists) {
+			return "", ErrAPIKeyAlreadyExists
+		}
+
+		// Anything other than a key collision is a real failure.
+		if !errors.Is(err, models.ErrDuplicatedAPIKey) {
+			return "", fmt.Errorf("error occurred while inserting new API key: %w", err)
+		}
+	}
+
+	// Return an error if every attempt collided.
+	return "", fmt.Errorf("error occurred while inserting new API key after %d attempts: %w", maxAPIKeyGenerationAttempts, err)
+}
+
+// maxAPIKeyDeletions bounds how many keys a single bulk deletion may name.
+const maxAPIKeyDeletions = 50
+
+// DeleteAPIKeys deletes the user's own API keys named by key IDs or key prefixes in one transaction,
+// and drops the cached validation of every deleted key so it stops working immediately.
+// Keys of other users are never matched and are reported as not found, like keys that don't exist.
+// It returns one result per identifier, in order, or an error wrapping ErrInvalidAPIKeyDeletion
+// if the list is empty, too long or contains a blank identifier.
+func (s *UsersService) DeleteAPIKeys(userID int, identifiers []string) ([]APIKeyDeletionResult, error) {
+	// Validate the list before touching the database.
+	if len(identifiers) == 0 || len(identifiers) > maxAPIKeyDeletions {
+		return nil, fmt.Errorf("%w: between 1 and %d keys must be given", ErrInvalidAPIKeyDeletion, maxAPIKeyDeletions)
+	}
+	cleaned := make([]string, len(identifiers))
+	for i, identifier := range identifiers {
+		if cleaned[i] = strings.TrimSpace(identifier); cleaned[i] == "" {
+			return nil, fmt.Errorf("%w: key identifiers can't be blank", ErrInvalidAPIKeyDeletion)
+		}
+	}
+
+	// Delete the matching keys of the user.
+	deletions, err := s.db.DeleteUserAPIKeys(userID, cleaned)
+	if err != nil {
+		return nil, fmt.Errorf("error occurred while deleting API keys: %w", err)
+	}
+
+	results := make([]APIKeyDeletionResult, len(deletions))
+	for i, deletion := range deletions {
+		results[i] = APIKeyDeletionResult{ID: deletion.Identifier, Deleted: deletion.Err == nil}
+		if deletion.Err != nil {
+			results[i].Error = deletion.Err.Error()
+			continue
+		}
+
+		// The key is gone from the database; a stale cache entry would keep it working for a while.
+		if err := s.apiKeys.InvalidateAPIKey(deletion.APIKey); err != nil {
+			log.Printf("failed to invalidate deleted API key of user %d: %v", userID, err)
+		}
+	}
+
+	return results, nil
 }
 
 // FetchUserAPIKey retrieves the API key for a specific user by their user ID.
@@ -125,3 +354,278 @@ func (s *UsersService) FetchUserAPIKey(userID int) (string, error) {
 	// Return the retrieved API key.
 	return apiKey, nil
 }
+
+// ListUsers retrieves a page of users and the total user count for pagination.
+// It returns the users on the requested page, the total number of users, or an error if the retrieval fails.
+func (s *UsersService) ListUsers(limit, offset int) ([]User, int, error) {
+	// Retrieve the requested page of users from the database.
+	rows, err := s.db.ListUsers(limit, offset)
+	if err != nil {
+		return nil, 0, fmt.Errorf("error occurred while listing users: %w", err)
+	}
+
+	// Retrieve the total number of users so clients can paginate.
+	total, err := s.db.CountUsers()
+	if err != nil {
+		return nil, 0, fmt.Errorf("error occurred while counting users: %w", err)
+	}
+
+	// Convert the database rows into the service representation.
+	users := make([]User, 0, len(rows))
+	for _, row := range rows {
+		users = append(users, User{
+			ID:        row.ID,
+			Name:      row.Name,
+			Surname:   row.Surname,
+			Username:  row.Username,
+			CreatedAt: row.CreatedAt,
+		})
+	}
+
+	// Return the page of users and the total count.
+	return users, total, nil
+}
+
+// revokedTokenKey derives the Redis key marking the JWT with the given ID as revoked.
+func revokedTokenKey(jti string) string {
+	return "revoked_jwt:" + jti
+}
+
+// RevokeToken blacklists the JWT with the given ID in Redis until the token's own expiry,
+// after which the entry is no longer needed since the token is rejected as expired anyway.
+// Without Redis, the token is blacklisted in process memory instead.
+func (s *UsersService) RevokeToken(jti string, expiresAt time.Time) error {
+	// An already expired token doesn't need to be blacklisted.
+	now := s.clk.Now()
+	ttl := expiresAt.Sub(now)
+	if ttl <= 0 {
+		return nil
+	}
+	if !s.redisClient.configured() {
+		s.redisClient.local.set(revokedTokenKey(jti), ttl, now)
+		return nil
+	}
+
+	// Store the token ID with an expiry matching the token's.
+	err := s.redisClient.Set(context.Background(), s.redisClient.prefixed(revokedTokenKey(jti)), 1, ttl).Err()
+	if err != nil {
+		return fmt.Errorf("error occurred while revoking token: %w", err)
+	}
+
+	return nil
+}
+
+// IsTokenRevoked reports whether the JWT with the given ID is blacklisted in Redis (or in process memory without Redis).
+func (s *UsersService) IsTokenRevoked(jti string) (bool, error) {
+	if !s.redisClient.configured() {
+		return s.redisClient.local.exists(revokedTokenKey(jti), s.clk.Now()), nil
+	}
+
+	exists, err := s.redisClient.Exists(context.Background(), s.redisClient.prefixed(revokedTokenKey(jti))).Result()
+	if err != nil {
+		return false, fmt.Errorf("error occurred while checking revoked token: %w", err)
+	}
+
+	return exists > 0, nil
+}
+
+// tokensValidAfterCacheTTL is how long a user's tokens_valid_after timestamp is cached in Redis,
+// sparing the JWT middleware a database lookup on every request.
+const tokensValidAfterCacheTTL = 5 * time.Minute
+
+// tokensValidAfterKey derives the Redis key caching the tokens_valid_after timestamp of a user, in Unix milliseconds.
+// The key name changed when the timestamp gained millisecond precision, so values cached in seconds are never misread.
+func tokensValidAfterKey(userID int) string {
+	return fmt.Sprintf("tokens_valid_after_ms:%d", userID)
+}
+
+// ChangePassword verifies the user's current password, replaces it with the new one
+// and invalidates all sessions issued before the change.
+func (s *UsersService) ChangePassword(userID int, currentPassword, newPassword string) error {
+	// Retrieve the stored password hash of the user.
+	passwordHash, err := s.db.RetrievePasswordHash(userID)
+	if err != nil {
+		if errors.Is(err, models.ErrUserNotFound) {
+			return ErrUserNotFound
+		}
+		return fmt.Errorf("error occurred while retrieving password hash: %w", err)
+	}
+
+	// Verify the current password before allowing the change.
+	if err := verifyPassword(passwordHash, currentPassword); err != nil {
+		return ErrInvalidUserCredentials
+	}
+
+	// Hash and store the new password with the configured algorithm.
+	hashed_password, err := s.hasher.Hash(newPassword)
+	if err != nil {
+		return fmt.Errorf("error occurred while hashing password in the service section: %w", err)
+	}
+	if err := s.db.UpdateUserPassword(userID, []byte(hashed_password)); err != nil {
+		if errors.Is(err, models.ErrUserNotFound) {
+			return ErrUserNotFound
+		}
+		return fmt.Errorf("error occurred while updating password: %w", err)
+	}
+
+	// Log out every session that was started with the old password.
+	return s.InvalidateSessions(userID)
+}
+
+// InvalidateSessions records the current time as the user's tokens_valid_after timestamp,
+// so that every JWT issued before now is rejected, and refreshes the cached value.
+func (s *UsersService) InvalidateSessions(userID int) error {
+	// Make sure the user exists, since the update itself cannot tell.
+	if _, err := s.db.RetrieveTokensValidAfter(userID); err != nil {
+		if errors.Is(err, models.ErrUserNotFound) {
+			return ErrUserNotFound
+		}
+		return fmt.Errorf("error occurred while retrieving tokens_valid_after: %w", err)
+	}
+
+	// JWT "iat" claims have millisecond precision (see helpers.CreateAndSignJWT), so the timestamp is truncated to match.
+	validAfter := s.clk.Now().Truncate(time.Millisecond)
+	if err := s.db.UpdateTokensValidAfter(userID, validAfter); err != nil {
+		return fmt.Errorf("error occurred while updating tokens_valid_after: %w", err)
+	}
+
+	// Refresh the cache right away so the middleware rejects old tokens immediately.
+	if !s.redisClient.configured() {
+		return nil
+	}
+	err := s.redisClient.Set(context.Background(), s.redisClient.prefixed(tokensValidAfterKey(userID)), validAfter.UnixMilli(), tokensValidAfterCacheTTL).Err()
+	if err != nil {
+		return fmt.Errorf("error occurred while caching tokens_valid_after: %w", err)
+	}
+
+	return nil
+}
+
+// TokensValidAfter returns the time before which the user's JWTs are rejected.
+// The value is read from Redis when cached, and from the database (then cached) otherwise.
+// Without Redis, every call reads the database.
+func (s *UsersService) TokensValidAfter(userID int) (time.Time, error) {
+	key := tokensValidAfterKey(userID)
+
+	// Serve the timestamp from the cache when possible.
+	if s.redisClient.configured() {
+		cached, err := s.redisClient.Get(context.Background(), s.redisClient.prefixed(key)).Int64()
+		if err == nil {
+			if cached == 0 {
+				return time.Time{}, nil
+			}
+			return time.UnixMilli(cached), nil
+		}
+		if !errors.Is(err, redis.Nil) {
+			return time.Time{}, fmt.Errorf("error occurred while reading cached tokens_valid_after: %w", err)
+		}
+	}
+
+	// Fall back to the database.
+	validAfter, err := s.db.RetrieveTokensValidAfter(userID)
+	if err != nil {
+		if errors.Is(err, models.ErrUserNotFound) {
+			return time.Time{}, ErrUserNotFound
+		}
+		return time.Time{}, fmt.Errorf("error occurred while retrieving tokens_valid_after: %w", err)
+	}
+
+	// Cache the value; zero is stored as 0 so that "never invalidated" is cached too.
+	if !s.redisClient.configured() {
+		return validAfter, nil
+	}
+	var unixMilli int64
+	if !validAfter.IsZero() {
+		unixMilli = validAfter.UnixMilli()
+	}
+	if err := s.redisClient.Set(context.Background(), s.redisClient.prefixed(key), unixMilli, tokensValidAfterCacheTTL).Err(); err != nil {
+		return time.Time{}, fmt.Errorf("error occurred while caching tokens_valid_after: %w", err)
+	}
+
+	return validAfter, nil
+}
+
+// preferencesCacheTTL is how long a user's preferences are cached in Redis,
+// sparing weather requests made with a JWT a database lookup each.
+const preferencesCacheTTL = 5 * time.Minute
+
+// preferencesKey derives the Redis key caching the preferences of a user.
+func preferencesKey(userID int) string {
+	return fmt.Sprintf("preferences:%d", userID)
+}
+
+// SetPreferences validates and stores the user's preferred units and language, and refreshes the cached copy.
+// Empty values clear a preference, falling back to the system defaults.
+func (s *UsersService) SetPreferences(userID int, prefs Preferences) error {
+	// Accept the same values as the units and lang query parameters.
+	if prefs.Units != "" && !units.Valid(prefs.Units) {
+		return fmt.Errorf("%w: units must be one of '%s', '%s' or '%s'", ErrInvalidPreferences, units.Metric, units.Both, units.Kelvin)
+	}
+	if prefs.Lang != "" && !ValidLang(prefs.Lang) {
+		return fmt.Errorf("%w: lang must be a WeatherAPI language code (e.g. 'fr' or 'zh_tw')", ErrInvalidPreferences)
+	}
+
+	// Make sure the user exists, since the update itself cannot tell.
+	if _, _, err := s.db.RetrieveUserPreferences(userID); err != nil {
+		if errors.Is(err, models.ErrUserNotFound) {
+			return ErrUserNotFound
+		}
+		return fmt.Errorf("error occurred while retrieving preferences: %w", err)
+	}
+	if err := s.db.UpdateUserPreferences(userID, prefs.Units, prefs.Lang); err != nil {
+		return fmt.Errorf("error occurred while updating preferences: %w", err)
+	}
+
+	// Refresh the cache right away so the next weather request uses the new preferences.
+	if err := s.cachePreferences(userID, prefs); err != nil {
+		return fmt.Errorf("error occurred while caching preferences: %w", err)
+	}
+
+	return nil
+}
+
+// PreferredSettings returns the user's preferred units and language.
+// The preferences are read from Redis when cached, and from the database (then cached) otherwise.
+// Without Redis, every call reads the database.
+func (s *UsersService) PreferredSettings(userID int) (string, string, error) {
+	// Serve the preferences from the cache when possible.
+	if s.redisClient.configured() {
+		cached, err := s.redisClient.Get(context.Background(), s.redisClient.prefixed(preferencesKey(userID))).Bytes()
+		if err == nil {
+			var prefs Preferences
+			if err := json.Unmarshal(cached, &prefs); err == nil {
+				return prefs.Units, prefs.Lang, nil
+			}
+		} else if !errors.Is(err, redis.Nil) {
+			return "", "", fmt.Errorf("error occurred while reading cached preferences: %w", err)
+		}
+	}
+
+	// Fall back to the database.
+	units, lang, err := s.db.RetrieveUserPreferences(userID)
+	if err != nil {
+		if errors.Is(err, models.ErrUserNotFound) {
+			return "", "", ErrUserNotFound
+		}
+		return "", "", fmt.Errorf("error occurred while retrieving preferences: %w", err)
+	}
+
+	// Cache the preferences; unset ones are cached too, so users without preferences don't hit the database.
+	if err := s.cachePreferences(userID, Preferences{Units: units, Lang: lang}); err != nil {
+		return "", "", fmt.Errorf("error occurred while caching preferences: %w", err)
+	}
+
+	return units, lang, nil
+}
+
+// cachePreferences stores the user's preferences in Redis for preferencesCacheTTL. It does nothing without Redis.
+func (s *UsersService) cachePreferences(userID int, prefs Preferences) error {
+	if !s.redisClient.configured() {
+		return nil
+	}
+	jsonData, err := json.Marshal(prefs)
+	if err != nil {
+		return err
+	}
+	return s.redisClient.Set(context.Background(), s.redisClient.prefixed(preferencesKey(userID)), jsonData, preferencesCacheTTL).Err()
+}
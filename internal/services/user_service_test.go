@@ -0,0 +1,246 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"havoAPI/internal/clock"
+	"havoAPI/internal/models"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// fakeUsersDB stands in for the users and api_keys tables. Only the methods a test sets are usable;
+// calling any other method of the embedded nil interface panics, pointing at an unexpected query.
+type fakeUsersDB struct {
+	models.DBContractUsers
+
+	insertUserAPIKey  func(userID int, apiKey string, scopes []string) error
+	deleteUserAPIKeys func(userID int, identifiers []string) ([]models.APIKeyDeletion, error)
+
+	// apiKeyCount is the number of API keys every user already owns.
+	apiKeyCount int
+
+	// tokensValidAfter holds the tokens_valid_after column of the known users.
+	tokensValidAfter map[int]time.Time
+
+	// preferences holds the units and lang columns of the known users.
+	preferences map[int]Preferences
+}
+
+func (db *fakeUsersDB) CountUserAPIKeys(userID int) (int, error) {
+	return db.apiKeyCount, nil
+}
+
+func (db *fakeUsersDB) InsertUserAPIKey(userID int, apiKey string, scopes []string) error {
+	return db.insertUserAPIKey(userID, apiKey, scopes)
+}
+
+func (db *fakeUsersDB) DeleteUserAPIKeys(userID int, identifiers []string) ([]models.APIKeyDeletion, error) {
+	return db.deleteUserAPIKeys(userID, identifiers)
+}
+
+func (db *fakeUsersDB) RetrieveTokensValidAfter(userID int) (time.Time, error) {
+	validAfter, ok := db.tokensValidAfter[userID]
+	if !ok {
+		return time.Time{}, models.ErrUserNotFound
+	}
+	return validAfter, nil
+}
+
+func (db *fakeUsersDB) UpdateTokensValidAfter(userID int, validAfter time.Time) error {
+	db.tokensValidAfter[userID] = validAfter
+	return nil
+}
+
+func (db *fakeUsersDB) RetrieveUserPreferences(userID int) (string, string, error) {
+	prefs, ok := db.preferences[userID]
+	if !ok {
+		return "", "", models.ErrUserNotFound
+	}
+	return prefs.Units, prefs.Lang, nil
+}
+
+func (db *fakeUsersDB) UpdateUserPreferences(userID int, units, lang string) error {
+	db.preferences[userID] = Preferences{Units: units, Lang: lang}
+	return nil
+}
+
+// fakeAPIKeyInvalidator records the API keys whose cached validation was dropped.
+type fakeAPIKeyInvalidator struct {
+	invalidated []string
+}
+
+func (i *fakeAPIKeyInvalidator) InvalidateAPIKey(apiKey string) error {
+	i.invalidated = append(i.invalidated, apiKey)
+	return nil
+}
+
+// testMaxAPIKeys is the maximum number of API keys per user of the test users services.
+const testMaxAPIKeys = 3
+
+// newTestUsersService creates a UsersService backed by the given fake tables and an in-memory Redis.
+// Passwords are hashed with the cheapest bcrypt cost to keep the tests fast, and API key invalidations
+// are only recorded; tests involving the validation cache replace apiKeys with a WeatherAPIService.
+// The service clock is a *clock.Fake starting at testNow, and users can own up to testMaxAPIKeys keys.
+func newTestUsersService(t *testing.T, db models.DBContractUsers) (*UsersService, *miniredis.Miniredis) {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	redisClient := &RedisClient{Client: redis.NewClient(&redis.Options{Addr: mr.Addr()})}
+	t.Cleanup(func() { redisClient.Close() })
+	return NewUsersService(db, redisClient, BcryptHasher{Cost: bcrypt.MinCost}, &fakeAPIKeyInvalidator{}, clock.NewFake(testNow), testMaxAPIKeys), mr
+}
+
+func TestCreateAPIKeyRetriesOnCollision(t *testing.T) {
+	var inserted []string
+	db := &fakeUsersDB{insertUserAPIKey: func(userID int, apiKey string, scopes []string) error {
+		inserted = append(inserted, apiKey)
+		if len(inserted) == 1 {
+			return models.ErrDuplicatedAPIKey
+		}
+		return nil
+	}}
+	s, _ := newTestUsersService(t, db)
+
+	key, err := s.CreateAPIKey(1, nil)
+	if err != nil {
+		t.Fatalf("CreateAPIKey() failed: %v", err)
+	}
+	if len(inserted) != 2 || inserted[0] == inserted[1] {
+		t.Fatalf("inserted keys %v, want a retry with a fresh key", inserted)
+	}
+	if key.Key != inserted[1] {
+		t.Errorf("CreateAPIKey() returned %q, want the key of the second attempt %q", key.Key, inserted[1])
+	}
+}
+
+func TestCreateAPIKeyGivesUpAfterRepeatedCollisions(t *testing.T) {
+	attempts := 0
+	db := &fakeUsersDB{insertUserAPIKey: func(int, string, []string) error {
+		attempts++
+		return models.ErrDuplicatedAPIKey
+	}}
+	s, _ := newTestUsersService(t, db)
+
+	if _, err := s.CreateAPIKey(1, nil); !errors.Is(err, models.ErrDuplicatedAPIKey) {
+		t.Errorf("CreateAPIKey() = %v, want ErrDuplicatedAPIKey", err)
+	}
+	if attempts != maxAPIKeyGenerationAttempts {
+		t.Errorf("made %d attempts, want %d", attempts, maxAPIKeyGenerationAttempts)
+	}
+}
+
+func TestGenerateNewApiKeyReportsExistingKey(t *testing.T) {
+	db := &fakeUsersDB{insertUserAPIKey: func(int, string, []string) error {
+		return models.ErrUserAPIKeyExists
+	}}
+	s, _ := newTestUsersService(t, db)
+
+	if err := s.GenerateNewApiKey(1); !errors.Is(err, ErrAPIKeyAlreadyExists) {
+		t.Errorf("GenerateNewApiKey() = %v, want ErrAPIKeyAlreadyExists", err)
+	}
+}
+
+func TestDeleteAPIKeysStopsCachedKeysAtOnce(t *testing.T) {
+	ts := newTestService(t, nil)
+	if _, err := ts.APIKeyAuthorization(context.Background(), "valid-key"); err != nil {
+		t.Fatalf("APIKeyAuthorization failed: %v", err)
+	}
+
+	db := &fakeUsersDB{deleteUserAPIKeys: func(userID int, identifiers []string) ([]models.APIKeyDeletion, error) {
+		ts.db.remove("valid-key")
+		return []models.APIKeyDeletion{{Identifier: identifiers[0], APIKey: "valid-key"}}, nil
+	}}
+	users, _ := newTestUsersService(t, db)
+	users.apiKeys = ts.WeatherAPIService
+
+	results, err := users.DeleteAPIKeys(1, []string{"valid-ke"})
+	if err != nil || len(results) != 1 || !results[0].Deleted {
+		t.Fatalf("DeleteAPIKeys = %+v, %v; want one deleted key", results, err)
+	}
+
+	// The cached validation is gone, so the key is rejected without waiting for the TTL
+	if _, err := ts.APIKeyAuthorization(context.Background(), "valid-key"); !errors.Is(err, ErrAPIKeyNotFound) {
+		t.Errorf("APIKeyAuthorization after deletion: err = %v, want ErrAPIKeyNotFound", err)
+	}
+}
+
+func TestInvalidateSessionsUsesTheServiceClock(t *testing.T) {
+	db := &fakeUsersDB{tokensValidAfter: map[int]time.Time{1: {}}}
+	s, _ := newTestUsersService(t, db)
+	clk := s.clk.(*clock.Fake)
+
+	// The timestamp is truncated to the millisecond precision of the JWT iat claim
+	clk.Set(testNow.Add(1500 * time.Microsecond))
+	if err := s.InvalidateSessions(1); err != nil {
+		t.Fatalf("InvalidateSessions failed: %v", err)
+	}
+	want := testNow.Add(time.Millisecond)
+	if got := db.tokensValidAfter[1]; !got.Equal(want) {
+		t.Errorf("stored tokens_valid_after = %v, want %v", got, want)
+	}
+
+	// The cached value is the one the middleware reads, so it must match without a database lookup
+	db.tokensValidAfter[1] = time.Time{}
+	got, err := s.TokensValidAfter(1)
+	if err != nil {
+		t.Fatalf("TokensValidAfter failed: %v", err)
+	}
+	if !got.Equal(want) {
+		t.Errorf("TokensValidAfter = %v, want %v", got, want)
+	}
+
+	// A later invalidation moves the timestamp along with the clock
+	clk.Advance(time.Hour)
+	if err := s.InvalidateSessions(1); err != nil {
+		t.Fatalf("InvalidateSessions failed: %v", err)
+	}
+	if got, _ := s.TokensValidAfter(1); !got.Equal(want.Add(time.Hour)) {
+		t.Errorf("TokensValidAfter = %v after an hour, want %v", got, want.Add(time.Hour))
+	}
+}
+
+func TestInvalidateSessionsOfUnknownUser(t *testing.T) {
+	s, _ := newTestUsersService(t, &fakeUsersDB{tokensValidAfter: map[int]time.Time{}})
+	if err := s.InvalidateSessions(2); !errors.Is(err, ErrUserNotFound) {
+		t.Errorf("InvalidateSessions returned %v, want ErrUserNotFound", err)
+	}
+}
+
+func TestCreateAPIKeyEnforcesTheLimitPerUser(t *testing.T) {
+	tests := []struct {
+		name    string
+		owned   int
+		wantErr error
+	}{
+		{name: "first additional key", owned: 1},
+		{name: "last allowed key", owned: testMaxAPIKeys - 1},
+		{name: "limit reached", owned: testMaxAPIKeys, wantErr: ErrAPIKeyLimitReached},
+		{name: "limit lowered below the owned keys", owned: testMaxAPIKeys + 2, wantErr: ErrAPIKeyLimitReached},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			inserted := 0
+			db := &fakeUsersDB{apiKeyCount: tt.owned, insertUserAPIKey: func(int, string, []string) error {
+				inserted++
+				return nil
+			}}
+			s, _ := newTestUsersService(t, db)
+
+			_, err := s.CreateAPIKey(1, []string{ScopeCurrent})
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("CreateAPIKey() error = %v, want %v", err, tt.wantErr)
+			}
+			wantInserted := 1
+			if tt.wantErr != nil {
+				wantInserted = 0
+			}
+			if inserted != wantInserted {
+				t.Errorf("inserted %d keys, want %d", inserted, wantInserted)
+			}
+		})
+	}
+}
@@ -0,0 +1,62 @@
+package services
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// withMeta sets the freshness metadata of weather data served from the cache entry under key, when requested.
+// The time the entry was cached is stored with it; its remaining TTL is looked up, except for a stale copy
+// (served after the entry expired), which has no entry left to expire.
+func (s *WeatherAPIService) withMeta(data FormattedWeatherData, key string, opts WeatherOptions) FormattedWeatherData {
+	if !opts.Meta {
+		return data
+	}
+
+	meta := &WeatherMeta{Cached: true, UpstreamObservedAt: data.LastUpdated}
+	if !data.CachedAt.IsZero() {
+		cachedAt := data.CachedAt.UTC().Truncate(time.Second)
+		meta.CachedAt = &cachedAt
+	}
+	if !data.Stale {
+		ttl, err := s.redisClient.TTL(context.Background(), s.redisClient.prefixed(key)).Result()
+		if err != nil {
+			// The metadata is informational; a failed lookup must not fail the request.
+			log.Printf("failed to get TTL of %s for the response metadata: %v", key, err)
+		} else if ttl > 0 {
+			meta.TTLRemainingSeconds = int(ttl.Seconds())
+		}
+	}
+
+	data.Meta = meta
+	return data
+}
+
+// exceedsMaxAge reports whether cached data is older than the cache tolerance maxAge, zero accepting any age.
+// Like withMeta, the age is measured from the time stored with the entry, which a changed CACHE_TTL can't shift.
+// Entries cached before the time was stored have an unknown age, so they never satisfy a tolerance.
+func (s *WeatherAPIService) exceedsMaxAge(data FormattedWeatherData, maxAge time.Duration) bool {
+	if maxAge <= 0 {
+		return false
+	}
+	return data.CachedAt.IsZero() || s.clk.Now().Sub(data.CachedAt) > maxAge
+}
+
+// withFreshMeta sets the freshness metadata of weather data that was just fetched from the upstream, when requested.
+// Data stored in the cache by this request starts with the full CacheTTL; other data is never cached.
+func (s *WeatherAPIService) withFreshMeta(data FormattedWeatherData, cached bool, opts WeatherOptions) FormattedWeatherData {
+	if !opts.Meta {
+		return data
+	}
+
+	meta := &WeatherMeta{Cached: false, UpstreamObservedAt: data.LastUpdated}
+	if cached {
+		cachedAt := s.clk.Now().UTC().Truncate(time.Second)
+		meta.CachedAt = &cachedAt
+		meta.TTLRemainingSeconds = int(s.cfg.CacheTTL.Seconds())
+	}
+
+	data.Meta = meta
+	return data
+}
@@ -0,0 +1,76 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"havoAPI/api/config"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestMetaReportsTheStoredCacheTime(t *testing.T) {
+	ts := newTestService(t, func(cfg *config.Config) {
+		cfg.StaleCacheMaxAge = time.Hour
+	})
+	if _, err := ts.FetchWeatherData(context.Background(), "London", WeatherOptions{}); err != nil {
+		t.Fatalf("FetchWeatherData failed: %v", err)
+	}
+
+	// A CACHE_TTL changed since the entry was written must not shift its cache time
+	ts.advance(10 * time.Minute)
+	ts.cfg.CacheTTL = time.Hour
+
+	data, err := ts.FetchWeatherData(context.Background(), "London", WeatherOptions{Meta: true})
+	if err != nil {
+		t.Fatalf("FetchWeatherData failed: %v", err)
+	}
+	if data.Meta == nil || !data.Meta.Cached {
+		t.Fatalf("meta = %+v, want data served from the cache", data.Meta)
+	}
+	if data.Meta.CachedAt == nil || !data.Meta.CachedAt.Equal(testNow) {
+		t.Errorf("cached_at = %v, want %v", data.Meta.CachedAt, testNow)
+	}
+	if want := int((20 * time.Minute).Seconds()); data.Meta.TTLRemainingSeconds != want {
+		t.Errorf("ttl_remaining_seconds = %d, want %d", data.Meta.TTLRemainingSeconds, want)
+	}
+
+	// A stale copy keeps the time it was cached
+	ts.upstream.handle(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	})
+	ts.advance(30 * time.Minute)
+	data, err = ts.FetchWeatherData(context.Background(), "London", WeatherOptions{Meta: true})
+	if err != nil {
+		t.Fatalf("FetchWeatherData with a stale copy failed: %v", err)
+	}
+	if !data.Stale || data.Meta == nil || data.Meta.CachedAt == nil || !data.Meta.CachedAt.Equal(testNow) {
+		t.Errorf("stale = %v with meta %+v, want the stale copy cached at %v", data.Stale, data.Meta, testNow)
+	}
+	if data.Meta != nil && data.Meta.TTLRemainingSeconds != 0 {
+		t.Errorf("ttl_remaining_seconds of a stale copy = %d, want 0", data.Meta.TTLRemainingSeconds)
+	}
+}
+
+func TestReadCachedWeatherDataAcceptsEntriesWithoutACacheTime(t *testing.T) {
+	ts := newTestService(t, nil)
+	jsonData, err := json.Marshal(FormattedWeatherData{Name: "Paris", TempC: 15})
+	if err != nil {
+		t.Fatalf("json.Marshal failed: %v", err)
+	}
+	ts.redis.Set(weatherCacheKey("Paris"), string(jsonData))
+
+	data, err := readCachedWeatherData(ts.redisClient, weatherCacheKey("Paris"))
+	if err != nil {
+		t.Fatalf("readCachedWeatherData failed: %v", err)
+	}
+	if data.Name != "Paris" || data.TempC != 15 {
+		t.Errorf("read %s at %v °C, want Paris at 15 °C", data.Name, data.TempC)
+	}
+	if !data.CachedAt.IsZero() {
+		t.Errorf("cache time = %v, want none", data.CachedAt)
+	}
+	if data.ContentHash != weatherContentHash(jsonData) {
+		t.Errorf("content hash = %q, want the hash of the entry", data.ContentHash)
+	}
+}
@@ -0,0 +1,61 @@
+package services
+
+import (
+	"net/url"
+	"regexp"
+	"time"
+)
+
+// WeatherOptions carries the optional settings of a weather lookup.
+// The zero value requests the default behavior: metric units, English texts and no air quality data.
+// New lookup settings belong here rather than in additional FetchWeatherData parameters.
+type WeatherOptions struct {
+	Units string // Units is the unit system of the response (units.Metric if empty). It is applied after caching.
+	Lang  string // Lang is the WeatherAPI language code of the condition text (English if empty).
+	AQI   bool   // AQI requests the air quality data of the location.
+	Meta  bool   // Meta requests the freshness metadata of the data (see WeatherMeta). It is never part of the cache key.
+
+	// ForceRefresh skips the cached entry and fetches the data live, replacing the shared entry with the result.
+	// It is never part of the cache key.
+	ForceRefresh bool
+
+	// MaxAge is the client's cache tolerance: a cached entry older than it is fetched live again, replacing
+	// the shared entry, even though it hasn't expired. Zero accepts any unexpired entry, and values shorter than
+	// MinClientMaxAge are rejected with ErrMaxAgeTooShort. It is never part of the cache key.
+	MaxAge time.Duration
+}
+
+// langPattern matches the language codes accepted by WeatherAPI (e.g. "fr" or "zh_tw").
+var langPattern = regexp.MustCompile(`^[a-z]{2,3}(_[a-z]{2,4})?$`)
+
+// ValidLang reports whether lang has the shape of a WeatherAPI language code.
+func ValidLang(lang string) bool {
+	return langPattern.MatchString(lang)
+}
+
+// cacheKey derives the Redis key of the weather data for a location fetched with these options.
+// Options that change the upstream response are part of the key, while the default options map to
+// the plain weatherCacheKey so that existing entries, the cron refresh and alerts keep sharing it.
+// Units are derived from the cached metric values and therefore never part of the key.
+func (o WeatherOptions) cacheKey(location string) string {
+	key := weatherCacheKey(location)
+	if o.Lang != "" {
+		key += ":lang=" + o.Lang
+	}
+	if o.AQI {
+		key += ":aqi"
+	}
+	return key
+}
+
+// upstreamParams returns the query string parameters these options add to a WeatherAPI request.
+func (o WeatherOptions) upstreamParams() string {
+	params := "&aqi=no"
+	if o.AQI {
+		params = "&aqi=yes"
+	}
+	if o.Lang != "" {
+		params += "&lang=" + url.QueryEscape(o.Lang)
+	}
+	return params
+}
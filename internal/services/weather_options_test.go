@@ -0,0 +1,120 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"havoAPI/api/config"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestForceRefreshBypassesTheCache(t *testing.T) {
+	ts := newTestService(t, nil)
+	if _, err := ts.FetchWeatherData(context.Background(), "London", WeatherOptions{}); err != nil {
+		t.Fatalf("FetchWeatherData failed: %v", err)
+	}
+	ts.upstream.handle(func(w http.ResponseWriter, r *http.Request) {
+		writeCurrentWeather(w, "London", 25)
+	})
+
+	// The cached entry is present, so a regular lookup doesn't reach WeatherAPI
+	data, err := ts.FetchWeatherData(context.Background(), "London", WeatherOptions{})
+	if err != nil {
+		t.Fatalf("FetchWeatherData failed: %v", err)
+	}
+	if data.TempC != 20 || ts.upstream.count() != 1 {
+		t.Fatalf("got %v °C after %d upstream requests, want the cached 20 °C after 1", data.TempC, ts.upstream.count())
+	}
+
+	data, err = ts.FetchWeatherData(context.Background(), "London", WeatherOptions{ForceRefresh: true})
+	if err != nil {
+		t.Fatalf("FetchWeatherData with a refresh failed: %v", err)
+	}
+	if data.TempC != 25 || ts.upstream.count() != 2 {
+		t.Errorf("refresh got %v °C after %d upstream requests, want the live 25 °C after 2", data.TempC, ts.upstream.count())
+	}
+
+	// The live data replaces the shared entry
+	data, err = ts.FetchWeatherData(context.Background(), "London", WeatherOptions{})
+	if err != nil {
+		t.Fatalf("FetchWeatherData failed: %v", err)
+	}
+	if data.TempC != 25 || ts.upstream.count() != 2 {
+		t.Errorf("got %v °C after %d upstream requests, want the refreshed 25 °C after 2", data.TempC, ts.upstream.count())
+	}
+}
+
+func TestMaxAgeMeasuresTheStoredCacheTime(t *testing.T) {
+	ts := newTestService(t, func(cfg *config.Config) {
+		cfg.CacheTTL = 30 * time.Minute
+		cfg.MinClientMaxAge = time.Minute
+	})
+	if _, err := ts.FetchWeatherData(context.Background(), "London", WeatherOptions{}); err != nil {
+		t.Fatalf("FetchWeatherData failed: %v", err)
+	}
+	ts.upstream.handle(func(w http.ResponseWriter, r *http.Request) {
+		writeCurrentWeather(w, "London", 25)
+	})
+	fetch := func() FormattedWeatherData {
+		t.Helper()
+		data, err := ts.FetchWeatherData(context.Background(), "London", WeatherOptions{MaxAge: 10 * time.Minute})
+		if err != nil {
+			t.Fatalf("FetchWeatherData with a max age failed: %v", err)
+		}
+		return data
+	}
+
+	// A CACHE_TTL changed since the entry was written must not age it
+	ts.advance(5 * time.Minute)
+	ts.cfg.CacheTTL = time.Hour
+	if data := fetch(); data.TempC != 20 || ts.upstream.count() != 1 {
+		t.Fatalf("entry cached 5 minutes ago: got %v °C after %d upstream requests, want the cached 20 °C after 1", data.TempC, ts.upstream.count())
+	}
+
+	// An entry older than the tolerance is replaced by the live data
+	ts.advance(6 * time.Minute)
+	if data := fetch(); data.TempC != 25 || ts.upstream.count() != 2 {
+		t.Fatalf("entry cached 11 minutes ago: got %v °C after %d upstream requests, want the live 25 °C after 2", data.TempC, ts.upstream.count())
+	}
+	if data := fetch(); data.TempC != 25 || ts.upstream.count() != 2 {
+		t.Errorf("refreshed entry: got %v °C after %d upstream requests, want the cached 25 °C after 2", data.TempC, ts.upstream.count())
+	}
+}
+
+func TestMaxAgeRefetchesEntriesWithoutACacheTime(t *testing.T) {
+	ts := newTestService(t, nil)
+	jsonData, err := json.Marshal(FormattedWeatherData{Name: "London", TempC: 15})
+	if err != nil {
+		t.Fatalf("json.Marshal failed: %v", err)
+	}
+	ts.redis.Set(weatherCacheKey("London"), string(jsonData))
+
+	data, err := ts.FetchWeatherData(context.Background(), "London", WeatherOptions{MaxAge: time.Hour})
+	if err != nil {
+		t.Fatalf("FetchWeatherData failed: %v", err)
+	}
+	if data.TempC != 20 || ts.upstream.count() != 1 {
+		t.Errorf("got %v °C after %d upstream requests, want the live 20 °C after 1", data.TempC, ts.upstream.count())
+	}
+}
+
+func TestMaxAgeBelowTheMinimumIsRejected(t *testing.T) {
+	ts := newTestService(t, func(cfg *config.Config) {
+		cfg.MinClientMaxAge = time.Minute
+	})
+
+	_, err := ts.FetchWeatherData(context.Background(), "London", WeatherOptions{MaxAge: 30 * time.Second})
+	if !errors.Is(err, ErrMaxAgeTooShort) {
+		t.Fatalf("FetchWeatherData with max age 30s = %v, want ErrMaxAgeTooShort", err)
+	}
+	if ts.upstream.count() != 0 {
+		t.Errorf("upstream received %d requests, want none", ts.upstream.count())
+	}
+
+	// The minimum itself is accepted
+	if _, err := ts.FetchWeatherData(context.Background(), "London", WeatherOptions{MaxAge: time.Minute}); err != nil {
+		t.Errorf("FetchWeatherData with max age 1m failed: %v", err)
+	}
+}
@@ -0,0 +1,83 @@
+package services
+
+import (
+	"sync"
+)
+
+// weatherUpdateBuffer is the number of updates queued for a subscriber before new ones are dropped.
+// A subscriber that falls this far behind is too slow to keep up anyway, and must never block the cache refresh.
+const weatherUpdateBuffer = 16
+
+// weatherUpdates fans out freshly cached weather data to the subscribers of each location.
+// Subscribers are keyed by the cache key of their location, so every query form of a location
+// (e.g. "london" and "London") receives the same updates.
+type weatherUpdates struct {
+	mu          sync.Mutex
+	subscribers map[string]map[chan FormattedWeatherData]struct{}
+}
+
+// newWeatherUpdates creates an empty set of subscriptions.
+func newWeatherUpdates() *weatherUpdates {
+	return &weatherUpdates{subscribers: make(map[string]map[chan FormattedWeatherData]struct{})}
+}
+
+// subscribe registers a single channel receiving the updates of every given cache key.
+// The returned function removes the subscription; it must be called once the subscriber is done.
+func (u *weatherUpdates) subscribe(keys []string) (<-chan FormattedWeatherData, func()) {
+	ch := make(chan FormattedWeatherData, weatherUpdateBuffer)
+
+	u.mu.Lock()
+	for _, key := range keys {
+		if u.subscribers[key] == nil {
+			u.subscribers[key] = make(map[chan FormattedWeatherData]struct{})
+		}
+		u.subscribers[key][ch] = struct{}{}
+	}
+	u.mu.Unlock()
+
+	unsubscribe := func() {
+		u.mu.Lock()
+		defer u.mu.Unlock()
+		for _, key := range keys {
+			delete(u.subscribers[key], ch)
+			if len(u.subscribers[key]) == 0 {
+				delete(u.subscribers, key)
+			}
+		}
+	}
+	return ch, unsubscribe
+}
+
+// publish sends the weather data cached under key to its subscribers without blocking:
+// subscribers whose buffer is full miss this update.
+func (u *weatherUpdates) publish(key string, weatherData FormattedWeatherData) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	for ch := range u.subscribers[key] {
+		select {
+		case ch <- weatherData:
+		default:
+		}
+	}
+}
+
+// SubscribeWeatherUpdates subscribes to the weather data of the given locations, which is delivered on the
+// returned channel every time it is refreshed in the cache. The returned function ends the subscription.
+// It returns ErrInvalidCoordinates or ErrLocationNotAllowed if a location can never be served.
+func (s *WeatherAPIService) SubscribeWeatherUpdates(queries []string) (<-chan FormattedWeatherData, func(), error) {
+	keys := make([]string, 0, len(queries))
+	for _, q := range queries {
+		normalized, err := normalizeQuery(q)
+		if err != nil {
+			return nil, nil, err
+		}
+		if !s.allowlist.mayAllowQuery(normalized) {
+			return nil, nil, ErrLocationNotAllowed
+		}
+		keys = append(keys, weatherCacheKey(normalized))
+	}
+
+	ch, unsubscribe := s.updates.subscribe(keys)
+	return ch, unsubscribe, nil
+}
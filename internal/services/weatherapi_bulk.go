@@ -0,0 +1,256 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// bulkUpstreamRequest is the body of a request to WeatherAPI's native bulk endpoint.
+type bulkUpstreamRequest struct {
+	Locations []bulkUpstreamLocation `json:"locations"` // Locations lists every query to resolve in one call.
+}
+
+// bulkUpstreamLocation is a single query in a native bulk request.
+// CustomID is echoed back by WeatherAPI and is used to match results to their query.
+type bulkUpstreamLocation struct {
+	Q        string `json:"q"`         // Q is the location query.
+	CustomID string `json:"custom_id"` // CustomID identifies the query in the response.
+}
+
+// bulkUpstreamResponse is the response of WeatherAPI's native bulk endpoint.
+type bulkUpstreamResponse struct {
+	Bulk []struct {
+		Query struct {
+			CustomID string `json:"custom_id"` // CustomID echoes the identifier sent with the query.
+			Weather         // Weather holds the location and current data when the query was resolved.
+			Error    *struct {
+				Code    int    `json:"code"`    // Code is the WeatherAPI error code (e.g. 1006 for no location found).
+				Message string `json:"message"` // Message is the human-readable error description.
+			} `json:"error"` // Error is set when the query could not be resolved.
+		} `json:"query"`
+	} `json:"bulk"`
+}
+
+// fetchBulkUpstream retrieves weather data for multiple locations, serving cached ones from Redis
+// and fetching all remaining ones with batched calls to WeatherAPI's native bulk endpoint.
+// Results are stored at their query's index in found or notFound, and fresh results are cached like single lookups.
+func (s *WeatherAPIService) fetchBulkUpstream(queries []string) ([]*FormattedWeatherData, []string, error) {
+	found := make([]*FormattedWeatherData, len(queries))
+	notFound := make([]string, len(queries))
+
+	// Resolve what we can locally and collect the queries that need an upstream call.
+	keys := make([]string, len(queries))
+	var request bulkUpstreamRequest
+	for i, q := range queries {
+		normalized, err := normalizeQuery(q)
+		if err != nil {
+			if message, ok := invalidBulkQuery(q, err); ok {
+				notFound[i] = message
+				continue
+			}
+			return nil, nil, err
+		}
+		keys[i] = weatherCacheKey(normalized)
+
+		// Skip locations that can never pass the allowlist.
+		if !s.allowlist.mayAllowQuery(normalized) {
+			notFound[i] = fmt.Sprintf("'%s' is not allowed", q)
+			continue
+		}
+
+		// Serve the location from the cache when possible.
+		cachedData, err := s.retrieveWeatherDataFromRedisCache(keys[i], 0)
+		if err == nil {
+			found[i] = &cachedData
+			continue
+		}
+		if !errors.Is(err, ErrNoDataCache) {
+			return nil, nil, err
+		}
+
+		// Skip locations that were recently reported as not found.
+		if s.isKnownNotFound(keys[i]) {
+			notFound[i] = fmt.Sprintf("'%s' not found", q)
+			continue
+		}
+
+		request.Locations = append(request.Locations, bulkUpstreamLocation{Q: normalized, CustomID: strconv.Itoa(i)})
+	}
+
+	// Fetch all cache misses in batches small enough for the upstream per-request cap.
+	if err := s.resolveBulkBatches(bulkBatches(request, s.cfg.WeatherAPIBulkBatchSize), queries, keys, found, notFound); err != nil {
+		return nil, nil, err
+	}
+
+	return found, notFound, nil
+}
+
+// invalidBulkQuery reports a query that can never be resolved, such as coordinates out of range
+// or a malformed airport code, with the message listed under not_found. Every element of a bulk request
+// is validated on its own, so one bad coordinate pair doesn't fail the named locations next to it.
+func invalidBulkQuery(q string, err error) (string, bool) {
+	switch {
+	case errors.Is(err, ErrInvalidCoordinates):
+		return fmt.Sprintf("'%s' has invalid coordinates", q), true
+	case errors.Is(err, ErrInvalidIATACode):
+		return fmt.Sprintf("'%s' is not a valid IATA code", q), true
+	}
+	return "", false
+}
+
+// bulkBatches splits a native bulk request into requests of at most batchSize locations, keeping their order.
+func bulkBatches(request bulkUpstreamRequest, batchSize int) []bulkUpstreamRequest {
+	var batches []bulkUpstreamRequest
+	for start := 0; start < len(request.Locations); start += batchSize {
+		end := min(start+batchSize, len(request.Locations))
+		batches = append(batches, bulkUpstreamRequest{Locations: request.Locations[start:end]})
+	}
+	return batches
+}
+
+// resolveBulkBatches sends the native bulk batches with at most WeatherAPIBulkConcurrency calls in flight.
+// Every batch stores its results at distinct query indexes, so they can be written concurrently
+// and the merged result keeps the input order. It returns the first error of any batch.
+func (s *WeatherAPIService) resolveBulkBatches(batches []bulkUpstreamRequest, queries, keys []string, found []*FormattedWeatherData, notFound []string) error {
+	var wg sync.WaitGroup
+	var once sync.Once
+	var firstErr error
+	semaphore := make(chan struct{}, s.cfg.WeatherAPIBulkConcurrency)
+
+	for _, batch := range batches {
+		wg.Add(1)
+		semaphore <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-semaphore }()
+
+			if err := s.resolveBulkUpstream(batch, queries, keys, found, notFound); err != nil {
+				once.Do(func() { firstErr = err })
+			}
+		}()
+	}
+	wg.Wait()
+
+	return firstErr
+}
+
+// resolveBulkUpstream sends the native bulk request and stores each result at its query's index
+// in found or notFound. It returns an error if the call fails or a query is missing from the response.
+func (s *WeatherAPIService) resolveBulkUpstream(request bulkUpstreamRequest, queries, keys []string, found []*FormattedWeatherData, notFound []string) error {
+	body, err := json.Marshal(request)
+	if err != nil {
+		return fmt.Errorf("failed to marshal bulk request: %w", err)
+	}
+
+	// Send the bulk request to the weather API.
+	url := fmt.Sprintf("%s/current.json?key=%s&q=bulk", s.cfg.WeatherAPIBaseURL, s.cfg.WeatherAPIKey)
+	resBody, err := s.postToWeatherApi(url, body)
+	if err != nil {
+		return err
+	}
+
+	// Parse the response body.
+	var response bulkUpstreamResponse
+	if err := json.Unmarshal(resBody, &response); err != nil {
+		if _, ok := err.(*json.SyntaxError); ok {
+			return ErrUnexpectedEndOfJSONInput
+		}
+		return fmt.Errorf("error occurred while unmarshaling bulk JSON: %w", err)
+	}
+
+	// Match every result to its query through the custom ID.
+	for _, item := range response.Bulk {
+		i, err := strconv.Atoi(item.Query.CustomID)
+		if err != nil || i < 0 || i >= len(queries) {
+			return fmt.Errorf("unexpected custom_id %q in bulk response", item.Query.CustomID)
+		}
+
+		if item.Query.Error != nil {
+			// Unknown locations are reported and negatively cached; any other error fails the bulk call.
+			if item.Query.Error.Code != upstreamNoLocationFoundCode {
+				return fmt.Errorf("weatherapi bulk request failed: %w", upstreamErrorForCode(item.Query.Error.Code, item.Query.Error.Message))
+			}
+			notFound[i] = fmt.Sprintf("'%s' not found", queries[i])
+			s.rememberNotFound(keys[i])
+			continue
+		}
+
+		// An empty result fails the bulk call, like an empty single lookup does.
+		if err := validateUpstreamWeather(item.Query.Weather); err != nil {
+			return fmt.Errorf("weatherapi bulk request failed for '%s': %w", queries[i], err)
+		}
+
+		// Format the weather data, compute its trend and cache it like a single lookup would.
+		formattedData := s.withTempTrend(keys[i], formatWeatherData(item.Query.Weather))
+		hash, err := s.cacheTheWeatherDataToRedis(keys[i], formattedData)
+		if err != nil {
+			log.Printf("Error caching weather data: %v", err)
+		}
+		formattedData.ContentHash = hash
+		found[i] = &formattedData
+	}
+
+	// Every requested location must have been answered.
+	for _, location := range request.Locations {
+		i, _ := strconv.Atoi(location.CustomID)
+		if found[i] == nil && notFound[i] == "" {
+			return fmt.Errorf("bulk response is missing a result for '%s'", queries[i])
+		}
+	}
+
+	return nil
+}
+
+// BulkResult is the outcome of a single query of a bulk lookup.
+// Exactly one of Data, NotModified and Error is set.
+type BulkResult struct {
+	Query       string                // Query is the location query as passed in.
+	Data        *FormattedWeatherData // Data is the weather data of a found location with newer data than the client's.
+	NotModified bool                  // NotModified is set when the data is not newer than the time given for the query.
+	Error       string                // Error describes why the location was not found (e.g. "'Atlantis' not found").
+}
+
+// bulkResults pairs every query with its outcome, preserving query order.
+// A found location is reported as not modified when its query has a time in modifiedSince
+// and its observation is not newer than that time. Data without an observation time is always returned.
+func bulkResults(queries []string, found []*FormattedWeatherData, notFound []string, modifiedSince map[string]time.Time) []BulkResult {
+	results := make([]BulkResult, 0, len(queries))
+	for i, q := range queries {
+		switch {
+		case found[i] != nil:
+			since, conditional := modifiedSince[q]
+			if conditional && !found[i].LastUpdated.IsZero() && !found[i].LastUpdated.After(since) {
+				results = append(results, BulkResult{Query: q, NotModified: true})
+				continue
+			}
+			results = append(results, BulkResult{Query: q, Data: found[i]})
+		case notFound[i] != "":
+			results = append(results, BulkResult{Query: q, Error: notFound[i]})
+		}
+	}
+	return results
+}
+
+// SplitBulkResults lists the outcomes of a bulk lookup by kind, each in query order: the data of the found
+// locations, the reasons of the locations not found and the queries whose data was not modified.
+// The data list is empty rather than nil, so that a bulk lookup finding no location is encoded as an empty array.
+func SplitBulkResults(results []BulkResult) ([]FormattedWeatherData, []string, []string) {
+	found := []FormattedWeatherData{}
+	var notFound, notModified []string
+	for _, result := range results {
+		switch {
+		case result.Data != nil:
+			found = append(found, *result.Data)
+		case result.NotModified:
+			notModified = append(notModified, result.Query)
+		default:
+			notFound = append(notFound, result.Error)
+		}
+	}
+	return found, notFound, notModified
+}
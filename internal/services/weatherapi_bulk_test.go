@@ -0,0 +1,115 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"havoAPI/api/config"
+	"net/http"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestSplitBulkResultsWithoutFoundLocations(t *testing.T) {
+	found := make([]*FormattedWeatherData, 2)
+	data, notFound, _ := SplitBulkResults(bulkResults([]string{"Atlantis", "El Dorado"}, found, []string{"Atlantis", "El Dorado"}, nil))
+	if data == nil || len(data) != 0 {
+		t.Errorf("data = %#v, want an empty list rather than nil", data)
+	}
+	if len(notFound) != 2 {
+		t.Errorf("notFound = %v, want both locations", notFound)
+	}
+}
+
+func TestBulkResultsFollowTheQueries(t *testing.T) {
+	lastUpdated := testNow.Add(-time.Hour)
+	london := &FormattedWeatherData{Name: "London", LastUpdated: lastUpdated}
+	paris := &FormattedWeatherData{Name: "Paris", LastUpdated: lastUpdated}
+	queries := []string{"London", "Atlantis", "Paris", "London"}
+	found := []*FormattedWeatherData{london, nil, paris, london}
+	notFound := []string{"", "Atlantis: not found", "", ""}
+
+	results := bulkResults(queries, found, notFound, map[string]time.Time{"Paris": lastUpdated})
+	want := []BulkResult{
+		{Query: "London", Data: london},
+		{Query: "Atlantis", Error: "Atlantis: not found"},
+		{Query: "Paris", NotModified: true},
+		{Query: "London", Data: london},
+	}
+	if !slices.Equal(results, want) {
+		t.Errorf("bulkResults() = %+v, want %+v", results, want)
+	}
+}
+
+// writeBulkWeather answers a native bulk request with weather data named after every query.
+func writeBulkWeather(w http.ResponseWriter, r *http.Request) {
+	var request bulkUpstreamRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	type result struct {
+		CustomID string `json:"custom_id"`
+		Weather
+	}
+	type item struct {
+		Query result `json:"query"`
+	}
+	var response struct {
+		Bulk []item `json:"bulk"`
+	}
+	for _, location := range request.Locations {
+		query := result{CustomID: location.CustomID}
+		query.Location.Name = location.Q
+		query.Location.Country = "Testland"
+		query.Location.TzID = "UTC"
+		query.Location.LocalTimeEpoch = testNow.Unix()
+		query.Current.TempC = 20
+		query.Current.LastUpdatedEpoch = testNow.Unix()
+		response.Bulk = append(response.Bulk, item{Query: query})
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(response)
+}
+
+func TestFetchBulkWeatherDataMixesNamesAndCoordinates(t *testing.T) {
+	queries := []string{"London", "48.8566,2.3522", "Paris", "91,0", "-33.87,151.21"}
+	wantNames := []string{"London", "48.8566,2.3522", "Paris", "-33.87,151.21"}
+
+	for _, native := range []bool{false, true} {
+		t.Run(fmt.Sprintf("native bulk %v", native), func(t *testing.T) {
+			ts := newTestService(t, func(cfg *config.Config) {
+				cfg.WeatherAPIBulkEnabled = native
+				cfg.WeatherAPIBulkBatchSize = 50
+				cfg.WeatherAPIBulkConcurrency = 1
+			})
+			if native {
+				ts.upstream.handle(func(w http.ResponseWriter, r *http.Request) {
+					if r.URL.Query().Get("q") != "bulk" {
+						t.Errorf("native bulk path sent a single lookup for %q", r.URL.Query().Get("q"))
+					}
+					writeBulkWeather(w, r)
+				})
+			}
+
+			results, err := ts.FetchBulkWeatherData(queries, nil)
+			if err != nil {
+				t.Fatalf("FetchBulkWeatherData failed: %v", err)
+			}
+			data, notFound, _ := SplitBulkResults(results)
+
+			// Every valid element resolves on its own, in the order of the request
+			var names []string
+			for _, d := range data {
+				names = append(names, d.Name)
+			}
+			if !slices.Equal(names, wantNames) {
+				t.Errorf("found %q, want %q", names, wantNames)
+			}
+			if want := []string{"'91,0' has invalid coordinates"}; !slices.Equal(notFound, want) {
+				t.Errorf("not found = %q, want %q", notFound, want)
+			}
+		})
+	}
+}
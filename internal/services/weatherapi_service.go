@@ -1,20 +1,30 @@
 package services
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 
 	"havoAPI/api/config"
+	"havoAPI/internal/clock"
 	"havoAPI/internal/models"
+	"havoAPI/internal/tracing"
 	"io"
 	"log"
 	"net/http"
+	neturl "net/url"
+	"slices"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/redis/go-redis/v9"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // WeatherAPIServiceInterface defines the methods for interacting with weather data.
@@ -22,20 +32,64 @@ import (
 // and updating weather data in a Redis cache.
 type WeatherAPIServiceInterface interface {
 	// FetchBulkWeatherData retrieves weather data for multiple locations.
-	// It returns an array of formatted weather data and an array of locations not found.
-	FetchBulkWeatherData(queries []string) ([]FormattedWeatherData, []string, error)
+	// It returns the outcome of every query, in query order: its data, or the reason it was not found,
+	// or that its data is not newer than the time given for it in modifiedSince (nil for none).
+	// SplitBulkResults lists the outcomes by kind.
+	FetchBulkWeatherData(queries []string, modifiedSince map[string]time.Time) ([]BulkResult, error)
 
-	// FetchWeatherData retrieves weather data for a single location.
+	// FetchWeatherData retrieves weather data for a single location, with the settings given in opts.
 	// It returns the formatted weather data or an error if the location is not found or the request fails.
-	FetchWeatherData(query string) (FormattedWeatherData, error)
+	FetchWeatherData(ctx context.Context, query string, opts WeatherOptions) (FormattedWeatherData, error)
 
-	// APIKeyAuthorization checks if the provided API key is valid for a user.
-	// It returns true if the API key is valid, otherwise false along with an error if any.
-	APIKeyAuthorization(apiKey string) (bool, error)
+	// FetchWeatherDataByIP retrieves weather data for the location of the given IP address, with the settings given in opts.
+	// The result is never cached because it is specific to a single caller.
+	FetchWeatherDataByIP(ctx context.Context, ip string, opts WeatherOptions) (FormattedWeatherData, error)
+
+	// FetchAstronomyData retrieves sun and moon data for a location on a date (YYYY-MM-DD, today at the location if empty).
+	// It returns ErrNoLocationFound if the location does not exist.
+	FetchAstronomyData(query, date string) (AstronomyData, error)
+
+	// FetchHistoryData retrieves the daily weather summaries of a location from date to endDate (YYYY-MM-DD, inclusive;
+	// endDate defaults to date). It returns ErrInvalidHistoryRange if the range can't be served.
+	FetchHistoryData(query, date, endDate string) (HistoryData, error)
+
+	// SearchLocations returns all locations matching the query, so ambiguous queries can be disambiguated.
+	// The context bounds the upstream request. It returns ErrNoLocationFound if nothing matches,
+	// and ErrLocationNotAllowed if no match passes the location allowlist.
+	SearchLocations(ctx context.Context, query string) ([]LocationCandidate, error)
+
+	// CachedWeatherDataHash returns the content hash of the weather data cached for a location with the given options,
+	// without fetching or deserializing the data. It returns ErrNoDataCache if no data is currently cached for the location.
+	CachedWeatherDataHash(query string, opts WeatherOptions) (string, error)
+
+	// CachedWeatherDataTTL reports how long the cached weather data for a location remains valid.
+	// It returns ErrNoDataCache if no data is currently cached for the location.
+	CachedWeatherDataTTL(query string) (time.Duration, error)
+
+	// APIKeyAuthorization checks if the provided API key is valid for a user and returns the scopes it is limited to.
+	// It returns ErrAPIKeyNotFound if the key does not exist. The context carries the trace of the request.
+	APIKeyAuthorization(ctx context.Context, apiKey string) (APIKeyScopes, error)
+
+	// AllowAnonymousRequest counts a request without an API key against the daily allowance of the client IP
+	// and returns the number of requests left today. It returns ErrAnonymousAccessDisabled if no allowance
+	// is configured and ErrAnonymousLimitExceeded once it is used up.
+	AllowAnonymousRequest(ip string) (int, error)
+
+	// SubscribeWeatherUpdates subscribes to the weather data of the given locations, delivered on the returned
+	// channel whenever it is refreshed in the cache. The returned function must be called to end the subscription.
+	SubscribeWeatherUpdates(queries []string) (<-chan FormattedWeatherData, func(), error)
+
+	// InvalidateAPIKey drops the cached validation of an API key, so that a disabled or deleted key stops working at once.
+	InvalidateAPIKey(apiKey string) error
 
 	// UpdateWeatherDataInTheRedisCache updates all weather data in the Redis cache.
 	// This involves deleting the current cache and fetching new data for predefined locations.
-	UpdateWeatherDataInTheRedisCache() error
+	// The optional progress callback is called after every location.
+	UpdateWeatherDataInTheRedisCache(progress func(RefreshProgress)) error
+
+	// WatchCacheRefresh follows the cache refresh in progress, delivering its progress and a final event on the
+	// returned channel. It returns ErrNoCacheRefreshInProgress if no refresh is running.
+	WatchCacheRefresh() (<-chan RefreshEvent, func(), error)
 }
 
 // WeatherAPIService is a concrete implementation of the WeatherAPIServiceInterface.
@@ -45,198 +99,640 @@ type WeatherAPIService struct {
 	db models.DBContractWeatherapi
 
 	// redisClient is a Redis client used for caching weather data.
-	redisClient *redis.Client
-}
+	redisClient *RedisClient
 
-// NewWeatherAPIService initializes a new instance of WeatherAPIService.
-// It connects to a Redis instance using credentials loaded from environment variables.
-func NewWeatherAPIService(db models.DBContractWeatherapi) *WeatherAPIService {
-	// Load Redis address from the environment.
-	redisAddr, err := config.LoadEnvironmentVariable("REDIS_ADDR")
-	if err != nil {
-		log.Fatal("failed to receive redis address from .env file")
-	}
+	// cfg holds the application config (WeatherAPI credentials, cache TTL, etc.).
+	cfg *config.Config
 
-	// Load Redis password from the environment.
-	redisPass, err := config.LoadEnvironmentVariable("REDIS_PASS")
-	if err != nil {
-		log.Fatal("failed to receive redis password from .env file")
-	}
+	// allowlist restricts which locations may be queried; it is empty (permitting everything) unless configured.
+	allowlist locationAllowlist
 
-	// Initialize Redis client with the loaded credentials.
-	rdb := redis.NewClient(&redis.Options{
-		Addr:        redisAddr,
-		Password:    redisPass,
-		DB:          0,
-		DialTimeout: 5 * time.Second,
-	})
+	// httpClient sends the requests to WeatherAPI, bounding every attempt by the configured timeout.
+	httpClient *http.Client
+
+	// breaker fails requests to WeatherAPI fast while it keeps failing.
+	breaker *circuitBreaker
+
+	// updates pushes freshly cached weather data to the clients streaming it.
+	updates *weatherUpdates
+
+	// clk is the time source of the service's time-dependent behavior (e.g. astronomy cache expiry).
+	clk clock.Clock
+
+	// refreshing is set while UpdateWeatherDataInTheRedisCache runs, so that refreshes never overlap.
+	refreshing atomic.Bool
 
+	// refreshFeed delivers the progress of the running refresh to the admins following it.
+	refreshFeed *refreshFeed
+
+	// footprint keeps the last measured size of the weather cache, reported by the metrics endpoint.
+	footprint cacheFootprintCache
+
+	// stats counts the weather cache hits and misses of client requests, reported by the admin endpoint.
+	stats *cacheStats
+}
+
+// NewWeatherAPIService initializes a new instance of WeatherAPIService.
+// It uses the provided Redis client for caching weather data and the provided clock as its time source.
+func NewWeatherAPIService(db models.DBContractWeatherapi, redisClient *RedisClient, cfg *config.Config, clk clock.Clock) *WeatherAPIService {
 	// Return the newly created WeatherAPIService instance.
 	return &WeatherAPIService{
 		db:          db,
-		redisClient: rdb,
+		redisClient: redisClient,
+		cfg:         cfg,
+		allowlist:   newLocationAllowlist(cfg.AllowedLocations, cfg.AllowedCountries),
+		httpClient:  &http.Client{Timeout: cfg.WeatherAPITimeout},
+		breaker:     newCircuitBreaker(cfg.UpstreamBreakerThreshold, cfg.UpstreamBreakerCooldown, clk),
+		updates:     newWeatherUpdates(),
+		refreshFeed: newRefreshFeed(),
+		clk:         clk,
+		stats:       newCacheStats(clk.Now()),
 	}
 }
 
+// Ping verifies that the Redis cache is reachable.
+// It is used by the readiness probe.
+func (s *WeatherAPIService) Ping(ctx context.Context) error {
+	return s.redisClient.Ping(ctx).Err()
+}
+
+// CheckUpstream verifies that WeatherAPI accepts the configured API key, using a single location search
+// (the cheapest call of the API). It is used by the check command, not by the readiness probe,
+// so that probes never spend the upstream quota.
+func (s *WeatherAPIService) CheckUpstream(ctx context.Context) error {
+	apiURL := fmt.Sprintf("%s/search.json?key=%s&q=London", s.cfg.WeatherAPIBaseURL, s.cfg.WeatherAPIKey)
+	_, err := s.requestToWeatherApi(ctx, apiURL)
+	return err
+}
+
 // FetchWeatherData retrieves weather data for a single location, either from the Redis cache or by querying the weather API.
 // If data is not in the cache, it makes a request to the weather API and caches the result.
-func (s *WeatherAPIService) FetchWeatherData(q string) (FormattedWeatherData, error) {
-	// Capitalize the first letter of the location for consistent formatting.
-	q = capitalizeFirstLetter(q)
+// The context bounds the upstream request, and opts select the units, language and extra data of the result.
+func (s *WeatherAPIService) FetchWeatherData(ctx context.Context, q string, opts WeatherOptions) (FormattedWeatherData, error) {
+	// Keep the query as sent, to tell the client whether it was resolved to another name.
+	query := q
+
+	// Normalize the location for consistent formatting, validating coordinate queries.
+	q, err := normalizeQuery(q)
+	if err != nil {
+		return FormattedWeatherData{}, err
+	}
+
+	// Reject locations that can never pass the allowlist before any upstream call.
+	if !s.allowlist.mayAllowQuery(q) {
+		return FormattedWeatherData{}, ErrLocationNotAllowed
+	}
+
+	// Reject cache tolerances so short that they would turn every request into an upstream call.
+	if opts.MaxAge > 0 && opts.MaxAge < s.cfg.MinClientMaxAge {
+		return FormattedWeatherData{}, fmt.Errorf("%w of %d seconds", ErrMaxAgeTooShort, int(s.cfg.MinClientMaxAge.Seconds()))
+	}
 
-	// Attempt to retrieve the weather data from Redis cache.
-	cachedData, err := s.retrieveWeatherDataFromRedisCache(q)
+	// Derive the cache key once so that reads and writes always use the same key.
+	key := opts.cacheKey(q)
+
+	// Attempt to retrieve the weather data from Redis cache, traced as a child span of the request.
+	// A miss is an expected outcome rather than a failure of the span.
+	// A forced refresh, or an entry older than the client's max age, is treated as a miss,
+	// so the live data replaces the cached entry.
+	var cachedData FormattedWeatherData
+	if opts.ForceRefresh {
+		err = ErrNoDataCache
+	} else {
+		_, cacheSpan := tracing.Tracer().Start(ctx, "cache lookup", trace.WithAttributes(attribute.String("cache.key", key)))
+		cachedData, err = s.retrieveWeatherDataFromRedisCache(key, opts.MaxAge)
+		cacheSpan.SetAttributes(attribute.Bool("cache.hit", err == nil))
+		if errors.Is(err, ErrNoDataCache) {
+			tracing.End(cacheSpan, nil)
+		} else {
+			tracing.End(cacheSpan, err)
+		}
+	}
 	if errors.Is(err, nil) {
+		// Country-level allowlist entries can only be checked once the location is resolved.
+		if !s.allowlist.allowsLocation(q, cachedData) {
+			return FormattedWeatherData{}, ErrLocationNotAllowed
+		}
 		// If data is found in the cache, return it.
-		return cachedData, nil
+		return ApplyUnits(s.attribute(withMatchInfo(query, s.withMeta(cachedData, key, opts))), opts.Units), nil
 	}
 
 	// If no data is found in the cache, attempt to fetch it from the weather API.
 	if errors.Is(err, ErrNoDataCache) {
-		// Load the Weather API key from the environment.
-		apiKeyForWeatherAPI, err := config.LoadEnvironmentVariable("API_KEY_FOR_WEATHERAPI")
-		if err != nil {
-			return FormattedWeatherData{}, err
+		// Skip the upstream call if this location was recently reported as not found, unless forced.
+		if !opts.ForceRefresh && s.isKnownNotFound(key) {
+			return FormattedWeatherData{}, ErrNoLocationFound
 		}
 
-		// Format the query for the API request.
-		query := strings.Replace(q, " ", "%20", -1)
-		url := fmt.Sprintf("http://api.weatherapi.com/v1/current.json?key=%s&q=%s&aqi=no", apiKeyForWeatherAPI, query)
-
-		// Make the request to the weather API.
-		resBody, err := requestToWeatherApi(url)
+		formattedData, err := s.fetchCurrentWeatherFromUpstream(ctx, q, opts)
 		if err != nil {
-			// Return specific error if no location is found.
+			// Remember locations that don't exist so repeated lookups don't waste upstream quota.
 			if errors.Is(err, ErrNoLocationFound) {
-				return FormattedWeatherData{}, ErrNoLocationFound
+				s.rememberNotFound(key)
 			}
-			return FormattedWeatherData{}, err
-		}
 
-		// Parse the response body into a Weather struct.
-		var weatherData Weather
-		err = json.Unmarshal(resBody, &weatherData)
-		if err != nil {
-			// Handle JSON parsing errors.
-			if _, ok := err.(*json.SyntaxError); ok {
-				return FormattedWeatherData{}, ErrUnexpectedEndOfJSONInput
+			// Serve the last good data instead of failing while WeatherAPI is unavailable.
+			// Coordinate queries without a copy of their own may fall back on a nearby cached location.
+			servedKey := key
+			staleData, err := s.fallBackToStaleCopy(key, err)
+			if err != nil {
+				staleData, servedKey, err = s.fallBackToNearestCached(q, opts, err)
+				if err != nil {
+					return FormattedWeatherData{}, err
+				}
+			}
+			if !s.allowlist.allowsLocation(q, staleData) {
+				return FormattedWeatherData{}, ErrLocationNotAllowed
 			}
-			return FormattedWeatherData{}, fmt.Errorf("error occurred while unmarshaling JSON: %w", err)
+			return ApplyUnits(s.attribute(withMatchInfo(query, s.withMeta(staleData, servedKey, opts))), opts.Units), nil
 		}
 
-		// Format the weather data and cache it in Redis.
-		formattedData := formatWeatherData(weatherData)
-		err = s.cacheTheWeatherDataToRedis(query, formattedData)
+		// Compare the temperature against the previous snapshot of the location.
+		formattedData = s.withTempTrend(key, formattedData)
+
+		// Cache the formatted weather data in Redis. A failure only costs the cache entry: the fresh data
+		// is still served, without a content hash, like a lookup with the cache disabled.
+		formattedData.ContentHash, err = s.cacheTheWeatherDataToRedis(key, formattedData)
 		if err != nil {
-			log.Fatalf("Error caching weather data: %v", err)
+			log.Printf("Error caching weather data: %v", err)
+		}
+
+		// Country-level allowlist entries can only be checked once the location is resolved.
+		if !s.allowlist.allowsLocation(q, formattedData) {
+			return FormattedWeatherData{}, ErrLocationNotAllowed
 		}
 
 		// Return the formatted weather data.
-		return formattedData, nil
+		return ApplyUnits(s.attribute(withMatchInfo(query, s.withFreshMeta(formattedData, s.cfg.CacheEnabled, opts))), opts.Units), nil
 	}
 
 	// Return an error if something else went wrong.
 	return FormattedWeatherData{}, err
 }
 
+// FetchWeatherDataByIP retrieves weather data for the location of the given IP address.
+// The query is passed to WeatherAPI untouched (no capitalization), and the result is not cached,
+// since IP-based lookups are specific to a single caller.
+func (s *WeatherAPIService) FetchWeatherDataByIP(ctx context.Context, ip string, opts WeatherOptions) (FormattedWeatherData, error) {
+	weatherData, err := s.fetchCurrentWeatherFromUpstream(ctx, ip, opts)
+	if err != nil {
+		return FormattedWeatherData{}, err
+	}
+
+	// The caller's location must be allowed like any other.
+	if !s.allowlist.allowsLocation(ip, weatherData) {
+		return FormattedWeatherData{}, ErrLocationNotAllowed
+	}
+
+	// IP lookups keep no snapshots, so there is nothing to compare the temperature against.
+	weatherData.TempTrend = TempTrendUnknown
+	return ApplyUnits(s.attribute(s.withFreshMeta(weatherData, false, opts)), opts.Units), nil
+}
+
+// attribute sets the data source attribution on the weather data.
+// It is applied when data leaves the service rather than when it is cached,
+// so that changing the configured attribution takes effect immediately.
+func (s *WeatherAPIService) attribute(weatherData FormattedWeatherData) FormattedWeatherData {
+	weatherData.Source = s.cfg.Attribution
+	return weatherData
+}
+
+// fetchCurrentWeatherFromUpstream requests the current weather for a query from WeatherAPI
+// and returns it formatted, without touching the cache.
+func (s *WeatherAPIService) fetchCurrentWeatherFromUpstream(ctx context.Context, q string, opts WeatherOptions) (FormattedWeatherData, error) {
+	// Format the query for the API request.
+	query := strings.Replace(q, " ", "%20", -1)
+	url := fmt.Sprintf("%s/current.json?key=%s&q=%s%s", s.cfg.WeatherAPIBaseURL, s.cfg.WeatherAPIKey, query, opts.upstreamParams())
+
+	// Make the request to the weather API.
+	resBody, err := s.requestToWeatherApi(ctx, url)
+	if err != nil {
+		// Return specific error if no location is found.
+		if errors.Is(err, ErrNoLocationFound) {
+			return FormattedWeatherData{}, ErrNoLocationFound
+		}
+		return FormattedWeatherData{}, err
+	}
+
+	// Parse the response body into a Weather struct.
+	var weatherData Weather
+	err = json.Unmarshal(resBody, &weatherData)
+	if err != nil {
+		// Handle JSON parsing errors.
+		if _, ok := err.(*json.SyntaxError); ok {
+			return FormattedWeatherData{}, ErrUnexpectedEndOfJSONInput
+		}
+		return FormattedWeatherData{}, fmt.Errorf("error occurred while unmarshaling JSON: %w", err)
+	}
+
+	// Refuse to cache or return an empty response as weather data.
+	if err := validateUpstreamWeather(weatherData); err != nil {
+		return FormattedWeatherData{}, err
+	}
+
+	// Return the formatted weather data.
+	return formatWeatherData(weatherData), nil
+}
+
+// SearchLocations queries WeatherAPI's search endpoint and returns every location matching the query.
+// Unlike FetchWeatherData, it does not pick the first match, which lets callers detect ambiguous queries.
+// The context bounds the upstream request, which is traced as part of the client's request.
+// Like every other lookup, it only lists the candidates the location allowlist permits.
+func (s *WeatherAPIService) SearchLocations(ctx context.Context, q string) ([]LocationCandidate, error) {
+	// Normalize the location for consistent formatting, validating coordinate queries.
+	q, err := normalizeQuery(q)
+	if err != nil {
+		return nil, err
+	}
+
+	// Reject locations that can never pass the allowlist before any upstream call.
+	if !s.allowlist.mayAllowQuery(q) {
+		return nil, ErrLocationNotAllowed
+	}
+
+	// Escape the query, so that characters such as '&' or '#' can't add parameters to the API request.
+	apiURL := fmt.Sprintf("%s/search.json?key=%s&q=%s", s.cfg.WeatherAPIBaseURL, s.cfg.WeatherAPIKey, neturl.QueryEscape(q))
+
+	// Make the request to the weather API.
+	resBody, err := s.requestToWeatherApi(ctx, apiURL)
+	if err != nil {
+		return nil, err
+	}
+
+	// Parse the response body into a list of candidates.
+	var candidates []LocationCandidate
+	err = json.Unmarshal(resBody, &candidates)
+	if err != nil {
+		if _, ok := err.(*json.SyntaxError); ok {
+			return nil, ErrUnexpectedEndOfJSONInput
+		}
+		return nil, fmt.Errorf("error occurred while unmarshaling JSON: %w", err)
+	}
+
+	// An empty result means the query matched nothing.
+	if len(candidates) == 0 {
+		return nil, ErrNoLocationFound
+	}
+
+	// Drop the candidates outside the allowlist; country entries can only be checked on resolved locations.
+	allowed := slices.DeleteFunc(candidates, func(candidate LocationCandidate) bool {
+		return !s.allowlist.allowsLocation(q, FormattedWeatherData{Name: candidate.Name, Country: candidate.Country})
+	})
+	if len(allowed) == 0 {
+		return nil, ErrLocationNotAllowed
+	}
+
+	// Return all matching candidates.
+	return allowed, nil
+}
+
+// CachedWeatherDataTTL checks whether weather data for a location exists in the Redis cache
+// without fetching or deserializing it, and returns the remaining time to live of the entry.
+func (s *WeatherAPIService) CachedWeatherDataTTL(q string) (time.Duration, error) {
+	// Use the same normalization and key derivation as the read and write paths.
+	q, err := normalizeQuery(q)
+	if err != nil {
+		return 0, err
+	}
+	key := weatherCacheKey(q)
+
+	// Nothing is cached while caching is disabled.
+	if !s.cfg.CacheEnabled {
+		return 0, ErrNoDataCache
+	}
+
+	// Ask Redis for the remaining TTL; negative values mean the key is missing or has no expiry.
+	ttl, err := s.redisClient.TTL(context.Background(), s.redisClient.prefixed(key)).Result()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get TTL from Redis: %w", err)
+	}
+	if ttl < 0 {
+		return 0, ErrNoDataCache
+	}
+
+	// Return the remaining lifetime of the cached entry.
+	return ttl, nil
+}
+
 // FetchBulkWeatherData retrieves weather data for multiple locations, handling both found and not found locations.
-func (s *WeatherAPIService) FetchBulkWeatherData(queries []string) ([]FormattedWeatherData, []string, error) {
-	var bulkWeatherData []FormattedWeatherData
+// When the native WeatherAPI bulk endpoint is enabled, all uncached locations are fetched in a single upstream call;
+// if that call fails, it falls back to fetching each location separately.
+// Queries listed in modifiedSince are only returned if their observation is newer than the given time;
+// the others are reported in the not-modified list instead.
+func (s *WeatherAPIService) FetchBulkWeatherData(queries []string, modifiedSince map[string]time.Time) ([]BulkResult, error) {
+	var found []*FormattedWeatherData
 	var notFound []string
+	var err error
+
+	if s.cfg.WeatherAPIBulkEnabled {
+		found, notFound, err = s.fetchBulkUpstream(queries)
+		if err != nil {
+			log.Printf("native bulk request failed, falling back to per-location requests: %v", err)
+		}
+	}
+	if !s.cfg.WeatherAPIBulkEnabled || err != nil {
+		found, notFound, err = s.fetchBulkPerLocation(queries)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Attribute every result and enforce the allowlist, since the native bulk path bypasses FetchWeatherData.
+	for i, data := range found {
+		if data == nil {
+			continue
+		}
+		if !s.allowlist.allowsLocation(queries[i], *data) {
+			found[i] = nil
+			notFound[i] = fmt.Sprintf("'%s' is not allowed", queries[i])
+			continue
+		}
+		*data = s.attribute(withMatchInfo(queries[i], *data))
+	}
+
+	return bulkResults(queries, found, notFound, modifiedSince), nil
+}
+
+// fetchBulkPerLocation retrieves weather data for multiple locations with one FetchWeatherData call per location.
+// Results are stored at their query's index in found or notFound. The default options are used,
+// so the results share their cache entries with single lookups and the native bulk path.
+func (s *WeatherAPIService) fetchBulkPerLocation(queries []string) ([]*FormattedWeatherData, []string, error) {
+	found := make([]*FormattedWeatherData, len(queries))
+	notFound := make([]string, len(queries))
 
 	// Loop through each query and attempt to fetch its weather data.
-	for _, q := range queries {
-		weatherData, err := s.FetchWeatherData(q)
+	for i, q := range queries {
+		weatherData, err := s.FetchWeatherData(context.Background(), q, WeatherOptions{})
 		if err != nil {
 			// If no location is found, add it to the notFound list.
 			if errors.Is(err, ErrNoLocationFound) {
-				notFound = append(notFound, fmt.Sprintf("'%s' not found", q))
+				notFound[i] = fmt.Sprintf("'%s' not found", q)
+				continue
+			} else if message, ok := invalidBulkQuery(q, err); ok {
+				// Out-of-range coordinates and malformed airport codes can never be found, so report them alongside unknown locations.
+				notFound[i] = message
+				continue
+			} else if errors.Is(err, ErrLocationNotAllowed) {
+				// Locations outside the allowlist are reported alongside unknown locations too.
+				notFound[i] = fmt.Sprintf("'%s' is not allowed", q)
 				continue
 			} else {
 				return nil, nil, err
 			}
 		}
-		// Append the found weather data to the result.
-		bulkWeatherData = append(bulkWeatherData, weatherData)
+		// Store the found weather data at the query's index.
+		found[i] = &weatherData
 	}
 
-	// Return the bulk weather data and any locations that were not found.
-	if len(notFound) > 0 {
-		return bulkWeatherData, notFound, nil
-	}
-	return bulkWeatherData, nil, nil
+	return found, notFound, nil
 }
 
-// APIKeyAuthorization checks whether the provided API key is valid.
-func (s *WeatherAPIService) APIKeyAuthorization(apiKey string) (bool, error) {
+// APIKeyAuthorization checks whether the provided API key is valid and returns the scopes it is limited to.
+// Successful validations are cached in Redis for a short time so that repeated requests
+// from the same key don't each hit the database. With the cache disabled, every request checks the database.
+// The check is traced as a child span of the request, telling whether it was served from the cache.
+func (s *WeatherAPIService) APIKeyAuthorization(ctx context.Context, apiKey string) (scopes APIKeyScopes, err error) {
+	_, span := tracing.Tracer().Start(ctx, "APIKeyAuthorization")
+	defer func() { tracing.End(span, err) }()
+
+	// Serve the result from the cache when the key was validated recently.
+	if s.cfg.CacheEnabled {
+		if scopes, ok := cachedAPIKeyScopes(s.redisClient, apiKey); ok {
+			span.SetAttributes(attribute.Bool("cache.hit", true))
+			return scopes, nil
+		}
+	}
+	span.SetAttributes(attribute.Bool("cache.hit", false))
+
 	// Check the validity of the API key by querying the database.
-	isKeyTrue, err := s.db.CheckUserAPIKey(apiKey)
+	scopes, err = s.db.CheckUserAPIKey(apiKey)
 	if err != nil {
 		// Return an error if the key is not found or another issue occurs.
 		if errors.Is(err, models.ErrAPIKeyNotFound) {
-			return false, ErrAPIKeyNotFound
+			return nil, ErrAPIKeyNotFound
 		}
-		return false, fmt.Errorf("error occurred while checking user API key: %w", err)
+		return nil, fmt.Errorf("error occurred while checking user API key: %w", err)
 	}
 
-	// Return true if the API key is valid, otherwise false.
-	return isKeyTrue, nil
+	// Remember the successful validation for the next requests.
+	if s.cfg.CacheEnabled {
+		rememberValidAPIKey(s.redisClient, apiKey, scopes)
+	}
+
+	// Return the scopes of the valid API key.
+	return scopes, nil
+}
+
+// InvalidateAPIKey drops the cached validation of the given API key.
+// It must be called whenever a key is disabled or deleted.
+func (s *WeatherAPIService) InvalidateAPIKey(apiKey string) error {
+	if !s.cfg.CacheEnabled {
+		return nil
+	}
+	return invalidateAPIKeyCache(s.redisClient, apiKey)
 }
 
 // requestToWeatherApi sends a GET request to the Weather API and returns the response body.
-func requestToWeatherApi(url string) ([]byte, error) {
-	// Send a GET request to the given URL.
-	response, err := http.Get(url)
+// The request is abandoned when the context is canceled, e.g. because the client went away.
+func (s *WeatherAPIService) requestToWeatherApi(ctx context.Context, url string) ([]byte, error) {
+	// Build a GET request to the given URL.
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build GET request to %s: %w", redactURL(url), err)
+	}
+
+	// Send the request and return the response body.
+	return s.sendRequestToWeatherApi(request)
+}
+
+// postToWeatherApi sends a POST request with a JSON body to the Weather API and returns the response body.
+func (s *WeatherAPIService) postToWeatherApi(url string, body []byte) ([]byte, error) {
+	// Build a POST request to the given URL.
+	request, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
 	if err != nil {
-		return nil, fmt.Errorf("failed to send GET request to the given URL: %w", err)
+		return nil, fmt.Errorf("failed to build POST request to %s: %w", redactURL(url), err)
+	}
+	request.Header.Set("Content-Type", "application/json")
+
+	// Send the request and return the response body.
+	return s.sendRequestToWeatherApi(request)
+}
+
+// sendRequestToWeatherApi sends the given request to the Weather API and returns the response body.
+// Transient failures (network errors, 5xx and 429 responses) are retried up to the configured number of times
+// with exponential backoff; other failures, such as an unknown location, are returned at once.
+// Retries stop as soon as the request's context is canceled.
+// While the circuit breaker is open, it returns ErrUpstreamCircuitOpen without sending anything.
+func (s *WeatherAPIService) sendRequestToWeatherApi(request *http.Request) ([]byte, error) {
+	if !s.breaker.allow() {
+		return nil, ErrUpstreamCircuitOpen
+	}
+
+	for attempt := 0; ; attempt++ {
+		body, retryAfter, err := s.attemptRequestToWeatherApi(request)
+		if err == nil || retryAfter < 0 || attempt >= s.cfg.WeatherAPIMaxRetries {
+			// A request canceled by its client says nothing about WeatherAPI's health.
+			if err != nil && request.Context().Err() != nil {
+				s.breaker.release()
+			} else {
+				s.breaker.record(err != nil && retryAfter >= 0)
+			}
+			return body, err
+		}
+
+		// Wait before the next attempt, unless the caller gives up first.
+		wait := max(retryAfter, retryBackoff(attempt))
+		log.Printf("weatherapi request failed (attempt %d of %d), retrying in %v: %v", attempt+1, s.cfg.WeatherAPIMaxRetries+1, wait, err)
+		if !sleepContext(request.Context(), wait) {
+			s.breaker.release()
+			return nil, err
+		}
+
+		// Rewind the body of requests that carry one (e.g. bulk POSTs) for the next attempt.
+		if request.GetBody != nil {
+			if request.Body, err = request.GetBody(); err != nil {
+				s.breaker.release()
+				return nil, fmt.Errorf("failed to rewind request body: %w", err)
+			}
+		}
+	}
+}
+
+// attemptRequestToWeatherApi makes a single attempt at the given request and returns the response body.
+// On failure, it also reports whether the attempt may be retried: a negative duration means it may not,
+// and a non-negative one is the minimum wait requested by WeatherAPI (0 if it did not ask for any).
+// Every attempt is traced as a client span with the response status, and its trace context is propagated
+// to WeatherAPI in the request headers.
+func (s *WeatherAPIService) attemptRequestToWeatherApi(request *http.Request) (body []byte, retryAfter time.Duration, err error) {
+	url := request.URL.String()
+
+	ctx, span := tracing.Tracer().Start(request.Context(), "weatherapi "+request.URL.Path,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("http.request.method", request.Method),
+			attribute.String("url.full", redactURL(url)),
+		),
+	)
+	defer func() { tracing.End(span, err) }()
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(request.Header))
+
+	// Identify this service to WeatherAPI instead of sending Go's default User-Agent.
+	request.Header.Set("User-Agent", s.cfg.WeatherAPIUserAgent)
+
+	// Send the request to the given URL.
+	response, err := s.httpClient.Do(request.WithContext(ctx))
+	if err != nil {
+		// The transport error embeds the full request URL, so redact it before it can reach any log.
+		var urlErr *neturl.Error
+		if errors.As(err, &urlErr) {
+			urlErr.URL = redactURL(urlErr.URL)
+		}
+		err = fmt.Errorf("failed to send %s request to %s: %w", request.Method, redactURL(url), err)
+
+		// Network errors are transient, but a canceled request must not be retried.
+		if request.Context().Err() != nil {
+			return nil, -1, err
+		}
+		return nil, 0, err
 	}
 	defer response.Body.Close()
+	span.SetAttributes(attribute.Int("http.response.status_code", response.StatusCode))
 
-	// Check if the response indicates an error or invalid location.
-	if response.StatusCode == http.StatusBadRequest {
-		return nil, ErrNoLocationFound
+	// Read the response body, up to one byte past the limit so that an oversized body can be told apart
+	// from one of exactly the limit. A misbehaving upstream streaming without end must not exhaust our memory.
+	limit := s.cfg.WeatherAPIMaxResponseBytes
+	body, err = io.ReadAll(io.LimitReader(response.Body, limit+1))
+	if err != nil {
+		return nil, 0, fmt.Errorf("error occurred while reading response body of weatherapi: %w", err)
+	}
+	// The same request would only get the same oversized body again, so it is not retried.
+	if int64(len(body)) > limit {
+		return nil, -1, fmt.Errorf("%w: body from %s exceeds %d bytes", ErrUpstreamUnavailable, redactURL(url), limit)
 	}
 
-	// If the response status is not OK, return an error.
+	// If the response status is not OK, map WeatherAPI's error code to a sentinel error.
 	if response.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("error occurred: weatherapi response status code is not 200: %w", err)
+		err := parseUpstreamError(response.StatusCode, body, redactURL(url))
+		if !retryableStatus(response.StatusCode) {
+			return nil, -1, err
+		}
+		return nil, parseRetryAfter(response.Header.Get("Retry-After")), err
 	}
 
-	// Read the response body.
-	body, err := io.ReadAll(response.Body)
-	if err != nil {
-		return nil, fmt.Errorf("error occurred while reading response body of weatherapi: %w", err)
+	// An empty 200 response is an upstream incident rather than data, and may well succeed on a retry.
+	if len(bytes.TrimSpace(body)) == 0 {
+		return nil, 0, fmt.Errorf("%w: empty body from %s", ErrUpstreamUnavailable, redactURL(url))
 	}
 
 	// Return the response body.
-	return body, nil
+	return body, 0, nil
 }
 
-// cacheTheWeatherDataToRedis stores the weather data for a specific location in Redis.
-func (s *WeatherAPIService) cacheTheWeatherDataToRedis(location string, weatherData FormattedWeatherData) error {
-	// Marshal the weather data into JSON format.
-	jsonData, err := json.Marshal(weatherData)
+// cacheTheWeatherDataToRedis stores the weather data for a specific location in Redis, together with its content hash.
+// The key is expected to be derived with weatherCacheKey. It returns the content hash of the stored data.
+func (s *WeatherAPIService) cacheTheWeatherDataToRedis(key string, weatherData FormattedWeatherData) (string, error) {
+	// With the cache disabled nothing is stored, and the data has no content hash to revalidate against.
+	if !s.cfg.CacheEnabled {
+		s.updates.publish(key, s.attribute(weatherData))
+		return "", nil
+	}
+
+	// Marshal the weather data into JSON format, along with the time it is cached.
+	cachedAt := s.clk.Now().UTC()
+	jsonData, hash, err := encodeCachedWeatherData(weatherData, cachedAt)
 	if err != nil {
-		return fmt.Errorf("failed to marshal weatherData: %w", err)
+		return "", err
 	}
 
-	// Set the cached data in Redis with a 30-minute expiration time.
-	err = s.redisClient.Set(context.Background(), location, jsonData, 30*time.Minute).Err()
+	// Set the cached data and its hash in Redis with the configured expiration time, atomically,
+	// so that the hash never describes another version of the data.
+	_, err = s.redisClient.TxPipelined(context.Background(), func(pipe redis.Pipeliner) error {
+		pipe.Set(context.Background(), s.redisClient.prefixed(key), jsonData, s.cfg.CacheTTL)
+		pipe.Set(context.Background(), s.redisClient.prefixed(etagCacheKey(key)), hash, s.cfg.CacheTTL)
+		return nil
+	})
 	if err != nil {
-		return fmt.Errorf("failed to set data in Redis: %w", err)
+		return "", fmt.Errorf("failed to set data in Redis: %w", err)
 	}
 
-	// Return nil if the operation was successful.
-	return nil
+	// Keep a longer-lived copy to fall back on when WeatherAPI fails after the entry expired.
+	s.rememberStaleCopy(key, weatherData, cachedAt)
+
+	// Push the fresh data to the clients streaming this location.
+	s.updates.publish(key, s.attribute(weatherData))
+
+	// Return the hash if the operation was successful.
+	return hash, nil
 }
 
 // retrieveWeatherDataFromRedisCache attempts to fetch weather data from Redis cache for a location.
-func (s *WeatherAPIService) retrieveWeatherDataFromRedisCache(location string) (FormattedWeatherData, error) {
-	// Capitalize the first letter of the location for consistent formatting.
-	location = capitalizeFirstLetter(location)
+// The key is expected to be derived with weatherCacheKey. Every lookup counts as a hit or miss in the cache stats.
+// An entry older than the client's cache tolerance maxAge (zero accepting any age) is a miss,
+// and with the cache disabled, every location is a miss.
+func (s *WeatherAPIService) retrieveWeatherDataFromRedisCache(key string, maxAge time.Duration) (FormattedWeatherData, error) {
+	if !s.cfg.CacheEnabled {
+		return FormattedWeatherData{}, ErrNoDataCache
+	}
+	weatherData, err := readCachedWeatherData(s.redisClient, key)
+	if err == nil && s.exceedsMaxAge(weatherData, maxAge) {
+		weatherData, err = FormattedWeatherData{}, ErrNoDataCache
+	}
+	if err == nil || errors.Is(err, ErrNoDataCache) {
+		s.stats.record(key, err == nil)
+	}
+	return weatherData, err
+}
+
+// readCachedWeatherData reads the weather data cached under the given key.
+// It is shared by every service that consumes cached weather data without calling the upstream.
+// Without Redis, nothing is cached.
+func readCachedWeatherData(redisClient *RedisClient, key string) (FormattedWeatherData, error) {
+	if !redisClient.configured() {
+		return FormattedWeatherData{}, ErrNoDataCache
+	}
 
 	// Attempt to get cached data from Redis.
-	jsonData, err := s.redisClient.Get(context.Background(), location).Result()
+	jsonData, err := redisClient.Get(context.Background(), redisClient.prefixed(key)).Result()
 	if err != nil {
 		// Return an error if data is not found in the cache.
 		if errors.Is(err, redis.Nil) {
@@ -246,41 +742,149 @@ func (s *WeatherAPIService) retrieveWeatherDataFromRedisCache(location string) (
 	}
 
 	// Unmarshal the cached data into a FormattedWeatherData object.
-	var weatherData FormattedWeatherData
-	err = json.Unmarshal([]byte(jsonData), &weatherData)
+	return decodeCachedWeatherData([]byte(jsonData))
+}
+
+// cachedWeatherEntry is the form in which weather data is stored in the cache: the data, along with the time it was
+// stored. The content hash covers Data alone, so storing identical data again keeps its entity tag.
+type cachedWeatherEntry struct {
+	CachedAt time.Time       `json:"cached_at"`
+	Data     json.RawMessage `json:"data"`
+}
+
+// encodeCachedWeatherData serializes weather data as it is cached at cachedAt, returning the entry and its content hash.
+func encodeCachedWeatherData(weatherData FormattedWeatherData, cachedAt time.Time) ([]byte, string, error) {
+	data, err := json.Marshal(weatherData)
 	if err != nil {
+		return nil, "", fmt.Errorf("failed to marshal weatherData: %w", err)
+	}
+	entry, err := json.Marshal(cachedWeatherEntry{CachedAt: cachedAt, Data: data})
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to marshal weatherData: %w", err)
+	}
+	return entry, weatherContentHash(data), nil
+}
+
+// decodeCachedWeatherData parses a cache entry written by encodeCachedWeatherData, setting the content hash
+// and the time the data was cached. Entries cached before the time was stored hold the bare data;
+// they are read as such, with a zero CachedAt, until they expire.
+func decodeCachedWeatherData(jsonData []byte) (FormattedWeatherData, error) {
+	var entry cachedWeatherEntry
+	if err := json.Unmarshal(jsonData, &entry); err != nil {
 		return FormattedWeatherData{}, fmt.Errorf("failed to unmarshal data: %w", err)
 	}
+	if entry.Data == nil {
+		entry = cachedWeatherEntry{Data: jsonData}
+	}
 
-	// Return the cached weather data.
+	var weatherData FormattedWeatherData
+	if err := json.Unmarshal(entry.Data, &weatherData); err != nil {
+		return FormattedWeatherData{}, fmt.Errorf("failed to unmarshal data: %w", err)
+	}
+	weatherData.ContentHash = weatherContentHash(entry.Data)
+	weatherData.CachedAt = entry.CachedAt
 	return weatherData, nil
 }
 
-// deleteAllWeatherDataFromRedisCache clears all weather data from the Redis cache.
-func (s *WeatherAPIService) deleteAllWeatherDataFromRedisCache() error {
-	// Flush the entire Redis database.
-	err := s.redisClient.FlushDB(context.Background()).Err()
+// isKnownNotFound reports whether a negative-cache marker exists for the given cache key.
+// Redis errors are logged and treated as a miss so that lookups fall through to the upstream.
+// With the cache disabled, no location is known.
+func (s *WeatherAPIService) isKnownNotFound(key string) bool {
+	if !s.cfg.CacheEnabled {
+		return false
+	}
+	exists, err := s.redisClient.Exists(context.Background(), s.redisClient.prefixed(negativeCacheKey(key))).Result()
 	if err != nil {
-		return fmt.Errorf("failed to flush Redis database: %v", err)
+		log.Printf("failed to check negative cache for %s: %v", key, err)
+		return false
+	}
+	return exists > 0
+}
+
+// rememberNotFound stores a short-lived negative-cache marker for the given cache key.
+// Failing to store it only costs an extra upstream call later, so errors are logged and ignored.
+func (s *WeatherAPIService) rememberNotFound(key string) {
+	if !s.cfg.CacheEnabled {
+		return
+	}
+	err := s.redisClient.Set(context.Background(), s.redisClient.prefixed(negativeCacheKey(key)), 1, s.cfg.NegativeCacheTTL).Err()
+	if err != nil {
+		log.Printf("failed to set negative cache for %s: %v", key, err)
+	}
+}
+
+// deleteAllWeatherDataFromRedisCache clears all weather data, content hashes and negative-cache markers from the Redis cache.
+// Only keys under this service's prefix are deleted, so revoked tokens, cached API key validations,
+// temperature snapshots and other environments sharing the server are left untouched.
+func (s *WeatherAPIService) deleteAllWeatherDataFromRedisCache() error {
+	ctx := context.Background()
+	for _, pattern := range []string{weatherCacheKey("*"), etagCacheKey(weatherCacheKey("*")), negativeCacheKey(weatherCacheKey("*"))} {
+		if err := s.redisClient.deleteByPattern(ctx, pattern); err != nil {
+			return fmt.Errorf("failed to delete weather data from Redis: %w", err)
+		}
 	}
 	return nil
 }
 
+// refreshProgressInterval is the number of locations after which a cache refresh logs its progress.
+const refreshProgressInterval = 50
+
+// refreshedLocations are the locations fetched by every cache refresh. Other locations are only cached
+// for a while after a client asked for them, and are dropped by the next refresh.
+var refreshedLocations = []string{"Afghanistan", "Albania", "Algeria", "Andorra", "Angola", "Anguilla", "Antigua &amp; Barbuda", "Argentina", "Armenia", "Aruba", "Australia", "Austria", "Azerbaijan", "Bahamas", "Bahrain", "Bangladesh", "Barbados", "Belarus", "Belgium", "Belize", "Benin", "Bermuda", "Bhutan", "Bolivia", "Bosnia &amp; Herzegovina", "Botswana", "Brazil", "British Virgin Islands", "Brunei", "Bulgaria", "Burkina Faso", "Burundi", "Cambodia", "Cameroon", "Cape Verde", "Cayman Islands", "Chad", "Chile", "China", "Colombia", "Congo", "Cook Islands", "Costa Rica", "Cote D Ivoire", "Croatia", "Cruise Ship", "Cuba", "Cyprus", "Czech Republic", "Denmark", "Djibouti", "Dominica", "Dominican Republic", "Ecuador", "Egypt", "El Salvador", "Equatorial Guinea", "Estonia", "Ethiopia", "Falkland Islands", "Faroe Islands", "Fiji", "Finland", "France", "French Polynesia", "French West Indies", "Gabon", "Gambia", "Georgia", "Germany", "Ghana", "Gibraltar", "Greece", "Greenland", "Grenada", "Guam", "Guatemala", "Guernsey", "Guinea", "Guinea Bissau", "Guyana", "Haiti", "Honduras", "Hong Kong", "Hungary", "Iceland", "India", "Indonesia", "Iran", "Iraq", "Ireland", "Isle of Man", "Israel", "Italy", "Jamaica", "Japan", "Jersey", "Jordan", "Kazakhstan", "Kenya", "Kuwait", "Kyrgyz Republic", "Laos", "Latvia", "Lebanon", "Lesotho", "Liberia", "Libya", "Liechtenstein", "Lithuania", "Luxembourg", "Macau", "Macedonia", "Madagascar", "Malawi", "Malaysia", "Maldives", "Mali", "Malta", "Mauritania", "Mauritius", "Mexico", "Moldova", "Monaco", "Mongolia", "Montenegro", "Montserrat", "Morocco", "Mozambique", "Namibia", "Nepal", "Netherlands", "Netherlands Antilles", "New Caledonia", "New Zealand", "Nicaragua", "Niger", "Nigeria", "Norway", "Oman", "Pakistan", "Palestine", "Panama", "Papua New Guinea", "Paraguay", "Peru", "Philippines", "Poland", "Portugal", "Puerto Rico", "Qatar", "Reunion", "Romania", "Russia", "Rwanda", "Saint Pierre &amp; Miquelon", "Samoa", "San Marino", "Satellite", "Saudi Arabia", "Senegal", "Serbia", "Seychelles", "Sierra Leone", "Singapore", "Slovakia", "Slovenia", "South Africa", "South Korea", "Spain", "Sri Lanka", "St Kitts &amp; Nevis", "St Lucia", "St Vincent", "St. Lucia", "Sudan", "Suriname", "Swaziland", "Sweden", "Switzerland", "Syria", "Taiwan", "Tajikistan", "Tanzania", "Thailand", "Timor L'Este", "Togo", "Tonga", "Trinidad &amp; Tobago", "Tunisia", "Turkey", "Turkmenistan", "Turks &amp; Caicos", "Uganda", "Ukraine", "United Arab Emirates", "United Kingdom", "Uruguay", "Uzbekistan", "Venezuela", "Vietnam", "Virgin Islands (US)", "Yemen", "Zambia", "Zimbabwe"}
+
 // UpdateWeatherDataInTheRedisCache deletes the current weather data in Redis and updates it with new data
 // for a predefined list of countries.
-func (s *WeatherAPIService) UpdateWeatherDataInTheRedisCache() error {
+// A refresh can take longer than the cron interval, so it returns ErrCacheRefreshInProgress
+// instead of starting a second, concurrent refresh that would double the upstream load.
+// If progress is not nil, it is called synchronously after every location, so a slow callback slows the refresh down.
+// The progress is also delivered to the clients following the refresh with WatchCacheRefresh.
+func (s *WeatherAPIService) UpdateWeatherDataInTheRedisCache(progress func(RefreshProgress)) (err error) {
+	// There is no cache to fill while caching is disabled.
+	if !s.cfg.CacheEnabled {
+		return ErrCacheDisabled
+	}
+
+	// Skip this run if the previous refresh is still going.
+	if !s.refreshing.CompareAndSwap(false, true) {
+		log.Println("cache refresh already in progress, skipping this run")
+		return ErrCacheRefreshInProgress
+	}
+	defer s.refreshing.Store(false)
+
+	// Let admins follow the refresh until it returns.
+	s.refreshFeed.begin()
+	defer func() { s.refreshFeed.finish(err) }()
+
 	// Delete all existing weather data from Redis.
-	err := s.deleteAllWeatherDataFromRedisCache()
+	err = s.deleteAllWeatherDataFromRedisCache()
 	if err != nil {
 		return err
 	}
 
-	// List of predefined countries to update weather data for.
-	var country_list = []string{"Afghanistan", "Albania", "Algeria", "Andorra", "Angola", "Anguilla", "Antigua &amp; Barbuda", "Argentina", "Armenia", "Aruba", "Australia", "Austria", "Azerbaijan", "Bahamas", "Bahrain", "Bangladesh", "Barbados", "Belarus", "Belgium", "Belize", "Benin", "Bermuda", "Bhutan", "Bolivia", "Bosnia &amp; Herzegovina", "Botswana", "Brazil", "British Virgin Islands", "Brunei", "Bulgaria", "Burkina Faso", "Burundi", "Cambodia", "Cameroon", "Cape Verde", "Cayman Islands", "Chad", "Chile", "China", "Colombia", "Congo", "Cook Islands", "Costa Rica", "Cote D Ivoire", "Croatia", "Cruise Ship", "Cuba", "Cyprus", "Czech Republic", "Denmark", "Djibouti", "Dominica", "Dominican Republic", "Ecuador", "Egypt", "El Salvador", "Equatorial Guinea", "Estonia", "Ethiopia", "Falkland Islands", "Faroe Islands", "Fiji", "Finland", "France", "French Polynesia", "French West Indies", "Gabon", "Gambia", "Georgia", "Germany", "Ghana", "Gibraltar", "Greece", "Greenland", "Grenada", "Guam", "Guatemala", "Guernsey", "Guinea", "Guinea Bissau", "Guyana", "Haiti", "Honduras", "Hong Kong", "Hungary", "Iceland", "India", "Indonesia", "Iran", "Iraq", "Ireland", "Isle of Man", "Israel", "Italy", "Jamaica", "Japan", "Jersey", "Jordan", "Kazakhstan", "Kenya", "Kuwait", "Kyrgyz Republic", "Laos", "Latvia", "Lebanon", "Lesotho", "Liberia", "Libya", "Liechtenstein", "Lithuania", "Luxembourg", "Macau", "Macedonia", "Madagascar", "Malawi", "Malaysia", "Maldives", "Mali", "Malta", "Mauritania", "Mauritius", "Mexico", "Moldova", "Monaco", "Mongolia", "Montenegro", "Montserrat", "Morocco", "Mozambique", "Namibia", "Nepal", "Netherlands", "Netherlands Antilles", "New Caledonia", "New Zealand", "Nicaragua", "Niger", "Nigeria", "Norway", "Oman", "Pakistan", "Palestine", "Panama", "Papua New Guinea", "Paraguay", "Peru", "Philippines", "Poland", "Portugal", "Puerto Rico", "Qatar", "Reunion", "Romania", "Russia", "Rwanda", "Saint Pierre &amp; Miquelon", "Samoa", "San Marino", "Satellite", "Saudi Arabia", "Senegal", "Serbia", "Seychelles", "Sierra Leone", "Singapore", "Slovakia", "Slovenia", "South Africa", "South Korea", "Spain", "Sri Lanka", "St Kitts &amp; Nevis", "St Lucia", "St Vincent", "St. Lucia", "Sudan", "Suriname", "Swaziland", "Sweden", "Switzerland", "Syria", "Taiwan", "Tajikistan", "Tanzania", "Thailand", "Timor L'Este", "Togo", "Tonga", "Trinidad &amp; Tobago", "Tunisia", "Turkey", "Turkmenistan", "Turks &amp; Caicos", "Uganda", "Ukraine", "United Arab Emirates", "United Kingdom", "Uruguay", "Uzbekistan", "Venezuela", "Vietnam", "Virgin Islands (US)", "Yemen", "Zambia", "Zimbabwe"}
-
 	// Fetch weather data for each country and cache it.
-	for _, location := range country_list {
-		_, err := s.FetchWeatherData(location)
+	state := RefreshProgress{Total: len(refreshedLocations)}
+	for i, location := range refreshedLocations {
+		// Log the progress now and then, since a full refresh takes a few minutes.
+		if i > 0 && i%refreshProgressInterval == 0 {
+			log.Printf("cache refresh: %d of %d locations done", i, len(refreshedLocations))
+		}
+
+		_, err := s.FetchWeatherData(context.Background(), location, WeatherOptions{})
+
+		// Report the outcome of this location to the caller.
+		state.Location = location
+		state.Done = i + 1
+		if err != nil {
+			state.Failed++
+		} else {
+			state.Succeeded++
+		}
+		s.refreshFeed.publish(state)
+		if progress != nil {
+			progress(state)
+		}
+
 		if err != nil {
 			log.Printf("Error fetching data for %s: %v", location, err)
 			continue
@@ -0,0 +1,269 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+func TestFetchWeatherDataCachesMultiWordLocations(t *testing.T) {
+	ts := newTestService(t, nil)
+
+	// The first lookup misses the cache and the second must be served from it,
+	// however the query is spelled and URL-encoded for the upstream.
+	for _, q := range []string{"new york", "New York", "  new york "} {
+		data, err := ts.FetchWeatherData(context.Background(), q, WeatherOptions{})
+		if err != nil {
+			t.Fatalf("FetchWeatherData(%q) failed: %v", q, err)
+		}
+		if data.Name != "New York" {
+			t.Errorf("FetchWeatherData(%q) returned %q, want New York", q, data.Name)
+		}
+	}
+
+	if got := ts.upstream.count(); got != 1 {
+		t.Errorf("upstream received %d requests, want 1", got)
+	}
+	if !ts.redis.Exists("weather:New York") {
+		t.Errorf("no cache entry under weather:New York; keys: %v", ts.redis.Keys())
+	}
+}
+
+func TestFetchWeatherDataCachesPostalCodes(t *testing.T) {
+	tests := []struct {
+		name      string
+		spellings []string
+		want      string
+	}{
+		{name: "US zip", spellings: []string{"90210", " 90210 "}, want: "90210"},
+		{name: "UK postcode", spellings: []string{"sw1a 1aa", "SW1A1AA", "Sw1a 1Aa"}, want: "SW1A 1AA"},
+		{name: "Canadian postal code", spellings: []string{"k1a0b1", "K1A 0B1"}, want: "K1A 0B1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts := newTestService(t, nil)
+			for _, q := range tt.spellings {
+				data, err := ts.FetchWeatherData(context.Background(), q, WeatherOptions{})
+				if err != nil {
+					t.Fatalf("FetchWeatherData(%q) failed: %v", q, err)
+				}
+				if data.MatchedNameDiffers {
+					t.Errorf("FetchWeatherData(%q) reports a differing name for a postal code", q)
+				}
+			}
+
+			// Every spelling shares the entry of the upper-cased code sent upstream
+			if got := ts.upstream.count(); got != 1 {
+				t.Errorf("upstream received %d requests, want 1", got)
+			}
+			if got := ts.upstream.lastQuery(); got != tt.want {
+				t.Errorf("upstream was asked for %q, want %q", got, tt.want)
+			}
+			if !ts.redis.Exists("weather:" + tt.want) {
+				t.Errorf("no cache entry under weather:%s; keys: %v", tt.want, ts.redis.Keys())
+			}
+		})
+	}
+}
+
+func TestFetchWeatherDataRemembersUnknownLocations(t *testing.T) {
+	ts := newTestService(t, nil)
+	ts.upstream.handle(func(w http.ResponseWriter, r *http.Request) {
+		writeUpstreamError(w, http.StatusBadRequest, 1006)
+	})
+
+	for i := 0; i < 2; i++ {
+		if _, err := ts.FetchWeatherData(context.Background(), "Atlantiss", WeatherOptions{}); !errors.Is(err, ErrNoLocationFound) {
+			t.Fatalf("lookup %d: err = %v, want ErrNoLocationFound", i+1, err)
+		}
+	}
+	if got := ts.upstream.count(); got != 1 {
+		t.Errorf("upstream received %d requests, want 1", got)
+	}
+
+	// Once the marker expires, the location is looked up again
+	ts.advance(ts.cfg.NegativeCacheTTL + time.Second)
+	if _, err := ts.FetchWeatherData(context.Background(), "Atlantiss", WeatherOptions{}); !errors.Is(err, ErrNoLocationFound) {
+		t.Fatalf("lookup after expiry: err = %v, want ErrNoLocationFound", err)
+	}
+	if got := ts.upstream.count(); got != 2 {
+		t.Errorf("upstream received %d requests after the marker expired, want 2", got)
+	}
+}
+
+func TestFetchWeatherDataRejectsOutOfRangeCoordinates(t *testing.T) {
+	ts := newTestService(t, nil)
+
+	for _, q := range []string{"91,0", "0,-180.5", "999,999"} {
+		if _, err := ts.FetchWeatherData(context.Background(), q, WeatherOptions{}); !errors.Is(err, ErrInvalidCoordinates) {
+			t.Errorf("FetchWeatherData(%q) error = %v, want ErrInvalidCoordinates", q, err)
+		}
+	}
+	if got := ts.upstream.count(); got != 0 {
+		t.Errorf("upstream received %d requests for invalid coordinates, want 0", got)
+	}
+
+	// The boundaries themselves are valid and reach the upstream
+	if _, err := ts.FetchWeatherData(context.Background(), "-90,180", WeatherOptions{}); err != nil {
+		t.Fatalf("FetchWeatherData(-90,180) failed: %v", err)
+	}
+	if got := ts.upstream.lastQuery(); got != "-90,180" {
+		t.Errorf("upstream query = %q, want -90,180", got)
+	}
+}
+
+func TestAPIKeyAuthorizationForgetsDisabledKeysWithinTTL(t *testing.T) {
+	ts := newTestService(t, nil)
+
+	for i := 0; i < 2; i++ {
+		if _, err := ts.APIKeyAuthorization(context.Background(), "valid-key"); err != nil {
+			t.Fatalf("APIKeyAuthorization %d failed: %v", i+1, err)
+		}
+	}
+	if ts.db.lookups != 1 {
+		t.Errorf("database served %d lookups, want 1", ts.db.lookups)
+	}
+
+	// A key disabled without invalidating its cache entry keeps working until the entry expires, and no longer
+	ts.db.remove("valid-key")
+	if _, err := ts.APIKeyAuthorization(context.Background(), "valid-key"); err != nil {
+		t.Fatalf("APIKeyAuthorization within the TTL failed: %v", err)
+	}
+	ts.advance(validAPIKeyCacheTTL + time.Second)
+	if _, err := ts.APIKeyAuthorization(context.Background(), "valid-key"); !errors.Is(err, ErrAPIKeyNotFound) {
+		t.Errorf("APIKeyAuthorization after the TTL: err = %v, want ErrAPIKeyNotFound", err)
+	}
+}
+
+func TestInvalidateAPIKeyStopsDisabledKeysAtOnce(t *testing.T) {
+	ts := newTestService(t, nil)
+	if _, err := ts.APIKeyAuthorization(context.Background(), "valid-key"); err != nil {
+		t.Fatalf("APIKeyAuthorization failed: %v", err)
+	}
+
+	ts.db.remove("valid-key")
+	if err := ts.InvalidateAPIKey("valid-key"); err != nil {
+		t.Fatalf("InvalidateAPIKey failed: %v", err)
+	}
+	if _, err := ts.APIKeyAuthorization(context.Background(), "valid-key"); !errors.Is(err, ErrAPIKeyNotFound) {
+		t.Errorf("APIKeyAuthorization after invalidation: err = %v, want ErrAPIKeyNotFound", err)
+	}
+}
+
+func TestFetchWeatherDataMapsUpstreamErrorCodes(t *testing.T) {
+	// The statuses WeatherAPI documents for each error code
+	tests := []struct {
+		code   int
+		status int
+		want   error
+	}{
+		{1002, http.StatusUnauthorized, ErrUpstreamUnauthorized},
+		{1003, http.StatusBadRequest, ErrUpstreamMissingQuery},
+		{1005, http.StatusBadRequest, ErrUpstreamInvalidRequest},
+		{1006, http.StatusBadRequest, ErrNoLocationFound},
+		{2006, http.StatusUnauthorized, ErrUpstreamUnauthorized},
+		{2007, http.StatusForbidden, ErrUpstreamQuotaExceeded},
+		{2008, http.StatusForbidden, ErrUpstreamKeyDisabled},
+		{9999, http.StatusBadRequest, ErrUpstreamInternal},
+	}
+	for _, tt := range tests {
+		t.Run(strconv.Itoa(tt.code), func(t *testing.T) {
+			ts := newTestService(t, nil)
+			ts.upstream.handle(func(w http.ResponseWriter, r *http.Request) {
+				writeUpstreamError(w, tt.status, tt.code)
+			})
+
+			_, err := ts.FetchWeatherData(context.Background(), "London", WeatherOptions{})
+			if !errors.Is(err, tt.want) {
+				t.Errorf("FetchWeatherData() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+// failingWrites is a Redis hook failing every SET, alone or in a pipeline, while letting reads through.
+type failingWrites struct{}
+
+func (failingWrites) DialHook(next redis.DialHook) redis.DialHook { return next }
+
+func (failingWrites) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		if cmd.Name() == "set" {
+			return errors.New("simulated write failure")
+		}
+		return next(ctx, cmd)
+	}
+}
+
+func (failingWrites) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []redis.Cmder) error {
+		for _, cmd := range cmds {
+			if cmd.Name() == "set" {
+				return errors.New("simulated write failure")
+			}
+		}
+		return next(ctx, cmds)
+	}
+}
+
+func TestFetchWeatherDataServesFreshDataWhenCachingFails(t *testing.T) {
+	ts := newTestService(t, nil)
+	ts.redisClient.AddHook(failingWrites{})
+
+	data, err := ts.FetchWeatherData(context.Background(), "London", WeatherOptions{})
+	if err != nil {
+		t.Fatalf("FetchWeatherData failed: %v", err)
+	}
+	if data.Name != "London" || data.TempC != 20 {
+		t.Errorf("FetchWeatherData returned %+v, want the fresh London data", data)
+	}
+
+	// Nothing was stored, so the data has no content hash to revalidate against
+	if data.ContentHash != "" {
+		t.Errorf("ContentHash = %q, want none for uncached data", data.ContentHash)
+	}
+	if ts.redis.Exists("weather:London") {
+		t.Error("weather:London was cached despite the failing writes")
+	}
+}
+
+func TestSearchLocationsEscapesTheQuery(t *testing.T) {
+	ts := newTestService(t, nil)
+	var params map[string][]string
+	ts.upstream.handle(func(w http.ResponseWriter, r *http.Request) {
+		params = r.URL.Query()
+		w.Write([]byte(`[{"name": "Paris", "country": "France"}]`))
+	})
+
+	candidates, err := ts.SearchLocations(context.Background(), "Paris&Lang=Xx#Fragment")
+	if err != nil {
+		t.Fatalf("SearchLocations failed: %v", err)
+	}
+	if len(candidates) != 1 || candidates[0].Name != "Paris" {
+		t.Errorf("candidates = %+v, want Paris", candidates)
+	}
+	if got := params["q"]; len(got) != 1 || got[0] != "Paris&Lang=Xx#Fragment" {
+		t.Errorf("upstream received q = %q, want the query as sent", got)
+	}
+	if _, ok := params["Lang"]; ok {
+		t.Errorf("the query added a Lang parameter to the upstream request: %v", params)
+	}
+}
+
+func TestSearchLocationsStopsWithTheContext(t *testing.T) {
+	ts := newTestService(t, nil)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, err := ts.SearchLocations(ctx, "Springfield"); err == nil {
+		t.Fatal("SearchLocations succeeded after its context was canceled")
+	}
+	if got := ts.upstream.count(); got != 0 {
+		t.Errorf("upstream received %d requests, want none", got)
+	}
+}
@@ -0,0 +1,62 @@
+// Package tracing sets up OpenTelemetry tracing of the requests served by the application.
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"havoAPI/internal/version"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// serviceName identifies the application's spans in the tracing backend.
+const serviceName = "havoAPI"
+
+// Setup installs the W3C trace context propagator and, if an OTLP endpoint is configured, a tracer provider
+// exporting spans to it over OTLP/HTTP. The exporter reads the endpoint and its other settings
+// (e.g. OTEL_EXPORTER_OTLP_HEADERS) from the standard OTEL_EXPORTER_OTLP_* environment variables.
+// Without an endpoint, spans are not recorded, but the trace context of incoming requests is still passed on
+// to WeatherAPI. It returns a function flushing the buffered spans on shutdown.
+func Setup(ctx context.Context, endpoint string) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	if endpoint == "" {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	exporter, err := otlptracehttp.New(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create the OTLP trace exporter: %w", err)
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(resource.NewWithAttributes(semconv.SchemaURL,
+			semconv.ServiceName(serviceName),
+			semconv.ServiceVersion(version.Version),
+		)),
+	)
+	otel.SetTracerProvider(provider)
+	return provider.Shutdown, nil
+}
+
+// Tracer returns the tracer of the application. It is a no-op until Setup installs an exporting provider.
+func Tracer() trace.Tracer {
+	return otel.Tracer(serviceName)
+}
+
+// End ends a span, marking it as failed with err unless err is nil.
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
@@ -0,0 +1,50 @@
+// Package units defines the unit systems of weather responses and the pure conversions between them.
+// It is shared by the HTTP layer, which validates the 'units' request option, and the services,
+// which apply it, so that neither has to import the other for it.
+package units
+
+import "math"
+
+// Supported values of the 'units' request option.
+const (
+	Metric = "metric" // Metric returns metric values only (the default).
+	Both   = "both"   // Both returns metric values together with derived imperial values.
+	Kelvin = "kelvin" // Kelvin returns metric values together with the temperature in Kelvin.
+)
+
+// Valid reports whether units is a supported value of the 'units' request option.
+func Valid(units string) bool {
+	switch units {
+	case Metric, Both, Kelvin:
+		return true
+	default:
+		return false
+	}
+}
+
+// CelsiusToFahrenheit converts a temperature from degrees Celsius to degrees Fahrenheit,
+// rounded to one decimal place like the upstream values.
+func CelsiusToFahrenheit(tempC float64) float64 {
+	return roundToOneDecimal(tempC*9/5 + 32)
+}
+
+// AbsoluteZeroC is absolute zero (0 K) in degrees Celsius.
+const AbsoluteZeroC = -273.15
+
+// CelsiusToKelvin converts a temperature from degrees Celsius to Kelvin.
+// The offset has two decimals, so the result is rounded to two decimal places to keep it exact
+// (e.g. 20.5 °C is 293.65 K), and it is never below absolute zero.
+func CelsiusToKelvin(tempC float64) float64 {
+	return max(math.Round((tempC-AbsoluteZeroC)*100)/100, 0)
+}
+
+// KphToMph converts a speed from kilometers per hour to miles per hour,
+// rounded to one decimal place like the upstream values.
+func KphToMph(kph float64) float64 {
+	return roundToOneDecimal(kph / 1.609344)
+}
+
+// roundToOneDecimal rounds a value to one decimal place.
+func roundToOneDecimal(value float64) float64 {
+	return math.Round(value*10) / 10
+}
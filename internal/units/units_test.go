@@ -0,0 +1,79 @@
+package units
+
+import (
+	"math"
+	"testing"
+)
+
+func TestValid(t *testing.T) {
+	for _, system := range []string{Metric, Both, Kelvin} {
+		if !Valid(system) {
+			t.Errorf("Valid(%q) = false, want true", system)
+		}
+	}
+	for _, system := range []string{"", "imperial", "Metric", "kelvin "} {
+		if Valid(system) {
+			t.Errorf("Valid(%q) = true, want false", system)
+		}
+	}
+}
+
+func TestCelsiusToFahrenheit(t *testing.T) {
+	tests := []struct {
+		tempC, want float64
+	}{
+		{0, 32},
+		{100, 212},
+		{-40, -40},
+		{21.3, 70.3},
+	}
+	for _, tt := range tests {
+		if got := CelsiusToFahrenheit(tt.tempC); got != tt.want {
+			t.Errorf("CelsiusToFahrenheit(%v) = %v, want %v", tt.tempC, got, tt.want)
+		}
+	}
+}
+
+func TestCelsiusToKelvin(t *testing.T) {
+	tests := []struct {
+		name        string
+		tempC, want float64
+	}{
+		{name: "freezing point", tempC: 0, want: 273.15},
+		{name: "boiling point", tempC: 100, want: 373.15},
+		{name: "one decimal", tempC: 20.5, want: 293.65},
+		{name: "negative", tempC: -40, want: 233.15},
+		{name: "absolute zero", tempC: AbsoluteZeroC, want: 0},
+		{name: "just above absolute zero", tempC: -273.14, want: 0.01},
+		{name: "just below absolute zero", tempC: -273.16, want: 0},
+		{name: "far below absolute zero", tempC: -1000, want: 0},
+		{name: "float noise", tempC: 0.1 + 0.2, want: 273.45},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := CelsiusToKelvin(tt.tempC)
+			if got != tt.want {
+				t.Errorf("CelsiusToKelvin(%v) = %v, want %v", tt.tempC, got, tt.want)
+			}
+			// Clamped values must not render as "-0"
+			if math.Signbit(got) {
+				t.Errorf("CelsiusToKelvin(%v) = %v, want a non-negative value", tt.tempC, got)
+			}
+		})
+	}
+}
+
+func TestKphToMph(t *testing.T) {
+	tests := []struct {
+		kph, want float64
+	}{
+		{0, 0},
+		{1.609344, 1},
+		{100, 62.1},
+	}
+	for _, tt := range tests {
+		if got := KphToMph(tt.kph); got != tt.want {
+			t.Errorf("KphToMph(%v) = %v, want %v", tt.kph, got, tt.want)
+		}
+	}
+}
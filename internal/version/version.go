@@ -0,0 +1,12 @@
+// Package version reports the version of the application build.
+package version
+
+// Version is the version of the running build. It is "dev" for local builds and is set at release time with
+//
+//	go build -ldflags "-X havoAPI/internal/version.Version=1.4.0" ./cmd/havoAPI
+var Version = "dev"
+
+// UserAgent returns the User-Agent the application identifies itself with to upstream services.
+func UserAgent() string {
+	return "obhavoAPI/" + Version
+}
@@ -0,0 +1 @@
+ALTER TABLE api_keys DROP INDEX idx_user_id_unique;
@@ -0,0 +1 @@
+ALTER TABLE api_keys ADD UNIQUE INDEX idx_user_id_unique (user_id);
@@ -0,0 +1 @@
+ALTER TABLE users ADD COLUMN tokens_valid_after TIMESTAMP NULL DEFAULT NULL;
@@ -0,0 +1,28 @@
+CREATE TABLE thresholds (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    user_id INT NOT NULL,
+    location VARCHAR(255) NOT NULL,
+    metric VARCHAR(32) NOT NULL,
+    operator VARCHAR(2) NOT NULL,
+    value DOUBLE NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+ALTER TABLE thresholds ADD INDEX idx_thresholds_user_id (user_id);
+
+CREATE TABLE alerts (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    threshold_id INT NOT NULL,
+    user_id INT NOT NULL,
+    location VARCHAR(255) NOT NULL,
+    metric VARCHAR(32) NOT NULL,
+    operator VARCHAR(2) NOT NULL,
+    threshold_value DOUBLE NOT NULL,
+    observed_value DOUBLE NOT NULL,
+    triggered_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (threshold_id) REFERENCES thresholds(id) ON DELETE CASCADE,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+ALTER TABLE alerts ADD INDEX idx_alerts_user_id_triggered_at (user_id, triggered_at);
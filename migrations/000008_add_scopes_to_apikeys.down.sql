@@ -0,0 +1,6 @@
+-- The unique index allows a single key per user, so every user keeps only their oldest key (the signup key).
+-- Their additional keys, and the usage recorded for them, are deleted.
+DELETE newer FROM api_keys newer
+    JOIN api_keys older ON older.user_id = newer.user_id AND older.id < newer.id;
+ALTER TABLE api_keys DROP COLUMN scopes;
+ALTER TABLE api_keys ADD UNIQUE INDEX idx_user_id_unique (user_id);
@@ -0,0 +1,2 @@
+ALTER TABLE api_keys DROP INDEX idx_user_id_unique;
+ALTER TABLE api_keys ADD COLUMN scopes VARCHAR(255) NULL;
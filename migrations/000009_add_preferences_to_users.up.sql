@@ -0,0 +1,2 @@
+ALTER TABLE users ADD COLUMN preferred_units VARCHAR(16) NULL DEFAULT NULL;
+ALTER TABLE users ADD COLUMN preferred_lang VARCHAR(8) NULL DEFAULT NULL;
@@ -0,0 +1 @@
+ALTER TABLE thresholds DROP COLUMN triggered;
@@ -0,0 +1 @@
+ALTER TABLE thresholds ADD COLUMN triggered BOOLEAN NOT NULL DEFAULT FALSE;